@@ -0,0 +1,123 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AgentPoolSpec defines the desired state of AgentPool, the policy by which worker agents are
+// created for queued migrations and deleted once they are idle
+// +kubebuilder:validation:XValidation:rule="!has(self.minAgents) || self.minAgents <= self.maxAgents",message="minAgents must not be greater than maxAgents"
+type AgentPoolSpec struct {
+	// MinAgents is the number of worker agents the pool keeps even when no migrations run
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinAgents int `json:"minAgents,omitempty"`
+
+	// MaxAgents is the largest number of worker agents the pool creates
+	// +kubebuilder:validation:Minimum=1
+	MaxAgents int `json:"maxAgents"`
+
+	// OpenstackFlavorID is the flavor of the agent VMs
+	OpenstackFlavorID string `json:"openstackFlavorID"`
+
+	// TargetMigrationsPerAgent is the number of migrations an agent is sized for. Agents are added when the
+	// running and queued migrations exceed what the ready agents are sized for.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=2
+	// +optional
+	TargetMigrationsPerAgent int `json:"targetMigrationsPerAgent,omitempty"`
+
+	// ScaleDownCooldown is how long an agent of the pool must run no migrations before it is drained and deleted
+	// +kubebuilder:default="15m"
+	// +optional
+	ScaleDownCooldown metav1.Duration `json:"scaleDownCooldown,omitempty"`
+}
+
+// AgentPoolAgent is the observed state of a worker agent of an AgentPool
+type AgentPoolAgent struct {
+	// Name is the name of the StellarisMigrateNode of the agent
+	Name string `json:"name"`
+
+	// Phase is the phase of the StellarisMigrateNode of the agent
+	Phase StellarisMigrateNodePhase `json:"phase,omitempty"`
+
+	// ActiveMigrations is the number of migrations placed on the agent
+	ActiveMigrations int `json:"activeMigrations,omitempty"`
+
+	// IdleSince is when the agent last stopped running migrations
+	IdleSince *metav1.Time `json:"idleSince,omitempty"`
+
+	// Draining is true once the agent is cordoned to be deleted
+	Draining bool `json:"draining,omitempty"`
+}
+
+// AgentPoolStatus defines the observed state of AgentPool
+type AgentPoolStatus struct {
+	// DesiredAgents is the number of worker agents the pool scales to
+	DesiredAgents int `json:"desiredAgents,omitempty"`
+
+	// ReadyAgents is the number of worker agents of the pool that can run migrations
+	ReadyAgents int `json:"readyAgents,omitempty"`
+
+	// ActiveMigrations is the number of migrations placed on any agent
+	ActiveMigrations int `json:"activeMigrations,omitempty"`
+
+	// QueuedMigrations is the number of migrations waiting for a free agent
+	QueuedMigrations int `json:"queuedMigrations,omitempty"`
+
+	// Agents are the worker agents created by the pool
+	Agents []AgentPoolAgent `json:"agents,omitempty"`
+
+	// LastScaleTime is when the pool last created or drained an agent
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=`.spec.minAgents`,name=Min,type=integer
+// +kubebuilder:printcolumn:JSONPath=`.spec.maxAgents`,name=Max,type=integer
+// +kubebuilder:printcolumn:JSONPath=`.status.desiredAgents`,name=Desired,type=integer
+// +kubebuilder:printcolumn:JSONPath=`.status.readyAgents`,name=Ready,type=integer
+// +kubebuilder:printcolumn:JSONPath=`.status.queuedMigrations`,name=Queued,type=integer
+
+// AgentPool is the Schema for the agentpools API that scales the worker agents running migrations
+// with the number of running and queued migrations
+type AgentPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec defines the desired state of AgentPool
+	Spec AgentPoolSpec `json:"spec,omitempty"`
+
+	// Status defines the observed state of AgentPool
+	Status AgentPoolStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AgentPoolList contains a list of AgentPool
+type AgentPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentPool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentPool{}, &AgentPoolList{})
+}
@@ -25,7 +25,7 @@ import (
 // tracking the detailed progression through various stages including validation, data copying,
 // disk conversion, and cutover. Each phase provides visibility into the migration's progress,
 // enabling precise monitoring and troubleshooting of the migration workflow.
-// +kubebuilder:validation:Enum=Pending;Validating;AwaitingDataCopyStart;CopyingBlocks;CopyingChangedBlocks;ConvertingDisk;AwaitingCutOverStartTime;AwaitingAdminCutOver;Succeeded;Failed;Unknown
+// +kubebuilder:validation:Enum=Pending;Validating;AwaitingDataCopyStart;CopyingBlocks;CopyingChangedBlocks;ConvertingDisk;AwaitingCutOverStartTime;AwaitingAdminCutOver;Succeeded;Failed;Unknown;RollingBack;RolledBack
 type VMMigrationPhase string
 
 // MigrationConditionType represents the type of condition for a migration, used to track
@@ -56,6 +56,10 @@ const (
 	VMMigrationPhaseFailed VMMigrationPhase = "Failed"
 	// VMMigrationPhaseUnknown indicates the migration state is unknown
 	VMMigrationPhaseUnknown VMMigrationPhase = "Unknown"
+	// VMMigrationPhaseRollingBack indicates a completed cutover is being reverted to VMware
+	VMMigrationPhaseRollingBack VMMigrationPhase = "RollingBack"
+	// VMMigrationPhaseRolledBack indicates the cutover was reverted and the source VM runs in VMware again
+	VMMigrationPhaseRolledBack VMMigrationPhase = "RolledBack"
 )
 
 // MigrationSpec defines the desired state of Migration
@@ -76,6 +80,33 @@ type MigrationSpec struct {
 	// after a successful migration to prevent network conflicts. Defaults to false.
 	// +optional
 	DisconnectSourceNetwork bool `json:"disconnectSourceNetwork,omitempty"`
+
+	// Rollback reverts a completed cutover. The OpenStack server, ports and volumes created by the
+	// migration are deleted and the source VM is restored and powered on again.
+	// +optional
+	Rollback bool `json:"rollback,omitempty"`
+
+	// RollbackOnHealthCheckFailure rolls the migration back if the health check of the target VM fails
+	// +optional
+	RollbackOnHealthCheckFailure bool `json:"rollbackOnHealthCheckFailure,omitempty"`
+}
+
+// MigrationTargetResources are the OpenStack resources created by a migration
+type MigrationTargetResources struct {
+	// ServerID is the ID of the OpenStack server
+	ServerID string `json:"serverID,omitempty"`
+	// PortIDs are the IDs of the ports created for the server. Ports given in the migration plan are not included.
+	PortIDs []string `json:"portIDs,omitempty"`
+	// VolumeIDs are the IDs of the volumes the disks were copied to
+	VolumeIDs []string `json:"volumeIDs,omitempty"`
+}
+
+// SourceVMChanges are the changes made to the source VM by the post-migration actions
+type SourceVMChanges struct {
+	// RenamedTo is the name the source VM was renamed to
+	RenamedTo string `json:"renamedTo,omitempty"`
+	// OriginalFolder is the inventory path of the folder the source VM was moved out of
+	OriginalFolder string `json:"originalFolder,omitempty"`
 }
 
 // MigrationStatus defines the observed state of Migration
@@ -88,6 +119,143 @@ type MigrationStatus struct {
 
 	// AgentName is the name of the agent where migration is running
 	AgentName string `json:"agentName,omitempty"`
+
+	// TargetResources are the OpenStack resources created by the migration
+	TargetResources *MigrationTargetResources `json:"targetResources,omitempty"`
+
+	// SourceVMChanges are the changes made to the source VM after the migration
+	SourceVMChanges *SourceVMChanges `json:"sourceVMChanges,omitempty"`
+
+	// Progress is the progress reported by the v2v-helper pod of the migration
+	Progress *MigrationProgress `json:"progress,omitempty"`
+
+	// Connections are the vCenter session and ESXi NFC connections the v2v-helper pod of the migration opens
+	Connections *MigrationConnections `json:"connections,omitempty"`
+
+	// TestBoot is the result of the test boot of the migrated VM on an isolated network
+	TestBoot *TestBootResult `json:"testBoot,omitempty"`
+
+	// Snapshots are the snapshots of the source VM the disks were synced from, one per copy iteration
+	Snapshots []MigrationSnapshot `json:"snapshots,omitempty"`
+
+	// VolumeExtensions are the volumes extended because their source disk grew during the migration
+	VolumeExtensions []VolumeExtension `json:"volumeExtensions,omitempty"`
+}
+
+// TestBootResult is the result of the test boot of a migrated VM published by the v2v-helper
+type TestBootResult struct {
+	// Succeeded is true if the converted copy of the VM booted and passed all health checks
+	Succeeded bool `json:"succeeded"`
+
+	// Checks are the results of the steps and health checks of the test boot, in the order they ran
+	Checks []PreflightCheck `json:"checks,omitempty"`
+
+	// CompletionTime is when the test boot finished and its resources were deleted
+	CompletionTime metav1.Time `json:"completionTime"`
+}
+
+// SnapshotMode is how a snapshot of the source VM was taken
+type SnapshotMode string
+
+const (
+	// SnapshotModeQuiesced means VMware Tools quiesced the guest before the snapshot was taken
+	SnapshotModeQuiesced SnapshotMode = "Quiesced"
+	// SnapshotModeCrashConsistent means the snapshot was taken without quiescing the running guest
+	SnapshotModeCrashConsistent SnapshotMode = "CrashConsistent"
+	// SnapshotModePoweredOff means the snapshot was taken with the source VM powered off
+	SnapshotModePoweredOff SnapshotMode = "PoweredOff"
+)
+
+// MigrationSnapshot is a snapshot of the source VM taken for a copy iteration, published by the v2v-helper
+type MigrationSnapshot struct {
+	// Iteration is the copy iteration synced from the snapshot, 0 for the full copy of the disks
+	Iteration int `json:"iteration"`
+
+	// Mode is how the snapshot was taken
+	Mode SnapshotMode `json:"mode"`
+
+	// Message is why a quiesced snapshot fell back to a crash-consistent one
+	Message string `json:"message,omitempty"`
+
+	// CreationTime is when the snapshot was taken
+	CreationTime metav1.Time `json:"creationTime"`
+}
+
+// VolumeExtension is the extension of a volume whose source disk grew during the migration, published by the
+// v2v-helper
+type VolumeExtension struct {
+	// Disk is the name of the source disk
+	Disk string `json:"disk"`
+
+	// VolumeID is the ID of the extended volume
+	VolumeID string `json:"volumeID"`
+
+	// Iteration is the copy iteration in which the growth of the disk was detected
+	Iteration int `json:"iteration"`
+
+	// OldSizeGB is the size of the volume before the extension
+	OldSizeGB int `json:"oldSizeGB"`
+
+	// NewSizeGB is the size of the volume after the extension
+	NewSizeGB int `json:"newSizeGB"`
+
+	// ExtensionTime is when the volume was extended
+	ExtensionTime metav1.Time `json:"extensionTime"`
+}
+
+// MigrationConnections are the connections to VMware a migration holds, counted against the connection budgets
+// of the vCenter and of the ESXi host
+type MigrationConnections struct {
+	// VCenter is the host of the vCenter the v2v-helper opens a session with
+	VCenter string `json:"vCenter"`
+
+	// ESXiHost is the ESXi host the disks of the VM are read from
+	ESXiHost string `json:"esxiHost,omitempty"`
+
+	// NFCConnections is the number of NFC connections opened on the ESXi host, one per disk
+	NFCConnections int `json:"nfcConnections,omitempty"`
+}
+
+// MigrationProgress is the typed progress of a migration published by the v2v-helper
+type MigrationProgress struct {
+	// Phase is the phase the v2v-helper is in
+	Phase VMMigrationPhase `json:"phase,omitempty"`
+
+	// Iteration is the changed block copy iteration, 0 during the full copy of the disks
+	Iteration int `json:"iteration,omitempty"`
+
+	// BytesDone is the number of bytes copied in the current iteration across all disks
+	BytesDone int64 `json:"bytesDone,omitempty"`
+
+	// BytesTotal is the number of bytes to copy in the current iteration across all disks
+	BytesTotal int64 `json:"bytesTotal,omitempty"`
+
+	// ETA is the estimated time at which the copy of the current iteration completes
+	ETA *metav1.Time `json:"eta,omitempty"`
+
+	// Disks is the copy progress of each disk in the current iteration
+	Disks []DiskProgress `json:"disks,omitempty"`
+
+	// LastUpdateTime is the time the v2v-helper last published the progress
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// DiskProgress is the copy progress of a disk in the current iteration
+type DiskProgress struct {
+	// Index is the index of the disk in the VM
+	Index int `json:"index"`
+
+	// BytesDone is the number of bytes of the disk copied
+	BytesDone int64 `json:"bytesDone,omitempty"`
+
+	// BytesTotal is the number of bytes of the disk to copy
+	BytesTotal int64 `json:"bytesTotal,omitempty"`
+
+	// BytesPerSecond is the average copy throughput of the disk
+	BytesPerSecond int64 `json:"bytesPerSecond,omitempty"`
+
+	// ETA is the estimated time at which the copy of the disk completes
+	ETA *metav1.Time `json:"eta,omitempty"`
 }
 
 // +kubebuilder:object:root=true
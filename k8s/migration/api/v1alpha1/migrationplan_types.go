@@ -24,8 +24,10 @@ import (
 // MigrationPlanStrategy defines the strategy for executing a migration plan including
 // scheduling options and migration type (hot or cold)
 type MigrationPlanStrategy struct {
+	// Type is the migration method. Defaults to the DEFAULT_MIGRATION_METHOD of the stellaris-migrate settings.
 	// +kubebuilder:validation:Enum=hot;cold
-	Type string `json:"type"`
+	// +optional
+	Type string `json:"type,omitempty"`
 	// +kubebuilder:validation:Type=string
 	// +kubebuilder:validation:Format:=date-time
 	DataCopyStart metav1.Time `json:"dataCopyStart,omitempty"`
@@ -35,6 +37,16 @@ type MigrationPlanStrategy struct {
 	// +kubebuilder:validation:Type=string
 	// +kubebuilder:validation:Format:=date-time
 	VMCutoverEnd metav1.Time `json:"vmCutoverEnd,omitempty"`
+	// CutoverWindow is the recurring maintenance window in which VMs are cut over. A VM whose final sync and
+	// instance creation are not expected to finish before the window closes keeps syncing changed blocks and is
+	// cut over in a later window. Cutovers initiated by the admin are not held to the window.
+	// +optional
+	CutoverWindow *CutoverWindow `json:"cutoverWindow,omitempty"`
+	// CutoverFinalizeDuration is the time kept free before the end of the cutover window or VMCutoverEnd to convert
+	// the disks and create the instance after the final sync
+	// +kubebuilder:default:="30m"
+	// +optional
+	CutoverFinalizeDuration metav1.Duration `json:"cutoverFinalizeDuration,omitempty"`
 	// +kubebuilder:default:=false
 	AdminInitiatedCutOver bool `json:"adminInitiatedCutOver,omitempty"`
 	// +kubebuilder:default:=false
@@ -43,8 +55,94 @@ type MigrationPlanStrategy struct {
 	HealthCheckPort string `json:"healthCheckPort,omitempty"`
 	// +kubebuilder:default:=false
 	DisconnectSourceNetwork bool `json:"disconnectSourceNetwork,omitempty"`
+	// RollbackOnHealthCheckFailure rolls a migration back to VMware if the health check of the target VM fails
+	// +kubebuilder:default:=false
+	RollbackOnHealthCheckFailure bool `json:"rollbackOnHealthCheckFailure,omitempty"`
+	// BandwidthLimitMbps caps the rate at which the disks of each VM are read from VMware, in megabits per second.
+	// 0 means no limit.
+	// +kubebuilder:validation:Minimum=0
+	BandwidthLimitMbps int `json:"bandwidthLimitMbps,omitempty"`
+	// CopyWindow is the daily time window in which changed blocks are synced.
+	// Outside the window the sync is paused until the window opens again, a sync still running when the window
+	// closes is stopped and runs again once it opens.
+	CopyWindow *CopyWindow `json:"copyWindow,omitempty"`
+	// DataVerification compares the copied volumes with the source disks before they are converted.
+	// sampled checks evenly spread ranges of each disk, full checks every byte.
+	// +kubebuilder:validation:Enum=none;sampled;full
+	DataVerification string `json:"dataVerification,omitempty"`
+	// VTPMPolicy decides how VMs with a virtual TPM are migrated. refuse fails their migration,
+	// recreate boots them with an emulated TPM 2.0. The keys sealed to the source TPM are not carried over.
+	// +kubebuilder:validation:Enum=refuse;recreate
+	// +kubebuilder:default:=refuse
+	VTPMPolicy string `json:"vtpmPolicy,omitempty"`
+	// ReconfigureGuestNetwork writes a static network configuration for each NIC into the guest during
+	// conversion, instead of switching the guest to DHCP. The address, prefix, gateway and DNS come from
+	// the guest networks of the VMwareMachine, or from the target subnet for NICs with a fixed IP override.
+	// +kubebuilder:default:=false
+	ReconfigureGuestNetwork bool `json:"reconfigureGuestNetwork,omitempty"`
+	// TestBoot boots a converted copy of each VM on an isolated network once its disks are copied, while the source
+	// VM keeps running, and records in the Migration whether it booted and passed the health checks. It only runs
+	// for hot migrations.
+	// +optional
+	TestBoot *TestBoot `json:"testBoot,omitempty"`
+	// SnapshotConsistency decides how the snapshots the disks are synced from are taken while the VM runs.
+	// quiesced has VMware Tools quiesce the guest first, calling VSS writers on Windows and the pre-freeze and
+	// post-thaw scripts on Linux. A quiesced snapshot that fails or times out falls back to a crash-consistent one.
+	// +kubebuilder:validation:Enum=crashConsistent;quiesced
+	// +kubebuilder:default:=crashConsistent
+	SnapshotConsistency string `json:"snapshotConsistency,omitempty"`
+	// QuiesceTimeout is how long a quiesced snapshot may take before it falls back to a crash-consistent one
+	// +kubebuilder:default:="5m"
+	// +optional
+	QuiesceTimeout metav1.Duration `json:"quiesceTimeout,omitempty"`
+}
+
+// TestBoot configures the test boot of a migrated VM before its cutover
+type TestBoot struct {
+	// CIDR is the CIDR of the subnet of the isolated network. Defaults to a random /24 of 100.64.0.0/10, so that
+	// test boots running on the same agent do not share a subnet.
+	// +optional
+	CIDR string `json:"cidr,omitempty"`
+	// Timeout is how long the converted copy may take to boot and pass the health checks
+	// +kubebuilder:default:="30m"
+	// +optional
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+}
+
+// CopyWindow defines a daily time window for copying data
+type CopyWindow struct {
+	// Start is the time of day the window opens, in HH:MM format
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+	// End is the time of day the window closes, in HH:MM format.
+	// A window ending before it starts spans midnight.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+	// TimeZone is the IANA time zone of Start and End, for example Europe/Berlin. Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
+// CutoverWindow defines a recurring time window in which VMs are cut over
+type CutoverWindow struct {
+	// Start is the time of day the window opens, in HH:MM format
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+	// End is the time of day the window closes, in HH:MM format.
+	// A window ending before it starts spans midnight.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+	// TimeZone is the IANA time zone of Start and End, for example Europe/Berlin. Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+	// Days are the days of the week on which the window opens. Defaults to every day.
+	// +listType=set
+	// +optional
+	Days []CutoverWindowDay `json:"days,omitempty"`
+}
+
+// CutoverWindowDay is a day of the week on which a cutover window opens
+// +kubebuilder:validation:Enum=Mon;Tue;Wed;Thu;Fri;Sat;Sun
+type CutoverWindowDay string
+
 // AdvancedOptions defines advanced configuration options for the migration process
 // including granular selection of volumes, networks, and ports
 type AdvancedOptions struct {
@@ -64,6 +162,126 @@ type PostMigrationAction struct {
 	FolderName   string `json:"folderName,omitempty"`
 }
 
+// MigrationHookPoint is the point of the cutover at which a hook runs
+// +kubebuilder:validation:Enum=PreFinalSnapshot;PostSourcePowerOff;PostTargetBoot;PostHealthCheck
+type MigrationHookPoint string
+
+const (
+	// MigrationHookPreFinalSnapshot runs before the source VM is powered off and its final snapshot is taken
+	MigrationHookPreFinalSnapshot MigrationHookPoint = "PreFinalSnapshot"
+	// MigrationHookPostSourcePowerOff runs after the source VM is powered off
+	MigrationHookPostSourcePowerOff MigrationHookPoint = "PostSourcePowerOff"
+	// MigrationHookPostTargetBoot runs after the OpenStack instance is active
+	MigrationHookPostTargetBoot MigrationHookPoint = "PostTargetBoot"
+	// MigrationHookPostHealthCheck runs after the health check of the OpenStack instance passed
+	MigrationHookPostHealthCheck MigrationHookPoint = "PostHealthCheck"
+)
+
+// MigrationHookFailurePolicy is what happens to the migration when a hook fails
+// +kubebuilder:validation:Enum=Fail;Ignore
+type MigrationHookFailurePolicy string
+
+const (
+	// MigrationHookFailurePolicyFail fails the migration, a hook failing before the source VM is powered off
+	// leaves the source VM running
+	MigrationHookFailurePolicyFail MigrationHookFailurePolicy = "Fail"
+	// MigrationHookFailurePolicyIgnore records the failure and carries on with the migration
+	MigrationHookFailurePolicyIgnore MigrationHookFailurePolicy = "Ignore"
+)
+
+// MigrationHook is an action run at a point of the cutover of a VM. Exactly one of Job, HTTP and GuestCommand is set.
+type MigrationHook struct {
+	// Name identifies the hook in the conditions of the migration
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+	// Point is the point of the cutover at which the hook runs
+	Point MigrationHookPoint `json:"point"`
+	// Job runs a container image as a Kubernetes Job
+	// +optional
+	Job *MigrationHookJob `json:"job,omitempty"`
+	// HTTP calls a webhook
+	// +optional
+	HTTP *MigrationHookHTTP `json:"http,omitempty"`
+	// GuestCommand runs a command in the source VM through VMware Tools. It can only run before the source VM is
+	// powered off.
+	// +optional
+	GuestCommand *MigrationHookGuestCommand `json:"guestCommand,omitempty"`
+	// Timeout is how long the hook may run before it fails
+	// +kubebuilder:default:="10m"
+	// +optional
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+	// FailurePolicy is what happens to the migration when the hook fails
+	// +kubebuilder:default:=Fail
+	// +optional
+	FailurePolicy MigrationHookFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// MigrationHookJob runs a container image as a Kubernetes Job in the namespace of the migration. The container gets
+// the name of the VM, the migration and the hook point in the VM_NAME, MIGRATION_NAME and HOOK_POINT env vars.
+type MigrationHookJob struct {
+	// Image is the container image to run
+	Image string `json:"image"`
+	// Command overrides the entrypoint of the image
+	// +optional
+	Command []string `json:"command,omitempty"`
+	// Args are the arguments of the command
+	// +optional
+	Args []string `json:"args,omitempty"`
+	// Env are additional env vars of the container
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+}
+
+// MigrationHookHTTP calls a webhook with a JSON body describing the VM, the migration and the hook point.
+// Any 2xx response is a success.
+type MigrationHookHTTP struct {
+	// URL is the URL of the webhook
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+	// Method is the HTTP method of the call
+	// +kubebuilder:validation:Enum=GET;POST;PUT;PATCH
+	// +kubebuilder:default:=POST
+	// +optional
+	Method string `json:"method,omitempty"`
+	// HeadersSecretRef is a secret in the namespace of the migration whose keys and values are sent as headers,
+	// for example an Authorization header
+	// +optional
+	HeadersSecretRef *corev1.LocalObjectReference `json:"headersSecretRef,omitempty"`
+	// InsecureSkipVerify skips the verification of the TLS certificate of the webhook
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// MigrationHookGuestCommand runs a program in the source VM through VMware Tools and waits for it to exit.
+// A non zero exit code is a failure.
+type MigrationHookGuestCommand struct {
+	// Path is the absolute path of the program in the guest
+	Path string `json:"path"`
+	// Args are the arguments of the program
+	// +optional
+	Args string `json:"args,omitempty"`
+	// CredentialsSecretRef is a secret in the namespace of the migration with the username and password of a guest
+	// user, in the username and password keys
+	CredentialsSecretRef corev1.LocalObjectReference `json:"credentialsSecretRef"`
+}
+
+// MigrationHookResult is the result of a hook run by the v2v-helper
+type MigrationHookResult struct {
+	// Name is the name of the hook
+	Name string `json:"name"`
+	// Point is the point of the cutover at which the hook ran
+	Point MigrationHookPoint `json:"point"`
+	// Succeeded is true if the hook succeeded
+	Succeeded bool `json:"succeeded"`
+	// Ignored is true if the hook failed and its failure policy let the migration carry on
+	Ignored bool `json:"ignored,omitempty"`
+	// Message describes the result of the hook
+	Message string `json:"message,omitempty"`
+	// CompletionTime is when the hook finished
+	CompletionTime metav1.Time `json:"completionTime"`
+}
+
 // MigrationPlanSpec defines the desired state of MigrationPlan including
 // the migration template, strategy, and the list of virtual machines to migrate
 type MigrationPlanSpec struct {
@@ -72,6 +290,74 @@ type MigrationPlanSpec struct {
 	// VirtualMachines is a list of virtual machines to be migrated
 	VirtualMachines [][]string `json:"virtualMachines"`
 	SecurityGroups  []string   `json:"securityGroups,omitempty"`
+	// DryRun resolves and validates everything the migration of each VM needs without creating any
+	// Jobs or volumes, and writes the result to status.dryRunReport
+	DryRun bool `json:"dryRun,omitempty"`
+	// NetworkOverrides places the NICs of individual VMs on a chosen network, subnet and fixed IP.
+	// They are validated against Neutron before the migration starts and cannot be combined with granular ports.
+	NetworkOverrides []VMNetworkOverride `json:"networkOverrides,omitempty"`
+	// SnapshotOverrides set the snapshot consistency of individual VMs, overriding the one of the migration strategy
+	SnapshotOverrides []VMSnapshotOverride `json:"snapshotOverrides,omitempty"`
+	// DiskOverrides select the disks of individual VMs that are copied and the volume type of each of them
+	DiskOverrides []VMDiskOverride `json:"diskOverrides,omitempty"`
+}
+
+// VMDiskOverride selects the disks of a VM that are copied
+type VMDiskOverride struct {
+	// VMName is the name of the VM in vCenter
+	VMName string `json:"vmName"`
+	// SkipIndependentNonPersistent skips the independent non-persistent disks of the VM, whose content is
+	// discarded when the VM powers off
+	SkipIndependentNonPersistent bool `json:"skipIndependentNonPersistent,omitempty"`
+	// Disks are the overrides of the disks of the VM. Disks without an override are copied to a volume of the
+	// volume type from the storage mapping.
+	Disks []DiskOverride `json:"disks,omitempty"`
+}
+
+// DiskOverride overrides how a single disk is migrated
+type DiskOverride struct {
+	// Name is the label of the disk in vCenter, such as "Hard disk 2"
+	Name string `json:"name"`
+	// Exclude skips the disk, it is neither copied nor attached to the target VM. The boot disk cannot be excluded.
+	Exclude bool `json:"exclude,omitempty"`
+	// VolumeType is the Cinder volume type of the disk, overriding the one from the storage mapping
+	VolumeType string `json:"volumeType,omitempty"`
+	// Multiattach creates the volume of the disk as a multiattach volume. Its volume type must allow multiattach.
+	Multiattach bool `json:"multiattach,omitempty"`
+}
+
+// VMSnapshotOverride overrides the snapshot consistency of a VM
+type VMSnapshotOverride struct {
+	// VMName is the name of the VM in vCenter
+	VMName string `json:"vmName"`
+	// SnapshotConsistency decides how the snapshots of the VM are taken
+	// +kubebuilder:validation:Enum=crashConsistent;quiesced
+	SnapshotConsistency string `json:"snapshotConsistency"`
+}
+
+// VMNetworkOverride overrides the target network settings of the NICs of a VM
+type VMNetworkOverride struct {
+	// VMName is the name of the VM in vCenter
+	VMName string `json:"vmName"`
+	// NICs are the overrides of the NICs of the VM. NICs without an override use the network mapping.
+	NICs []NICNetworkOverride `json:"nics"`
+}
+
+// NICNetworkOverride overrides the target network settings of a single NIC
+type NICNetworkOverride struct {
+	// Index is the position of the NIC on the source VM, starting at 0
+	// +kubebuilder:validation:Minimum=0
+	Index int `json:"index"`
+	// Network is the name of the target network. Defaults to the network from the network mapping.
+	Network string `json:"network,omitempty"`
+	// Subnet is the name or ID of the subnet of the target network to take the IP from.
+	// Defaults to the subnet containing FixedIP, or the first subnet of the network.
+	Subnet string `json:"subnet,omitempty"`
+	// FixedIP is the IP address assigned to the NIC. Defaults to an address allocated by Neutron.
+	FixedIP string `json:"fixedIP,omitempty"`
+	// PreserveMAC keeps the MAC address of the source NIC. When false a new MAC address is generated.
+	// +kubebuilder:default:=true
+	PreserveMAC *bool `json:"preserveMAC,omitempty"`
 }
 
 // MigrationPlanSpecPerVM defines the configuration that applies to each VM in the migration plan
@@ -87,6 +373,25 @@ type MigrationPlanSpecPerVM struct {
 	// +kubebuilder:default:="echo \"Add your startup script here!\""
 	FirstBootScript     string               `json:"firstBootScript,omitempty"`
 	PostMigrationAction *PostMigrationAction `json:"postMigrationAction,omitempty"`
+	// JobOptions configures the scheduling and resources of the v2v-helper pods, overriding the options of the template
+	// +optional
+	JobOptions *MigrationJobOptions `json:"jobOptions,omitempty"`
+	// Priority orders the migration plans waiting for a free migration slot, plans with a higher priority start first
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+	// MaxConcurrentMigrations is the max number of VMs of the plan migrating at the same time, 0 for no limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConcurrentMigrations int `json:"maxConcurrentMigrations,omitempty"`
+	// MaxConcurrentCutovers is the max number of VMs of the plan cutting over at the same time, 0 for no limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConcurrentCutovers int `json:"maxConcurrentCutovers,omitempty"`
+	// Hooks are run at defined points of the cutover of each VM, in the order they are listed
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Hooks []MigrationHook `json:"hooks,omitempty"`
 }
 
 // MigrationPlanStatus defines the observed state of MigrationPlan including
@@ -97,11 +402,70 @@ type MigrationPlanStatus struct {
 	MigrationStatus corev1.PodPhase `json:"migrationStatus"`
 	// MigrationMessage is the message associated with the migration
 	MigrationMessage string `json:"migrationMessage"`
+	// DryRunReport is the pre-flight report of the last dry run of the migration plan
+	DryRunReport *DryRunReport `json:"dryRunReport,omitempty"`
+	// QueuePosition is the position of the plan among the plans waiting for a free migration slot, 0 when the plan
+	// is not waiting
+	QueuePosition int `json:"queuePosition,omitempty"`
+}
+
+// PreflightCheckResult is the result of a pre-flight check
+type PreflightCheckResult string
+
+const (
+	// PreflightCheckPassed means the check found nothing that would stop the migration
+	PreflightCheckPassed PreflightCheckResult = "Passed"
+	// PreflightCheckWarning means the migration can run but needs attention
+	PreflightCheckWarning PreflightCheckResult = "Warning"
+	// PreflightCheckFailed means the migration would fail
+	PreflightCheckFailed PreflightCheckResult = "Failed"
+)
+
+// PreflightCheck is the result of a single pre-flight check of a VM
+type PreflightCheck struct {
+	// Name is the name of the check
+	Name string `json:"name"`
+	// Result is the result of the check
+	// +kubebuilder:validation:Enum=Passed;Warning;Failed
+	Result PreflightCheckResult `json:"result"`
+	// Message describes the result of the check
+	Message string `json:"message,omitempty"`
+}
+
+// VMPreflightReport is the pre-flight report of a single VM of a migration plan
+type VMPreflightReport struct {
+	// VMName is the name of the VM
+	VMName string `json:"vmName"`
+	// Ready is true if none of the checks of the VM failed
+	Ready bool `json:"ready"`
+	// TargetNetworks are the OpenStack networks the NICs of the VM are mapped to
+	TargetNetworks []string `json:"targetNetworks,omitempty"`
+	// TargetVolumeTypes are the Cinder volume types the disks of the VM are mapped to
+	TargetVolumeTypes []string `json:"targetVolumeTypes,omitempty"`
+	// TargetFlavorID is the ID of the flavor the VM would be created with
+	TargetFlavorID string `json:"targetFlavorId,omitempty"`
+	// SecurityGroupIDs are the IDs of the security groups of the migration plan
+	SecurityGroupIDs []string `json:"securityGroupIds,omitempty"`
+	// Checks are the results of the pre-flight checks of the VM
+	Checks []PreflightCheck `json:"checks,omitempty"`
+}
+
+// DryRunReport is the pre-flight report of a dry run of a migration plan
+type DryRunReport struct {
+	// ObservedGeneration is the generation of the migration plan the report was made for
+	ObservedGeneration int64 `json:"observedGeneration"`
+	// CompletedAt is the time the dry run completed
+	CompletedAt metav1.Time `json:"completedAt"`
+	// Ready is true if all VMs of the migration plan are ready to migrate
+	Ready bool `json:"ready"`
+	// VMs are the pre-flight reports of the VMs of the migration plan
+	VMs []VMPreflightReport `json:"vms,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:JSONPath=`.status.migrationStatus`,name=Status,type=string
+// +kubebuilder:printcolumn:JSONPath=`.status.queuePosition`,name=Queue,type=integer,priority=1
 
 // MigrationPlan is the Schema for the migrationplans API that defines
 // how to migrate virtual machines from VMware to OpenStack including migration strategy and scheduling.
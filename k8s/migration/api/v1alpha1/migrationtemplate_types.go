@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -52,6 +53,37 @@ type MigrationTemplateSpec struct {
 	// UseFlavorless indicates if the migration should use flavorless VM creation for PCD.
 	// +optional
 	UseFlavorless bool `json:"useFlavorless,omitempty"`
+	// JobOptions configures the scheduling and resources of the v2v-helper pods of the VMs migrated with the template
+	// +optional
+	JobOptions *MigrationJobOptions `json:"jobOptions,omitempty"`
+}
+
+// MigrationJobOptions configures the scheduling and resources of the v2v-helper pods that migrate the VMs.
+// Options of a migration plan take precedence over those of its template, which take precedence over the
+// V2V_HELPER_* defaults of the stellaris-migrate settings.
+type MigrationJobOptions struct {
+	// Resources are the compute resources of the v2v-helper container. They are merged per resource, and a limit
+	// below the request of the same resource is raised to the request.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// NodeSelector restricts the v2v-helper pods to nodes with these labels, for example agents close to a datastore
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Affinity are the scheduling constraints of the v2v-helper pods
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// Tolerations let the v2v-helper pods run on tainted nodes
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// PriorityClassName is the priority class of the v2v-helper pods
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// VDDKPath is the directory on the node holding the VMware VDDK libraries
+	// +optional
+	VDDKPath string `json:"vddkPath,omitempty"`
+	// VirtioWinPath is the directory on the node where the virtio-win drivers are cached
+	// +optional
+	VirtioWinPath string `json:"virtioWinPath,omitempty"`
 }
 
 // +kubebuilder:object:root=true
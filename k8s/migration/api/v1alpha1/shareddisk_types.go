@@ -0,0 +1,110 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// SharedDiskPhase is the phase of a SharedDisk
+type SharedDiskPhase string
+
+const (
+	// SharedDiskPhaseAvailable is the phase of a shared disk that has not been copied
+	SharedDiskPhaseAvailable SharedDiskPhase = "Available"
+	// SharedDiskPhaseCopied is the phase of a shared disk whose copy can be attached to the instances of its owner VMs
+	SharedDiskPhaseCopied SharedDiskPhase = "Copied"
+)
+
+// SharedDiskSpec defines the desired state of SharedDisk, a VMDK that several VMs write to in multi-writer mode,
+// such as the shared disks of Oracle RAC or Windows failover clusters
+type SharedDiskSpec struct {
+	// FileName is the path of the VMDK on its datastore, which identifies the disk across its owner VMs
+	FileName string `json:"fileName"`
+
+	// DiskSize is the size of the disk in bytes
+	DiskSize int64 `json:"diskSize"`
+
+	// OwnerVMs are the VMs the disk is attached to
+	OwnerVMs []string `json:"ownerVMs"`
+}
+
+// SharedDiskStatus defines the observed state of SharedDisk
+type SharedDiskStatus struct {
+	// Phase is Available until the disk is copied into its multiattach Cinder volume
+	// +kubebuilder:validation:Enum=Available;Copied
+	Phase SharedDiskPhase `json:"phase,omitempty"`
+
+	// CopiedBy is the migration that copied the disk
+	CopiedBy string `json:"copiedBy,omitempty"`
+
+	// CopiedByUID is the UID of the migration that copied the disk. A retried migration is a new Migration, so the
+	// UID tells the copy of the current attempt from the copy of an earlier one.
+	CopiedByUID types.UID `json:"copiedByUID,omitempty"`
+
+	// CinderVolumeID is the multiattach Cinder volume the disk was copied into
+	CinderVolumeID string `json:"cinderVolumeID,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=`.spec.fileName`,name=File,type=string
+// +kubebuilder:printcolumn:JSONPath=`.spec.ownerVMs`,name=Owners,type=string
+// +kubebuilder:printcolumn:JSONPath=`.status.phase`,name=Phase,type=string
+// +kubebuilder:printcolumn:JSONPath=`.status.cinderVolumeID`,name=Volume,type=string
+
+// SharedDisk is the Schema for the shareddisks API. A shared disk is copied once, by the migration of one of its
+// owner VMs, into a multiattach Cinder volume that is attached to the instances of all its owner VMs.
+type SharedDisk struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec defines the desired state of SharedDisk
+	Spec SharedDiskSpec `json:"spec,omitempty"`
+
+	// Status defines the observed state of SharedDisk
+	Status SharedDiskStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SharedDiskList contains a list of SharedDisk
+type SharedDiskList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SharedDisk `json:"items"`
+}
+
+// SharedDiskAssignment tells the migration of a VM how to handle one of the shared disks of the VM
+type SharedDiskAssignment struct {
+	// DiskName is the label of the disk on the VM, e.g. "Hard disk 2"
+	DiskName string `json:"diskName"`
+
+	// SharedDisk is the name of the SharedDisk
+	SharedDisk string `json:"sharedDisk"`
+
+	// OwnerVMs are the VMs the disk is attached to
+	OwnerVMs []string `json:"ownerVMs"`
+
+	// Copy is true for the one migration that copies the disk. The migrations of the other owner VMs attach the copy.
+	Copy bool `json:"copy,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SharedDisk{}, &SharedDiskList{})
+}
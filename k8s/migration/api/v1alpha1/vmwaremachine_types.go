@@ -30,6 +30,10 @@ type VMInfo struct {
 	Datastores []string `json:"datastores,omitempty"`
 	// Disks is the list of disks for the virtual machine
 	Disks []string `json:"disks,omitempty"`
+	// BootDisk is the disk of Disks the firmware of the virtual machine boots from
+	BootDisk string `json:"bootDisk,omitempty"`
+	// DiskSizes is the list of sizes in bytes of the disks, in the same order as Disks
+	DiskSizes []int64 `json:"diskSizes,omitempty"`
 	// Networks is the list of networks for the virtual machine
 	Networks []string `json:"networks,omitempty"`
 	// IPAddress is the IP address of the virtual machine
@@ -50,6 +54,13 @@ type VMInfo struct {
 	AssignedIP string `json:"assignedIp,omitempty"`
 	// RDMDisks is the list of RDM disks for the virtual machine
 	RDMDisks []RDMDiskInfo `json:"rdmDisks,omitempty"`
+	// SharedDisks is the list of disks of Disks the virtual machine shares with other virtual machines in
+	// multi-writer mode
+	SharedDisks []SharedDiskInfo `json:"sharedDisks,omitempty"`
+	// VTPM is true if the virtual machine has a virtual TPM
+	VTPM bool `json:"vtpm,omitempty"`
+	// Encrypted is true if the virtual machine or any of its disks is encrypted
+	Encrypted bool `json:"encrypted,omitempty"`
 	// NetworkInterfaces is the list of network interfaces for the virtual machine expect the lo device
 	NetworkInterfaces []NIC `json:"networkInterfaces,omitempty"`
 	// GuestNetworks is the list of network interfaces for the virtual machine as reported by the guest
@@ -72,6 +83,7 @@ type GuestNetwork struct {
 	PrefixLength int32    `json:"prefixLength,omitempty"` // Subnet mask length
 	DNS          []string `json:"dns,omitempty"`          // DNS servers
 	Device       string   `json:"device,omitempty"`       // e.g. eth0
+	Gateway      string   `json:"gateway,omitempty"`      // Default gateway of the interface
 }
 
 // VMwareMachineSpec defines the desired state of VMwareMachine
@@ -145,6 +157,16 @@ type RDMDiskInfo struct {
 	OpenstackVolumeRef OpenStackVolumeRefInfo `json:"openstackVolumeRef,omitempty"`
 }
 
+// SharedDiskInfo contains information about a disk shared with other virtual machines in multi-writer mode
+type SharedDiskInfo struct {
+	// DiskName is the label of the disk on the virtual machine
+	DiskName string `json:"diskName"`
+	// FileName is the path of the VMDK on its datastore
+	FileName string `json:"fileName"`
+	// SharedDisk is the name of the SharedDisk of the disk
+	SharedDisk string `json:"sharedDisk"`
+}
+
 func init() {
 	SchemeBuilder.Register(&VMwareMachine{}, &VMwareMachineList{})
 }
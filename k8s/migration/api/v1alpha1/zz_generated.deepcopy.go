@@ -57,6 +57,126 @@ func (in *AdvancedOptions) DeepCopy() *AdvancedOptions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentPool) DeepCopyInto(out *AgentPool) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentPool.
+func (in *AgentPool) DeepCopy() *AgentPool {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AgentPool) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentPoolAgent) DeepCopyInto(out *AgentPoolAgent) {
+	*out = *in
+	if in.IdleSince != nil {
+		in, out := &in.IdleSince, &out.IdleSince
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentPoolAgent.
+func (in *AgentPoolAgent) DeepCopy() *AgentPoolAgent {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentPoolAgent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentPoolList) DeepCopyInto(out *AgentPoolList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AgentPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentPoolList.
+func (in *AgentPoolList) DeepCopy() *AgentPoolList {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentPoolList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AgentPoolList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentPoolSpec) DeepCopyInto(out *AgentPoolSpec) {
+	*out = *in
+	out.ScaleDownCooldown = in.ScaleDownCooldown
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentPoolSpec.
+func (in *AgentPoolSpec) DeepCopy() *AgentPoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentPoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentPoolStatus) DeepCopyInto(out *AgentPoolStatus) {
+	*out = *in
+	if in.Agents != nil {
+		in, out := &in.Agents, &out.Agents
+		*out = make([]AgentPoolAgent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastScaleTime != nil {
+		in, out := &in.LastScaleTime, &out.LastScaleTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentPoolStatus.
+func (in *AgentPoolStatus) DeepCopy() *AgentPoolStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentPoolStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BMConfig) DeepCopyInto(out *BMConfig) {
 	*out = *in
@@ -295,6 +415,98 @@ func (in *ClusterMigrationStatus) DeepCopy() *ClusterMigrationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CopyWindow) DeepCopyInto(out *CopyWindow) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CopyWindow.
+func (in *CopyWindow) DeepCopy() *CopyWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(CopyWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CutoverWindow) DeepCopyInto(out *CutoverWindow) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]CutoverWindowDay, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CutoverWindow.
+func (in *CutoverWindow) DeepCopy() *CutoverWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(CutoverWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskOverride) DeepCopyInto(out *DiskOverride) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskOverride.
+func (in *DiskOverride) DeepCopy() *DiskOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskProgress) DeepCopyInto(out *DiskProgress) {
+	*out = *in
+	if in.ETA != nil {
+		in, out := &in.ETA, &out.ETA
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskProgress.
+func (in *DiskProgress) DeepCopy() *DiskProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DryRunReport) DeepCopyInto(out *DryRunReport) {
+	*out = *in
+	in.CompletedAt.DeepCopyInto(&out.CompletedAt)
+	if in.VMs != nil {
+		in, out := &in.VMs, &out.VMs
+		*out = make([]VMPreflightReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DryRunReport.
+func (in *DryRunReport) DeepCopy() *DryRunReport {
+	if in == nil {
+		return nil
+	}
+	out := new(DryRunReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ESXIMigration) DeepCopyInto(out *ESXIMigration) {
 	*out = *in
@@ -461,6 +673,171 @@ func (in *Migration) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationConnections) DeepCopyInto(out *MigrationConnections) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationConnections.
+func (in *MigrationConnections) DeepCopy() *MigrationConnections {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationConnections)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationHook) DeepCopyInto(out *MigrationHook) {
+	*out = *in
+	if in.Job != nil {
+		in, out := &in.Job, &out.Job
+		*out = new(MigrationHookJob)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(MigrationHookHTTP)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GuestCommand != nil {
+		in, out := &in.GuestCommand, &out.GuestCommand
+		*out = new(MigrationHookGuestCommand)
+		**out = **in
+	}
+	out.Timeout = in.Timeout
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationHook.
+func (in *MigrationHook) DeepCopy() *MigrationHook {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationHookGuestCommand) DeepCopyInto(out *MigrationHookGuestCommand) {
+	*out = *in
+	out.CredentialsSecretRef = in.CredentialsSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationHookGuestCommand.
+func (in *MigrationHookGuestCommand) DeepCopy() *MigrationHookGuestCommand {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationHookGuestCommand)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationHookHTTP) DeepCopyInto(out *MigrationHookHTTP) {
+	*out = *in
+	if in.HeadersSecretRef != nil {
+		in, out := &in.HeadersSecretRef, &out.HeadersSecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationHookHTTP.
+func (in *MigrationHookHTTP) DeepCopy() *MigrationHookHTTP {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationHookHTTP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationHookJob) DeepCopyInto(out *MigrationHookJob) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationHookJob.
+func (in *MigrationHookJob) DeepCopy() *MigrationHookJob {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationHookJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationHookResult) DeepCopyInto(out *MigrationHookResult) {
+	*out = *in
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationHookResult.
+func (in *MigrationHookResult) DeepCopy() *MigrationHookResult {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationHookResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationJobOptions) DeepCopyInto(out *MigrationJobOptions) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationJobOptions.
+func (in *MigrationJobOptions) DeepCopy() *MigrationJobOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationJobOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MigrationList) DeepCopyInto(out *MigrationList) {
 	*out = *in
@@ -499,7 +876,7 @@ func (in *MigrationPlan) DeepCopyInto(out *MigrationPlan) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlan.
@@ -572,6 +949,25 @@ func (in *MigrationPlanSpec) DeepCopyInto(out *MigrationPlanSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.NetworkOverrides != nil {
+		in, out := &in.NetworkOverrides, &out.NetworkOverrides
+		*out = make([]VMNetworkOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SnapshotOverrides != nil {
+		in, out := &in.SnapshotOverrides, &out.SnapshotOverrides
+		*out = make([]VMSnapshotOverride, len(*in))
+		copy(*out, *in)
+	}
+	if in.DiskOverrides != nil {
+		in, out := &in.DiskOverrides, &out.DiskOverrides
+		*out = make([]VMDiskOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlanSpec.
@@ -594,6 +990,18 @@ func (in *MigrationPlanSpecPerVM) DeepCopyInto(out *MigrationPlanSpecPerVM) {
 		*out = new(PostMigrationAction)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.JobOptions != nil {
+		in, out := &in.JobOptions, &out.JobOptions
+		*out = new(MigrationJobOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = make([]MigrationHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlanSpecPerVM.
@@ -609,6 +1017,11 @@ func (in *MigrationPlanSpecPerVM) DeepCopy() *MigrationPlanSpecPerVM {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MigrationPlanStatus) DeepCopyInto(out *MigrationPlanStatus) {
 	*out = *in
+	if in.DryRunReport != nil {
+		in, out := &in.DryRunReport, &out.DryRunReport
+		*out = new(DryRunReport)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlanStatus.
@@ -627,6 +1040,23 @@ func (in *MigrationPlanStrategy) DeepCopyInto(out *MigrationPlanStrategy) {
 	in.DataCopyStart.DeepCopyInto(&out.DataCopyStart)
 	in.VMCutoverStart.DeepCopyInto(&out.VMCutoverStart)
 	in.VMCutoverEnd.DeepCopyInto(&out.VMCutoverEnd)
+	if in.CutoverWindow != nil {
+		in, out := &in.CutoverWindow, &out.CutoverWindow
+		*out = new(CutoverWindow)
+		(*in).DeepCopyInto(*out)
+	}
+	out.CutoverFinalizeDuration = in.CutoverFinalizeDuration
+	if in.CopyWindow != nil {
+		in, out := &in.CopyWindow, &out.CopyWindow
+		*out = new(CopyWindow)
+		**out = **in
+	}
+	if in.TestBoot != nil {
+		in, out := &in.TestBoot, &out.TestBoot
+		*out = new(TestBoot)
+		**out = **in
+	}
+	out.QuiesceTimeout = in.QuiesceTimeout
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlanStrategy.
@@ -639,6 +1069,49 @@ func (in *MigrationPlanStrategy) DeepCopy() *MigrationPlanStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationProgress) DeepCopyInto(out *MigrationProgress) {
+	*out = *in
+	if in.ETA != nil {
+		in, out := &in.ETA, &out.ETA
+		*out = (*in).DeepCopy()
+	}
+	if in.Disks != nil {
+		in, out := &in.Disks, &out.Disks
+		*out = make([]DiskProgress, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationProgress.
+func (in *MigrationProgress) DeepCopy() *MigrationProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationSnapshot) DeepCopyInto(out *MigrationSnapshot) {
+	*out = *in
+	in.CreationTime.DeepCopyInto(&out.CreationTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationSnapshot.
+func (in *MigrationSnapshot) DeepCopy() *MigrationSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MigrationSpec) DeepCopyInto(out *MigrationSpec) {
 	*out = *in
@@ -664,14 +1137,78 @@ func (in *MigrationStatus) DeepCopyInto(out *MigrationStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.TargetResources != nil {
+		in, out := &in.TargetResources, &out.TargetResources
+		*out = new(MigrationTargetResources)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SourceVMChanges != nil {
+		in, out := &in.SourceVMChanges, &out.SourceVMChanges
+		*out = new(SourceVMChanges)
+		**out = **in
+	}
+	if in.Progress != nil {
+		in, out := &in.Progress, &out.Progress
+		*out = new(MigrationProgress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Connections != nil {
+		in, out := &in.Connections, &out.Connections
+		*out = new(MigrationConnections)
+		**out = **in
+	}
+	if in.TestBoot != nil {
+		in, out := &in.TestBoot, &out.TestBoot
+		*out = new(TestBootResult)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Snapshots != nil {
+		in, out := &in.Snapshots, &out.Snapshots
+		*out = make([]MigrationSnapshot, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeExtensions != nil {
+		in, out := &in.VolumeExtensions, &out.VolumeExtensions
+		*out = make([]VolumeExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationStatus.
+func (in *MigrationStatus) DeepCopy() *MigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationTargetResources) DeepCopyInto(out *MigrationTargetResources) {
+	*out = *in
+	if in.PortIDs != nil {
+		in, out := &in.PortIDs, &out.PortIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VolumeIDs != nil {
+		in, out := &in.VolumeIDs, &out.VolumeIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationStatus.
-func (in *MigrationStatus) DeepCopy() *MigrationStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationTargetResources.
+func (in *MigrationTargetResources) DeepCopy() *MigrationTargetResources {
 	if in == nil {
 		return nil
 	}
-	out := new(MigrationStatus)
+	out := new(MigrationTargetResources)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -681,7 +1218,7 @@ func (in *MigrationTemplate) DeepCopyInto(out *MigrationTemplate) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationTemplate.
@@ -769,6 +1306,11 @@ func (in *MigrationTemplateSpec) DeepCopyInto(out *MigrationTemplateSpec) {
 	*out = *in
 	out.Source = in.Source
 	out.Destination = in.Destination
+	if in.JobOptions != nil {
+		in, out := &in.JobOptions, &out.JobOptions
+		*out = new(MigrationJobOptions)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationTemplateSpec.
@@ -796,6 +1338,26 @@ func (in *NIC) DeepCopy() *NIC {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NICNetworkOverride) DeepCopyInto(out *NICNetworkOverride) {
+	*out = *in
+	if in.PreserveMAC != nil {
+		in, out := &in.PreserveMAC, &out.PreserveMAC
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NICNetworkOverride.
+func (in *NICNetworkOverride) DeepCopy() *NICNetworkOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(NICNetworkOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Network) DeepCopyInto(out *Network) {
 	*out = *in
@@ -1342,6 +1904,21 @@ func (in *PostMigrationAction) DeepCopy() *PostMigrationAction {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightCheck) DeepCopyInto(out *PreflightCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightCheck.
+func (in *PreflightCheck) DeepCopy() *PreflightCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(PreflightCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RDMDisk) DeepCopyInto(out *RDMDisk) {
 	*out = *in
@@ -1727,6 +2304,63 @@ func (in *StorageMappingStatus) DeepCopy() *StorageMappingStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TestBoot) DeepCopyInto(out *TestBoot) {
+	*out = *in
+	out.Timeout = in.Timeout
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestBoot.
+func (in *TestBoot) DeepCopy() *TestBoot {
+	if in == nil {
+		return nil
+	}
+	out := new(TestBoot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TestBootResult) DeepCopyInto(out *TestBootResult) {
+	*out = *in
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make([]PreflightCheck, len(*in))
+		copy(*out, *in)
+	}
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestBootResult.
+func (in *TestBootResult) DeepCopy() *TestBootResult {
+	if in == nil {
+		return nil
+	}
+	out := new(TestBootResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMDiskOverride) DeepCopyInto(out *VMDiskOverride) {
+	*out = *in
+	if in.Disks != nil {
+		in, out := &in.Disks, &out.Disks
+		*out = make([]DiskOverride, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMDiskOverride.
+func (in *VMDiskOverride) DeepCopy() *VMDiskOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(VMDiskOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMInfo) DeepCopyInto(out *VMInfo) {
 	*out = *in
@@ -1740,6 +2374,11 @@ func (in *VMInfo) DeepCopyInto(out *VMInfo) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.DiskSizes != nil {
+		in, out := &in.DiskSizes, &out.DiskSizes
+		*out = make([]int64, len(*in))
+		copy(*out, *in)
+	}
 	if in.Networks != nil {
 		in, out := &in.Networks, &out.Networks
 		*out = make([]string, len(*in))
@@ -1752,6 +2391,11 @@ func (in *VMInfo) DeepCopyInto(out *VMInfo) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SharedDisks != nil {
+		in, out := &in.SharedDisks, &out.SharedDisks
+		*out = make([]SharedDiskInfo, len(*in))
+		copy(*out, *in)
+	}
 	if in.NetworkInterfaces != nil {
 		in, out := &in.NetworkInterfaces, &out.NetworkInterfaces
 		*out = make([]NIC, len(*in))
@@ -1776,6 +2420,63 @@ func (in *VMInfo) DeepCopy() *VMInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMNetworkOverride) DeepCopyInto(out *VMNetworkOverride) {
+	*out = *in
+	if in.NICs != nil {
+		in, out := &in.NICs, &out.NICs
+		*out = make([]NICNetworkOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMNetworkOverride.
+func (in *VMNetworkOverride) DeepCopy() *VMNetworkOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(VMNetworkOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMPreflightReport) DeepCopyInto(out *VMPreflightReport) {
+	*out = *in
+	if in.TargetNetworks != nil {
+		in, out := &in.TargetNetworks, &out.TargetNetworks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TargetVolumeTypes != nil {
+		in, out := &in.TargetVolumeTypes, &out.TargetVolumeTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecurityGroupIDs != nil {
+		in, out := &in.SecurityGroupIDs, &out.SecurityGroupIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make([]PreflightCheck, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMPreflightReport.
+func (in *VMPreflightReport) DeepCopy() *VMPreflightReport {
+	if in == nil {
+		return nil
+	}
+	out := new(VMPreflightReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMSequenceInfo) DeepCopyInto(out *VMSequenceInfo) {
 	*out = *in
@@ -1791,6 +2492,21 @@ func (in *VMSequenceInfo) DeepCopy() *VMSequenceInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMSnapshotOverride) DeepCopyInto(out *VMSnapshotOverride) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMSnapshotOverride.
+func (in *VMSnapshotOverride) DeepCopy() *VMSnapshotOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(VMSnapshotOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMwareCluster) DeepCopyInto(out *VMwareCluster) {
 	*out = *in
@@ -2169,6 +2885,150 @@ func (in *VMwareMachineStatus) DeepCopy() *VMwareMachineStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedDisk) DeepCopyInto(out *SharedDisk) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedDisk.
+func (in *SharedDisk) DeepCopy() *SharedDisk {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedDisk)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SharedDisk) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedDiskAssignment) DeepCopyInto(out *SharedDiskAssignment) {
+	*out = *in
+	if in.OwnerVMs != nil {
+		in, out := &in.OwnerVMs, &out.OwnerVMs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedDiskAssignment.
+func (in *SharedDiskAssignment) DeepCopy() *SharedDiskAssignment {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedDiskAssignment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedDiskInfo) DeepCopyInto(out *SharedDiskInfo) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedDiskInfo.
+func (in *SharedDiskInfo) DeepCopy() *SharedDiskInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedDiskInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedDiskList) DeepCopyInto(out *SharedDiskList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SharedDisk, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedDiskList.
+func (in *SharedDiskList) DeepCopy() *SharedDiskList {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedDiskList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SharedDiskList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedDiskSpec) DeepCopyInto(out *SharedDiskSpec) {
+	*out = *in
+	if in.OwnerVMs != nil {
+		in, out := &in.OwnerVMs, &out.OwnerVMs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedDiskSpec.
+func (in *SharedDiskSpec) DeepCopy() *SharedDiskSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedDiskSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedDiskStatus) DeepCopyInto(out *SharedDiskStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedDiskStatus.
+func (in *SharedDiskStatus) DeepCopy() *SharedDiskStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedDiskStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SourceVMChanges) DeepCopyInto(out *SourceVMChanges) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SourceVMChanges.
+func (in *SourceVMChanges) DeepCopy() *SourceVMChanges {
+	if in == nil {
+		return nil
+	}
+	out := new(SourceVMChanges)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StellarisMigrateNode) DeepCopyInto(out *StellarisMigrateNode) {
 	*out = *in
@@ -2263,3 +3123,19 @@ func (in *StellarisMigrateNodeStatus) DeepCopy() *StellarisMigrateNodeStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeExtension) DeepCopyInto(out *VolumeExtension) {
+	*out = *in
+	in.ExtensionTime.DeepCopyInto(&out.ExtensionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeExtension.
+func (in *VolumeExtension) DeepCopy() *VolumeExtension {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeExtension)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -40,6 +40,8 @@ import (
 
 	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
 	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/internal/controller"
+	webhookv1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/internal/webhook/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -95,6 +97,7 @@ func main() {
 
 	webhookServer := webhook.NewServer(webhook.Options{
 		TLSOpts: tlsOpts,
+		CertDir: constants.WebhookCertDir,
 	})
 
 	// create manager
@@ -137,6 +140,20 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "RDMDisk")
 		os.Exit(1)
 	}
+	// Create a single ctx from signal handler to be reused
+	ctx := ctrl.SetupSignalHandler()
+
+	// Webhooks are served unless disabled, they are never served in local mode as the API server cannot reach them
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" && !local {
+		if err = utils.EnsureWebhookCertificates(ctx, mgr.GetAPIReader(), mgr.GetClient(), constants.WebhookCertDir); err != nil {
+			setupLog.Error(err, "unable to set up webhook certificates")
+			os.Exit(1)
+		}
+		if err = webhookv1alpha1.SetupWebhooksWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhooks")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err = mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -155,9 +172,6 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Create a single ctx from signal handler to be reused
-	ctx := ctrl.SetupSignalHandler()
-
 	setupLog.Info("starting manager")
 	// Start the manager's cache first
 	go func() {
@@ -266,6 +280,13 @@ func SetupControllers(mgr ctrl.Manager, local bool) error {
 		setupLog.Error(err, "unable to create controller", "controller", "StellarisMigrateNode")
 		return err
 	}
+	if err := (&controller.AgentPoolReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AgentPool")
+		return err
+	}
 	if err := (&controller.RollingMigrationPlanReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
@@ -0,0 +1,331 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/utils"
+)
+
+// AgentPoolReconciler reconciles an AgentPool object
+type AgentPoolReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=migrate.k8s.stellaris.io,resources=agentpools,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=migrate.k8s.stellaris.io,resources=agentpools/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=migrate.k8s.stellaris.io,resources=agentpools/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=update;patch
+
+// Reconcile scales the worker agents of an AgentPool with the running and queued migrations. Agents are
+// created as soon as the migrations exceed what the agents are sized for. Agents that ran no migrations
+// for the cooldown are cordoned first, and deleted once the migrations placed on them before have finished.
+func (r *AgentPoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctxlog := log.FromContext(ctx).WithName(constants.AgentPoolControllerName)
+
+	agentpool := &migratev1alpha1.AgentPool{}
+	if err := r.Get(ctx, req.NamespacedName, agentpool); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrapf(err, "failed to get AgentPool '%s'", req.Name)
+	}
+	// The agents are deleted with the pool through their owner references
+	if !agentpool.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	vjNodes := &migratev1alpha1.StellarisMigrateNodeList{}
+	if err := r.List(ctx, vjNodes, client.InNamespace(agentpool.Namespace),
+		client.MatchingLabels{constants.AgentPoolLabel: agentpool.Name}); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to list stellaris-migrate nodes of the agent pool")
+	}
+	migrations := &migratev1alpha1.MigrationList{}
+	if err := r.List(ctx, migrations); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to list migrations")
+	}
+	agents, err := utils.GetMigrationAgents(ctx, r.Client, nil)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to get migration agents")
+	}
+
+	status := migratev1alpha1.AgentPoolStatus{LastScaleTime: agentpool.Status.LastScaleTime}
+	migrationsOnNode := map[string]int{}
+	for i := range migrations.Items {
+		migration := &migrations.Items[i]
+		if utils.IsMigrationOnAgent(migration) {
+			status.ActiveMigrations++
+			migrationsOnNode[migration.Status.AgentName]++
+		}
+		if utils.IsMigrationQueued(migration) {
+			status.QueuedMigrations++
+		}
+	}
+
+	poolNodes := map[string]bool{}
+	for i := range vjNodes.Items {
+		poolNodes[vjNodes.Items[i].Name] = true
+	}
+	otherAgents := 0
+	for _, agent := range agents {
+		if poolNodes[agent.NodeName] {
+			status.ReadyAgents++
+		} else {
+			otherAgents++
+		}
+	}
+
+	// The agents that are not part of the pool, such as the master, take their share of the migrations first
+	target := max(agentpool.Spec.TargetMigrationsPerAgent, 1)
+	needed := (status.ActiveMigrations+status.QueuedMigrations+target-1)/target - otherAgents
+	status.DesiredAgents = min(max(needed, agentpool.Spec.MinAgents), agentpool.Spec.MaxAgents)
+
+	idleSince := map[string]*metav1.Time{}
+	for _, agent := range agentpool.Status.Agents {
+		idleSince[agent.Name] = agent.IdleSince
+	}
+	now := metav1.Now()
+	var serving, draining []*migratev1alpha1.StellarisMigrateNode
+	for i := range vjNodes.Items {
+		vjNode := &vjNodes.Items[i]
+		agent := migratev1alpha1.AgentPoolAgent{
+			Name:             vjNode.Name,
+			Phase:            vjNode.Status.Phase,
+			ActiveMigrations: migrationsOnNode[vjNode.Name],
+			Draining:         vjNode.Annotations[constants.AgentDrainingAnnotation] == "true",
+		}
+		if agent.ActiveMigrations == 0 && agent.Phase == constants.StellarisMigrateNodePhaseNodeReady {
+			agent.IdleSince = idleSince[vjNode.Name]
+			if agent.IdleSince == nil {
+				agent.IdleSince = &now
+			}
+		}
+		status.Agents = append(status.Agents, agent)
+		switch {
+		case !vjNode.DeletionTimestamp.IsZero():
+		case agent.Draining:
+			draining = append(draining, vjNode)
+		default:
+			serving = append(serving, vjNode)
+		}
+	}
+
+	scaled, err := r.scaleAgents(ctx, ctxlog, agentpool, &status, serving, draining)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if scaled {
+		status.LastScaleTime = &now
+	}
+
+	agentpool.Status = status
+	if err := r.Status().Update(ctx, agentpool); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to update agent pool status")
+	}
+	return ctrl.Result{RequeueAfter: constants.AgentPoolRequeueInterval}, nil
+}
+
+// scaleAgents brings the agents serving migrations to the desired number. Draining agents are brought back before
+// new ones are created, and draining agents without migrations are deleted. It reports whether agents were
+// added or drained.
+func (r *AgentPoolReconciler) scaleAgents(ctx context.Context, ctxlog logr.Logger,
+	agentpool *migratev1alpha1.AgentPool,
+	status *migratev1alpha1.AgentPoolStatus,
+	serving, draining []*migratev1alpha1.StellarisMigrateNode) (bool, error) {
+	scaled := false
+	migrationsOnNode := map[string]int{}
+	for _, agent := range status.Agents {
+		migrationsOnNode[agent.Name] = agent.ActiveMigrations
+	}
+
+	for len(serving) < status.DesiredAgents && len(draining) > 0 {
+		vjNode := draining[0]
+		draining = draining[1:]
+		ctxlog.Info("Bringing back draining agent", "agentpool", agentpool.Name, "agent", vjNode.Name)
+		if err := r.setAgentDraining(ctx, vjNode, false); err != nil {
+			return scaled, err
+		}
+		serving = append(serving, vjNode)
+		scaled = true
+	}
+	taken := map[string]bool{}
+	for _, agent := range status.Agents {
+		taken[agent.Name] = true
+	}
+	for len(serving) < status.DesiredAgents {
+		vjNode, err := r.createAgent(ctx, agentpool, taken)
+		if err != nil {
+			return scaled, err
+		}
+		ctxlog.Info("Created agent", "agentpool", agentpool.Name, "agent", vjNode.Name,
+			"activeMigrations", status.ActiveMigrations, "queuedMigrations", status.QueuedMigrations)
+		serving = append(serving, vjNode)
+		status.Agents = append(status.Agents, migratev1alpha1.AgentPoolAgent{Name: vjNode.Name})
+		scaled = true
+	}
+
+	if len(serving) > status.DesiredAgents {
+		// Drain the agents that have been idle the longest
+		idle := []migratev1alpha1.AgentPoolAgent{}
+		for _, agent := range status.Agents {
+			if agent.IdleSince != nil && !agent.Draining &&
+				agent.IdleSince.Add(agentpool.Spec.ScaleDownCooldown.Duration).Before(metav1.Now().Time) {
+				idle = append(idle, agent)
+			}
+		}
+		sort.Slice(idle, func(i, j int) bool { return idle[i].IdleSince.Before(idle[j].IdleSince) })
+		for _, agent := range idle[:min(len(idle), len(serving)-status.DesiredAgents)] {
+			for _, vjNode := range serving {
+				if vjNode.Name != agent.Name {
+					continue
+				}
+				ctxlog.Info("Draining idle agent", "agentpool", agentpool.Name, "agent", vjNode.Name, "idleSince", agent.IdleSince)
+				if err := r.setAgentDraining(ctx, vjNode, true); err != nil {
+					return scaled, err
+				}
+				scaled = true
+			}
+		}
+	}
+
+	// Migrations placed on an agent just before it was cordoned still run to completion
+	for _, vjNode := range draining {
+		if migrationsOnNode[vjNode.Name] > 0 {
+			continue
+		}
+		ctxlog.Info("Deleting drained agent", "agentpool", agentpool.Name, "agent", vjNode.Name)
+		if err := r.Delete(ctx, vjNode); err != nil && !apierrors.IsNotFound(err) {
+			return scaled, errors.Wrapf(err, "failed to delete stellaris-migrate node '%s'", vjNode.Name)
+		}
+	}
+
+	for i := range status.Agents {
+		for _, vjNode := range serving {
+			if vjNode.Name == status.Agents[i].Name {
+				status.Agents[i].Draining = vjNode.Annotations[constants.AgentDrainingAnnotation] == "true"
+			}
+		}
+	}
+	return scaled, nil
+}
+
+// createAgent creates a worker StellarisMigrateNode owned by the agent pool. The StellarisMigrateNode controller
+// then creates its VM from the image of the master. Agents are named after the pool and the lowest index not taken,
+// so that a reconcile working from a stale cache does not add a second agent: the agent the previous reconcile
+// created already exists under the same name and is returned in place of a new one.
+func (r *AgentPoolReconciler) createAgent(ctx context.Context,
+	agentpool *migratev1alpha1.AgentPool, taken map[string]bool) (*migratev1alpha1.StellarisMigrateNode, error) {
+	imageID, err := utils.GetImageID(ctx, r.Client)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get image id")
+	}
+	creds, err := utils.GetOpenstackCredsForMaster(ctx, r.Client)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get openstack creds")
+	}
+	vjNode := &migratev1alpha1.StellarisMigrateNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      agentName(agentpool, taken),
+			Namespace: agentpool.Namespace,
+			Labels:    map[string]string{constants.AgentPoolLabel: agentpool.Name},
+		},
+		Spec: migratev1alpha1.StellarisMigrateNodeSpec{
+			NodeRole: constants.NodeRoleWorker,
+			OpenstackCreds: corev1.ObjectReference{
+				Kind:      "OpenstackCreds",
+				Name:      creds.Name,
+				Namespace: creds.Namespace,
+			},
+			OpenstackFlavorID: agentpool.Spec.OpenstackFlavorID,
+			OpenstackImageID:  imageID,
+		},
+	}
+	if err := controllerutil.SetControllerReference(agentpool, vjNode, r.Scheme); err != nil {
+		return nil, errors.Wrap(err, "failed to set owner reference")
+	}
+	taken[vjNode.Name] = true
+	if err := r.Create(ctx, vjNode); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, errors.Wrapf(err, "failed to create stellaris-migrate node '%s'", vjNode.Name)
+	}
+	return vjNode, nil
+}
+
+// agentName returns the name of the next agent of a pool, with the lowest index not taken by an agent of the pool
+func agentName(agentpool *migratev1alpha1.AgentPool, taken map[string]bool) string {
+	for index := 0; ; index++ {
+		name := fmt.Sprintf("%s-%d", agentpool.Name, index)
+		if !taken[name] {
+			return name
+		}
+	}
+}
+
+// setAgentDraining cordons or uncordons the node of an agent, so that no migrations are placed on it while it
+// drains, and marks the StellarisMigrateNode accordingly
+func (r *AgentPoolReconciler) setAgentDraining(ctx context.Context, vjNode *migratev1alpha1.StellarisMigrateNode, drain bool) error {
+	node := &corev1.Node{}
+	err := r.Get(ctx, client.ObjectKey{Name: vjNode.Name}, node)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to get node '%s'", vjNode.Name)
+	}
+	if err == nil && node.Spec.Unschedulable != drain {
+		patch := client.MergeFrom(node.DeepCopy())
+		node.Spec.Unschedulable = drain
+		if err := r.Patch(ctx, node, patch); err != nil {
+			return errors.Wrapf(err, "failed to cordon node '%s'", vjNode.Name)
+		}
+	}
+
+	patch := client.MergeFrom(vjNode.DeepCopy())
+	if drain {
+		if vjNode.Annotations == nil {
+			vjNode.Annotations = map[string]string{}
+		}
+		vjNode.Annotations[constants.AgentDrainingAnnotation] = "true"
+	} else {
+		delete(vjNode.Annotations, constants.AgentDrainingAnnotation)
+	}
+	if err := r.Patch(ctx, vjNode, patch); err != nil {
+		return errors.Wrapf(err, "failed to mark stellaris-migrate node '%s'", vjNode.Name)
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AgentPoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&migratev1alpha1.AgentPool{}).
+		Owns(&migratev1alpha1.StellarisMigrateNode{}).
+		Complete(r)
+}
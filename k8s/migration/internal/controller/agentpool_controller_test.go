@@ -0,0 +1,234 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+)
+
+var _ = ginkgo.Describe("AgentPool Controller", func() {
+	ginkgo.Context("When reconciling a resource", func() {
+		const resourceName = "test-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+		agentpool := &migratev1alpha1.AgentPool{}
+
+		ginkgo.BeforeEach(func() {
+			ginkgo.By("creating the custom resource for the Kind AgentPool")
+			err := k8sClient.Get(ctx, typeNamespacedName, agentpool)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &migratev1alpha1.AgentPool{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: "default",
+					},
+					Spec: migratev1alpha1.AgentPoolSpec{
+						MaxAgents:         2,
+						OpenstackFlavorID: "flavor",
+					},
+				}
+				gomega.Expect(k8sClient.Create(ctx, resource)).To(gomega.Succeed())
+			}
+		})
+
+		ginkgo.AfterEach(func() {
+			resource := &migratev1alpha1.AgentPool{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			ginkgo.By("Cleanup the specific resource instance AgentPool")
+			gomega.Expect(k8sClient.Delete(ctx, resource)).To(gomega.Succeed())
+		})
+
+		ginkgo.It("should not create agents without migrations", func() {
+			ginkgo.By("Reconciling the created resource")
+			controllerReconciler := &AgentPoolReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			gomega.Expect(k8sClient.Get(ctx, typeNamespacedName, agentpool)).To(gomega.Succeed())
+			gomega.Expect(agentpool.Status.DesiredAgents).To(gomega.Equal(0))
+			gomega.Expect(agentpool.Status.Agents).To(gomega.BeEmpty())
+		})
+	})
+})
+
+var _ = ginkgo.Describe("AgentPool scaling", func() {
+	ctx := context.Background()
+	namespace := constants.NamespaceMigrationSystem
+	poolKey := types.NamespacedName{Name: "pool", Namespace: namespace}
+	var (
+		k8sClient  client.Client
+		reconciler *AgentPoolReconciler
+		agentpool  *migratev1alpha1.AgentPool
+	)
+	queuedMigration := func(name string) client.Object {
+		return &migratev1alpha1.Migration{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Status: migratev1alpha1.MigrationStatus{
+				Phase: migratev1alpha1.VMMigrationPhasePending,
+				Conditions: []corev1.PodCondition{
+					{Type: constants.MigrationConditionTypeAgentPlacement, Status: corev1.ConditionFalse},
+				},
+			},
+		}
+	}
+	poolAgents := func() []migratev1alpha1.StellarisMigrateNode {
+		vjNodes := &migratev1alpha1.StellarisMigrateNodeList{}
+		gomega.Expect(k8sClient.List(ctx, vjNodes, client.InNamespace(namespace),
+			client.MatchingLabels{constants.AgentPoolLabel: "pool"})).To(gomega.Succeed())
+		return vjNodes.Items
+	}
+	build := func(objects ...client.Object) {
+		master := &migratev1alpha1.StellarisMigrateNode{
+			ObjectMeta: metav1.ObjectMeta{Name: constants.StellarisMigrateMasterNodeName, Namespace: namespace},
+			Spec: migratev1alpha1.StellarisMigrateNodeSpec{
+				NodeRole:         constants.NodeRoleMaster,
+				OpenstackImageID: "image",
+				OpenstackCreds:   corev1.ObjectReference{Name: "creds"},
+			},
+		}
+		creds := &migratev1alpha1.OpenstackCreds{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: namespace}}
+		k8sClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).
+			WithStatusSubresource(&migratev1alpha1.AgentPool{}).
+			WithObjects(append(objects, agentpool, master, creds)...).Build()
+		reconciler = &AgentPoolReconciler{Client: k8sClient, Scheme: scheme.Scheme}
+	}
+
+	ginkgo.BeforeEach(func() {
+		agentpool = &migratev1alpha1.AgentPool{
+			ObjectMeta: metav1.ObjectMeta{Name: "pool", Namespace: namespace, UID: "uid-pool"},
+			Spec: migratev1alpha1.AgentPoolSpec{
+				MaxAgents:                3,
+				OpenstackFlavorID:        "flavor",
+				TargetMigrationsPerAgent: 1,
+				ScaleDownCooldown:        metav1.Duration{Duration: time.Minute},
+			},
+		}
+	})
+
+	ginkgo.It("creates agents for the queued migrations", func() {
+		build(queuedMigration("vm-1"), queuedMigration("vm-2"))
+
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: poolKey})
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		agents := poolAgents()
+		gomega.Expect(agents).To(gomega.HaveLen(2))
+		gomega.Expect([]string{agents[0].Name, agents[1].Name}).To(gomega.ConsistOf("pool-0", "pool-1"))
+		gomega.Expect(agents[0].Spec.OpenstackImageID).To(gomega.Equal("image"))
+		gomega.Expect(agents[0].Spec.OpenstackFlavorID).To(gomega.Equal("flavor"))
+
+		gomega.Expect(k8sClient.Get(ctx, poolKey, agentpool)).To(gomega.Succeed())
+		gomega.Expect(agentpool.Status.DesiredAgents).To(gomega.Equal(2))
+		gomega.Expect(agentpool.Status.QueuedMigrations).To(gomega.Equal(2))
+	})
+
+	ginkgo.It("does not create a second agent from a stale view of the agents", func() {
+		build()
+		status := &migratev1alpha1.AgentPoolStatus{DesiredAgents: 1}
+
+		// The agent created by the first scale up is missing from the view of the second one
+		for range 2 {
+			scaled, err := reconciler.scaleAgents(ctx, log.FromContext(ctx), agentpool, status.DeepCopy(), nil, nil)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(scaled).To(gomega.BeTrue())
+		}
+		agents := poolAgents()
+		gomega.Expect(agents).To(gomega.HaveLen(1))
+		gomega.Expect(agents[0].Name).To(gomega.Equal("pool-0"))
+	})
+
+	ginkgo.It("drains idle agents after the cooldown and deletes them once drained", func() {
+		idleSince := metav1.NewTime(time.Now().Add(-time.Hour))
+		agentpool.Status.Agents = []migratev1alpha1.AgentPoolAgent{{Name: "pool-0", IdleSince: &idleSince}}
+		build(
+			&migratev1alpha1.StellarisMigrateNode{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pool-0",
+					Namespace: namespace,
+					Labels:    map[string]string{constants.AgentPoolLabel: "pool"},
+				},
+				Spec:   migratev1alpha1.StellarisMigrateNodeSpec{NodeRole: constants.NodeRoleWorker},
+				Status: migratev1alpha1.StellarisMigrateNodeStatus{Phase: constants.StellarisMigrateNodePhaseNodeReady},
+			},
+			&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool-0"},
+				Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}},
+			},
+		)
+
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: poolKey})
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		agents := poolAgents()
+		gomega.Expect(agents).To(gomega.HaveLen(1))
+		gomega.Expect(agents[0].Annotations).To(gomega.HaveKeyWithValue(constants.AgentDrainingAnnotation, "true"))
+		node := &corev1.Node{}
+		gomega.Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "pool-0"}, node)).To(gomega.Succeed())
+		gomega.Expect(node.Spec.Unschedulable).To(gomega.BeTrue())
+
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: poolKey})
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(poolAgents()).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("keeps agents that became idle within the cooldown", func() {
+		idleSince := metav1.Now()
+		agentpool.Status.Agents = []migratev1alpha1.AgentPoolAgent{{Name: "pool-0", IdleSince: &idleSince}}
+		build(&migratev1alpha1.StellarisMigrateNode{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "pool-0",
+				Namespace: namespace,
+				Labels:    map[string]string{constants.AgentPoolLabel: "pool"},
+			},
+			Spec:   migratev1alpha1.StellarisMigrateNodeSpec{NodeRole: constants.NodeRoleWorker},
+			Status: migratev1alpha1.StellarisMigrateNodeStatus{Phase: constants.StellarisMigrateNodePhaseNodeReady},
+		})
+
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: poolKey})
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		agents := poolAgents()
+		gomega.Expect(agents).To(gomega.HaveLen(1))
+		gomega.Expect(agents[0].Annotations).NotTo(gomega.HaveKey(constants.AgentDrainingAnnotation))
+	})
+})
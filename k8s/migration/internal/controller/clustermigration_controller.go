@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/metrics"
 	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/scope"
 	utils "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/utils"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -56,6 +57,7 @@ func (r *ClusterMigrationReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	if err := r.Get(ctx, req.NamespacedName, clusterMigration); err != nil {
 		if apierrors.IsNotFound(err) {
 			ctxlog.Info("Resource not found, likely deleted", "clustermigration", req.NamespacedName)
+			metrics.DeleteClusterMigration(req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		ctxlog.Error(err, "Failed to get ClusterMigration resource", "clustermigration", req.NamespacedName)
@@ -96,8 +98,10 @@ func (r *ClusterMigrationReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 	if !clusterMigration.DeletionTimestamp.IsZero() {
 		ctxlog.Info("Resource is being deleted, reconciling deletion", "clustermigration", req.NamespacedName)
+		metrics.DeleteClusterMigration(req.Namespace, req.Name)
 		return r.reconcileDelete(ctx, scope)
 	}
+	defer metrics.ObserveClusterMigration(clusterMigration)
 
 	ctxlog.Info("Reconciling normal state", "clustermigration", req.NamespacedName)
 	return r.reconcileNormal(ctx, scope)
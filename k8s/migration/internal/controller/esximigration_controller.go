@@ -30,6 +30,7 @@ import (
 	"github.com/pkg/errors"
 	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
 	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/metrics"
 	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/scope"
 	utils "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/utils"
 	providers "github.com/kashyapshashankv/stellaris-migrate/pkg/vpwned/sdk/providers"
@@ -56,6 +57,7 @@ func (r *ESXIMigrationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	if err := r.Get(ctx, req.NamespacedName, esxiMigration); err != nil {
 		if apierrors.IsNotFound(err) {
 			ctxlog.Info("Resource not found, likely deleted", "esximigration", req.NamespacedName)
+			metrics.DeleteESXIMigration(req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		ctxlog.Error(err, "Failed to get ESXIMigration resource", "esximigration", req.NamespacedName)
@@ -97,8 +99,10 @@ func (r *ESXIMigrationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 	if !esxiMigration.DeletionTimestamp.IsZero() {
 		ctxlog.Info("Resource is being deleted, reconciling deletion", "esximigration", req.NamespacedName)
+		metrics.DeleteESXIMigration(req.Namespace, req.Name)
 		return r.reconcileDelete(ctx, scope)
 	}
+	defer metrics.ObserveESXIMigration(esxiMigration)
 
 	return r.reconcileNormal(ctx, scope)
 }
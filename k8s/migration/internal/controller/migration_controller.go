@@ -27,8 +27,10 @@ import (
 	openstackconst "github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -38,11 +40,19 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/volumes"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/volumeattach"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
 	"github.com/pkg/errors"
 	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
 	constants "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/metrics"
 	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/scope"
 	utils "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/utils"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vcenter"
+	"github.com/vmware/govmomi/session"
 )
 
 // MigrationReconciler reconciles a Migration object
@@ -65,6 +75,9 @@ const migrationFinalizer = "migration.migrate.k8s.stellaris.io/finalizer"
 // +kubebuilder:rbac:groups=migrate.k8s.stellaris.io,resources=bmconfigs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=migrate.k8s.stellaris.io,resources=bmconfigs/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=migrate.k8s.stellaris.io,resources=shareddisks,verbs=get;list;watch
+// +kubebuilder:rbac:groups=migrate.k8s.stellaris.io,resources=shareddisks/status,verbs=get;update;patch
 
 // Reconcile reconciles a Migration object
 func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
@@ -75,6 +88,7 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	if err := r.Get(ctx, req.NamespacedName, migration); err != nil {
 		if apierrors.IsNotFound(err) {
 			// Object deleted successfully
+			metrics.DeleteMigration(req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		ctxlog.Error(err, fmt.Sprintf("Unexpected error reading Migration '%s' object", migration.Name))
@@ -99,6 +113,13 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	// Handle deletion reconciliation
 	if !migration.DeletionTimestamp.IsZero() {
 		if controllerutil.ContainsFinalizer(migration, migrationFinalizer) {
+			deleted, err := r.deleteKeptVolumes(ctx, migration)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if !deleted {
+				return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+			}
 			if err := r.reconcileDelete(ctx, migration); err != nil {
 				return ctrl.Result{}, err
 			}
@@ -108,9 +129,15 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 				return ctrl.Result{}, err
 			}
 		}
+		metrics.DeleteMigration(migration.Namespace, migration.Name)
 		return ctrl.Result{}, nil
 	}
 
+	previousPhase := migration.Status.Phase
+	defer func() {
+		metrics.ObserveMigration(migration, previousPhase)
+	}()
+
 	// Adding finalizer if it doesn't exist
 	if !controllerutil.ContainsFinalizer(migration, migrationFinalizer) {
 		controllerutil.AddFinalizer(migration, migrationFinalizer)
@@ -122,6 +149,11 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	ctxlog.Info("Reconciling Migration object")
 
+	// The helper pod is gone by the time a rollback is requested, so it is handled before looking for it
+	if migration.Spec.Rollback {
+		return r.reconcileRollback(ctx, migration)
+	}
+
 	// Get the pod phase
 	pod, err := r.GetPod(ctx, migrationScope)
 	if err != nil {
@@ -140,12 +172,76 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}
 
-	pod.Labels["startCutover"] = utils.SetCutoverLabel(migration.Spec.InitiateCutover, pod.Labels["startCutover"])
+	cutoverLabel := utils.SetCutoverLabel(migration.Spec.InitiateCutover, pod.Labels["startCutover"])
+	if !migration.Spec.InitiateCutover && utils.IsCutoverGated(pod) {
+		cutoverLabel, err = r.reconcileCutoverSlot(ctx, migration, pod.Labels["startCutover"])
+		if apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) {
+			// Another migration was given a slot in the meantime
+			return ctrl.Result{Requeue: true}, nil
+		} else if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to reconcile cutover slot")
+		}
+	} else if migration.Spec.InitiateCutover && cutoverLabel != constants.StartCutOverYes {
+		// VMs sharing disks cut over together, the admin starting the cutover of one of them starts it for all
+		sharedDiskCutover, err := utils.GetSharedDiskCutover(ctx, r.Client, migration)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to check VMs sharing disks")
+		}
+		if sharedDiskCutover != nil && sharedDiskCutover.Started {
+			cutoverLabel = constants.StartCutOverYes
+		}
+	}
+	pod.Labels["startCutover"] = cutoverLabel
 	if err = r.Update(ctx, pod); err != nil {
 		ctxlog.Error(err, fmt.Sprintf("Failed to update Pod '%s'", pod.Name))
 		return ctrl.Result{}, err
 	}
 
+	progress, err := utils.GetMigrationProgress(pod)
+	if err != nil {
+		ctxlog.Error(err, "Ignoring progress of migration pod", "pod", pod.Name)
+	} else if progress != nil {
+		migration.Status.Progress = progress
+	}
+
+	hookResults, err := utils.GetMigrationHookResults(pod)
+	if err != nil {
+		ctxlog.Error(err, "Ignoring hook results of migration pod", "pod", pod.Name)
+	} else {
+		migration.Status.Conditions = utils.CreateHookConditions(migration, hookResults)
+	}
+
+	testBoot, err := utils.GetMigrationTestBoot(pod)
+	if err != nil {
+		ctxlog.Error(err, "Ignoring test boot result of migration pod", "pod", pod.Name)
+	} else if testBoot != nil {
+		migration.Status.TestBoot = testBoot
+		migration.Status.Conditions = utils.CreateTestBootCondition(migration, testBoot)
+	}
+
+	snapshots, err := utils.GetMigrationSnapshots(pod)
+	if err != nil {
+		ctxlog.Error(err, "Ignoring snapshots of migration pod", "pod", pod.Name)
+	} else if snapshots != nil {
+		migration.Status.Snapshots = snapshots
+	}
+
+	volumeExtensions, err := utils.GetMigrationVolumeExtensions(pod)
+	if err != nil {
+		ctxlog.Error(err, "Ignoring volume extensions of migration pod", "pod", pod.Name)
+	} else if volumeExtensions != nil {
+		migration.Status.VolumeExtensions = volumeExtensions
+	}
+
+	sharedDiskVolumes, err := utils.GetMigrationSharedDiskVolumes(pod)
+	if err != nil {
+		ctxlog.Error(err, "Ignoring shared disks of migration pod", "pod", pod.Name)
+	} else if len(sharedDiskVolumes) > 0 {
+		if err := utils.SetSharedDisksCopied(ctx, r.Client, migration, sharedDiskVolumes); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to record copied shared disks")
+		}
+	}
+
 	if constants.VMMigrationStatesEnum[migration.Status.Phase] <= constants.VMMigrationStatesEnum[migratev1alpha1.VMMigrationPhaseValidating] {
 		migration.Status.Phase = migratev1alpha1.VMMigrationPhaseValidating
 	}
@@ -162,6 +258,7 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	migration.Status.Conditions = utils.CreateValidatedCondition(migration, filteredEvents)
 	migration.Status.Conditions = utils.CreateDataCopyCondition(migration, filteredEvents)
 	migration.Status.Conditions = utils.CreateMigratingCondition(migration, filteredEvents)
+	migration.Status.Conditions = utils.CreateDataVerifiedCondition(migration, filteredEvents)
 	migration.Status.Conditions = utils.CreateFailedCondition(migration, filteredEvents)
 
 	migration.Status.AgentName = pod.Spec.NodeName
@@ -169,6 +266,23 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	if err != nil {
 		return ctrl.Result{}, errors.Wrap(err, "error setting migration phase")
 	}
+	if migration.Status.Phase == migratev1alpha1.VMMigrationPhaseFailed {
+		if err := utils.ResetSharedDisks(ctx, r.Client, migration); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to reset shared disks")
+		}
+	}
+	if migration.Status.Phase == migratev1alpha1.VMMigrationPhaseSucceeded && migration.Spec.RollbackOnHealthCheckFailure &&
+		healthCheckFailed(filteredEvents) {
+		ctxlog.Info("Health check of the target VM failed, rolling back the migration", "migration", migration.Name)
+		if err := r.Status().Update(ctx, migration); err != nil {
+			return ctrl.Result{}, err
+		}
+		migration.Spec.Rollback = true
+		if err := r.Update(ctx, migration); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to request rollback of migration")
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
 	if err := r.Status().Update(ctx, migration); err != nil {
 		ctxlog.Error(err, fmt.Sprintf("Failed to update status of Migration '%s'", migration.Name))
 		return ctrl.Result{}, err
@@ -182,6 +296,99 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	return ctrl.Result{}, nil
 }
 
+// reconcileCutoverSlot returns the startCutover label of a migration whose cutover is gated. The label is set
+// to yes once the helper waits for the cutover and fewer migrations cut over than the settings and the migration
+// plan allow. The migration keeps its slot, recorded in its CutoverSlot condition, until it finishes. Slots are
+// taken in the cutover slots ConfigMap first, so that two reconciles cannot both take the last one: the second
+// fails with a conflict and is requeued. A VM sharing disks with other VMs only cuts over once all of them wait for
+// the cutover, so that they cut over together. The VMs count as one for the limits: once one of them is given a
+// slot, the others are given one as well.
+func (r *MigrationReconciler) reconcileCutoverSlot(ctx context.Context, migration *migratev1alpha1.Migration, currentLabel string) (string, error) {
+	if currentLabel == constants.StartCutOverYes {
+		return constants.StartCutOverYes, nil
+	}
+	phase := migration.Status.Phase
+	if migration.Status.Progress != nil && migration.Status.Progress.Phase != "" {
+		phase = migration.Status.Progress.Phase
+	}
+	if phase != migratev1alpha1.VMMigrationPhaseAwaitingAdminCutOver {
+		return constants.StartCutOverNo, nil
+	}
+
+	settings, err := utils.GetMigrateSettings(ctx, r.Client)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get stellaris-migrate settings")
+	}
+	planLimit := 0
+	migrationplan := &migratev1alpha1.MigrationPlan{}
+	err = r.Get(ctx, types.NamespacedName{Name: migration.Spec.MigrationPlan, Namespace: migration.Namespace}, migrationplan)
+	if err == nil {
+		planLimit = migrationplan.Spec.MaxConcurrentCutovers
+	} else if !apierrors.IsNotFound(err) {
+		return "", errors.Wrapf(err, "failed to get migration plan '%s'", migration.Spec.MigrationPlan)
+	}
+	slots, err := utils.GetCutoverSlots(ctx, r.Client, migration)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get cutover slots")
+	}
+	sharedDiskCutover, err := utils.GetSharedDiskCutover(ctx, r.Client, migration)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to check VMs sharing disks")
+	}
+
+	condition := utils.GeneratePodCondition(constants.MigrationConditionTypeCutoverSlot, corev1.ConditionTrue,
+		"SlotFree", "Cutover started", metav1.Now())
+	label := constants.StartCutOverYes
+	switch {
+	case sharedDiskCutover != nil && len(sharedDiskCutover.NotReady) > 0:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "SharedDiskOwnersNotReady"
+		condition.Message = fmt.Sprintf("Waiting until VMs %s sharing disks are ready to cut over",
+			strings.Join(sharedDiskCutover.NotReady, ", "))
+		label = constants.StartCutOverNo
+	case slots.Held:
+		// The slot was taken by an earlier reconcile that failed to update the status
+	case sharedDiskCutover != nil && sharedDiskCutover.SlotHeld:
+		condition.Reason = "SharedDiskGroup"
+		condition.Message = "Cutover started with the VMs sharing disks"
+	case settings.MaxConcurrentCutovers > 0 && slots.Total >= settings.MaxConcurrentCutovers:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "CutoverSlotsFull"
+		condition.Message = fmt.Sprintf("Waiting until fewer than %d migrations cut over", settings.MaxConcurrentCutovers)
+		label = constants.StartCutOverNo
+	case planLimit > 0 && slots.Plan >= planLimit:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "PlanCutoverSlotsFull"
+		condition.Message = fmt.Sprintf("Waiting until fewer than %d migrations of the plan cut over", planLimit)
+		label = constants.StartCutOverNo
+	}
+
+	unchanged := false
+	conditions := []corev1.PodCondition{}
+	for _, c := range migration.Status.Conditions {
+		if c.Type != constants.MigrationConditionTypeCutoverSlot {
+			conditions = append(conditions, c)
+		} else if c.Reason == condition.Reason {
+			condition.LastTransitionTime = c.LastTransitionTime
+			unchanged = true
+		}
+	}
+	if label == constants.StartCutOverYes && !slots.Held {
+		if err := slots.Take(ctx, r.Client, migration); err != nil {
+			return "", err
+		}
+	}
+	if unchanged {
+		return label, nil
+	}
+	migration.Status.Conditions = append(conditions, *condition)
+	// The slot is recorded before the helper is told, so that it is counted by the next migrations waiting for one
+	if err := r.Status().Update(ctx, migration); err != nil {
+		return "", errors.Wrap(err, "failed to update migration status")
+	}
+	return label, nil
+}
+
 // reconcileDelete handles the cleanup logic when Migration object is deleted.
 func (r *MigrationReconciler) reconcileDelete(ctx context.Context, migration *migratev1alpha1.Migration) error {
 	ctxlog := log.FromContext(ctx).WithName(constants.MigrationControllerName)
@@ -192,6 +399,12 @@ func (r *MigrationReconciler) reconcileDelete(ctx context.Context, migration *mi
 		return nil
 	}
 
+	if migration.Status.Phase != migratev1alpha1.VMMigrationPhaseSucceeded {
+		if err := utils.ResetSharedDisks(ctx, r.Client, migration); err != nil {
+			return errors.Wrap(err, "failed to reset shared disks")
+		}
+	}
+
 	vmwareCredsName, err := utils.GetVMwareCredsNameFromMigration(ctx, r.Client, migration)
 	if err != nil {
 		ctxlog.Error(err, "Failed to get VMware credentials name for migration")
@@ -226,6 +439,61 @@ func (r *MigrationReconciler) reconcileDelete(ctx context.Context, migration *mi
 	return nil
 }
 
+// deleteKeptVolumes deletes the volumes the v2v-helper kept after failed attempts of a deleted migration to resume
+// its disk copy, unless the migration is deleted to be retried. The v2v-helper is stopped first, and volumes still
+// attached to a helper VM are detached. It reports whether the volumes are all gone.
+func (r *MigrationReconciler) deleteKeptVolumes(ctx context.Context, migration *migratev1alpha1.Migration) (bool, error) {
+	ctxlog := log.FromContext(ctx).WithName(constants.MigrationControllerName)
+	if migration.Annotations[constants.MigrationRetryAnnotation] != "" || migration.Spec.VMName == "" {
+		return true, nil
+	}
+	vmwareCredsName, err := utils.GetVMwareCredsNameFromMigration(ctx, r.Client, migration)
+	if err != nil {
+		ctxlog.Error(err, "Failed to get VMware credentials name for migration, not deleting kept volumes")
+		return true, nil
+	}
+	vmK8sName, err := utils.GetK8sCompatibleVMWareObjectName(migration.Spec.VMName, vmwareCredsName)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get vm name")
+	}
+	switch migration.Status.Phase {
+	case migratev1alpha1.VMMigrationPhaseSucceeded, migratev1alpha1.VMMigrationPhaseRollingBack, migratev1alpha1.VMMigrationPhaseRolledBack:
+		// The volumes are those of the target instance, a checkpoint left behind does not refer to kept volumes
+		return true, utils.DeleteCopyCheckpoint(ctx, r.Client, vmK8sName)
+	}
+	volumeIDs, err := utils.GetCopyCheckpointVolumes(ctx, r.Client, vmK8sName)
+	if err != nil {
+		return false, err
+	}
+	if len(volumeIDs) == 0 {
+		return true, utils.DeleteCopyCheckpoint(ctx, r.Client, vmK8sName)
+	}
+
+	jobName, err := utils.GetJobNameForVMName(migration.Spec.VMName, vmwareCredsName)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get job name")
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: jobName, Namespace: migration.Namespace}}
+	propagation := metav1.DeletePropagationBackground
+	if err := r.Delete(ctx, job, &client.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !apierrors.IsNotFound(err) {
+		return false, errors.Wrapf(err, "failed to delete job %s", jobName)
+	}
+
+	openstackClients, err := r.getRollbackOpenStackClients(ctx, migration)
+	if err != nil {
+		return false, err
+	}
+	if err := detachVolumes(openstackClients, volumeIDs); err != nil {
+		return false, err
+	}
+	deleted, err := deleteVolumes(openstackClients, volumeIDs)
+	if err != nil || !deleted {
+		return false, err
+	}
+	ctxlog.Info("Deleted volumes kept to resume the disk copy", "migration", migration.Name, "volumes", volumeIDs)
+	return true, utils.DeleteCopyCheckpoint(ctx, r.Client, vmK8sName)
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *MigrationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
@@ -241,6 +509,14 @@ func (r *MigrationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 					if !ok {
 						return false
 					}
+					if oldpod.Annotations[openstackconst.MigrationProgressAnnotation] != newpod.Annotations[openstackconst.MigrationProgressAnnotation] ||
+						oldpod.Annotations[openstackconst.MigrationHookResultsAnnotation] != newpod.Annotations[openstackconst.MigrationHookResultsAnnotation] ||
+						oldpod.Annotations[openstackconst.MigrationTestBootAnnotation] != newpod.Annotations[openstackconst.MigrationTestBootAnnotation] ||
+						oldpod.Annotations[openstackconst.MigrationSnapshotsAnnotation] != newpod.Annotations[openstackconst.MigrationSnapshotsAnnotation] ||
+						oldpod.Annotations[openstackconst.MigrationVolumeExtensionsAnnotation] != newpod.Annotations[openstackconst.MigrationVolumeExtensionsAnnotation] ||
+						oldpod.Annotations[openstackconst.MigrationSharedDisksAnnotation] != newpod.Annotations[openstackconst.MigrationSharedDisksAnnotation] {
+						return true
+					}
 					for _, condition := range newpod.Status.Conditions {
 						// Ignores the disk percentage updates in the pod custom conditions
 						if condition.Type == "Progressing" && !strings.Contains(condition.Message, "Progress:") {
@@ -269,6 +545,11 @@ func (r *MigrationReconciler) SetupMigrationPhase(ctx context.Context, scope *sc
 		return err
 	}
 
+	// The phase published by the helper is used when there is one, the events are only parsed for older helpers
+	if progress := scope.Migration.Status.Progress; progress != nil && progress.Phase != "" {
+		return r.setPhaseFromProgress(ctx, scope, progress.Phase, pod)
+	}
+
 	IgnoredPhases := []migratev1alpha1.VMMigrationPhase{
 		migratev1alpha1.VMMigrationPhaseValidating,
 		migratev1alpha1.VMMigrationPhasePending}
@@ -330,6 +611,25 @@ loop:
 	return nil
 }
 
+// setPhaseFromProgress sets the phase of the migration to the phase published by the helper. Finished and
+// rolled back migrations keep their phase.
+func (r *MigrationReconciler) setPhaseFromProgress(ctx context.Context, scope *scope.MigrationScope, phase migratev1alpha1.VMMigrationPhase, pod *corev1.Pod) error {
+	switch scope.Migration.Status.Phase {
+	case migratev1alpha1.VMMigrationPhaseSucceeded, migratev1alpha1.VMMigrationPhaseFailed,
+		migratev1alpha1.VMMigrationPhaseRollingBack, migratev1alpha1.VMMigrationPhaseRolledBack:
+		return nil
+	}
+	switch {
+	case phase == migratev1alpha1.VMMigrationPhaseSucceeded:
+		return r.markMigrationSuccessful(ctx, scope)
+	case phase == migratev1alpha1.VMMigrationPhaseAwaitingAdminCutOver && pod.Labels["startCutover"] == "yes":
+		// The helper moves on as soon as it sees the label, keep the phase it had until then
+		return nil
+	}
+	scope.Migration.Status.Phase = phase
+	return nil
+}
+
 // Extracted function to handle successful migration updates
 func (r *MigrationReconciler) markMigrationSuccessful(ctx context.Context, scope *scope.MigrationScope) error {
 	scope.Migration.Status.Phase = migratev1alpha1.VMMigrationPhaseSucceeded
@@ -404,3 +704,317 @@ func (r *MigrationReconciler) GetPod(ctx context.Context, scope *scope.Migration
 	}
 	return &podList.Items[0], nil
 }
+
+// rollbackStep is a step of the rollback of a migration. run returns false while the step is still in progress.
+type rollbackStep struct {
+	condition corev1.PodConditionType
+	run       func() (bool, error)
+}
+
+// reconcileRollback reverts a completed cutover. The OpenStack server, its ports and volumes are
+// deleted, then the source VM is restored and powered on. Each step is recorded as a condition
+// and is not repeated once it is done.
+func (r *MigrationReconciler) reconcileRollback(ctx context.Context, migration *migratev1alpha1.Migration) (ctrl.Result, error) {
+	ctxlog := log.FromContext(ctx).WithName(constants.MigrationControllerName)
+
+	switch migration.Status.Phase {
+	case migratev1alpha1.VMMigrationPhaseRolledBack:
+		return ctrl.Result{}, nil
+	case migratev1alpha1.VMMigrationPhaseSucceeded, migratev1alpha1.VMMigrationPhaseRollingBack:
+	default:
+		ctxlog.Info("Only succeeded migrations can be rolled back", "migration", migration.Name, "phase", migration.Status.Phase)
+		return ctrl.Result{}, nil
+	}
+
+	if migration.Status.Phase != migratev1alpha1.VMMigrationPhaseRollingBack {
+		migration.Status.Phase = migratev1alpha1.VMMigrationPhaseRollingBack
+		if err := r.Status().Update(ctx, migration); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to update migration phase")
+		}
+	}
+	// The shared disks copied by the migration are no longer its copy once it is rolled back
+	if err := utils.ResetSharedDisks(ctx, r.Client, migration); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to reset shared disks")
+	}
+
+	openstackClients, err := r.getRollbackOpenStackClients(ctx, migration)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	vcClient, err := r.getRollbackVCenterClient(ctx, migration)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	defer func() {
+		if vcClient.VCClient != nil {
+			if err := session.NewManager(vcClient.VCClient).Logout(ctx); err != nil {
+				ctxlog.Error(err, "Failed to logout from vCenter")
+			}
+		}
+	}()
+
+	targetResources := migration.Status.TargetResources
+	if targetResources == nil {
+		targetResources = &migratev1alpha1.MigrationTargetResources{}
+	}
+	sourceVMChanges := migration.Status.SourceVMChanges
+	if sourceVMChanges == nil {
+		sourceVMChanges = &migratev1alpha1.SourceVMChanges{}
+	}
+
+	steps := []rollbackStep{
+		{constants.MigrationConditionTypeRollbackTargetInstanceDeleted, func() (bool, error) {
+			return deleteServer(openstackClients, targetResources.ServerID)
+		}},
+		{constants.MigrationConditionTypeRollbackTargetPortsDeleted, func() (bool, error) {
+			return deletePorts(openstackClients, targetResources.PortIDs)
+		}},
+		{constants.MigrationConditionTypeRollbackTargetVolumesDeleted, func() (bool, error) {
+			return deleteVolumes(openstackClients, targetResources.VolumeIDs)
+		}},
+		{constants.MigrationConditionTypeRollbackSourceVMRestored, func() (bool, error) {
+			return true, restoreSourceVM(ctx, vcClient, migration.Spec.VMName, sourceVMChanges)
+		}},
+		{constants.MigrationConditionTypeRollbackSourceNetworkReconnected, func() (bool, error) {
+			if !migration.Spec.DisconnectSourceNetwork {
+				return true, nil
+			}
+			return true, vcClient.ConnectNetworkInterfaces(ctx, migration.Spec.VMName)
+		}},
+		{constants.MigrationConditionTypeRollbackSourceVMPoweredOn, func() (bool, error) {
+			return true, vcClient.PowerOnVM(ctx, migration.Spec.VMName)
+		}},
+	}
+	done, err := r.runRollbackSteps(ctx, migration, steps)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !done {
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	if err := r.setVMwareMachineMigrated(ctx, migration, false); err != nil {
+		return ctrl.Result{}, err
+	}
+	migration.Status.Phase = migratev1alpha1.VMMigrationPhaseRolledBack
+	if err := r.Status().Update(ctx, migration); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to update migration phase")
+	}
+	ctxlog.Info("Migration rolled back", "migration", migration.Name)
+	return ctrl.Result{}, nil
+}
+
+// runRollbackSteps runs the rollback steps in order, skipping those recorded as done, and records the outcome of the
+// step it stops at. It reports whether all steps are done.
+func (r *MigrationReconciler) runRollbackSteps(ctx context.Context, migration *migratev1alpha1.Migration, steps []rollbackStep) (bool, error) {
+	for _, step := range steps {
+		idx := utils.GetConditonIndex(migration.Status.Conditions, step.condition, constants.MigrationReason)
+		if idx != -1 && migration.Status.Conditions[idx].Status == corev1.ConditionTrue {
+			continue
+		}
+		done, stepErr := step.run()
+		switch {
+		case stepErr != nil:
+			setRollbackCondition(migration, step.condition, corev1.ConditionFalse, stepErr.Error())
+		case !done:
+			setRollbackCondition(migration, step.condition, corev1.ConditionFalse, "In progress")
+		default:
+			setRollbackCondition(migration, step.condition, corev1.ConditionTrue, "Done")
+		}
+		if err := r.Status().Update(ctx, migration); err != nil {
+			return false, errors.Wrap(err, "failed to update rollback conditions")
+		}
+		if stepErr != nil {
+			return false, errors.Wrapf(stepErr, "rollback step %s failed", step.condition)
+		}
+		if !done {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// getRollbackOpenStackClients returns the clients of the destination OpenStack of a migration
+func (r *MigrationReconciler) getRollbackOpenStackClients(ctx context.Context, migration *migratev1alpha1.Migration) (*utils.OpenStackClients, error) {
+	openstackCredsName, err := utils.GetOpenstackCredsNameFromMigration(ctx, r.Client, migration)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get openstack credentials name")
+	}
+	openstackcreds := &migratev1alpha1.OpenstackCreds{}
+	if err := r.Get(ctx, types.NamespacedName{Name: openstackCredsName, Namespace: migration.Namespace}, openstackcreds); err != nil {
+		return nil, errors.Wrap(err, "failed to get openstack credentials")
+	}
+	openstackClients, err := utils.GetOpenStackClients(ctx, r.Client, openstackcreds)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get openstack clients")
+	}
+	return openstackClients, nil
+}
+
+// getRollbackVCenterClient returns a client of the source vCenter of a migration
+func (r *MigrationReconciler) getRollbackVCenterClient(ctx context.Context, migration *migratev1alpha1.Migration) (*vcenter.VCenterClient, error) {
+	vmwareCredsName, err := utils.GetVMwareCredsNameFromMigration(ctx, r.Client, migration)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get vmware credentials name")
+	}
+	vmwcreds := &migratev1alpha1.VMwareCreds{}
+	if err := r.Get(ctx, types.NamespacedName{Name: vmwareCredsName, Namespace: migration.Namespace}, vmwcreds); err != nil {
+		return nil, errors.Wrap(err, "failed to get vmware credentials")
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: vmwcreds.Spec.SecretRef.Name, Namespace: migration.Namespace}, secret); err != nil {
+		return nil, errors.Wrap(err, "failed to get vCenter Secret")
+	}
+	username, password, host, err := extractVCenterCredentials(secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid vCenter credentials")
+	}
+	vcClient, _, err := createVCenterClientAndDC(ctx, host, username, password, vmwcreds.Spec.DataCenter)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create vCenter client")
+	}
+	return vcClient, nil
+}
+
+// setVMwareMachineMigrated sets the migrated status of the VMwareMachine of a migration
+func (r *MigrationReconciler) setVMwareMachineMigrated(ctx context.Context, migration *migratev1alpha1.Migration, migrated bool) error {
+	vmwareCredsName, err := utils.GetVMwareCredsNameFromMigration(ctx, r.Client, migration)
+	if err != nil {
+		return errors.Wrap(err, "failed to get vmware credentials name")
+	}
+	name, err := utils.GetK8sCompatibleVMWareObjectName(migration.Spec.VMName, vmwareCredsName)
+	if err != nil {
+		return errors.Wrap(err, "failed to get vmware machine name")
+	}
+	vmwvm := &migratev1alpha1.VMwareMachine{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: migration.Namespace}, vmwvm); err != nil {
+		return errors.Wrap(err, "failed to get vmware machine")
+	}
+	if vmwvm.Status.Migrated == migrated {
+		return nil
+	}
+	vmwvm.Status.Migrated = migrated
+	return r.Status().Update(ctx, vmwvm)
+}
+
+// setRollbackCondition sets the condition of a rollback step on the migration
+func setRollbackCondition(migration *migratev1alpha1.Migration, conditionType corev1.PodConditionType, status corev1.ConditionStatus, message string) {
+	condition := utils.GeneratePodCondition(conditionType, status, constants.MigrationReason, message, metav1.Now())
+	idx := utils.GetConditonIndex(migration.Status.Conditions, conditionType, constants.MigrationReason)
+	if idx == -1 {
+		migration.Status.Conditions = append(migration.Status.Conditions, *condition)
+		return
+	}
+	migration.Status.Conditions[idx] = *condition
+}
+
+// healthCheckFailed reports whether the helper reported a failed health check of the target VM
+func healthCheckFailed(events *corev1.EventList) bool {
+	for i := range events.Items {
+		if strings.Contains(events.Items[i].Message, openstackconst.EventMessageHealthCheckFailed) {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteServer deletes the OpenStack server and reports whether it is gone
+func deleteServer(openstackClients *utils.OpenStackClients, serverID string) (bool, error) {
+	if serverID == "" {
+		return true, nil
+	}
+	if _, err := servers.Get(openstackClients.ComputeClient, serverID).Extract(); err != nil {
+		if isOpenStackNotFound(err) {
+			return true, nil
+		}
+		return false, errors.Wrapf(err, "failed to get server %s", serverID)
+	}
+	if err := servers.Delete(openstackClients.ComputeClient, serverID).ExtractErr(); err != nil && !isOpenStackNotFound(err) {
+		return false, errors.Wrapf(err, "failed to delete server %s", serverID)
+	}
+	return false, nil
+}
+
+// deletePorts deletes the OpenStack ports created by the migration
+func deletePorts(openstackClients *utils.OpenStackClients, portIDs []string) (bool, error) {
+	for _, portID := range portIDs {
+		if err := ports.Delete(openstackClients.NetworkingClient, portID).ExtractErr(); err != nil && !isOpenStackNotFound(err) {
+			return false, errors.Wrapf(err, "failed to delete port %s", portID)
+		}
+	}
+	return true, nil
+}
+
+// deleteVolumes deletes the volumes the disks were copied to and reports whether they are all gone.
+// Volumes still detaching from the deleted server are deleted on a later call.
+func deleteVolumes(openstackClients *utils.OpenStackClients, volumeIDs []string) (bool, error) {
+	done := true
+	for _, volumeID := range volumeIDs {
+		volume, err := volumes.Get(openstackClients.BlockStorageClient, volumeID).Extract()
+		if err != nil {
+			if isOpenStackNotFound(err) {
+				continue
+			}
+			return false, errors.Wrapf(err, "failed to get volume %s", volumeID)
+		}
+		done = false
+		if volume.Status == "in-use" || volume.Status == "detaching" || volume.Status == "deleting" {
+			continue
+		}
+		if err := volumes.Delete(openstackClients.BlockStorageClient, volumeID, volumes.DeleteOpts{}).ExtractErr(); err != nil && !isOpenStackNotFound(err) {
+			return false, errors.Wrapf(err, "failed to delete volume %s", volumeID)
+		}
+	}
+	return done, nil
+}
+
+// detachVolumes detaches the volumes that are attached to a server
+func detachVolumes(openstackClients *utils.OpenStackClients, volumeIDs []string) error {
+	for _, volumeID := range volumeIDs {
+		volume, err := volumes.Get(openstackClients.BlockStorageClient, volumeID).Extract()
+		if err != nil {
+			if isOpenStackNotFound(err) {
+				continue
+			}
+			return errors.Wrapf(err, "failed to get volume %s", volumeID)
+		}
+		if volume.Status != "in-use" {
+			continue
+		}
+		for _, attachment := range volume.Attachments {
+			if err := volumeattach.Delete(openstackClients.ComputeClient, attachment.ServerID, volumeID).ExtractErr(); err != nil && !isOpenStackNotFound(err) {
+				return errors.Wrapf(err, "failed to detach volume %s from server %s", volumeID, attachment.ServerID)
+			}
+		}
+	}
+	return nil
+}
+
+// restoreSourceVM undoes the rename and folder move done to the source VM after the migration
+func restoreSourceVM(ctx context.Context, vcClient *vcenter.VCenterClient, vmName string, changes *migratev1alpha1.SourceVMChanges) error {
+	if changes.RenamedTo != "" {
+		if _, err := vcClient.GetVMByName(ctx, changes.RenamedTo); err == nil {
+			if err := vcClient.RenameVM(ctx, changes.RenamedTo, vmName); err != nil {
+				return errors.Wrapf(err, "failed to rename VM '%s' back to '%s'", changes.RenamedTo, vmName)
+			}
+		}
+	}
+	if changes.OriginalFolder != "" {
+		folder, err := vcClient.GetVMFolderPath(ctx, vmName)
+		if err != nil {
+			return errors.Wrap(err, "failed to get folder of VM")
+		}
+		if folder != changes.OriginalFolder {
+			if err := vcClient.MoveVMFolder(ctx, vmName, changes.OriginalFolder); err != nil {
+				return errors.Wrapf(err, "failed to move VM '%s' back to folder '%s'", vmName, changes.OriginalFolder)
+			}
+		}
+	}
+	return nil
+}
+
+// isOpenStackNotFound reports whether an OpenStack request failed because the resource does not exist
+func isOpenStackNotFound(err error) bool {
+	var notFound gophercloud.ErrDefault404
+	return errors.As(err, &notFound)
+}
@@ -18,14 +18,34 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
 
+	"github.com/gophercloud/gophercloud"
 	"github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
-	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/soap"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/utils"
+	openstackconst "github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vcenter"
 )
 
 var _ = ginkgo.Describe("Migration Controller", func() {
@@ -81,3 +101,204 @@ var _ = ginkgo.Describe("Migration Controller", func() {
 		})
 	})
 })
+
+var _ = ginkgo.Describe("Migration rollback", func() {
+	ctx := context.Background()
+
+	ginkgo.Describe("runRollbackSteps", func() {
+		var (
+			reconciler *MigrationReconciler
+			migration  *migratev1alpha1.Migration
+			ran        []corev1.PodConditionType
+		)
+		step := func(condition corev1.PodConditionType, done bool, err error) rollbackStep {
+			return rollbackStep{condition, func() (bool, error) {
+				ran = append(ran, condition)
+				return done, err
+			}}
+		}
+		conditionOf := func(conditionType corev1.PodConditionType) *corev1.PodCondition {
+			updated := &migratev1alpha1.Migration{}
+			gomega.Expect(reconciler.Get(ctx, types.NamespacedName{Name: migration.Name, Namespace: migration.Namespace}, updated)).To(gomega.Succeed())
+			idx := utils.GetConditonIndex(updated.Status.Conditions, conditionType, constants.MigrationReason)
+			if idx == -1 {
+				return nil
+			}
+			return &updated.Status.Conditions[idx]
+		}
+
+		ginkgo.BeforeEach(func() {
+			ran = nil
+			migration = &migratev1alpha1.Migration{
+				ObjectMeta: metav1.ObjectMeta{Name: "migration-vm-1", Namespace: "default"},
+				Status:     migratev1alpha1.MigrationStatus{Phase: migratev1alpha1.VMMigrationPhaseRollingBack},
+			}
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithStatusSubresource(&migratev1alpha1.Migration{}).WithObjects(migration).Build()
+			reconciler = &MigrationReconciler{Client: k8sClient, Scheme: scheme.Scheme}
+		})
+
+		ginkgo.It("runs the steps in order and records each of them", func() {
+			done, err := reconciler.runRollbackSteps(ctx, migration, []rollbackStep{
+				step(constants.MigrationConditionTypeRollbackTargetInstanceDeleted, true, nil),
+				step(constants.MigrationConditionTypeRollbackTargetPortsDeleted, true, nil),
+				step(constants.MigrationConditionTypeRollbackTargetVolumesDeleted, true, nil),
+			})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(done).To(gomega.BeTrue())
+			gomega.Expect(ran).To(gomega.Equal([]corev1.PodConditionType{
+				constants.MigrationConditionTypeRollbackTargetInstanceDeleted,
+				constants.MigrationConditionTypeRollbackTargetPortsDeleted,
+				constants.MigrationConditionTypeRollbackTargetVolumesDeleted,
+			}))
+			gomega.Expect(conditionOf(constants.MigrationConditionTypeRollbackTargetVolumesDeleted).Status).To(gomega.Equal(corev1.ConditionTrue))
+		})
+
+		ginkgo.It("stops at a step in progress and resumes after the steps recorded as done", func() {
+			steps := []rollbackStep{
+				step(constants.MigrationConditionTypeRollbackTargetInstanceDeleted, true, nil),
+				step(constants.MigrationConditionTypeRollbackTargetPortsDeleted, false, nil),
+				step(constants.MigrationConditionTypeRollbackTargetVolumesDeleted, true, nil),
+			}
+			done, err := reconciler.runRollbackSteps(ctx, migration, steps)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(done).To(gomega.BeFalse())
+			gomega.Expect(conditionOf(constants.MigrationConditionTypeRollbackTargetPortsDeleted).Message).To(gomega.Equal("In progress"))
+			gomega.Expect(conditionOf(constants.MigrationConditionTypeRollbackTargetVolumesDeleted)).To(gomega.BeNil())
+
+			ran = nil
+			steps[1] = step(constants.MigrationConditionTypeRollbackTargetPortsDeleted, true, nil)
+			done, err = reconciler.runRollbackSteps(ctx, migration, steps)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(done).To(gomega.BeTrue())
+			gomega.Expect(ran).To(gomega.Equal([]corev1.PodConditionType{
+				constants.MigrationConditionTypeRollbackTargetPortsDeleted,
+				constants.MigrationConditionTypeRollbackTargetVolumesDeleted,
+			}))
+		})
+
+		ginkgo.It("records the error of a failed step and runs no further steps", func() {
+			done, err := reconciler.runRollbackSteps(ctx, migration, []rollbackStep{
+				step(constants.MigrationConditionTypeRollbackSourceVMRestored, false, fmt.Errorf("VM not found")),
+				step(constants.MigrationConditionTypeRollbackSourceVMPoweredOn, true, nil),
+			})
+			gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("VM not found")))
+			gomega.Expect(done).To(gomega.BeFalse())
+			gomega.Expect(ran).To(gomega.HaveLen(1))
+			condition := conditionOf(constants.MigrationConditionTypeRollbackSourceVMRestored)
+			gomega.Expect(condition.Status).To(gomega.Equal(corev1.ConditionFalse))
+			gomega.Expect(condition.Message).To(gomega.Equal("VM not found"))
+		})
+	})
+
+	ginkgo.Describe("deleteVolumes", func() {
+		var (
+			server   *httptest.Server
+			mu       sync.Mutex
+			statuses map[string]string
+			deleted  []string
+		)
+
+		ginkgo.BeforeEach(func() {
+			statuses = map[string]string{"in-use": "in-use", "available": "available"}
+			deleted = nil
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				id := strings.TrimPrefix(r.URL.Path, "/volumes/")
+				status, ok := statuses[id]
+				switch {
+				case !ok:
+					w.WriteHeader(http.StatusNotFound)
+				case r.Method == http.MethodDelete:
+					deleted = append(deleted, id)
+					delete(statuses, id)
+					w.WriteHeader(http.StatusAccepted)
+				default:
+					w.Header().Set("Content-Type", "application/json")
+					fmt.Fprintf(w, `{"volume": {"id": %q, "status": %q}}`, id, status)
+				}
+			}))
+		})
+
+		ginkgo.AfterEach(func() {
+			server.Close()
+		})
+
+		ginkgo.It("deletes the volumes that are not attached and waits for the others", func() {
+			openstackClients := &utils.OpenStackClients{BlockStorageClient: &gophercloud.ServiceClient{
+				ProviderClient: &gophercloud.ProviderClient{HTTPClient: *http.DefaultClient},
+				Endpoint:       server.URL + "/",
+			}}
+			volumeIDs := []string{"gone", "in-use", "available"}
+
+			done, err := deleteVolumes(openstackClients, volumeIDs)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(done).To(gomega.BeFalse())
+			gomega.Expect(deleted).To(gomega.Equal([]string{"available"}))
+
+			mu.Lock()
+			statuses["in-use"] = "available"
+			mu.Unlock()
+			done, err = deleteVolumes(openstackClients, volumeIDs)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(done).To(gomega.BeFalse())
+			gomega.Expect(deleted).To(gomega.Equal([]string{"available", "in-use"}))
+
+			done, err = deleteVolumes(openstackClients, volumeIDs)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(done).To(gomega.BeTrue())
+		})
+	})
+
+	ginkgo.Describe("restoreSourceVM", func() {
+		ginkgo.It("renames the VM back and moves it back to its folder", func() {
+			model := simulator.VPX()
+			gomega.Expect(model.Create()).To(gomega.Succeed())
+			defer model.Remove()
+			server := model.Service.NewServer()
+			defer server.Close()
+			u, err := soap.ParseURL(server.URL.String())
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			u.User = url.UserPassword("user", "pass")
+			client, err := govmomi.NewClient(ctx, u, true)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			vcClient := &vcenter.VCenterClient{
+				VCClient:            client.Client,
+				VCFinder:            find.NewFinder(client.Client, false),
+				VCPropertyCollector: property.DefaultCollector(client.Client),
+			}
+
+			folder, err := vcClient.VCFinder.Folder(ctx, "/DC0/vm")
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			_, err = folder.CreateFolder(ctx, "migrated")
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(vcClient.MoveVMFolder(ctx, "DC0_H0_VM0", "/DC0/vm/migrated")).To(gomega.Succeed())
+			gomega.Expect(vcClient.RenameVM(ctx, "DC0_H0_VM0", "DC0_H0_VM0-migrated")).To(gomega.Succeed())
+
+			gomega.Expect(restoreSourceVM(ctx, vcClient, "DC0_H0_VM0", &migratev1alpha1.SourceVMChanges{
+				RenamedTo:      "DC0_H0_VM0-migrated",
+				OriginalFolder: "/DC0/vm",
+			})).To(gomega.Succeed())
+			folderPath, err := vcClient.GetVMFolderPath(ctx, "DC0_H0_VM0")
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(folderPath).To(gomega.Equal("/DC0/vm"))
+
+			// Nothing is left to restore once the VM is back
+			gomega.Expect(restoreSourceVM(ctx, vcClient, "DC0_H0_VM0", &migratev1alpha1.SourceVMChanges{
+				RenamedTo:      "DC0_H0_VM0-migrated",
+				OriginalFolder: "/DC0/vm",
+			})).To(gomega.Succeed())
+		})
+	})
+
+	ginkgo.Describe("healthCheckFailed", func() {
+		ginkgo.It("looks for the failed health check event of the helper", func() {
+			events := &corev1.EventList{Items: []corev1.Event{{Message: "Copying disk 0"}}}
+			gomega.Expect(healthCheckFailed(events)).To(gomega.BeFalse())
+
+			events.Items = append(events.Items, corev1.Event{Message: openstackconst.EventMessageHealthCheckFailed + ": no ping reply"})
+			gomega.Expect(healthCheckFailed(events)).To(gomega.BeTrue())
+		})
+	})
+})
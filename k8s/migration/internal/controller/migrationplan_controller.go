@@ -18,6 +18,7 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/user"
@@ -33,12 +34,12 @@ import (
 	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
 	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/scope"
 	utils "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/utils"
+	openstackconst "github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vcenter"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -66,6 +67,30 @@ type MigrationPlanReconciler struct {
 
 var migrationPlanFinalizer = "migrationplan.migrate.stellaris.io/finalizer"
 
+// errMigrationsQueued is returned by TriggerMigration when VMs wait for a free migration agent or migration slot
+var errMigrationsQueued = errors.New("migrations are queued until a migration agent or slot is free")
+
+// connectionBudget tracks the vCenter sessions and ESXi NFC connections taken by a migration plan within one reconcile
+type connectionBudget struct {
+	vcenter string
+	usage   *utils.VCenterConnectionUsage
+}
+
+// migrationAdmission tracks the migration slots taken by a migration plan within one reconcile
+type migrationAdmission struct {
+	loaded        bool
+	running       int
+	planRunning   int
+	queuePosition int
+	waiting       bool
+}
+
+// take counts the slot of a migration whose job is about to be created
+func (a *migrationAdmission) take() {
+	a.running++
+	a.planRunning++
+}
+
 // The default image. This is replaced by Go linker flags in the Dockerfile
 var v2vimage = "stellaris/stellaris-migrate-v2v-helper:0.0.1"
 
@@ -138,7 +163,6 @@ func (r *MigrationPlanReconciler) reconcileNormal(ctx context.Context, scope *sc
 	return ctrl.Result{}, nil
 }
 
-//nolint:unparam //future use
 func (r *MigrationPlanReconciler) reconcileDelete(
 	ctx context.Context,
 	scope *scope.MigrationPlanScope) (ctrl.Result, error) {
@@ -148,6 +172,26 @@ func (r *MigrationPlanReconciler) reconcileDelete(
 	// The object is being deleted
 	ctxlog.Info(fmt.Sprintf("MigrationPlan '%s' CR is being deleted", migrationplan.Name))
 
+	// The Migrations are deleted before the plan, which owns the copy checkpoints they read to delete the volumes
+	// kept by failed attempts
+	migrations := &migratev1alpha1.MigrationList{}
+	if err := r.List(ctx, migrations, client.InNamespace(migrationplan.Namespace),
+		client.MatchingLabels{constants.MigrationPlanLabel: migrationplan.Name}); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to list migrations")
+	}
+	for i := range migrations.Items {
+		if !migrations.Items[i].DeletionTimestamp.IsZero() {
+			continue
+		}
+		if err := r.Delete(ctx, &migrations.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to delete migration %s", migrations.Items[i].Name)
+		}
+	}
+	if len(migrations.Items) > 0 {
+		ctxlog.Info(fmt.Sprintf("Waiting for %d migration(s) of MigrationPlan '%s' to be deleted", len(migrations.Items), migrationplan.Name))
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
 	// Now that the finalizer has completed deletion tasks, we can remove it
 	// to allow deletion of the Migration object
 	controllerutil.RemoveFinalizer(migrationplan, migrationPlanFinalizer)
@@ -189,8 +233,9 @@ func (r *MigrationPlanReconciler) getMigrationTemplateAndCreds(
 	return migrationtemplate, vmwcreds, secret, nil
 }
 
-func (r *MigrationPlanReconciler) reconcilePostMigration(ctx context.Context, scope *scope.MigrationPlanScope, vm string) error {
+func (r *MigrationPlanReconciler) reconcilePostMigration(ctx context.Context, scope *scope.MigrationPlanScope, migration *migratev1alpha1.Migration) error {
 	migrationplan := scope.MigrationPlan
+	vm := migration.Spec.VMName
 	ctxlog := log.FromContext(ctx).WithName(constants.MigrationControllerName)
 
 	if migrationplan.Spec.PostMigrationAction == nil {
@@ -204,6 +249,17 @@ func (r *MigrationPlanReconciler) reconcilePostMigration(ctx context.Context, sc
 		return nil
 	}
 
+	// The changes are recorded on the migration so that they are done once and a rollback can undo them
+	changes := migration.Status.SourceVMChanges
+	if changes == nil {
+		changes = &migratev1alpha1.SourceVMChanges{}
+	}
+	renameVM := migrationplan.Spec.PostMigrationAction.RenameVM != nil && *migrationplan.Spec.PostMigrationAction.RenameVM
+	moveToFolder := migrationplan.Spec.PostMigrationAction.MoveToFolder != nil && *migrationplan.Spec.PostMigrationAction.MoveToFolder
+	if (!renameVM || changes.RenamedTo != "") && (!moveToFolder || changes.OriginalFolder != "") {
+		return nil
+	}
+
 	// Get required resources
 	_, vmwcreds, secret, err := r.getMigrationTemplateAndCreds(ctx, migrationplan)
 	if err != nil {
@@ -231,28 +287,52 @@ func (r *MigrationPlanReconciler) reconcilePostMigration(ctx context.Context, sc
 		}
 	}()
 
-	if migrationplan.Spec.PostMigrationAction.RenameVM != nil && *migrationplan.Spec.PostMigrationAction.RenameVM {
-		if err := r.renameVM(ctx, vcClient, migrationplan, vm); err != nil {
-			return errors.Wrap(err, "failed to rename VM")
+	if renameVM {
+		if changes.RenamedTo == "" {
+			newVMName, err := r.renameVM(ctx, vcClient, migrationplan, vm)
+			if err != nil {
+				return errors.Wrap(err, "failed to rename VM")
+			}
+			changes.RenamedTo = newVMName
+			if err := r.recordSourceVMChanges(ctx, migration, changes); err != nil {
+				return err
+			}
 		}
-		vm += migrationplan.Spec.PostMigrationAction.Suffix
+		vm = changes.RenamedTo
 	}
 
-	if migrationplan.Spec.PostMigrationAction.MoveToFolder != nil && *migrationplan.Spec.PostMigrationAction.MoveToFolder {
+	if moveToFolder && changes.OriginalFolder == "" {
+		originalFolder, err := vcClient.GetVMFolderPath(ctx, vm)
+		if err != nil {
+			return errors.Wrap(err, "failed to get folder of VM")
+		}
 		if err := r.moveVMToFolder(ctx, vcClient, dc, migrationplan, vm); err != nil {
 			return errors.Wrap(err, "failed to move VM to folder")
 		}
+		changes.OriginalFolder = originalFolder
+		if err := r.recordSourceVMChanges(ctx, migration, changes); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// recordSourceVMChanges records the post-migration changes of the source VM on the migration
+func (r *MigrationPlanReconciler) recordSourceVMChanges(ctx context.Context, migration *migratev1alpha1.Migration, changes *migratev1alpha1.SourceVMChanges) error {
+	migration.Status.SourceVMChanges = changes
+	if err := r.Status().Update(ctx, migration); err != nil {
+		return errors.Wrapf(err, "failed to record source VM changes of migration '%s'", migration.Name)
+	}
+	return nil
+}
+
 func (*MigrationPlanReconciler) renameVM(
 	ctx context.Context,
 	vcClient *vcenter.VCenterClient,
 	migrationplan *migratev1alpha1.MigrationPlan,
 	vm string,
-) error {
+) (string, error) {
 	ctxlog := log.FromContext(ctx)
 	suffix := migrationplan.Spec.PostMigrationAction.Suffix
 	if suffix == "" {
@@ -261,7 +341,7 @@ func (*MigrationPlanReconciler) renameVM(
 	}
 	newVMName := vm + suffix
 	ctxlog.Info("Renaming VM", "oldName", vm, "newName", newVMName)
-	return vcClient.RenameVM(ctx, vm, newVMName)
+	return newVMName, vcClient.RenameVM(ctx, vm, newVMName)
 }
 
 func (*MigrationPlanReconciler) moveVMToFolder(
@@ -360,6 +440,9 @@ func (r *MigrationPlanReconciler) ReconcileMigrationPlanJob(ctx context.Context,
 		false, openstackcreds); !ok {
 		return ctrl.Result{}, errors.Wrapf(err, "failed to check openstackcreds status '%s'", migrationtemplate.Spec.Destination.OpenstackRef)
 	}
+	if migrationplan.Spec.DryRun {
+		return ctrl.Result{}, r.reconcileDryRun(ctx, migrationplan, migrationtemplate, vmwcreds, openstackcreds)
+	}
 	// Starting the Migrations
 	if migrationplan.Status.MigrationStatus == "" {
 		err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodRunning, "Migration(s) in progress")
@@ -377,10 +460,20 @@ func (r *MigrationPlanReconciler) ReconcileMigrationPlanJob(ctx context.Context,
 		return ctrl.Result{}, nil
 	}
 
-	for _, parallelvms := range migrationplan.Spec.VirtualMachines {
-		migrationobjs := &migratev1alpha1.MigrationList{}
-		err := r.TriggerMigration(ctx, migrationplan, migrationobjs, openstackcreds, vmwcreds, migrationtemplate, parallelvms)
+	for idx, parallelvms := range migrationplan.Spec.VirtualMachines {
+		fits, err := r.reconcileBatchQuota(ctx, migrationplan, migrationtemplate, vmwcreds, openstackcreds, idx)
 		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to check quota")
+		}
+		if !fits {
+			return ctrl.Result{RequeueAfter: constants.QuotaRecheckInterval}, nil
+		}
+		migrationobjs := &migratev1alpha1.MigrationList{}
+		queued := false
+		err = r.TriggerMigration(ctx, migrationplan, migrationobjs, openstackcreds, vmwcreds, migrationtemplate, parallelvms)
+		if errors.Is(err, errMigrationsQueued) {
+			queued = true
+		} else if err != nil {
 			if strings.Contains(err.Error(), "VDDK_MISSING") {
 				r.ctxlog.Info("Requeuing due to missing VDDK files.")
 				return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
@@ -393,7 +486,14 @@ func (r *MigrationPlanReconciler) ReconcileMigrationPlanJob(ctx context.Context,
 				r.ctxlog.Info(fmt.Sprintf("Migration for VM '%s' failed", migrationobjs.Items[i].Spec.VMName))
 				if migrationplan.Spec.Retry {
 					r.ctxlog.Info(fmt.Sprintf("Retrying migration for VM '%s'", migrationobjs.Items[i].Spec.VMName))
-					// Delete the migration so that it can be recreated
+					// Delete the migration so that it can be recreated, keeping the volumes to resume its disk copy
+					if migrationobjs.Items[i].Annotations == nil {
+						migrationobjs.Items[i].Annotations = map[string]string{}
+					}
+					migrationobjs.Items[i].Annotations[constants.MigrationRetryAnnotation] = "true"
+					if err := r.Update(ctx, &migrationobjs.Items[i]); err != nil {
+						return ctrl.Result{}, errors.Wrap(err, "failed to mark migration for retry")
+					}
 					err := r.Delete(ctx, &migrationobjs.Items[i])
 					if err != nil {
 						return ctrl.Result{}, errors.Wrap(err, "failed to delete migration")
@@ -414,14 +514,23 @@ func (r *MigrationPlanReconciler) ReconcileMigrationPlanJob(ctx context.Context,
 				}
 				return ctrl.Result{}, nil
 			case migratev1alpha1.VMMigrationPhaseSucceeded:
-				err := r.reconcilePostMigration(ctx, scope, migrationobjs.Items[i].Spec.VMName)
+				if migrationobjs.Items[i].Spec.Rollback {
+					r.ctxlog.Info(fmt.Sprintf("Waiting for rollback of VM '%s' to start", migrationobjs.Items[i].Spec.VMName))
+					return ctrl.Result{}, nil
+				}
+				err := r.reconcilePostMigration(ctx, scope, &migrationobjs.Items[i])
 				if err != nil {
 					r.ctxlog.Error(err, fmt.Sprintf("Post-migration actions failed for VM '%s'", migrationobjs.Items[i].Spec.VMName))
 					return ctrl.Result{}, errors.Wrap(err, "failed to reconcile post migration")
 				}
 				continue
+			case migratev1alpha1.VMMigrationPhaseRolledBack:
+				continue
 			default:
 				r.ctxlog.Info(fmt.Sprintf("Waiting for all VMs in parallel batch %d to complete: %v", i+1, parallelvms))
+				if queued {
+					return ctrl.Result{RequeueAfter: constants.AgentPlacementRecheckInterval}, nil
+				}
 				return ctrl.Result{}, nil
 			}
 		}
@@ -448,6 +557,500 @@ func (r *MigrationPlanReconciler) UpdateMigrationPlanStatus(ctx context.Context,
 	return nil
 }
 
+// reconcileBatchQuota checks that the Cinder and Nova quota left in the project covers a batch of VMs before
+// the batch starts, so that no VM is left half-migrated when the project runs out of quota. Batches that have
+// started are not checked again. If the batch does not fit, the migration plan waits in Pending with the reason
+// in its status.
+func (r *MigrationPlanReconciler) reconcileBatchQuota(ctx context.Context,
+	migrationplan *migratev1alpha1.MigrationPlan,
+	migrationtemplate *migratev1alpha1.MigrationTemplate,
+	vmwcreds *migratev1alpha1.VMwareCreds,
+	openstackcreds *migratev1alpha1.OpenstackCreds,
+	batch int) (bool, error) {
+	batches := migrationplan.Spec.VirtualMachines
+	for _, vm := range batches[batch] {
+		vmk8sname, err := utils.GetK8sCompatibleVMWareObjectName(vm, vmwcreds.Name)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get vm name")
+		}
+		migrationobj := &migratev1alpha1.Migration{}
+		err = r.Get(ctx, types.NamespacedName{Name: utils.MigrationNameFromVMName(vmk8sname), Namespace: migrationplan.Namespace}, migrationobj)
+		if err == nil {
+			return true, nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return false, errors.Wrap(err, "failed to get migration")
+		}
+	}
+
+	needs, err := r.batchQuotaNeeds(ctx, migrationplan, migrationtemplate, vmwcreds, openstackcreds, batches[batch:])
+	if err != nil {
+		return false, err
+	}
+	headroom, err := utils.GetQuotaHeadroom(ctx, r.Client, openstackcreds)
+	if err != nil {
+		// The batch is not started unchecked, the quota is fetched again on the next check
+		message := fmt.Sprintf("Waiting for quota to start batch %d of %d: %s", batch+1, len(batches), err)
+		r.ctxlog.Error(err, "Failed to get quota", "batch", batch+1)
+		if migrationplan.Status.MigrationStatus != corev1.PodPending || migrationplan.Status.MigrationMessage != message {
+			if err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodPending, message); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	if shortfalls := headroom.Shortfalls(needs[0]); len(shortfalls) > 0 {
+		message := fmt.Sprintf("Waiting for quota to start batch %d of %d: %s", batch+1, len(batches), strings.Join(shortfalls, ", "))
+		r.ctxlog.Info(message)
+		if migrationplan.Status.MigrationStatus != corev1.PodPending || migrationplan.Status.MigrationMessage != message {
+			if err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodPending, message); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	// Find how many of the following batches the quota covers as well
+	message := "Migration(s) in progress"
+	headroom.Take(needs[0])
+	for i := 1; i < len(needs); i++ {
+		if shortfalls := headroom.Shortfalls(needs[i]); len(shortfalls) > 0 {
+			message = fmt.Sprintf("Migration(s) in progress, quota covers batches %d-%d of %d, batch %d needs more: %s",
+				batch+1, batch+i, len(batches), batch+i+1, strings.Join(shortfalls, ", "))
+			break
+		}
+		headroom.Take(needs[i])
+	}
+	if migrationplan.Status.MigrationStatus != corev1.PodRunning || migrationplan.Status.MigrationMessage != message {
+		if err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodRunning, message); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// batchQuotaNeeds returns the quota each batch of VMs uses. VMs whose VMwareMachine or flavor cannot be found
+// are left out, the migration reports them when it starts. The sizes of the disks of VMs scanned before they were
+// recorded are looked up in vCenter.
+func (r *MigrationPlanReconciler) batchQuotaNeeds(ctx context.Context,
+	migrationplan *migratev1alpha1.MigrationPlan,
+	migrationtemplate *migratev1alpha1.MigrationTemplate,
+	vmwcreds *migratev1alpha1.VMwareCreds,
+	openstackcreds *migratev1alpha1.OpenstackCreds,
+	batches [][]string) ([]utils.MigrationQuota, error) {
+	vmMachines := &migratev1alpha1.VMwareMachineList{}
+	err := r.List(ctx, vmMachines, &client.ListOptions{Namespace: migrationtemplate.Namespace,
+		LabelSelector: labels.SelectorFromSet(map[string]string{constants.VMwareCredsLabel: vmwcreds.Name})})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list vmwaremachines")
+	}
+	var allFlavors []flavors.Flavor
+	if !migrationtemplate.Spec.UseFlavorless {
+		allFlavors, err = utils.ListAllFlavors(ctx, r.Client, openstackcreds)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list all flavors")
+		}
+	}
+
+	needs := make([]utils.MigrationQuota, len(batches))
+	for i, parallelvms := range batches {
+		for _, vm := range parallelvms {
+			var vmMachine *migratev1alpha1.VMwareMachine
+			for j := range vmMachines.Items {
+				if vmMachines.Items[j].Spec.VMInfo.Name == vm {
+					vmMachine = &vmMachines.Items[j]
+					break
+				}
+			}
+			if vmMachine == nil {
+				continue
+			}
+			// Flavorless migrations resize the base flavor to the CPU and memory of the VM
+			var flavor *flavors.Flavor
+			if !migrationtemplate.Spec.UseFlavorless {
+				flavor, err = targetFlavor(vmMachine, allFlavors)
+				if err != nil {
+					r.ctxlog.Info(fmt.Sprintf("Leaving VM '%s' out of the quota check of MigrationPlan '%s': %s", vm, migrationplan.Name, err))
+					continue
+				}
+			}
+			vminfo := vmMachine.Spec.VMInfo
+			if len(vminfo.DiskSizes) != len(vminfo.Disks) {
+				diskSizes, _, err := utils.GetVMwDiskInfo(ctx, r.Client, vmwcreds, vmwcreds.Spec.DataCenter, vm)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to get disk sizes of VM '%s'", vm)
+				}
+				vminfo.DiskSizes = diskSizes
+			}
+			needs[i].Add(utils.VMQuotaNeed(&vminfo, utils.GetVMDiskOverride(migrationplan, vm), flavor))
+		}
+	}
+	return needs, nil
+}
+
+// reconcileDryRun runs the pre-flight checks of every VM in the migration plan and writes the report
+// to the status of the migration plan. It does not create any Migrations, Jobs, ConfigMaps or volumes.
+func (r *MigrationPlanReconciler) reconcileDryRun(ctx context.Context,
+	migrationplan *migratev1alpha1.MigrationPlan,
+	migrationtemplate *migratev1alpha1.MigrationTemplate,
+	vmwcreds *migratev1alpha1.VMwareCreds,
+	openstackcreds *migratev1alpha1.OpenstackCreds) error {
+	if report := migrationplan.Status.DryRunReport; report != nil && report.ObservedGeneration == migrationplan.Generation {
+		return nil
+	}
+	r.ctxlog.Info(fmt.Sprintf("Running dry run of MigrationPlan '%s'", migrationplan.Name))
+
+	vmMachines := &migratev1alpha1.VMwareMachineList{}
+	err := r.List(ctx, vmMachines, &client.ListOptions{Namespace: migrationtemplate.Namespace,
+		LabelSelector: labels.SelectorFromSet(map[string]string{constants.VMwareCredsLabel: vmwcreds.Name})})
+	if err != nil {
+		return errors.Wrap(err, "failed to list vmwaremachines")
+	}
+	rdmDisks := &migratev1alpha1.RDMDiskList{}
+	if err = r.List(ctx, rdmDisks, client.InNamespace(migrationplan.Namespace)); err != nil {
+		return errors.Wrap(err, "failed to list rdmdisks")
+	}
+
+	// Resolve what is shared by all VMs once
+	dryrun := &dryRunResources{rdmDisks: rdmDisks.Items}
+	if migrationtemplate.Spec.UseFlavorless {
+		var osClients *utils.OpenStackClients
+		osClients, dryrun.flavorErr = utils.GetOpenStackClients(ctx, r.Client, openstackcreds)
+		if dryrun.flavorErr == nil {
+			dryrun.baseFlavor, dryrun.flavorErr = utils.FindHotplugBaseFlavor(osClients.ComputeClient)
+		}
+	} else {
+		dryrun.flavors, dryrun.flavorErr = utils.ListAllFlavors(ctx, r.Client, openstackcreds)
+	}
+	dryrun.quota, dryrun.quotaErr = utils.GetQuotaHeadroom(ctx, r.Client, openstackcreds)
+	dryrun.securityGroupIDs, dryrun.securityGroupsErr = resolveSecurityGroupIDs(migrationplan.Spec.SecurityGroups, openstackcreds)
+	settings, err := utils.GetMigrateSettings(ctx, r.Client)
+	if err != nil {
+		return errors.Wrap(err, "failed to get stellaris-migrate settings")
+	}
+	jobOptions, jobOptionsErr := utils.GetMigrationJobOptions(settings, migrationtemplate, migrationplan)
+	dryrun.jobOptionsErr = jobOptionsErr
+	// The controller can only check the VDDK directory it mounts itself
+	if jobOptionsErr != nil || jobOptions.VDDKPath == VDDKDirectory {
+		if files, err := os.ReadDir(VDDKDirectory); err != nil {
+			dryrun.vddkErr = errors.Wrap(err, "VDDK directory could not be read")
+		} else if len(files) == 0 {
+			dryrun.vddkErr = errors.New("VDDK directory is empty")
+		}
+	} else {
+		dryrun.vddkPath = jobOptions.VDDKPath
+	}
+
+	report := &migratev1alpha1.DryRunReport{
+		ObservedGeneration: migrationplan.Generation,
+		Ready:              true,
+	}
+	ready := 0
+	for _, parallelvms := range migrationplan.Spec.VirtualMachines {
+		for _, vm := range parallelvms {
+			var vmMachine *migratev1alpha1.VMwareMachine
+			for i := range vmMachines.Items {
+				if vmMachines.Items[i].Spec.VMInfo.Name == vm {
+					vmMachine = &vmMachines.Items[i]
+					break
+				}
+			}
+			vmReport := r.preflightVM(ctx, migrationplan, migrationtemplate, vmwcreds, openstackcreds, vm, vmMachine, dryrun)
+			if vmReport.Ready {
+				ready++
+			} else {
+				report.Ready = false
+			}
+			report.VMs = append(report.VMs, vmReport)
+		}
+	}
+	report.CompletedAt = metav1.Now()
+
+	migrationplan.Status.DryRunReport = report
+	migrationplan.Status.MigrationMessage = fmt.Sprintf("Dry run completed: %d of %d VMs ready", ready, len(report.VMs))
+	if err := r.Status().Update(ctx, migrationplan); err != nil {
+		return errors.Wrap(err, "failed to update migration plan status")
+	}
+	return nil
+}
+
+// dryRunResources holds what the pre-flight checks of all VMs of a migration plan share
+type dryRunResources struct {
+	flavors           []flavors.Flavor
+	baseFlavor        *flavors.Flavor
+	flavorErr         error
+	quota             *utils.MigrationQuota
+	quotaErr          error
+	securityGroupIDs  []string
+	securityGroupsErr error
+	vddkErr           error
+	vddkPath          string
+	jobOptionsErr     error
+	rdmDisks          []migratev1alpha1.RDMDisk
+}
+
+// preflightVM resolves the target resources of a VM and checks that its migration can run. It only reads the
+// mappings, a dry run does not record them as validated. What the VM uses is taken off the quota headroom so that
+// later VMs are checked against what is left.
+func (r *MigrationPlanReconciler) preflightVM(ctx context.Context,
+	migrationplan *migratev1alpha1.MigrationPlan,
+	migrationtemplate *migratev1alpha1.MigrationTemplate,
+	vmwcreds *migratev1alpha1.VMwareCreds,
+	openstackcreds *migratev1alpha1.OpenstackCreds,
+	vm string, vmMachine *migratev1alpha1.VMwareMachine, dryrun *dryRunResources) migratev1alpha1.VMPreflightReport {
+	report := migratev1alpha1.VMPreflightReport{VMName: vm, Ready: true}
+	if vmMachine == nil {
+		addPreflightCheck(&report, "VMwareMachine", migratev1alpha1.PreflightCheckFailed,
+			fmt.Sprintf("VM '%s' not found in VMwareMachine", vm))
+		return report
+	}
+	if vmMachine.Spec.VMInfo.OSFamily == "" {
+		addPreflightCheck(&report, "VMwareMachine", migratev1alpha1.PreflightCheckFailed,
+			"OSFamily is not available for the VM, please set OSFamily explicitly in the VMwareMachine CR")
+	} else {
+		addPreflightCheck(&report, "VMwareMachine", migratev1alpha1.PreflightCheckPassed, vmMachine.Name)
+	}
+
+	switch {
+	case vmMachine.Spec.VMInfo.Encrypted:
+		addPreflightCheck(&report, "Encryption", migratev1alpha1.PreflightCheckFailed,
+			"VM is encrypted, decrypt it in vCenter before migrating it")
+	case vmMachine.Spec.VMInfo.VTPM && migrationplan.Spec.MigrationStrategy.VTPMPolicy != openstackconst.VTPMPolicyRecreate:
+		addPreflightCheck(&report, "Encryption", migratev1alpha1.PreflightCheckFailed,
+			"VM has a vTPM, set the vTPM policy to recreate to migrate it with an emulated TPM")
+	case vmMachine.Spec.VMInfo.VTPM:
+		addPreflightCheck(&report, "Encryption", migratev1alpha1.PreflightCheckWarning,
+			"VM has a vTPM, it is recreated empty on the target and keys sealed to it are not carried over")
+	default:
+		addPreflightCheck(&report, "Encryption", migratev1alpha1.PreflightCheckPassed, "")
+	}
+
+	advancedOptions := migrationplan.Spec.AdvancedOptions
+	openstacknws, _, err := r.resolveNetworks(ctx, migrationtemplate, openstackcreds, vmwcreds, vm)
+	if err == nil && len(advancedOptions.GranularNetworks) > 0 {
+		if err = utils.VerifyNetworks(ctx, r.Client, openstackcreds, advancedOptions.GranularNetworks); err == nil {
+			openstacknws = advancedOptions.GranularNetworks
+		}
+	}
+	if err == nil && len(advancedOptions.GranularPorts) > 0 {
+		err = utils.VerifyPorts(ctx, r.Client, openstackcreds, advancedOptions.GranularPorts)
+	}
+	if err != nil {
+		addPreflightCheck(&report, "NetworkMapping", migratev1alpha1.PreflightCheckFailed, err.Error())
+	} else {
+		addPreflightCheck(&report, "NetworkMapping", migratev1alpha1.PreflightCheckPassed, "")
+		if nicOverrides := utils.GetVMNetworkOverrides(migrationplan, vm); len(nicOverrides) > 0 {
+			if len(advancedOptions.GranularPorts) > 0 {
+				err = errors.New("network overrides cannot be combined with granular ports")
+			} else {
+				openstacknws, _, err = utils.ResolveNetworkOverrides(ctx, r.Client, openstackcreds, vm, nicOverrides, openstacknws)
+			}
+			if err != nil {
+				addPreflightCheck(&report, "NetworkOverrides", migratev1alpha1.PreflightCheckFailed, err.Error())
+			} else {
+				addPreflightCheck(&report, "NetworkOverrides", migratev1alpha1.PreflightCheckPassed, "")
+			}
+		}
+		if err == nil {
+			report.TargetNetworks = openstacknws
+		}
+	}
+
+	openstackvolumetypes, _, err := r.resolveVolumeTypes(ctx, migrationtemplate, vmwcreds, openstackcreds, vm)
+	if err == nil && len(advancedOptions.GranularVolumeTypes) > 0 {
+		if err = utils.VerifyStorage(ctx, r.Client, openstackcreds, advancedOptions.GranularVolumeTypes); err == nil {
+			openstackvolumetypes = advancedOptions.GranularVolumeTypes
+		}
+	}
+	if err != nil {
+		addPreflightCheck(&report, "StorageMapping", migratev1alpha1.PreflightCheckFailed, err.Error())
+	} else {
+		report.TargetVolumeTypes = openstackvolumetypes
+		addPreflightCheck(&report, "StorageMapping", migratev1alpha1.PreflightCheckPassed, "")
+	}
+	if diskOverride := utils.GetVMDiskOverride(migrationplan, vm); diskOverride != nil {
+		if err := utils.ValidateDiskOverride(ctx, r.Client, openstackcreds, diskOverride, &vmMachine.Spec.VMInfo); err != nil {
+			addPreflightCheck(&report, "DiskOverrides", migratev1alpha1.PreflightCheckFailed, err.Error())
+		} else {
+			addPreflightCheck(&report, "DiskOverrides", migratev1alpha1.PreflightCheckPassed, "")
+		}
+	}
+
+	var flavor *flavors.Flavor
+	switch {
+	case dryrun.flavorErr != nil:
+		addPreflightCheck(&report, "Flavor", migratev1alpha1.PreflightCheckFailed, dryrun.flavorErr.Error())
+	case dryrun.baseFlavor != nil:
+		report.TargetFlavorID = dryrun.baseFlavor.ID
+		addPreflightCheck(&report, "Flavor", migratev1alpha1.PreflightCheckPassed,
+			fmt.Sprintf("Flavorless migration using base flavor %s", dryrun.baseFlavor.Name))
+	default:
+		flavor, err = targetFlavor(vmMachine, dryrun.flavors)
+		if err != nil {
+			addPreflightCheck(&report, "Flavor", migratev1alpha1.PreflightCheckFailed, err.Error())
+			break
+		}
+		report.TargetFlavorID = flavor.ID
+		addPreflightCheck(&report, "Flavor", migratev1alpha1.PreflightCheckPassed,
+			fmt.Sprintf("Flavor %s with %d vCPUs and %d MB RAM", flavor.Name, flavor.VCPUs, flavor.RAM))
+	}
+
+	if dryrun.securityGroupsErr != nil {
+		addPreflightCheck(&report, "SecurityGroups", migratev1alpha1.PreflightCheckFailed, dryrun.securityGroupsErr.Error())
+	} else {
+		report.SecurityGroupIDs = dryrun.securityGroupIDs
+		addPreflightCheck(&report, "SecurityGroups", migratev1alpha1.PreflightCheckPassed, "")
+	}
+
+	switch {
+	case dryrun.vddkErr != nil:
+		addPreflightCheck(&report, "VDDK", migratev1alpha1.PreflightCheckFailed, dryrun.vddkErr.Error())
+	case dryrun.vddkPath != "":
+		addPreflightCheck(&report, "VDDK", migratev1alpha1.PreflightCheckWarning,
+			fmt.Sprintf("VDDK directory %s of the nodes running the migration could not be checked", dryrun.vddkPath))
+	default:
+		addPreflightCheck(&report, "VDDK", migratev1alpha1.PreflightCheckPassed, "")
+	}
+
+	if dryrun.jobOptionsErr != nil {
+		addPreflightCheck(&report, "JobOptions", migratev1alpha1.PreflightCheckFailed, dryrun.jobOptionsErr.Error())
+	} else {
+		addPreflightCheck(&report, "JobOptions", migratev1alpha1.PreflightCheckPassed, "")
+	}
+
+	diskSizes, cbtEnabled, err := utils.GetVMwDiskInfo(ctx, r.Client, vmwcreds, vmwcreds.Spec.DataCenter, vm)
+	if err != nil {
+		addPreflightCheck(&report, "Quota", migratev1alpha1.PreflightCheckFailed, err.Error())
+		addPreflightCheck(&report, "CBT", migratev1alpha1.PreflightCheckFailed, err.Error())
+	} else {
+		vminfo := vmMachine.Spec.VMInfo
+		vminfo.DiskSizes = diskSizes
+		checkQuota(&report, utils.VMQuotaNeed(&vminfo, utils.GetVMDiskOverride(migrationplan, vm), flavor), dryrun)
+		if cbtEnabled {
+			addPreflightCheck(&report, "CBT", migratev1alpha1.PreflightCheckPassed, "Changed block tracking is enabled")
+		} else {
+			addPreflightCheck(&report, "CBT", migratev1alpha1.PreflightCheckWarning,
+				"Changed block tracking is disabled, it will be enabled when the migration starts")
+		}
+	}
+
+	checkRDMDisks(&report, vmMachine.Spec.VMInfo.RDMDisks, dryrun.rdmDisks)
+	if len(vmMachine.Spec.VMInfo.SharedDisks) > 0 {
+		if _, err := utils.GetVMSharedDisks(ctx, r.Client, migrationplan, vm, &vmMachine.Spec.VMInfo); err != nil {
+			addPreflightCheck(&report, "SharedDisks", migratev1alpha1.PreflightCheckFailed, err.Error())
+		} else {
+			addPreflightCheck(&report, "SharedDisks", migratev1alpha1.PreflightCheckPassed,
+				fmt.Sprintf("%d shared disks, copied once and attached to the instances of all their owner VMs", len(vmMachine.Spec.VMInfo.SharedDisks)))
+		}
+	}
+	return report
+}
+
+// checkQuota checks that what the VM uses fits in the quota headroom left by the VMs checked before
+func checkQuota(report *migratev1alpha1.VMPreflightReport, need utils.MigrationQuota, dryrun *dryRunResources) {
+	if dryrun.quotaErr != nil {
+		addPreflightCheck(report, "Quota", migratev1alpha1.PreflightCheckWarning,
+			fmt.Sprintf("Could not get quota: %s", dryrun.quotaErr))
+		return
+	}
+	if shortfalls := dryrun.quota.Shortfalls(need); len(shortfalls) > 0 {
+		addPreflightCheck(report, "Quota", migratev1alpha1.PreflightCheckFailed, strings.Join(shortfalls, ", "))
+		return
+	}
+	dryrun.quota.Take(need)
+	addPreflightCheck(report, "Quota", migratev1alpha1.PreflightCheckPassed,
+		fmt.Sprintf("%d volumes, %d GB, %d cores, %d MB RAM", need.Volumes, need.Gigabytes, need.Cores, need.RAM))
+}
+
+// checkRDMDisks checks that every RDM disk of the VM has an RDMDisk resource that is ready to be managed in Cinder
+func checkRDMDisks(report *migratev1alpha1.VMPreflightReport, vmRDMDisks []migratev1alpha1.RDMDiskInfo, rdmDisks []migratev1alpha1.RDMDisk) {
+	if len(vmRDMDisks) == 0 {
+		addPreflightCheck(report, "RDMDisks", migratev1alpha1.PreflightCheckPassed, "No RDM disks")
+		return
+	}
+	var problems []string
+	for _, vmRDMDisk := range vmRDMDisks {
+		var rdmDisk *migratev1alpha1.RDMDisk
+		for i := range rdmDisks {
+			if rdmDisks[i].Spec.UUID == vmRDMDisk.UUID {
+				rdmDisk = &rdmDisks[i]
+				break
+			}
+		}
+		switch {
+		case rdmDisk == nil:
+			problems = append(problems, fmt.Sprintf("%s: no RDMDisk found", vmRDMDisk.DiskName))
+		case rdmDisk.Status.Phase == RDMPhaseError:
+			problems = append(problems, fmt.Sprintf("%s: RDMDisk %s is in phase %s", vmRDMDisk.DiskName, rdmDisk.Name, rdmDisk.Status.Phase))
+		default:
+			if err := ValidateRDMDiskFields(rdmDisk); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s", vmRDMDisk.DiskName, err))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		addPreflightCheck(report, "RDMDisks", migratev1alpha1.PreflightCheckFailed, strings.Join(problems, "; "))
+		return
+	}
+	addPreflightCheck(report, "RDMDisks", migratev1alpha1.PreflightCheckPassed, fmt.Sprintf("%d RDM disks ready", len(vmRDMDisks)))
+}
+
+// targetFlavor returns the flavor a VM is created with, which is the target flavor of the VMwareMachine
+// or else the closest flavor to the CPU and memory of the VM
+func targetFlavor(vmMachine *migratev1alpha1.VMwareMachine, allFlavors []flavors.Flavor) (*flavors.Flavor, error) {
+	if vmMachine.Spec.TargetFlavorID != "" {
+		for i := range allFlavors {
+			if allFlavors[i].ID == vmMachine.Spec.TargetFlavorID {
+				return &allFlavors[i], nil
+			}
+		}
+		return nil, errors.Errorf("target flavor %s not found", vmMachine.Spec.TargetFlavorID)
+	}
+	flavor, err := utils.GetClosestFlavour(vmMachine.Spec.VMInfo.CPU, vmMachine.Spec.VMInfo.Memory, allFlavors)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get closest flavor")
+	}
+	if flavor == nil {
+		return nil, errors.Errorf("no suitable flavor found for %d vCPUs and %d MB RAM", vmMachine.Spec.VMInfo.CPU, vmMachine.Spec.VMInfo.Memory)
+	}
+	return flavor, nil
+}
+
+// resolveSecurityGroupIDs resolves the security groups of a migration plan, given as names or IDs, to their IDs
+func resolveSecurityGroupIDs(securityGroups []string, openstackcreds *migratev1alpha1.OpenstackCreds) ([]string, error) {
+	var ids []string
+	for _, securityGroup := range securityGroups {
+		var matches []string
+		for _, group := range openstackcreds.Status.Openstack.SecurityGroups {
+			if group.ID == securityGroup {
+				matches = []string{group.ID}
+				break
+			}
+			if group.Name == securityGroup {
+				matches = append(matches, group.ID)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, errors.Errorf("security group %s not found", securityGroup)
+		case 1:
+			ids = append(ids, matches[0])
+		default:
+			return nil, errors.Errorf("security group name %s matches %d groups, use its ID instead", securityGroup, len(matches))
+		}
+	}
+	return ids, nil
+}
+
+// addPreflightCheck adds the result of a check to the pre-flight report of a VM
+func addPreflightCheck(report *migratev1alpha1.VMPreflightReport, name string, result migratev1alpha1.PreflightCheckResult, message string) {
+	report.Checks = append(report.Checks, migratev1alpha1.PreflightCheck{Name: name, Result: result, Message: message})
+	if result == migratev1alpha1.PreflightCheckFailed {
+		report.Ready = false
+	}
+}
+
 // CreateMigration creates a new Migration resource
 func (r *MigrationPlanReconciler) CreateMigration(ctx context.Context,
 	migrationplan *migratev1alpha1.MigrationPlan,
@@ -481,8 +1084,9 @@ func (r *MigrationPlanReconciler) CreateMigration(ctx context.Context,
 				MigrationPlan: migrationplan.Name,
 				VMName:        vm,
 				// PodRef will be set in the migration controller
-				InitiateCutover:         migrationplan.Spec.MigrationStrategy.AdminInitiatedCutOver,
-				DisconnectSourceNetwork: migrationplan.Spec.MigrationStrategy.DisconnectSourceNetwork,
+				InitiateCutover:              migrationplan.Spec.MigrationStrategy.AdminInitiatedCutOver,
+				DisconnectSourceNetwork:      migrationplan.Spec.MigrationStrategy.DisconnectSourceNetwork,
+				RollbackOnHealthCheckFailure: migrationplan.Spec.MigrationStrategy.RollbackOnHealthCheckFailure,
 			},
 		}
 		migrationobj.Labels = MergeLabels(migrationobj.Labels, migrationplan.Labels)
@@ -494,7 +1098,7 @@ func (r *MigrationPlanReconciler) CreateMigration(ctx context.Context,
 	return migrationobj, nil
 }
 
-// CreateJob creates a job to run v2v-helper
+// CreateJob creates a job to run v2v-helper, scheduled and sized with the given job options
 func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 	migrationplan *migratev1alpha1.MigrationPlan,
 	migrationtemplate *migratev1alpha1.MigrationTemplate,
@@ -503,7 +1107,8 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 	firstbootconfigMapName string,
 	vmwareSecretRef string,
 	openstackSecretRef string,
-	vmMachine *migratev1alpha1.VMwareMachine) error {
+	vmMachine *migratev1alpha1.VMwareMachine,
+	jobOptions *migratev1alpha1.MigrationJobOptions) error {
 	vmwarecreds, err := utils.GetVMwareCredsNameFromMigrationPlan(ctx, r.Client, migrationplan)
 	if err != nil {
 		return errors.Wrap(err, "failed to get vmware credentials")
@@ -516,9 +1121,18 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 	if err != nil {
 		return errors.Wrap(err, "failed to get job name")
 	}
+	settings, err := utils.GetMigrateSettings(ctx, r.Client)
+	if err != nil {
+		return errors.Wrap(err, "failed to get stellaris-migrate settings")
+	}
 	pointtrue := true
 	cutoverlabel := "yes"
-	if migrationplan.Spec.MigrationStrategy.AdminInitiatedCutOver {
+	// When cutovers are limited the pod waits with the label set to no until the migration controller gives it a slot.
+	// VMs sharing disks wait as well, as they cut over together. With an admin initiated cutover the migration
+	// controller starts the cutover of the VMs sharing disks once the admin starts it for one of them.
+	cutoverGated := !migrationplan.Spec.MigrationStrategy.AdminInitiatedCutOver &&
+		(utils.IsCutoverLimited(settings, migrationplan) || len(vmMachine.Spec.VMInfo.SharedDisks) > 0)
+	if migrationplan.Spec.MigrationStrategy.AdminInitiatedCutOver || cutoverGated {
 		cutoverlabel = "no"
 	}
 	envVars := []corev1.EnvVar{
@@ -530,6 +1144,14 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 				},
 			},
 		},
+		{
+			Name: "NODE_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "spec.nodeName",
+				},
+			},
+		},
 		{
 			Name:  "VMWARE_MACHINE_OBJECT_NAME",
 			Value: vmk8sname,
@@ -538,6 +1160,10 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 			Name:  "USE_FLAVORLESS",
 			Value: strconv.FormatBool(migrationtemplate.Spec.UseFlavorless),
 		},
+		{
+			Name:  constants.CutoverGatedEnvVar,
+			Value: strconv.FormatBool(cutoverGated),
+		},
 	}
 
 	if migrationtemplate.Spec.UseFlavorless {
@@ -555,6 +1181,9 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      jobName,
 				Namespace: migrationplan.Namespace,
+				Labels: map[string]string{
+					constants.MigrationPlanLabel: migrationplan.Name,
+				},
 			},
 			Spec: batchv1.JobSpec{
 				PodFailurePolicy: &batchv1.PodFailurePolicy{
@@ -575,6 +1204,11 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 							constants.VMNameLabel: vmk8sname,
 							"startCutover":        cutoverlabel,
 						},
+						// The v2v-helper adds the metrics port once it listens
+						Annotations: map[string]string{
+							openstackconst.PrometheusScrapeAnnotation: "true",
+							openstackconst.PrometheusPathAnnotation:   "/metrics",
+						},
 					},
 					Spec: corev1.PodSpec{
 						RestartPolicy:                 corev1.RestartPolicyNever,
@@ -582,6 +1216,10 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 						TerminationGracePeriodSeconds: ptr.To(constants.TerminationPeriod),
 						HostNetwork:                   true,
 						DNSPolicy:                     corev1.DNSClusterFirstWithHostNet,
+						NodeSelector:                  jobOptions.NodeSelector,
+						Affinity:                      jobOptions.Affinity,
+						Tolerations:                   jobOptions.Tolerations,
+						PriorityClassName:             jobOptions.PriorityClassName,
 						Containers: []corev1.Container{
 							{
 								Name:            "fedora",
@@ -637,18 +1275,7 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 										MountPath: "/home/fedora/virtio-win",
 									},
 								},
-								Resources: corev1.ResourceRequirements{
-									Requests: corev1.ResourceList{
-										corev1.ResourceCPU:              resource.MustParse("1000m"),
-										corev1.ResourceMemory:           resource.MustParse("1Gi"),
-										corev1.ResourceEphemeralStorage: resource.MustParse("3Gi"),
-									},
-									Limits: corev1.ResourceList{
-										corev1.ResourceCPU:              resource.MustParse("2000m"),
-										corev1.ResourceMemory:           resource.MustParse("3Gi"),
-										corev1.ResourceEphemeralStorage: resource.MustParse("3Gi"),
-									},
-								},
+								Resources: jobOptions.Resources,
 							},
 						},
 						Volumes: []corev1.Volume{
@@ -656,7 +1283,7 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 								Name: "vddk",
 								VolumeSource: corev1.VolumeSource{
 									HostPath: &corev1.HostPathVolumeSource{
-										Path: "/home/ubuntu/vmware-vix-disklib-distrib",
+										Path: jobOptions.VDDKPath,
 										Type: utils.NewHostPathType("Directory"),
 									},
 								},
@@ -693,7 +1320,7 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 								Name: "virtio-driver",
 								VolumeSource: corev1.VolumeSource{
 									HostPath: &corev1.HostPathVolumeSource{
-										Path: "/home/ubuntu/virtio-win",
+										Path: jobOptions.VirtioWinPath,
 										Type: utils.NewHostPathType("DirectoryOrCreate"),
 									},
 								},
@@ -759,7 +1386,7 @@ func (r *MigrationPlanReconciler) CreateMigrationConfigMap(ctx context.Context,
 	configMapName := utils.GetMigrationConfigMapName(vmname)
 	virtiodrivers := ""
 	if migrationtemplate.Spec.VirtioWinDriver == "" {
-		virtiodrivers = "https://fedorapeople.org/groups/virt/virtio-win/direct-downloads/stable-virtio/virtio-win.iso"
+		virtiodrivers = constants.DefaultVirtioWinDriver
 	} else {
 		virtiodrivers = migrationtemplate.Spec.VirtioWinDriver
 	}
@@ -791,6 +1418,29 @@ func (r *MigrationPlanReconciler) CreateMigrationConfigMap(ctx context.Context,
 		}
 	}
 
+	nicOverrides := utils.GetVMNetworkOverrides(migrationplan, vm)
+	if len(nicOverrides) > 0 {
+		if len(openstackports) > 0 {
+			return nil, errors.New("network overrides cannot be combined with granular ports")
+		}
+		openstacknws, nicOverrides, err = utils.ResolveNetworkOverrides(ctx, r.Client, openstackcreds, vm, nicOverrides, openstacknws)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to verify network overrides")
+		}
+	}
+
+	diskOverride := utils.GetVMDiskOverride(migrationplan, vm)
+	if diskOverride != nil {
+		if err = utils.ValidateDiskOverride(ctx, r.Client, openstackcreds, diskOverride, &vmMachine.Spec.VMInfo); err != nil {
+			return nil, errors.Wrap(err, "failed to verify disk overrides")
+		}
+	}
+
+	sharedDisks, err := utils.GetVMSharedDisks(ctx, r.Client, migrationplan, vm, &vmMachine.Spec.VMInfo)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get shared disks")
+	}
+
 	// Create MigrationConfigMap
 	configMap := &corev1.ConfigMap{}
 	err = r.Get(ctx, types.NamespacedName{Name: configMapName, Namespace: migrationplan.Namespace}, configMap)
@@ -822,6 +1472,38 @@ func (r *MigrationPlanReconciler) CreateMigrationConfigMap(ctx context.Context,
 			configMap.Data["TARGET_AVAILABILITY_ZONE"] = migrationtemplate.Spec.TargetPCDClusterName
 		}
 
+		if len(nicOverrides) > 0 {
+			networkOverrides, err := json.Marshal(nicOverrides)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to marshal network overrides")
+			}
+			configMap.Data["NETWORK_OVERRIDES"] = string(networkOverrides)
+		}
+
+		if diskOverride != nil {
+			diskOverrides, err := json.Marshal(diskOverride)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to marshal disk overrides")
+			}
+			configMap.Data["DISK_OVERRIDES"] = string(diskOverrides)
+		}
+
+		if len(sharedDisks) > 0 {
+			sharedDisksJSON, err := json.Marshal(sharedDisks)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to marshal shared disks")
+			}
+			configMap.Data["SHARED_DISKS"] = string(sharedDisksJSON)
+		}
+
+		if len(migrationplan.Spec.Hooks) > 0 {
+			hooks, err := json.Marshal(migrationplan.Spec.Hooks)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to marshal hooks")
+			}
+			configMap.Data["HOOKS"] = string(hooks)
+		}
+
 		// Check if assigned IP is set
 		if vmMachine.Spec.VMInfo.AssignedIP != "" {
 			configMap.Data["ASSIGNED_IP"] = vmMachine.Spec.VMInfo.AssignedIP
@@ -859,6 +1541,34 @@ func (r *MigrationPlanReconciler) CreateMigrationConfigMap(ctx context.Context,
 
 		configMap.Data["OS_FAMILY"] = vmMachine.Spec.VMInfo.OSFamily
 		configMap.Data["DISCONNECT_SOURCE_NETWORK"] = strconv.FormatBool(migrationobj.Spec.DisconnectSourceNetwork)
+		configMap.Data["DATA_VERIFICATION"] = migrationplan.Spec.MigrationStrategy.DataVerification
+		configMap.Data["VTPM_POLICY"] = migrationplan.Spec.MigrationStrategy.VTPMPolicy
+		configMap.Data["RECONFIGURE_GUEST_NETWORK"] = strconv.FormatBool(migrationplan.Spec.MigrationStrategy.ReconfigureGuestNetwork)
+		configMap.Data["BANDWIDTH_LIMIT_MBPS"] = strconv.Itoa(migrationplan.Spec.MigrationStrategy.BandwidthLimitMbps)
+
+		if copyWindow := migrationplan.Spec.MigrationStrategy.CopyWindow; copyWindow != nil {
+			configMap.Data["COPY_WINDOW_START"] = copyWindow.Start
+			configMap.Data["COPY_WINDOW_END"] = copyWindow.End
+			configMap.Data["COPY_WINDOW_TIMEZONE"] = copyWindow.TimeZone
+		}
+		if cutoverWindow := migrationplan.Spec.MigrationStrategy.CutoverWindow; cutoverWindow != nil {
+			configMap.Data["CUTOVER_WINDOW_START"] = cutoverWindow.Start
+			configMap.Data["CUTOVER_WINDOW_END"] = cutoverWindow.End
+			configMap.Data["CUTOVER_WINDOW_TIMEZONE"] = cutoverWindow.TimeZone
+			days := make([]string, 0, len(cutoverWindow.Days))
+			for _, day := range cutoverWindow.Days {
+				days = append(days, string(day))
+			}
+			configMap.Data["CUTOVER_WINDOW_DAYS"] = strings.Join(days, ",")
+		}
+		configMap.Data["CUTOVER_FINALIZE_DURATION"] = migrationplan.Spec.MigrationStrategy.CutoverFinalizeDuration.Duration.String()
+		if testBoot := migrationplan.Spec.MigrationStrategy.TestBoot; testBoot != nil {
+			configMap.Data["TEST_BOOT"] = "true"
+			configMap.Data["TEST_BOOT_CIDR"] = testBoot.CIDR
+			configMap.Data["TEST_BOOT_TIMEOUT"] = testBoot.Timeout.Duration.String()
+		}
+		configMap.Data["SNAPSHOT_CONSISTENCY"] = utils.GetVMSnapshotConsistency(migrationplan, vm)
+		configMap.Data["QUIESCE_TIMEOUT"] = migrationplan.Spec.MigrationStrategy.QuiesceTimeout.Duration.String()
 
 		if migrationtemplate.Spec.OSFamily != "" {
 			configMap.Data["OS_FAMILY"] = migrationtemplate.Spec.OSFamily
@@ -932,21 +1642,45 @@ func (r *MigrationPlanReconciler) reconcileMapping(ctx context.Context,
 	return openstacknws, openstackvolumetypes, nil
 }
 
-//nolint:dupl // Similar logic to storages reconciliation, excluding from linting to keep it readable
+// reconcileNetwork resolves the target networks of a VM and records in the NetworkMapping that they were validated
 func (r *MigrationPlanReconciler) reconcileNetwork(ctx context.Context,
 	migrationtemplate *migratev1alpha1.MigrationTemplate,
 	openstackcreds *migratev1alpha1.OpenstackCreds,
 	vmwcreds *migratev1alpha1.VMwareCreds,
 	vm string) ([]string, error) {
+	openstacknws, networkmap, err := r.resolveNetworks(ctx, migrationtemplate, openstackcreds, vmwcreds, vm)
+	if err != nil {
+		return nil, err
+	}
+	if networkmap.Status.NetworkmappingValidationStatus != string(corev1.PodSucceeded) {
+		networkmap.Status.NetworkmappingValidationStatus = string(corev1.PodSucceeded)
+		networkmap.Status.NetworkmappingValidationMessage = "NetworkMapping validated"
+		err = r.Status().Update(ctx, networkmap)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to update networkmapping status")
+		}
+	}
+	return openstacknws, nil
+}
+
+// resolveNetworks maps the networks of a VM to the target networks of the NetworkMapping and verifies them in
+// OpenStack unless the NetworkMapping was validated already. It does not change the NetworkMapping.
+//
+//nolint:dupl // Similar logic to storages resolution, excluding from linting to keep it readable
+func (r *MigrationPlanReconciler) resolveNetworks(ctx context.Context,
+	migrationtemplate *migratev1alpha1.MigrationTemplate,
+	openstackcreds *migratev1alpha1.OpenstackCreds,
+	vmwcreds *migratev1alpha1.VMwareCreds,
+	vm string) ([]string, *migratev1alpha1.NetworkMapping, error) {
 	vmnws, err := utils.GetVMwNetworks(ctx, r.Client, vmwcreds, vmwcreds.Spec.DataCenter, vm)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get network")
+		return nil, nil, errors.Wrap(err, "failed to get network")
 	}
 	// Fetch the networkmap
 	networkmap := &migratev1alpha1.NetworkMapping{}
 	err = r.Get(ctx, types.NamespacedName{Name: migrationtemplate.Spec.NetworkMapping, Namespace: migrationtemplate.Namespace}, networkmap)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to retrieve NetworkMapping CR")
+		return nil, nil, errors.Wrap(err, "failed to retrieve NetworkMapping CR")
 	}
 
 	openstacknws := []string{}
@@ -962,7 +1696,7 @@ func (r *MigrationPlanReconciler) reconcileNetwork(ctx context.Context,
 			}
 		}
 		if !found {
-			return nil, errors.Errorf("VMware network %q not found in NetworkMapping", vmnw)
+			return nil, nil, errors.Errorf("VMware network %q not found in NetworkMapping", vmnw)
 		}
 	}
 
@@ -980,33 +1714,51 @@ func (r *MigrationPlanReconciler) reconcileNetwork(ctx context.Context,
 	if networkmap.Status.NetworkmappingValidationStatus != string(corev1.PodSucceeded) {
 		err = utils.VerifyNetworks(ctx, r.Client, openstackcreds, uniqueTargetList)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to verify networks")
-		}
-		networkmap.Status.NetworkmappingValidationStatus = string(corev1.PodSucceeded)
-		networkmap.Status.NetworkmappingValidationMessage = "NetworkMapping validated"
-		err = r.Status().Update(ctx, networkmap)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to update networkmapping status")
+			return nil, nil, errors.Wrap(err, "failed to verify networks")
 		}
 	}
-	return openstacknws, nil
+	return openstacknws, networkmap, nil
 }
 
-//nolint:dupl // Similar logic to networks reconciliation, excluding from linting to keep it readable
+// reconcileStorage resolves the volume types of a VM and records in the StorageMapping that they were validated
 func (r *MigrationPlanReconciler) reconcileStorage(ctx context.Context,
 	migrationtemplate *migratev1alpha1.MigrationTemplate,
 	vmwcreds *migratev1alpha1.VMwareCreds,
 	openstackcreds *migratev1alpha1.OpenstackCreds,
 	vm string) ([]string, error) {
+	openstackvolumetypes, storagemap, err := r.resolveVolumeTypes(ctx, migrationtemplate, vmwcreds, openstackcreds, vm)
+	if err != nil {
+		return nil, err
+	}
+	if storagemap.Status.StoragemappingValidationStatus != string(corev1.PodSucceeded) {
+		storagemap.Status.StoragemappingValidationStatus = string(corev1.PodSucceeded)
+		storagemap.Status.StoragemappingValidationMessage = "StorageMapping validated"
+		err = r.Status().Update(ctx, storagemap)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to update storagemapping status")
+		}
+	}
+	return openstackvolumetypes, nil
+}
+
+// resolveVolumeTypes maps the datastores of a VM to the volume types of the StorageMapping and verifies them in
+// OpenStack unless the StorageMapping was validated already. It does not change the StorageMapping.
+//
+//nolint:dupl // Similar logic to networks resolution, excluding from linting to keep it readable
+func (r *MigrationPlanReconciler) resolveVolumeTypes(ctx context.Context,
+	migrationtemplate *migratev1alpha1.MigrationTemplate,
+	vmwcreds *migratev1alpha1.VMwareCreds,
+	openstackcreds *migratev1alpha1.OpenstackCreds,
+	vm string) ([]string, *migratev1alpha1.StorageMapping, error) {
 	vmds, err := utils.GetVMwDatastore(ctx, r.Client, vmwcreds, vmwcreds.Spec.DataCenter, vm)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get datastores")
+		return nil, nil, errors.Wrap(err, "failed to get datastores")
 	}
 	// Fetch the StorageMap
 	storagemap := &migratev1alpha1.StorageMapping{}
 	err = r.Get(ctx, types.NamespacedName{Name: migrationtemplate.Spec.StorageMapping, Namespace: migrationtemplate.Namespace}, storagemap)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to retrieve StorageMapping CR")
+		return nil, nil, errors.Wrap(err, "failed to retrieve StorageMapping CR")
 	}
 
 	openstackvolumetypes := []string{}
@@ -1018,21 +1770,15 @@ func (r *MigrationPlanReconciler) reconcileStorage(ctx context.Context,
 		}
 	}
 	if len(openstackvolumetypes) != len(vmds) {
-		return nil, errors.Errorf("VMware Datastore(s) not found in StorageMapping vm(%d) openstack(%d)", len(vmds), len(openstackvolumetypes))
+		return nil, nil, errors.Errorf("VMware Datastore(s) not found in StorageMapping vm(%d) openstack(%d)", len(vmds), len(openstackvolumetypes))
 	}
 	if storagemap.Status.StoragemappingValidationStatus != string(corev1.PodSucceeded) {
 		err = utils.VerifyStorage(ctx, r.Client, openstackcreds, openstackvolumetypes)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to verify datastores")
-		}
-		storagemap.Status.StoragemappingValidationStatus = string(corev1.PodSucceeded)
-		storagemap.Status.StoragemappingValidationMessage = "StorageMapping validated"
-		err = r.Status().Update(ctx, storagemap)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to update storagemapping status")
+			return nil, nil, errors.Wrap(err, "failed to verify datastores")
 		}
 	}
-	return openstackvolumetypes, nil
+	return openstackvolumetypes, storagemap, nil
 }
 
 // TriggerMigration triggers a migration process
@@ -1055,6 +1801,20 @@ func (r *MigrationPlanReconciler) TriggerMigration(ctx context.Context,
 	}
 	counter := len(nodeList.Items)
 
+	settings, err := utils.GetMigrateSettings(ctx, r.Client)
+	if err != nil {
+		return errors.Wrap(err, "failed to get stellaris-migrate settings")
+	}
+	jobOptions, err := utils.GetMigrationJobOptions(settings, migrationtemplate, migrationplan)
+	if err != nil {
+		return errors.Wrap(err, "failed to get v2v-helper job options")
+	}
+
+	var agents []utils.MigrationAgent
+	queued := 0
+	admission := &migrationAdmission{}
+	budget := &connectionBudget{}
+
 	vmMachines := &migratev1alpha1.VMwareMachineList{}
 
 	err = r.List(ctx, vmMachines, &client.ListOptions{Namespace: migrationtemplate.Namespace, LabelSelector: labels.SelectorFromSet(map[string]string{constants.VMwareCredsLabel: vmwcreds.Name})})
@@ -1122,9 +1882,47 @@ func (r *MigrationPlanReconciler) TriggerMigration(ctx context.Context,
 		if err != nil {
 			return errors.Wrapf(err, "failed to create Firstboot ConfigMap for VM %s", vm)
 		}
-		//nolint:gocritic // err is already declared above
-		if err = r.validateVDDKPresence(ctx, migrationobj, ctxlog); err != nil {
-			return err
+		// The controller can only check the VDDK directory it mounts itself
+		if jobOptions.VDDKPath == VDDKDirectory {
+			//nolint:gocritic // err is already declared above
+			if err = r.validateVDDKPresence(ctx, migrationobj, ctxlog); err != nil {
+				return err
+			}
+		}
+
+		admitted, newSlot, err := r.admitMigration(ctx, migrationplan, migrationobj, vm, vmwcreds, settings, admission)
+		if err != nil {
+			return errors.Wrapf(err, "failed to admit migration of VM %s", vm)
+		}
+		if !admitted {
+			queued++
+			continue
+		}
+
+		connections, budgeted, err := r.budgetMigration(ctx, migrationplan, migrationobj, vm, vmwcreds, vmMachineObj, settings, budget)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check connection budget of VM %s", vm)
+		}
+		if !budgeted {
+			queued++
+			continue
+		}
+
+		placedJobOptions, placed, err := r.placeMigration(ctx, migrationobj, vm, vmwcreds, jobOptions, settings, &agents)
+		if err != nil {
+			return errors.Wrapf(err, "failed to place migration of VM %s", vm)
+		}
+		if !placed {
+			queued++
+			continue
+		}
+		// The slot is only taken once the migration is placed, a migration queued by its budget or placement
+		// leaves it to the next VMs
+		if newSlot {
+			admission.take()
+		}
+		if err := r.recordConnections(ctx, migrationobj, connections, budget); err != nil {
+			return errors.Wrapf(err, "failed to record connections of VM %s", vm)
 		}
 
 		err = r.CreateJob(ctx,
@@ -1135,7 +1933,8 @@ func (r *MigrationPlanReconciler) TriggerMigration(ctx context.Context,
 			fbcm.Name,
 			vmwcreds.Spec.SecretRef.Name,
 			openstackcreds.Spec.SecretRef.Name,
-			vmMachineObj)
+			vmMachineObj,
+			placedJobOptions)
 		if err != nil {
 			return errors.Wrap(err, fmt.Sprintf("failed to create Job for VM %s", vm))
 		}
@@ -1147,6 +1946,263 @@ func (r *MigrationPlanReconciler) TriggerMigration(ctx context.Context,
 			time.Sleep(constants.MigrationTriggerDelay)
 		}
 	}
+	if err := r.updateQueuePosition(ctx, migrationplan, admission); err != nil {
+		return err
+	}
+	if queued > 0 {
+		ctxlog.Info("VMs are queued until a migration agent or slot is free", "queued", queued)
+		return errMigrationsQueued
+	}
+	return nil
+}
+
+// admitMigration reports whether the v2v-helper job of a migration may be created without running more migrations
+// than the settings and the migration plan allow, and whether the migration needs a new slot. Plans waiting for a
+// free slot of the settings are queued by priority, and only the first plan of the queue takes the slots that free
+// up. Migrations whose job exists are always admitted. admission is loaded on first use, the slot of an admitted
+// migration is only counted with admission.take once its job is about to be created.
+func (r *MigrationPlanReconciler) admitMigration(ctx context.Context,
+	migrationplan *migratev1alpha1.MigrationPlan,
+	migrationobj *migratev1alpha1.Migration,
+	vm string,
+	vmwcreds *migratev1alpha1.VMwareCreds,
+	settings *utils.VjailbreakSettings,
+	admission *migrationAdmission) (admitted, newSlot bool, err error) {
+	if settings.MaxConcurrentMigrations == 0 && migrationplan.Spec.MaxConcurrentMigrations == 0 {
+		return true, false, nil
+	}
+	jobName, err := utils.GetJobNameForVMName(vm, vmwcreds.Name)
+	if err != nil {
+		return false, false, errors.Wrap(err, "failed to get job name")
+	}
+	err = r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: migrationobj.Namespace}, &batchv1.Job{})
+	if err == nil {
+		return true, false, nil
+	} else if !apierrors.IsNotFound(err) {
+		return false, false, errors.Wrapf(err, "failed to get job '%s'", jobName)
+	}
+
+	if !admission.loaded {
+		admission.running, admission.planRunning, err = utils.CountRunningMigrationJobs(ctx, r.Client, migrationplan)
+		if err != nil {
+			return false, false, errors.Wrap(err, "failed to count running migrations")
+		}
+		if settings.MaxConcurrentMigrations > 0 {
+			admission.queuePosition, err = utils.GetMigrationPlanQueuePosition(ctx, r.Client, migrationplan)
+			if err != nil {
+				return false, false, errors.Wrap(err, "failed to get queue position of migration plan")
+			}
+		}
+		admission.loaded = true
+	}
+	if migrationplan.Spec.MaxConcurrentMigrations > 0 && admission.planRunning >= migrationplan.Spec.MaxConcurrentMigrations {
+		return false, false, nil
+	}
+	if settings.MaxConcurrentMigrations > 0 &&
+		(admission.queuePosition > 1 || admission.running >= settings.MaxConcurrentMigrations) {
+		admission.waiting = true
+		return false, false, nil
+	}
+	return true, true, nil
+}
+
+// updateQueuePosition publishes the position of a migration plan among the plans waiting for a free migration slot
+func (r *MigrationPlanReconciler) updateQueuePosition(ctx context.Context,
+	migrationplan *migratev1alpha1.MigrationPlan, admission *migrationAdmission) error {
+	position := 0
+	if admission.waiting {
+		position = admission.queuePosition
+	}
+	if position == migrationplan.Status.QueuePosition {
+		return nil
+	}
+	migrationplan.Status.QueuePosition = position
+	migrationplan.Status.MigrationMessage = "Migration(s) in progress"
+	if position > 0 {
+		migrationplan.Status.MigrationMessage = fmt.Sprintf("Waiting for a free migration slot, position %d in the queue", position)
+	}
+	if err := r.Status().Update(ctx, migrationplan); err != nil {
+		return errors.Wrap(err, "failed to update migration plan queue position")
+	}
+	return nil
+}
+
+// placeMigration picks the agent that runs the v2v-helper pod of a migration and pins the pod to it. It returns
+// false if every agent already runs the maximum number of migrations, in which case the migration stays Pending
+// with the reason in its AgentPlacement condition. Migrations whose Job exists are not placed again. agents is
+// loaded on first use and updated with each placement.
+func (r *MigrationPlanReconciler) placeMigration(ctx context.Context,
+	migrationobj *migratev1alpha1.Migration,
+	vm string,
+	vmwcreds *migratev1alpha1.VMwareCreds,
+	jobOptions *migratev1alpha1.MigrationJobOptions,
+	settings *utils.VjailbreakSettings,
+	agents *[]utils.MigrationAgent) (*migratev1alpha1.MigrationJobOptions, bool, error) {
+	if settings.MigrationPlacementStrategy == constants.PlacementStrategyScheduler {
+		return jobOptions, true, nil
+	}
+	jobName, err := utils.GetJobNameForVMName(vm, vmwcreds.Name)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to get job name")
+	}
+	err = r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: migrationobj.Namespace}, &batchv1.Job{})
+	if err == nil {
+		return jobOptions, true, nil
+	} else if !apierrors.IsNotFound(err) {
+		return nil, false, errors.Wrapf(err, "failed to get job '%s'", jobName)
+	}
+
+	if *agents == nil {
+		*agents, err = utils.GetMigrationAgents(ctx, r.Client, jobOptions.NodeSelector)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "failed to get migration agents")
+		}
+	}
+	agent := utils.PickMigrationAgent(*agents, settings.MigrationPlacementStrategy, settings.MaxMigrationsPerAgent)
+
+	condition := corev1.PodCondition{
+		Type:               constants.MigrationConditionTypeAgentPlacement,
+		Status:             corev1.ConditionTrue,
+		Reason:             "Placed",
+		LastTransitionTime: metav1.Now(),
+	}
+	if agent == nil {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "AgentsSaturated"
+		condition.Message = fmt.Sprintf("Queued until one of the %d ready migration agents runs fewer than %d migrations",
+			len(*agents), settings.MaxMigrationsPerAgent)
+		if len(*agents) == 0 {
+			condition.Reason = "NoAgentReady"
+			condition.Message = "Queued until a migration agent matching the node selector is ready"
+		}
+	} else {
+		agent.ActiveMigrations++
+		condition.Message = fmt.Sprintf("Placed on agent %s", agent.NodeName)
+		migrationobj.Status.AgentName = agent.NodeName
+	}
+
+	if err := r.updateMigrationCondition(ctx, migrationobj, condition, agent != nil); err != nil {
+		return nil, false, err
+	}
+	if agent == nil {
+		return nil, false, nil
+	}
+
+	placedJobOptions := jobOptions.DeepCopy()
+	placedJobOptions.NodeSelector = MergeLabels(map[string]string{corev1.LabelHostname: agent.NodeName}, jobOptions.NodeSelector)
+	return placedJobOptions, true, nil
+}
+
+// budgetMigration reports whether the v2v-helper pod of a migration can open its vCenter session and one NFC
+// connection per copied disk on the ESXi host of the VM without exceeding the budgets of the settings, and returns
+// the connections the pod opens. Migrations over budget stay Pending with the reason in their ConnectionBudget
+// condition. The connections are only recorded by recordConnections once the migration is placed. Migrations whose
+// Job exists are not checked again and return no connections. budget is loaded on first use.
+func (r *MigrationPlanReconciler) budgetMigration(ctx context.Context,
+	migrationplan *migratev1alpha1.MigrationPlan,
+	migrationobj *migratev1alpha1.Migration,
+	vm string,
+	vmwcreds *migratev1alpha1.VMwareCreds,
+	vmMachine *migratev1alpha1.VMwareMachine,
+	settings *utils.VjailbreakSettings,
+	budget *connectionBudget) (*migratev1alpha1.MigrationConnections, bool, error) {
+	jobName, err := utils.GetJobNameForVMName(vm, vmwcreds.Name)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to get job name")
+	}
+	err = r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: migrationobj.Namespace}, &batchv1.Job{})
+	if err == nil {
+		return nil, true, nil
+	} else if !apierrors.IsNotFound(err) {
+		return nil, false, errors.Wrapf(err, "failed to get job '%s'", jobName)
+	}
+
+	if budget.usage == nil {
+		credsInfo, err := utils.GetVMwareCredentialsFromSecret(ctx, r.Client, vmwcreds.Spec.SecretRef.Name)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "failed to get vCenter credentials")
+		}
+		budget.vcenter = credsInfo.Host
+		budget.usage, err = utils.GetVCenterConnectionUsage(ctx, r.Client)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "failed to get vCenter connection usage")
+		}
+	}
+	// A migration that was recorded before but whose Job was not created holds connections it does not use yet
+	if migrationobj.Status.Connections != nil {
+		budget.usage.Remove(migrationobj.Status.Connections)
+		migrationobj.Status.Connections = nil
+		if err := r.Status().Update(ctx, migrationobj); err != nil {
+			return nil, false, errors.Wrap(err, "failed to release connections of migration")
+		}
+	}
+	sharedDisks, err := utils.GetVMSharedDisks(ctx, r.Client, migrationplan, vm, &vmMachine.Spec.VMInfo)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to get shared disks")
+	}
+	connections := &migratev1alpha1.MigrationConnections{
+		VCenter:  budget.vcenter,
+		ESXiHost: vmMachine.Spec.VMInfo.ESXiName,
+		NFCConnections: utils.VMNFCConnections(&vmMachine.Spec.VMInfo,
+			utils.GetVMDiskOverride(migrationplan, vm), sharedDisks),
+	}
+
+	reason, message := budget.usage.Exceeds(connections, settings)
+	if reason != "" {
+		condition := corev1.PodCondition{
+			Type:               constants.MigrationConditionTypeConnectionBudget,
+			Status:             corev1.ConditionFalse,
+			Reason:             reason,
+			Message:            message,
+			LastTransitionTime: metav1.Now(),
+		}
+		return nil, false, r.updateMigrationCondition(ctx, migrationobj, condition, false)
+	}
+	return connections, true, nil
+}
+
+// recordConnections records the connections of a placed migration in its status, where later migrations count
+// them, and adds them to budget. Nothing is recorded for migrations whose Job exists.
+func (r *MigrationPlanReconciler) recordConnections(ctx context.Context,
+	migrationobj *migratev1alpha1.Migration,
+	connections *migratev1alpha1.MigrationConnections,
+	budget *connectionBudget) error {
+	if connections == nil {
+		return nil
+	}
+	budget.usage.Add(connections)
+	migrationobj.Status.Connections = connections
+	condition := corev1.PodCondition{
+		Type:               constants.MigrationConditionTypeConnectionBudget,
+		Status:             corev1.ConditionTrue,
+		Reason:             "WithinBudget",
+		Message:            fmt.Sprintf("Opens a session on vCenter %s and %d NFC connections", connections.VCenter, connections.NFCConnections),
+		LastTransitionTime: metav1.Now(),
+	}
+	return r.updateMigrationCondition(ctx, migrationobj, condition, true)
+}
+
+// updateMigrationCondition replaces the condition of the same type of a migration. The status is updated when the
+// condition changed or when force is set, and the transition time is kept when the reason and message did not change.
+func (r *MigrationPlanReconciler) updateMigrationCondition(ctx context.Context,
+	migrationobj *migratev1alpha1.Migration, condition corev1.PodCondition, force bool) error {
+	unchanged := false
+	conditions := []corev1.PodCondition{}
+	for _, c := range migrationobj.Status.Conditions {
+		if c.Type != condition.Type {
+			conditions = append(conditions, c)
+		} else if c.Reason == condition.Reason && c.Message == condition.Message {
+			condition.LastTransitionTime = c.LastTransitionTime
+			unchanged = true
+		}
+	}
+	if unchanged && !force {
+		return nil
+	}
+	migrationobj.Status.Conditions = append(conditions, condition)
+	if err := r.Status().Update(ctx, migrationobj); err != nil {
+		return errors.Wrap(err, "failed to update migration status")
+	}
 	return nil
 }
 
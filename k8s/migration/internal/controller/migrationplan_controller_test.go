@@ -23,11 +23,15 @@ import (
 	"github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/utils"
 )
 
 var _ = ginkgo.Describe("MigrationPlan Controller", func() {
@@ -80,3 +84,129 @@ var _ = ginkgo.Describe("MigrationPlan Controller", func() {
 		})
 	})
 })
+
+var _ = ginkgo.Describe("MigrationPlan connection budget", func() {
+	ctx := context.Background()
+	var (
+		k8sClient    client.Client
+		reconciler   *MigrationPlanReconciler
+		migrationobj *migratev1alpha1.Migration
+		budget       *connectionBudget
+	)
+	migrationplan := &migratev1alpha1.MigrationPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: "default"},
+		Spec:       migratev1alpha1.MigrationPlanSpec{VirtualMachines: [][]string{{"vm-1"}}},
+	}
+	vmwcreds := &migratev1alpha1.VMwareCreds{ObjectMeta: metav1.ObjectMeta{Name: "creds"}}
+	vmMachine := &migratev1alpha1.VMwareMachine{}
+	vmMachine.Spec.VMInfo.ESXiName = "esx1"
+	vmMachine.Spec.VMInfo.Disks = []string{"Hard disk 1", "Hard disk 2"}
+	settings := &utils.VjailbreakSettings{MaxVCenterSessions: 1}
+
+	ginkgo.BeforeEach(func() {
+		migrationobj = &migratev1alpha1.Migration{
+			ObjectMeta: metav1.ObjectMeta{Name: "migration-vm-1", Namespace: "default"},
+			Status:     migratev1alpha1.MigrationStatus{Phase: migratev1alpha1.VMMigrationPhasePending},
+		}
+		k8sClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).
+			WithStatusSubresource(&migratev1alpha1.Migration{}).WithObjects(migrationobj).Build()
+		reconciler = &MigrationPlanReconciler{Client: k8sClient, Scheme: scheme.Scheme}
+		budget = &connectionBudget{
+			vcenter: "vc1",
+			usage:   &utils.VCenterConnectionUsage{Sessions: map[string]int{}, NFCConnections: map[string]int{}},
+		}
+	})
+
+	ginkgo.It("records the connections only once the migration is placed", func() {
+		connections, budgeted, err := reconciler.budgetMigration(ctx, migrationplan, migrationobj, "vm-1", vmwcreds, vmMachine, settings, budget)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(budgeted).To(gomega.BeTrue())
+		gomega.Expect(connections).To(gomega.Equal(&migratev1alpha1.MigrationConnections{
+			VCenter: "vc1", ESXiHost: "esx1", NFCConnections: 2,
+		}))
+		gomega.Expect(budget.usage.Sessions["vc1"]).To(gomega.BeZero())
+		gomega.Expect(migrationobj.Status.Connections).To(gomega.BeNil())
+
+		gomega.Expect(reconciler.recordConnections(ctx, migrationobj, connections, budget)).To(gomega.Succeed())
+		gomega.Expect(budget.usage.Sessions["vc1"]).To(gomega.Equal(1))
+		stored := &migratev1alpha1.Migration{}
+		gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(migrationobj), stored)).To(gomega.Succeed())
+		gomega.Expect(stored.Status.Connections).To(gomega.Equal(connections))
+	})
+
+	ginkgo.It("queues migrations over budget", func() {
+		budget.usage.Add(&migratev1alpha1.MigrationConnections{VCenter: "vc1", ESXiHost: "esx2", NFCConnections: 1})
+		connections, budgeted, err := reconciler.budgetMigration(ctx, migrationplan, migrationobj, "vm-1", vmwcreds, vmMachine, settings, budget)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(budgeted).To(gomega.BeFalse())
+		gomega.Expect(connections).To(gomega.BeNil())
+	})
+
+	ginkgo.It("releases the connections of migrations whose Job was not created", func() {
+		stale := &migratev1alpha1.MigrationConnections{VCenter: "vc1", ESXiHost: "esx1", NFCConnections: 2}
+		budget.usage.Add(stale)
+		migrationobj.Status.Connections = stale
+		gomega.Expect(k8sClient.Status().Update(ctx, migrationobj)).To(gomega.Succeed())
+
+		_, budgeted, err := reconciler.budgetMigration(ctx, migrationplan, migrationobj, "vm-1", vmwcreds, vmMachine, settings, budget)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(budgeted).To(gomega.BeTrue())
+		gomega.Expect(budget.usage.Sessions["vc1"]).To(gomega.BeZero())
+		stored := &migratev1alpha1.Migration{}
+		gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(migrationobj), stored)).To(gomega.Succeed())
+		gomega.Expect(stored.Status.Connections).To(gomega.BeNil())
+	})
+})
+
+var _ = ginkgo.Describe("MigrationPlan admission", func() {
+	ctx := context.Background()
+	vmwcreds := &migratev1alpha1.VMwareCreds{ObjectMeta: metav1.ObjectMeta{Name: "creds"}}
+	migrationobj := &migratev1alpha1.Migration{ObjectMeta: metav1.ObjectMeta{Name: "migration", Namespace: "default"}}
+	migrationplan := &migratev1alpha1.MigrationPlan{ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: "default"}}
+	migrationplan.Spec.MaxConcurrentMigrations = 1
+
+	ginkgo.It("admits migrations until the plan runs its maximum", func() {
+		reconciler := &MigrationPlanReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(), Scheme: scheme.Scheme}
+		admission := &migrationAdmission{}
+
+		admitted, newSlot, err := reconciler.admitMigration(ctx, migrationplan, migrationobj, "vm-1", vmwcreds, &utils.VjailbreakSettings{}, admission)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(admitted).To(gomega.BeTrue())
+		gomega.Expect(newSlot).To(gomega.BeTrue())
+		admission.take()
+		admitted, _, err = reconciler.admitMigration(ctx, migrationplan, migrationobj, "vm-2", vmwcreds, &utils.VjailbreakSettings{}, admission)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(admitted).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("leaves the slot of a migration that is not placed to the next one", func() {
+		reconciler := &MigrationPlanReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(), Scheme: scheme.Scheme}
+		admission := &migrationAdmission{}
+
+		// vm-1 is admitted but then queued by its connection budget or placement, its slot is not taken
+		admitted, _, err := reconciler.admitMigration(ctx, migrationplan, migrationobj, "vm-1", vmwcreds, &utils.VjailbreakSettings{}, admission)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(admitted).To(gomega.BeTrue())
+		admitted, _, err = reconciler.admitMigration(ctx, migrationplan, migrationobj, "vm-2", vmwcreds, &utils.VjailbreakSettings{}, admission)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(admitted).To(gomega.BeTrue())
+	})
+
+	ginkgo.It("queues plans behind the first plan waiting for a slot of the settings", func() {
+		// Plans created at the same time are queued by name
+		first := &migratev1alpha1.MigrationPlan{ObjectMeta: metav1.ObjectMeta{Name: "first", Namespace: "default", UID: "uid-first"}}
+		first.Status.QueuePosition = 1
+		reconciler := &MigrationPlanReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(first).Build(),
+			Scheme: scheme.Scheme,
+		}
+		admission := &migrationAdmission{}
+
+		admitted, _, err := reconciler.admitMigration(ctx, migrationplan, migrationobj, "vm-1", vmwcreds,
+			&utils.VjailbreakSettings{MaxConcurrentMigrations: 5}, admission)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(admitted).To(gomega.BeFalse())
+		gomega.Expect(admission.waiting).To(gomega.BeTrue())
+		gomega.Expect(admission.queuePosition).To(gomega.Equal(2))
+	})
+})
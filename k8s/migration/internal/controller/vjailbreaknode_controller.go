@@ -241,8 +241,12 @@ func (r *StellarisMigrateNodeReconciler) updateActiveMigrations(ctx context.Cont
 	scope *scope.StellarisMigrateNodeScope) (ctrl.Result, error) {
 	vjNode := scope.StellarisMigrateNode
 
+	nodeName, err := utils.GetK8sNodeNameForStellarisMigrateNode(ctx, r.Client, vjNode)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to get node name")
+	}
 	// Get active migrations happening on the node
-	activeMigrations, err := utils.GetActiveMigrations(ctx, vjNode.Name, r.Client)
+	activeMigrations, err := utils.GetActiveMigrations(ctx, nodeName, r.Client)
 	if err != nil {
 		return ctrl.Result{}, errors.Wrap(err, "failed to get active migrations")
 	}
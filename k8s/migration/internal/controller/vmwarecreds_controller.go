@@ -47,6 +47,8 @@ type VMwareCredsReconciler struct {
 // +kubebuilder:rbac:groups=migrate.k8s.stellaris.io,resources=vmwarecreds/finalizers,verbs=update
 // +kubebuilder:rbac:groups=migrate.k8s.stellaris.io,resources=vmwarehosts,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=migrate.k8s.stellaris.io,resources=vmwareclusters,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=migrate.k8s.stellaris.io,resources=shareddisks,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=migrate.k8s.stellaris.io,resources=shareddisks/status,verbs=get;update;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
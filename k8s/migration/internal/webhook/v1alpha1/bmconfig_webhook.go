@@ -0,0 +1,123 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// defaultBootSourceRelease is the OS release provisioned on bare metal hosts if none is given
+const defaultBootSourceRelease = "jammy"
+
+// SetupBMConfigWebhookWithManager registers the webhooks of BMConfig with the manager
+func SetupBMConfigWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&migratev1alpha1.BMConfig{}).
+		WithDefaulter(&BMConfigCustomDefaulter{}).
+		WithValidator(&BMConfigCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-migrate-k8s-stellaris-io-v1alpha1-bmconfig,mutating=true,failurePolicy=fail,sideEffects=None,groups=migrate.k8s.stellaris.io,resources=bmconfigs,verbs=create;update,versions=v1alpha1,name=mbmconfig-v1alpha1.k8s.stellaris.io,admissionReviewVersions=v1
+
+// BMConfigCustomDefaulter sets the defaults of a BMConfig
+type BMConfigCustomDefaulter struct{}
+
+var _ admission.CustomDefaulter = &BMConfigCustomDefaulter{}
+
+// Default sets the provider and the boot source release if they are empty
+func (d *BMConfigCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	bmconfig, ok := obj.(*migratev1alpha1.BMConfig)
+	if !ok {
+		return fmt.Errorf("expected a BMConfig object but got %T", obj)
+	}
+	if bmconfig.Spec.ProviderType == "" {
+		bmconfig.Spec.ProviderType = migratev1alpha1.MAASProvider
+	}
+	if bmconfig.Spec.BootSource.Release == "" {
+		bmconfig.Spec.BootSource.Release = defaultBootSourceRelease
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-migrate-k8s-stellaris-io-v1alpha1-bmconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=migrate.k8s.stellaris.io,resources=bmconfigs,verbs=create;update,versions=v1alpha1,name=vbmconfig-v1alpha1.k8s.stellaris.io,admissionReviewVersions=v1
+
+// BMConfigCustomValidator validates a BMConfig
+type BMConfigCustomValidator struct{}
+
+var _ admission.CustomValidator = &BMConfigCustomValidator{}
+
+// ValidateCreate validates a new BMConfig
+func (v *BMConfigCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	bmconfig, ok := obj.(*migratev1alpha1.BMConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a BMConfig object but got %T", obj)
+	}
+	return nil, invalid("BMConfig", bmconfig.Name, validateBMConfigSpec(&bmconfig.Spec))
+}
+
+// ValidateUpdate validates a changed BMConfig
+func (v *BMConfigCustomValidator) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	bmconfig, ok := newObj.(*migratev1alpha1.BMConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a BMConfig object but got %T", newObj)
+	}
+	if bmconfig.DeletionTimestamp != nil {
+		return nil, nil
+	}
+	return nil, invalid("BMConfig", bmconfig.Name, validateBMConfigSpec(&bmconfig.Spec))
+}
+
+// ValidateDelete allows all deletions
+func (v *BMConfigCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateBMConfigSpec validates the connection details of the BMC provider
+func validateBMConfigSpec(spec *migratev1alpha1.BMConfigSpec) field.ErrorList {
+	specPath := field.NewPath("spec")
+	var errs field.ErrorList
+
+	if spec.ProviderType != migratev1alpha1.MAASProvider {
+		errs = append(errs, field.NotSupported(specPath.Child("providerType"), spec.ProviderType,
+			[]string{string(migratev1alpha1.MAASProvider)}))
+	}
+
+	if spec.APIUrl == "" {
+		errs = append(errs, field.Required(specPath.Child("apiUrl"), ""))
+	} else if u, err := url.Parse(spec.APIUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, field.Invalid(specPath.Child("apiUrl"), spec.APIUrl, "must be an http or https URL"))
+	}
+
+	// The secret is not echoed back in the error
+	switch {
+	case spec.APIKey == "":
+		errs = append(errs, field.Required(specPath.Child("apiKey"), ""))
+	case spec.ProviderType == migratev1alpha1.MAASProvider && len(strings.Split(spec.APIKey, ":")) != 3:
+		errs = append(errs, field.Invalid(specPath.Child("apiKey"), "<redacted>",
+			"must be a MAAS API key of the form consumer-key:token-key:token-secret"))
+	}
+	return errs
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupMigrationPlanWebhookWithManager registers the webhooks of MigrationPlan with the manager
+func SetupMigrationPlanWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&migratev1alpha1.MigrationPlan{}).
+		WithDefaulter(&MigrationPlanCustomDefaulter{Client: mgr.GetClient()}).
+		WithValidator(&MigrationPlanCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-migrate-k8s-stellaris-io-v1alpha1-migrationplan,mutating=true,failurePolicy=fail,sideEffects=None,groups=migrate.k8s.stellaris.io,resources=migrationplans,verbs=create;update,versions=v1alpha1,name=mmigrationplan-v1alpha1.k8s.stellaris.io,admissionReviewVersions=v1
+
+// MigrationPlanCustomDefaulter sets the defaults of a MigrationPlan
+type MigrationPlanCustomDefaulter struct {
+	Client client.Client
+}
+
+var _ admission.CustomDefaulter = &MigrationPlanCustomDefaulter{}
+
+// Default sets the migration method from the stellaris-migrate settings if none is given
+func (d *MigrationPlanCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	migrationplan, ok := obj.(*migratev1alpha1.MigrationPlan)
+	if !ok {
+		return fmt.Errorf("expected a MigrationPlan object but got %T", obj)
+	}
+	return defaultMigrationStrategy(ctx, d.Client, &migrationplan.Spec.MigrationStrategy)
+}
+
+// +kubebuilder:webhook:path=/validate-migrate-k8s-stellaris-io-v1alpha1-migrationplan,mutating=false,failurePolicy=fail,sideEffects=None,groups=migrate.k8s.stellaris.io,resources=migrationplans,verbs=create;update,versions=v1alpha1,name=vmigrationplan-v1alpha1.k8s.stellaris.io,admissionReviewVersions=v1
+
+// MigrationPlanCustomValidator validates a MigrationPlan
+type MigrationPlanCustomValidator struct {
+	Client client.Client
+}
+
+var _ admission.CustomValidator = &MigrationPlanCustomValidator{}
+
+// ValidateCreate validates a new MigrationPlan
+func (v *MigrationPlanCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	migrationplan, ok := obj.(*migratev1alpha1.MigrationPlan)
+	if !ok {
+		return nil, fmt.Errorf("expected a MigrationPlan object but got %T", obj)
+	}
+	return nil, invalid("MigrationPlan", migrationplan.Name, v.validate(ctx, migrationplan, nil))
+}
+
+// ValidateUpdate validates a changed MigrationPlan
+func (v *MigrationPlanCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	migrationplan, ok := newObj.(*migratev1alpha1.MigrationPlan)
+	if !ok {
+		return nil, fmt.Errorf("expected a MigrationPlan object but got %T", newObj)
+	}
+	old, ok := oldObj.(*migratev1alpha1.MigrationPlan)
+	if !ok {
+		return nil, fmt.Errorf("expected a MigrationPlan object but got %T", oldObj)
+	}
+	// Let finalizers be removed from plans that became invalid
+	if migrationplan.DeletionTimestamp != nil || reflect.DeepEqual(migrationplan.Spec, old.Spec) {
+		return nil, nil
+	}
+	return nil, invalid("MigrationPlan", migrationplan.Name, v.validate(ctx, migrationplan, old))
+}
+
+// ValidateDelete allows all deletions
+func (v *MigrationPlanCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate validates the spec of a MigrationPlan. old is nil on create.
+func (v *MigrationPlanCustomValidator) validate(ctx context.Context, migrationplan, old *migratev1alpha1.MigrationPlan) field.ErrorList {
+	specPath := field.NewPath("spec")
+	var oldSpecPerVM *migratev1alpha1.MigrationPlanSpecPerVM
+	if old != nil {
+		oldSpecPerVM = &old.Spec.MigrationPlanSpecPerVM
+	}
+	errs := validateMigrationPlanSpecPerVM(ctx, v.Client, migrationplan.Namespace,
+		&migrationplan.Spec.MigrationPlanSpecPerVM, oldSpecPerVM, specPath)
+	return append(errs, validateMigrationPlanVMs(&migrationplan.Spec, specPath)...)
+}
+
+// validateMigrationPlanVMs validates the VMs of a migration plan and the settings that refer to them
+func validateMigrationPlanVMs(spec *migratev1alpha1.MigrationPlanSpec, specPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	vmsPath := specPath.Child("virtualMachines")
+	vms := sets.New[string]()
+	for i, group := range spec.VirtualMachines {
+		for j, vm := range group {
+			switch {
+			case vm == "":
+				errs = append(errs, field.Required(vmsPath.Index(i).Index(j), "VM name must not be empty"))
+			case vms.Has(vm):
+				errs = append(errs, field.Duplicate(vmsPath.Index(i).Index(j), vm))
+			default:
+				vms.Insert(vm)
+			}
+		}
+	}
+	if vms.Len() == 0 {
+		errs = append(errs, field.Required(vmsPath, "at least one VM must be migrated"))
+	}
+
+	if !reflect.DeepEqual(spec.AdvancedOptions, migratev1alpha1.AdvancedOptions{}) &&
+		(len(spec.VirtualMachines) != 1 || len(spec.VirtualMachines[0]) != 1) {
+		errs = append(errs, field.Forbidden(specPath.Child("advancedOptions"),
+			"advanced options can only be set for a migration plan with a single VM"))
+	}
+
+	overridesPath := specPath.Child("networkOverrides")
+	if len(spec.NetworkOverrides) > 0 && len(spec.AdvancedOptions.GranularPorts) > 0 {
+		errs = append(errs, field.Forbidden(overridesPath, "network overrides cannot be combined with granular ports"))
+	}
+	overriddenVMs := sets.New[string]()
+	for i, override := range spec.NetworkOverrides {
+		path := overridesPath.Index(i)
+		switch {
+		case !vms.Has(override.VMName):
+			errs = append(errs, field.Invalid(path.Child("vmName"), override.VMName, "is not a VM of the migration plan"))
+		case overriddenVMs.Has(override.VMName):
+			errs = append(errs, field.Duplicate(path.Child("vmName"), override.VMName))
+		}
+		overriddenVMs.Insert(override.VMName)
+
+		indexes := sets.New[int]()
+		for j, nic := range override.NICs {
+			nicPath := path.Child("nics").Index(j)
+			if indexes.Has(nic.Index) {
+				errs = append(errs, field.Duplicate(nicPath.Child("index"), nic.Index))
+			}
+			indexes.Insert(nic.Index)
+			if nic.FixedIP != "" && net.ParseIP(nic.FixedIP) == nil {
+				errs = append(errs, field.Invalid(nicPath.Child("fixedIP"), nic.FixedIP, "must be an IP address"))
+			}
+		}
+	}
+
+	snapshotOverridesPath := specPath.Child("snapshotOverrides")
+	snapshotVMs := sets.New[string]()
+	for i, override := range spec.SnapshotOverrides {
+		path := snapshotOverridesPath.Index(i)
+		switch {
+		case !vms.Has(override.VMName):
+			errs = append(errs, field.Invalid(path.Child("vmName"), override.VMName, "is not a VM of the migration plan"))
+		case snapshotVMs.Has(override.VMName):
+			errs = append(errs, field.Duplicate(path.Child("vmName"), override.VMName))
+		}
+		snapshotVMs.Insert(override.VMName)
+	}
+	diskOverridesPath := specPath.Child("diskOverrides")
+	diskVMs := sets.New[string]()
+	for i, override := range spec.DiskOverrides {
+		path := diskOverridesPath.Index(i)
+		switch {
+		case !vms.Has(override.VMName):
+			errs = append(errs, field.Invalid(path.Child("vmName"), override.VMName, "is not a VM of the migration plan"))
+		case diskVMs.Has(override.VMName):
+			errs = append(errs, field.Duplicate(path.Child("vmName"), override.VMName))
+		}
+		diskVMs.Insert(override.VMName)
+		disks := sets.New[string]()
+		for j, disk := range override.Disks {
+			diskPath := path.Child("disks").Index(j)
+			switch {
+			case disk.Name == "":
+				errs = append(errs, field.Required(diskPath.Child("name"), "is required"))
+			case disks.Has(disk.Name):
+				errs = append(errs, field.Duplicate(diskPath.Child("name"), disk.Name))
+			}
+			disks.Insert(disk.Name)
+			if disk.Exclude && (disk.VolumeType != "" || disk.Multiattach) {
+				errs = append(errs, field.Invalid(diskPath.Child("exclude"), disk.Exclude,
+					"an excluded disk cannot have a volume type or be multiattach"))
+			}
+		}
+	}
+	return errs
+}
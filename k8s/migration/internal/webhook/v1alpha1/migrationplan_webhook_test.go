@@ -0,0 +1,182 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+)
+
+var _ = ginkgo.Describe("MigrationPlan Webhook", func() {
+	ctx := context.Background()
+	var (
+		migrationplan *migratev1alpha1.MigrationPlan
+		defaulter     *MigrationPlanCustomDefaulter
+		validator     *MigrationPlanCustomValidator
+	)
+
+	ginkgo.BeforeEach(func() {
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&migratev1alpha1.MigrationTemplate{ObjectMeta: metav1.ObjectMeta{Name: "template", Namespace: "default"}},
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      constants.StellarisMigrateSettingsConfigMapName,
+					Namespace: constants.NamespaceMigrationSystem,
+				},
+				Data: map[string]string{"DEFAULT_MIGRATION_METHOD": "cold"},
+			},
+		).Build()
+		defaulter = &MigrationPlanCustomDefaulter{Client: k8sClient}
+		validator = &MigrationPlanCustomValidator{Client: k8sClient}
+		migrationplan = &migratev1alpha1.MigrationPlan{
+			ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: "default"},
+			Spec: migratev1alpha1.MigrationPlanSpec{
+				MigrationPlanSpecPerVM: migratev1alpha1.MigrationPlanSpecPerVM{MigrationTemplate: "template"},
+				VirtualMachines:        [][]string{{"vm-1", "vm-2"}},
+			},
+		}
+	})
+
+	ginkgo.It("defaults the migration method from the settings", func() {
+		gomega.Expect(defaulter.Default(ctx, migrationplan)).To(gomega.Succeed())
+		gomega.Expect(migrationplan.Spec.MigrationStrategy.Type).To(gomega.Equal("cold"))
+
+		_, err := validator.ValidateCreate(ctx, migrationplan)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+
+	ginkgo.It("rejects a plan with a missing template, an invalid type and duplicate VMs", func() {
+		migrationplan.Spec.MigrationTemplate = "missing"
+		migrationplan.Spec.MigrationStrategy.Type = "warm"
+		migrationplan.Spec.VirtualMachines = append(migrationplan.Spec.VirtualMachines, []string{"vm-1"})
+
+		_, err := validator.ValidateCreate(ctx, migrationplan)
+		gomega.Expect(apierrors.IsInvalid(err)).To(gomega.BeTrue())
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("spec.migrationTemplate: Not found"))
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("spec.migrationStrategy.type: Unsupported value"))
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("spec.virtualMachines[1][0]: Duplicate value"))
+	})
+
+	ginkgo.It("rejects job options with a limit below the request and a relative path", func() {
+		migrationplan.Spec.JobOptions = &migratev1alpha1.MigrationJobOptions{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")},
+				Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+			},
+			VDDKPath: "vddk",
+		}
+
+		_, err := validator.ValidateCreate(ctx, migrationplan)
+		gomega.Expect(apierrors.IsInvalid(err)).To(gomega.BeTrue())
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("spec.jobOptions.resources.limits[memory]: Invalid value"))
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("spec.jobOptions.vddkPath: Invalid value"))
+	})
+
+	ginkgo.It("rejects negative concurrency limits", func() {
+		migrationplan.Spec.MaxConcurrentMigrations = -1
+		migrationplan.Spec.MaxConcurrentCutovers = -1
+
+		_, err := validator.ValidateCreate(ctx, migrationplan)
+		gomega.Expect(apierrors.IsInvalid(err)).To(gomega.BeTrue())
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("spec.maxConcurrentMigrations: Invalid value"))
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("spec.maxConcurrentCutovers: Invalid value"))
+	})
+
+	ginkgo.It("rejects hooks without exactly one action and guest commands after the power off", func() {
+		migrationplan.Spec.Hooks = []migratev1alpha1.MigrationHook{
+			{Name: "notify", Point: migratev1alpha1.MigrationHookPostTargetBoot},
+			{
+				Name:         "quiesce",
+				Point:        migratev1alpha1.MigrationHookPostSourcePowerOff,
+				GuestCommand: &migratev1alpha1.MigrationHookGuestCommand{Path: "/usr/local/bin/quiesce"},
+			},
+		}
+
+		_, err := validator.ValidateCreate(ctx, migrationplan)
+		gomega.Expect(apierrors.IsInvalid(err)).To(gomega.BeTrue())
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("exactly one of job, http and guestCommand must be set"))
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("spec.hooks[1].guestCommand: Forbidden"))
+	})
+
+	ginkgo.It("rejects a test boot CIDR that is not an IPv4 subnet", func() {
+		migrationplan.Spec.MigrationStrategy.TestBoot = &migratev1alpha1.TestBoot{CIDR: "fd00::/64"}
+
+		_, err := validator.ValidateCreate(ctx, migrationplan)
+		gomega.Expect(apierrors.IsInvalid(err)).To(gomega.BeTrue())
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("spec.migrationStrategy.testBoot.cidr: Invalid value"))
+	})
+
+	ginkgo.It("rejects disk overrides of unknown VMs, repeated disks and excluded disks with a volume type", func() {
+		migrationplan.Spec.DiskOverrides = []migratev1alpha1.VMDiskOverride{
+			{VMName: "vm-3"},
+			{
+				VMName: "vm-1",
+				Disks: []migratev1alpha1.DiskOverride{
+					{Name: "Hard disk 2", Exclude: true, VolumeType: "ssd"},
+					{Name: "Hard disk 2"},
+				},
+			},
+		}
+
+		_, err := validator.ValidateCreate(ctx, migrationplan)
+		gomega.Expect(apierrors.IsInvalid(err)).To(gomega.BeTrue())
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("spec.diskOverrides[0].vmName: Invalid value"))
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("spec.diskOverrides[1].disks[0].exclude: Invalid value"))
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("spec.diskOverrides[1].disks[1].name: Duplicate value"))
+	})
+
+	ginkgo.It("lets plans whose template was deleted be updated", func() {
+		migrationplan.Spec.MigrationTemplate = "deleted"
+		migrationplan.Spec.MigrationStrategy.Type = "hot"
+		updated := migrationplan.DeepCopy()
+		updated.Spec.Retry = true
+
+		_, err := validator.ValidateUpdate(ctx, migrationplan, updated)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+})
+
+var _ = ginkgo.Describe("NetworkMapping Webhook", func() {
+	ctx := context.Background()
+
+	ginkgo.It("removes repeated mappings and rejects sources mapped twice", func() {
+		networkmapping := &migratev1alpha1.NetworkMapping{
+			ObjectMeta: metav1.ObjectMeta{Name: "networks", Namespace: "default"},
+			Spec: migratev1alpha1.NetworkMappingSpec{Networks: []migratev1alpha1.Network{
+				{Source: "vm-network", Target: "public"},
+				{Source: "vm-network", Target: "public"},
+			}},
+		}
+		gomega.Expect((&NetworkMappingCustomDefaulter{}).Default(ctx, networkmapping)).To(gomega.Succeed())
+		gomega.Expect(networkmapping.Spec.Networks).To(gomega.HaveLen(1))
+
+		networkmapping.Spec.Networks = append(networkmapping.Spec.Networks, migratev1alpha1.Network{Source: "vm-network", Target: "private"})
+		_, err := (&NetworkMappingCustomValidator{}).ValidateCreate(ctx, networkmapping)
+		gomega.Expect(apierrors.IsInvalid(err)).To(gomega.BeTrue())
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring(`spec.networks[1].source: Duplicate value: "vm-network"`))
+	})
+})
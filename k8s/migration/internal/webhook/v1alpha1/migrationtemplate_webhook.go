@@ -0,0 +1,135 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupMigrationTemplateWebhookWithManager registers the webhooks of MigrationTemplate with the manager
+func SetupMigrationTemplateWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&migratev1alpha1.MigrationTemplate{}).
+		WithDefaulter(&MigrationTemplateCustomDefaulter{}).
+		WithValidator(&MigrationTemplateCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-migrate-k8s-stellaris-io-v1alpha1-migrationtemplate,mutating=true,failurePolicy=fail,sideEffects=None,groups=migrate.k8s.stellaris.io,resources=migrationtemplates,verbs=create;update,versions=v1alpha1,name=mmigrationtemplate-v1alpha1.k8s.stellaris.io,admissionReviewVersions=v1
+
+// MigrationTemplateCustomDefaulter sets the defaults of a MigrationTemplate
+type MigrationTemplateCustomDefaulter struct{}
+
+var _ admission.CustomDefaulter = &MigrationTemplateCustomDefaulter{}
+
+// Default sets the virtio-win driver of Windows VMs if none is given
+func (d *MigrationTemplateCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	migrationtemplate, ok := obj.(*migratev1alpha1.MigrationTemplate)
+	if !ok {
+		return fmt.Errorf("expected a MigrationTemplate object but got %T", obj)
+	}
+	if migrationtemplate.Spec.VirtioWinDriver == "" {
+		migrationtemplate.Spec.VirtioWinDriver = constants.DefaultVirtioWinDriver
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-migrate-k8s-stellaris-io-v1alpha1-migrationtemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=migrate.k8s.stellaris.io,resources=migrationtemplates,verbs=create;update,versions=v1alpha1,name=vmigrationtemplate-v1alpha1.k8s.stellaris.io,admissionReviewVersions=v1
+
+// MigrationTemplateCustomValidator validates a MigrationTemplate
+type MigrationTemplateCustomValidator struct {
+	Client client.Client
+}
+
+var _ admission.CustomValidator = &MigrationTemplateCustomValidator{}
+
+// ValidateCreate validates a new MigrationTemplate
+func (v *MigrationTemplateCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	migrationtemplate, ok := obj.(*migratev1alpha1.MigrationTemplate)
+	if !ok {
+		return nil, fmt.Errorf("expected a MigrationTemplate object but got %T", obj)
+	}
+	return v.validate(ctx, migrationtemplate)
+}
+
+// ValidateUpdate validates a changed MigrationTemplate
+func (v *MigrationTemplateCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	migrationtemplate, ok := newObj.(*migratev1alpha1.MigrationTemplate)
+	if !ok {
+		return nil, fmt.Errorf("expected a MigrationTemplate object but got %T", newObj)
+	}
+	old, ok := oldObj.(*migratev1alpha1.MigrationTemplate)
+	if !ok {
+		return nil, fmt.Errorf("expected a MigrationTemplate object but got %T", oldObj)
+	}
+	if migrationtemplate.DeletionTimestamp != nil || reflect.DeepEqual(migrationtemplate.Spec, old.Spec) {
+		return nil, nil
+	}
+	return v.validate(ctx, migrationtemplate)
+}
+
+// ValidateDelete allows all deletions
+func (v *MigrationTemplateCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate validates the spec of a MigrationTemplate. The mappings and credentials it refers to are often applied
+// together with the template, so missing ones only produce warnings.
+func (v *MigrationTemplateCustomValidator) validate(ctx context.Context,
+	migrationtemplate *migratev1alpha1.MigrationTemplate) (admission.Warnings, error) {
+	specPath := field.NewPath("spec")
+	spec := &migrationtemplate.Spec
+	var errs field.ErrorList
+	var warnings admission.Warnings
+
+	refs := []struct {
+		name string
+		path *field.Path
+		obj  client.Object
+		kind string
+	}{
+		{spec.NetworkMapping, specPath.Child("networkMapping"), &migratev1alpha1.NetworkMapping{}, "NetworkMapping"},
+		{spec.StorageMapping, specPath.Child("storageMapping"), &migratev1alpha1.StorageMapping{}, "StorageMapping"},
+		{spec.Source.VMwareRef, specPath.Child("source", "vmwareRef"), &migratev1alpha1.VMwareCreds{}, "VMwareCreds"},
+		{spec.Destination.OpenstackRef, specPath.Child("destination", "openstackRef"), &migratev1alpha1.OpenstackCreds{}, "OpenstackCreds"},
+	}
+	for _, ref := range refs {
+		if ref.name == "" {
+			errs = append(errs, field.Required(ref.path, ""))
+			continue
+		}
+		warnings = append(warnings, warnMissingReference(ctx, v.Client, ref.obj, ref.kind, migrationtemplate.Namespace, ref.name, ref.path)...)
+	}
+
+	if spec.VirtioWinDriver != "" {
+		if u, err := url.Parse(spec.VirtioWinDriver); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, field.Invalid(specPath.Child("virtioWinDriver"), spec.VirtioWinDriver, "must be an http or https URL"))
+		}
+	}
+	errs = append(errs, validateMigrationJobOptions(spec.JobOptions, specPath.Child("jobOptions"))...)
+	return warnings, invalid("MigrationTemplate", migrationtemplate.Name, errs)
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupNetworkMappingWebhookWithManager registers the webhooks of NetworkMapping with the manager
+func SetupNetworkMappingWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&migratev1alpha1.NetworkMapping{}).
+		WithDefaulter(&NetworkMappingCustomDefaulter{}).
+		WithValidator(&NetworkMappingCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-migrate-k8s-stellaris-io-v1alpha1-networkmapping,mutating=true,failurePolicy=fail,sideEffects=None,groups=migrate.k8s.stellaris.io,resources=networkmappings,verbs=create;update,versions=v1alpha1,name=mnetworkmapping-v1alpha1.k8s.stellaris.io,admissionReviewVersions=v1
+
+// NetworkMappingCustomDefaulter sets the defaults of a NetworkMapping
+type NetworkMappingCustomDefaulter struct{}
+
+var _ admission.CustomDefaulter = &NetworkMappingCustomDefaulter{}
+
+// Default removes mappings that are repeated with the same source and target
+func (d *NetworkMappingCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	networkmapping, ok := obj.(*migratev1alpha1.NetworkMapping)
+	if !ok {
+		return fmt.Errorf("expected a NetworkMapping object but got %T", obj)
+	}
+	networkmapping.Spec.Networks = dedupMappings(networkmapping.Spec.Networks)
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-migrate-k8s-stellaris-io-v1alpha1-networkmapping,mutating=false,failurePolicy=fail,sideEffects=None,groups=migrate.k8s.stellaris.io,resources=networkmappings,verbs=create;update,versions=v1alpha1,name=vnetworkmapping-v1alpha1.k8s.stellaris.io,admissionReviewVersions=v1
+
+// NetworkMappingCustomValidator validates a NetworkMapping
+type NetworkMappingCustomValidator struct{}
+
+var _ admission.CustomValidator = &NetworkMappingCustomValidator{}
+
+// ValidateCreate validates a new NetworkMapping
+func (v *NetworkMappingCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	networkmapping, ok := obj.(*migratev1alpha1.NetworkMapping)
+	if !ok {
+		return nil, fmt.Errorf("expected a NetworkMapping object but got %T", obj)
+	}
+	return nil, invalid("NetworkMapping", networkmapping.Name,
+		validateMappings(networkmapping.Spec.Networks, field.NewPath("spec", "networks")))
+}
+
+// ValidateUpdate validates a changed NetworkMapping
+func (v *NetworkMappingCustomValidator) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	networkmapping, ok := newObj.(*migratev1alpha1.NetworkMapping)
+	if !ok {
+		return nil, fmt.Errorf("expected a NetworkMapping object but got %T", newObj)
+	}
+	if networkmapping.DeletionTimestamp != nil {
+		return nil, nil
+	}
+	return nil, invalid("NetworkMapping", networkmapping.Name,
+		validateMappings(networkmapping.Spec.Networks, field.NewPath("spec", "networks")))
+}
+
+// ValidateDelete allows all deletions
+func (v *NetworkMappingCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// sourceTarget is the underlying type of the entries of NetworkMappings and StorageMappings
+type sourceTarget = struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// mapping is a source to target mapping of a NetworkMapping or StorageMapping
+type mapping interface {
+	~sourceTarget
+}
+
+// dedupMappings removes mappings that repeat an earlier mapping
+func dedupMappings[M mapping](mappings []M) []M {
+	var deduped []M
+	seen := map[M]bool{}
+	for _, m := range mappings {
+		if !seen[m] {
+			seen[m] = true
+			deduped = append(deduped, m)
+		}
+	}
+	return deduped
+}
+
+// validateMappings checks that there is at least one mapping and that every source is mapped to one target
+func validateMappings[M mapping](mappings []M, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if len(mappings) == 0 {
+		return append(errs, field.Required(path, "at least one mapping is required"))
+	}
+	sources := map[string]bool{}
+	for i, m := range mappings {
+		source, target := sourceTarget(m).Source, sourceTarget(m).Target
+		if source == "" {
+			errs = append(errs, field.Required(path.Index(i).Child("source"), ""))
+		} else if sources[source] {
+			errs = append(errs, field.Duplicate(path.Index(i).Child("source"), source))
+		}
+		if target == "" {
+			errs = append(errs, field.Required(path.Index(i).Child("target"), ""))
+		}
+		sources[source] = true
+	}
+	return errs
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// defaultVMMigrationBatchSize is the number of VMs of a cluster migrated in parallel if none is given
+const defaultVMMigrationBatchSize = 10
+
+// SetupRollingMigrationPlanWebhookWithManager registers the webhooks of RollingMigrationPlan with the manager
+func SetupRollingMigrationPlanWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&migratev1alpha1.RollingMigrationPlan{}).
+		WithDefaulter(&RollingMigrationPlanCustomDefaulter{Client: mgr.GetClient()}).
+		WithValidator(&RollingMigrationPlanCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-migrate-k8s-stellaris-io-v1alpha1-rollingmigrationplan,mutating=true,failurePolicy=fail,sideEffects=None,groups=migrate.k8s.stellaris.io,resources=rollingmigrationplans,verbs=create;update,versions=v1alpha1,name=mrollingmigrationplan-v1alpha1.k8s.stellaris.io,admissionReviewVersions=v1
+
+// RollingMigrationPlanCustomDefaulter sets the defaults of a RollingMigrationPlan
+type RollingMigrationPlanCustomDefaulter struct {
+	Client client.Client
+}
+
+var _ admission.CustomDefaulter = &RollingMigrationPlanCustomDefaulter{}
+
+// Default sets the migration method from the stellaris-migrate settings and the batch size of the clusters
+func (d *RollingMigrationPlanCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	rollingmigrationplan, ok := obj.(*migratev1alpha1.RollingMigrationPlan)
+	if !ok {
+		return fmt.Errorf("expected a RollingMigrationPlan object but got %T", obj)
+	}
+	for i := range rollingmigrationplan.Spec.ClusterSequence {
+		if rollingmigrationplan.Spec.ClusterSequence[i].VMMigrationBatchSize == 0 {
+			rollingmigrationplan.Spec.ClusterSequence[i].VMMigrationBatchSize = defaultVMMigrationBatchSize
+		}
+	}
+	return defaultMigrationStrategy(ctx, d.Client, &rollingmigrationplan.Spec.MigrationStrategy)
+}
+
+// +kubebuilder:webhook:path=/validate-migrate-k8s-stellaris-io-v1alpha1-rollingmigrationplan,mutating=false,failurePolicy=fail,sideEffects=None,groups=migrate.k8s.stellaris.io,resources=rollingmigrationplans,verbs=create;update,versions=v1alpha1,name=vrollingmigrationplan-v1alpha1.k8s.stellaris.io,admissionReviewVersions=v1
+
+// RollingMigrationPlanCustomValidator validates a RollingMigrationPlan
+type RollingMigrationPlanCustomValidator struct {
+	Client client.Client
+}
+
+var _ admission.CustomValidator = &RollingMigrationPlanCustomValidator{}
+
+// ValidateCreate validates a new RollingMigrationPlan
+func (v *RollingMigrationPlanCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	rollingmigrationplan, ok := obj.(*migratev1alpha1.RollingMigrationPlan)
+	if !ok {
+		return nil, fmt.Errorf("expected a RollingMigrationPlan object but got %T", obj)
+	}
+	return v.validate(ctx, rollingmigrationplan, nil)
+}
+
+// ValidateUpdate validates a changed RollingMigrationPlan
+func (v *RollingMigrationPlanCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	rollingmigrationplan, ok := newObj.(*migratev1alpha1.RollingMigrationPlan)
+	if !ok {
+		return nil, fmt.Errorf("expected a RollingMigrationPlan object but got %T", newObj)
+	}
+	old, ok := oldObj.(*migratev1alpha1.RollingMigrationPlan)
+	if !ok {
+		return nil, fmt.Errorf("expected a RollingMigrationPlan object but got %T", oldObj)
+	}
+	if rollingmigrationplan.DeletionTimestamp != nil || reflect.DeepEqual(rollingmigrationplan.Spec, old.Spec) {
+		return nil, nil
+	}
+	return v.validate(ctx, rollingmigrationplan, old)
+}
+
+// ValidateDelete allows all deletions
+func (v *RollingMigrationPlanCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate validates the spec of a RollingMigrationPlan. old is nil on create.
+func (v *RollingMigrationPlanCustomValidator) validate(ctx context.Context,
+	rollingmigrationplan, old *migratev1alpha1.RollingMigrationPlan) (admission.Warnings, error) {
+	specPath := field.NewPath("spec")
+	spec := &rollingmigrationplan.Spec
+	var oldSpecPerVM *migratev1alpha1.MigrationPlanSpecPerVM
+	if old != nil {
+		oldSpecPerVM = &old.Spec.MigrationPlanSpecPerVM
+	}
+	errs := validateMigrationPlanSpecPerVM(ctx, v.Client, rollingmigrationplan.Namespace,
+		&spec.MigrationPlanSpecPerVM, oldSpecPerVM, specPath)
+
+	sequencePath := specPath.Child("clusterSequence")
+	if len(spec.ClusterSequence) == 0 {
+		errs = append(errs, field.Required(sequencePath, "at least one cluster must be migrated"))
+	}
+	clusters := sets.New[string]()
+	vms := sets.New[string]()
+	for i, cluster := range spec.ClusterSequence {
+		clusterPath := sequencePath.Index(i)
+		switch {
+		case cluster.ClusterName == "":
+			errs = append(errs, field.Required(clusterPath.Child("clusterName"), ""))
+		case clusters.Has(cluster.ClusterName):
+			errs = append(errs, field.Duplicate(clusterPath.Child("clusterName"), cluster.ClusterName))
+		}
+		clusters.Insert(cluster.ClusterName)
+		if cluster.VMMigrationBatchSize < 1 {
+			errs = append(errs, field.Invalid(clusterPath.Child("vmMigrationBatchSize"), cluster.VMMigrationBatchSize,
+				"must be at least 1"))
+		}
+		for j, vm := range cluster.VMSequence {
+			vmPath := clusterPath.Child("vmSequence").Index(j).Child("vmName")
+			switch {
+			case vm.VMName == "":
+				errs = append(errs, field.Required(vmPath, ""))
+			case vms.Has(vm.VMName):
+				errs = append(errs, field.Duplicate(vmPath, vm.VMName))
+			}
+			vms.Insert(vm.VMName)
+		}
+	}
+
+	mappedClusters := sets.New[string]()
+	for i, clusterMapping := range spec.ClusterMapping {
+		mappingPath := specPath.Child("clusterMapping").Index(i)
+		switch {
+		case clusterMapping.VMwareClusterName == "":
+			errs = append(errs, field.Required(mappingPath.Child("vmwareClusterName"), ""))
+		case mappedClusters.Has(clusterMapping.VMwareClusterName):
+			errs = append(errs, field.Duplicate(mappingPath.Child("vmwareClusterName"), clusterMapping.VMwareClusterName))
+		}
+		mappedClusters.Insert(clusterMapping.VMwareClusterName)
+		if clusterMapping.PCDClusterName == "" {
+			errs = append(errs, field.Required(mappingPath.Child("pcdClusterName"), ""))
+		}
+	}
+
+	bmConfigPath := specPath.Child("bmConfigRef", "name")
+	var warnings admission.Warnings
+	if spec.BMConfigRef.Name == "" {
+		errs = append(errs, field.Required(bmConfigPath, ""))
+	} else if old == nil || old.Spec.BMConfigRef.Name != spec.BMConfigRef.Name {
+		warnings = warnMissingReference(ctx, v.Client, &migratev1alpha1.BMConfig{}, "BMConfig",
+			rollingmigrationplan.Namespace, spec.BMConfigRef.Name, bmConfigPath)
+	}
+	return warnings, invalid("RollingMigrationPlan", rollingmigrationplan.Name, errs)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupStorageMappingWebhookWithManager registers the webhooks of StorageMapping with the manager
+func SetupStorageMappingWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&migratev1alpha1.StorageMapping{}).
+		WithDefaulter(&StorageMappingCustomDefaulter{}).
+		WithValidator(&StorageMappingCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-migrate-k8s-stellaris-io-v1alpha1-storagemapping,mutating=true,failurePolicy=fail,sideEffects=None,groups=migrate.k8s.stellaris.io,resources=storagemappings,verbs=create;update,versions=v1alpha1,name=mstoragemapping-v1alpha1.k8s.stellaris.io,admissionReviewVersions=v1
+
+// StorageMappingCustomDefaulter sets the defaults of a StorageMapping
+type StorageMappingCustomDefaulter struct{}
+
+var _ admission.CustomDefaulter = &StorageMappingCustomDefaulter{}
+
+// Default removes mappings that are repeated with the same source and target
+func (d *StorageMappingCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	storagemapping, ok := obj.(*migratev1alpha1.StorageMapping)
+	if !ok {
+		return fmt.Errorf("expected a StorageMapping object but got %T", obj)
+	}
+	storagemapping.Spec.Storages = dedupMappings(storagemapping.Spec.Storages)
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-migrate-k8s-stellaris-io-v1alpha1-storagemapping,mutating=false,failurePolicy=fail,sideEffects=None,groups=migrate.k8s.stellaris.io,resources=storagemappings,verbs=create;update,versions=v1alpha1,name=vstoragemapping-v1alpha1.k8s.stellaris.io,admissionReviewVersions=v1
+
+// StorageMappingCustomValidator validates a StorageMapping
+type StorageMappingCustomValidator struct{}
+
+var _ admission.CustomValidator = &StorageMappingCustomValidator{}
+
+// ValidateCreate validates a new StorageMapping
+func (v *StorageMappingCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	storagemapping, ok := obj.(*migratev1alpha1.StorageMapping)
+	if !ok {
+		return nil, fmt.Errorf("expected a StorageMapping object but got %T", obj)
+	}
+	return nil, invalid("StorageMapping", storagemapping.Name,
+		validateMappings(storagemapping.Spec.Storages, field.NewPath("spec", "storages")))
+}
+
+// ValidateUpdate validates a changed StorageMapping
+func (v *StorageMappingCustomValidator) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	storagemapping, ok := newObj.(*migratev1alpha1.StorageMapping)
+	if !ok {
+		return nil, fmt.Errorf("expected a StorageMapping object but got %T", newObj)
+	}
+	if storagemapping.DeletionTimestamp != nil {
+		return nil, nil
+	}
+	return nil, invalid("StorageMapping", storagemapping.Name,
+		validateMappings(storagemapping.Spec.Storages, field.NewPath("spec", "storages")))
+}
+
+// ValidateDelete allows all deletions
+func (v *StorageMappingCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 provides the defaulting and validating admission webhooks of the migration CRDs.
+// They reject invalid objects when they are applied, instead of the reconcile failing on them later.
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhooksWithManager registers the webhooks of all migration CRDs with the manager
+func SetupWebhooksWithManager(mgr ctrl.Manager) error {
+	for _, setup := range []func(ctrl.Manager) error{
+		SetupMigrationPlanWebhookWithManager,
+		SetupMigrationTemplateWebhookWithManager,
+		SetupNetworkMappingWebhookWithManager,
+		SetupStorageMappingWebhookWithManager,
+		SetupRollingMigrationPlanWebhookWithManager,
+		SetupBMConfigWebhookWithManager,
+	} {
+		if err := setup(mgr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// invalid returns an Invalid error for the object of kind if there are any field errors
+func invalid(kind, name string, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(migratev1alpha1.GroupVersion.WithKind(kind).GroupKind(), name, errs)
+}
+
+// validateReference checks that the object name referenced at path exists in namespace. Nothing is checked when the
+// reference is unchanged on update, so that the controllers can update objects whose references were deleted.
+func validateReference(ctx context.Context, c client.Reader, obj client.Object, namespace, name, oldName string,
+	oldExists bool, path *field.Path) *field.Error {
+	if name == "" {
+		return field.Required(path, "")
+	}
+	if oldExists && name == oldName {
+		return nil
+	}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return field.NotFound(path, name)
+		}
+		return field.InternalError(path, err)
+	}
+	return nil
+}
+
+// warnMissingReference returns a warning if the object name of kind does not exist in namespace. It is used for
+// references that may legitimately be created after the referencing object.
+func warnMissingReference(ctx context.Context, c client.Reader, obj client.Object, kind, namespace, name string,
+	path *field.Path) admission.Warnings {
+	if name == "" {
+		return nil
+	}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, obj); apierrors.IsNotFound(err) {
+		return admission.Warnings{fmt.Sprintf("%s: %s %q does not exist yet", path, kind, name)}
+	}
+	return nil
+}
+
+// defaultMigrationStrategy applies the defaults of the migration strategy. The migration method comes from
+// the stellaris-migrate settings.
+func defaultMigrationStrategy(ctx context.Context, c client.Client, strategy *migratev1alpha1.MigrationPlanStrategy) error {
+	if strategy.Type == "" {
+		settings, err := utils.GetMigrateSettings(ctx, c)
+		if err != nil {
+			return err
+		}
+		strategy.Type = settings.DefaultMigrationMethod
+		if strategy.Type != "hot" && strategy.Type != "cold" {
+			strategy.Type = constants.DefaultMigrationMethod
+		}
+	}
+	if strategy.CopyWindow != nil && strategy.CopyWindow.TimeZone == "" {
+		strategy.CopyWindow.TimeZone = "UTC"
+	}
+	if strategy.CutoverWindow != nil && strategy.CutoverWindow.TimeZone == "" {
+		strategy.CutoverWindow.TimeZone = "UTC"
+	}
+	return nil
+}
+
+// validateMigrationStrategy validates the migration strategy shared by migration plans and rolling migration plans
+func validateMigrationStrategy(strategy *migratev1alpha1.MigrationPlanStrategy, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if strategy.Type != "hot" && strategy.Type != "cold" {
+		errs = append(errs, field.NotSupported(path.Child("type"), strategy.Type, []string{"hot", "cold"}))
+	}
+	if strategy.VMCutoverStart.After(strategy.VMCutoverEnd.Time) {
+		errs = append(errs, field.Invalid(path.Child("vmCutoverEnd"), strategy.VMCutoverEnd,
+			"must not be before vmCutoverStart"))
+	}
+	if !strategy.VMCutoverEnd.IsZero() && strategy.DataCopyStart.After(strategy.VMCutoverEnd.Time) {
+		errs = append(errs, field.Invalid(path.Child("dataCopyStart"), strategy.DataCopyStart,
+			"must not be after vmCutoverEnd"))
+	}
+	if strategy.PerformHealthChecks && strategy.HealthCheckPort != "" {
+		if port, err := strconv.Atoi(strategy.HealthCheckPort); err != nil || port < 1 || port > 65535 {
+			errs = append(errs, field.Invalid(path.Child("healthCheckPort"), strategy.HealthCheckPort,
+				"must be a port number between 1 and 65535"))
+		}
+	}
+	if strategy.CopyWindow != nil {
+		if _, err := time.LoadLocation(strategy.CopyWindow.TimeZone); err != nil {
+			errs = append(errs, field.Invalid(path.Child("copyWindow", "timeZone"), strategy.CopyWindow.TimeZone,
+				"must be an IANA time zone"))
+		}
+	}
+	if strategy.CutoverWindow != nil {
+		if _, err := time.LoadLocation(strategy.CutoverWindow.TimeZone); err != nil {
+			errs = append(errs, field.Invalid(path.Child("cutoverWindow", "timeZone"), strategy.CutoverWindow.TimeZone,
+				"must be an IANA time zone"))
+		}
+		if strategy.CutoverWindow.Start == strategy.CutoverWindow.End {
+			errs = append(errs, field.Invalid(path.Child("cutoverWindow", "end"), strategy.CutoverWindow.End,
+				"must not be the same as start"))
+		}
+	}
+	if strategy.CutoverFinalizeDuration.Duration < 0 {
+		errs = append(errs, field.Invalid(path.Child("cutoverFinalizeDuration"), strategy.CutoverFinalizeDuration.Duration.String(),
+			"must not be negative"))
+	}
+	if strategy.QuiesceTimeout.Duration < 0 {
+		errs = append(errs, field.Invalid(path.Child("quiesceTimeout"), strategy.QuiesceTimeout.Duration.String(),
+			"must not be negative"))
+	}
+	if strategy.TestBoot != nil {
+		if strategy.TestBoot.CIDR != "" {
+			if prefix, err := netip.ParsePrefix(strategy.TestBoot.CIDR); err != nil || !prefix.Addr().Is4() || prefix.Bits() > 29 {
+				errs = append(errs, field.Invalid(path.Child("testBoot", "cidr"), strategy.TestBoot.CIDR,
+					"must be an IPv4 CIDR of at least 8 addresses"))
+			}
+		}
+		if strategy.TestBoot.Timeout.Duration < 0 {
+			errs = append(errs, field.Invalid(path.Child("testBoot", "timeout"), strategy.TestBoot.Timeout.Duration.String(),
+				"must not be negative"))
+		}
+	}
+	return errs
+}
+
+// validateMigrationPlanSpecPerVM validates the per VM settings shared by migration plans and rolling migration
+// plans. old is nil on create.
+func validateMigrationPlanSpecPerVM(ctx context.Context, c client.Reader, namespace string,
+	spec, old *migratev1alpha1.MigrationPlanSpecPerVM, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	var oldTemplate string
+	if old != nil {
+		oldTemplate = old.MigrationTemplate
+	}
+	if err := validateReference(ctx, c, &migratev1alpha1.MigrationTemplate{}, namespace, spec.MigrationTemplate,
+		oldTemplate, old != nil, path.Child("migrationTemplate")); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, validateMigrationStrategy(&spec.MigrationStrategy, path.Child("migrationStrategy"))...)
+	errs = append(errs, validateMigrationJobOptions(spec.JobOptions, path.Child("jobOptions"))...)
+	if spec.MaxConcurrentMigrations < 0 {
+		errs = append(errs, field.Invalid(path.Child("maxConcurrentMigrations"), spec.MaxConcurrentMigrations, "must not be negative"))
+	}
+	if spec.MaxConcurrentCutovers < 0 {
+		errs = append(errs, field.Invalid(path.Child("maxConcurrentCutovers"), spec.MaxConcurrentCutovers, "must not be negative"))
+	}
+	errs = append(errs, validateMigrationHooks(spec.Hooks, path.Child("hooks"))...)
+	return errs
+}
+
+// validateMigrationHooks validates the hooks of a migration plan. Guest commands run through the VMware Tools of the
+// source VM, so they can only run while it is powered on.
+func validateMigrationHooks(hooks []migratev1alpha1.MigrationHook, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	names := sets.New[string]()
+	for i, hook := range hooks {
+		hookPath := path.Index(i)
+		if names.Has(hook.Name) {
+			errs = append(errs, field.Duplicate(hookPath.Child("name"), hook.Name))
+		}
+		names.Insert(hook.Name)
+
+		actions := 0
+		for _, set := range []bool{hook.Job != nil, hook.HTTP != nil, hook.GuestCommand != nil} {
+			if set {
+				actions++
+			}
+		}
+		if actions != 1 {
+			errs = append(errs, field.Invalid(hookPath, hook.Name, "exactly one of job, http and guestCommand must be set"))
+		}
+		if hook.GuestCommand != nil && hook.Point != migratev1alpha1.MigrationHookPreFinalSnapshot {
+			errs = append(errs, field.Forbidden(hookPath.Child("guestCommand"),
+				fmt.Sprintf("guest commands can only run at %s, while the source VM is powered on", migratev1alpha1.MigrationHookPreFinalSnapshot)))
+		}
+		if hook.HTTP != nil {
+			if u, err := url.Parse(hook.HTTP.URL); err != nil || u.Host == "" {
+				errs = append(errs, field.Invalid(hookPath.Child("http", "url"), hook.HTTP.URL, "must be an absolute URL"))
+			}
+		}
+		if hook.Timeout.Duration < 0 {
+			errs = append(errs, field.Invalid(hookPath.Child("timeout"), hook.Timeout.Duration.String(), "must not be negative"))
+		}
+	}
+	return errs
+}
+
+// validateMigrationJobOptions validates the options of the v2v-helper pods. Limits are only checked against the
+// requests given next to them, since the others are merged from the template and the settings.
+func validateMigrationJobOptions(opts *migratev1alpha1.MigrationJobOptions, path *field.Path) field.ErrorList {
+	if opts == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	resourcesPath := path.Child("resources")
+	for name, request := range opts.Resources.Requests {
+		if request.Sign() < 0 {
+			errs = append(errs, field.Invalid(resourcesPath.Child("requests").Key(string(name)), request.String(),
+				"must not be negative"))
+		}
+		if limit, ok := opts.Resources.Limits[name]; ok && limit.Cmp(request) < 0 {
+			errs = append(errs, field.Invalid(resourcesPath.Child("limits").Key(string(name)), limit.String(),
+				fmt.Sprintf("must be greater than or equal to the request %s", request.String())))
+		}
+	}
+	for _, p := range []struct {
+		value string
+		name  string
+	}{
+		{opts.VDDKPath, "vddkPath"},
+		{opts.VirtioWinPath, "virtioWinPath"},
+	} {
+		if p.value != "" && !filepath.IsAbs(p.value) {
+			errs = append(errs, field.Invalid(path.Child(p.name), p.value, "must be an absolute path"))
+		}
+	}
+	return errs
+}
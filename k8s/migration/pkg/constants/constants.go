@@ -27,6 +27,9 @@ const (
 	// StellarisMigrateNodeControllerName is the name of the stellaris-migrate node controller
 	StellarisMigrateNodeControllerName = "stellaris-migrate-node-controller"
 
+	// AgentPoolControllerName is the name of the agent pool controller
+	AgentPoolControllerName = "agentpool-controller"
+
 	// OpenstackCredsControllerName is the name of the openstack credentials controller
 	OpenstackCredsControllerName = "openstackcreds-controller" //nolint:gosec // not a password string
 
@@ -78,9 +81,25 @@ const (
 	// ClusterMigrationLabel is the label for cluster migration
 	ClusterMigrationLabel = "migrate.k8s.stellaris.io/clustermigration"
 
+	// MigrationPlanLabel is the label for the migration plan of a migration and of its v2v-helper job
+	MigrationPlanLabel = "migrationplan"
+
 	// RollingMigrationPlanLabel is the label for rolling migration plan
 	RollingMigrationPlanLabel = "migrate.k8s.stellaris.io/rollingmigrationplan"
 
+	// AgentPoolLabel is the label for the agent pool that created a stellaris-migrate node
+	AgentPoolLabel = "migrate.k8s.stellaris.io/agentpool"
+
+	// AgentDrainingAnnotation marks a stellaris-migrate node that its agent pool cordoned to delete it
+	AgentDrainingAnnotation = "migrate.k8s.stellaris.io/draining"
+
+	// MigrationRetryAnnotation marks a Migration deleted to be recreated by a retry of its plan. The volumes kept to
+	// resume its disk copy are not deleted with it.
+	MigrationRetryAnnotation = "migrate.k8s.stellaris.io/retry"
+
+	// AgentPoolRequeueInterval is the interval at which an agent pool compares its agents with the migrations
+	AgentPoolRequeueInterval = 30 * time.Second
+
 	// PauseMigrationLabel is the label for pausing rolling migration plan
 	PauseMigrationLabel = "migrate.k8s.stellaris.io/pause"
 
@@ -96,6 +115,9 @@ const (
 	// NodeRoleMaster is the role of the master node
 	NodeRoleMaster = "master"
 
+	// NodeRoleWorker is the role of the worker nodes
+	NodeRoleWorker = "worker"
+
 	// InternalIPAnnotation is the annotation for internal IP
 	InternalIPAnnotation = "k3s.io/internal-ip"
 
@@ -147,12 +169,33 @@ const (
 	// MigrationTriggerDelay is the delay for migration trigger
 	MigrationTriggerDelay = 5 * time.Second
 
+	// QuotaRecheckInterval is the interval at which a migration plan waiting for quota checks it again
+	QuotaRecheckInterval = 1 * time.Minute
+
+	// AgentPlacementRecheckInterval is the interval at which a migration plan with VMs queued for a free agent
+	// or a free migration slot tries to start them again
+	AgentPlacementRecheckInterval = 30 * time.Second
+
+	// PlacementStrategyLeastMigrations places a migration on the agent running the fewest migrations
+	PlacementStrategyLeastMigrations = "LeastMigrations"
+
+	// PlacementStrategyMostFreeBandwidth places a migration on the agent with the lowest copy throughput. The
+	// bandwidth of the agents is not measured, they are assumed to have the same bandwidth, so that the agent
+	// copying the fewest bytes per second has the most free bandwidth.
+	PlacementStrategyMostFreeBandwidth = "MostFreeBandwidth"
+
+	// PlacementStrategyScheduler leaves the placement of migrations to the Kubernetes scheduler
+	PlacementStrategyScheduler = "Scheduler"
+
 	// MigrationReason is the reason for migration
 	MigrationReason = "Migration"
 
 	// StartCutOverYes is the value for start cut over yes
 	StartCutOverYes = "yes"
 
+	// CutoverGatedEnvVar tells the v2v-helper that its cutover waits for a free cutover slot rather than for the admin
+	CutoverGatedEnvVar = "CUTOVER_GATED"
+
 	// MaxVCPUs is the maximum number of vCPUs
 	OSFamilyWindows = "windows"
 	OSFamilyLinux   = "linux"
@@ -189,6 +232,15 @@ const (
 	// DefaultMigrationMethod is the default migration method
 	DefaultMigrationMethod = "hot"
 
+	// DefaultVirtioWinDriver is the virtio-win ISO used for Windows VMs when the migration template sets none
+	DefaultVirtioWinDriver = "https://fedorapeople.org/groups/virt/virtio-win/direct-downloads/stable-virtio/virtio-win.iso"
+
+	// DefaultVDDKPath is the directory on the nodes holding the VMware VDDK libraries
+	DefaultVDDKPath = "/home/ubuntu/vmware-vix-disklib-distrib"
+
+	// DefaultVirtioWinPath is the directory on the nodes where the virtio-win drivers are cached
+	DefaultVirtioWinPath = "/home/ubuntu/virtio-win"
+
 	// VCenterScanConcurrencyLimit is the max number of vcenter scan pods
 	VCenterScanConcurrencyLimit = 100
 
@@ -197,6 +249,30 @@ const (
 
 	// StellarisMigrateSettingsConfigMapName is the name of the stellaris-migrate settings configmap
 	StellarisMigrateSettingsConfigMapName = "stellaris-migrate-settings"
+
+	// CutoverSlotsConfigMapName is the name of the configmap recording the migrations given a cutover slot
+	CutoverSlotsConfigMapName = "migration-cutover-slots"
+
+	// WebhookServiceName is the name of the service in front of the admission webhooks of the controller
+	WebhookServiceName = "migration-webhook-service"
+
+	// WebhookCertSecretName is the name of the secret holding the CA and serving certificate of the webhooks
+	WebhookCertSecretName = "migration-webhook-server-cert" //nolint:gosec // not a password string
+
+	// MutatingWebhookConfigurationName is the name of the mutating webhook configuration of the controller
+	MutatingWebhookConfigurationName = "migration-mutating-webhook-configuration"
+
+	// ValidatingWebhookConfigurationName is the name of the validating webhook configuration of the controller
+	ValidatingWebhookConfigurationName = "migration-validating-webhook-configuration"
+
+	// WebhookCertDir is the directory the webhook server reads its serving certificate from
+	WebhookCertDir = "/tmp/k8s-webhook-server/serving-certs"
+
+	// WebhookCertValidity is how long the generated webhook certificates are valid
+	WebhookCertValidity = 5 * 365 * 24 * time.Hour
+
+	// WebhookCertRenewBefore is how long before expiry the webhook certificates are regenerated
+	WebhookCertRenewBefore = 30 * 24 * time.Hour
 )
 
 // CloudInitScript contains the cloud-init script for VM initialization
@@ -224,6 +300,38 @@ runcmd:
 	MigrationConditionTypeValidated corev1.PodConditionType = "Validated"
 	MigrationConditionTypeFailed    corev1.PodConditionType = "Failed"
 
+	// MigrationConditionTypeAgentPlacement represents the condition type for the placement of a migration on an agent
+	MigrationConditionTypeAgentPlacement corev1.PodConditionType = "AgentPlacement"
+
+	// MigrationConditionTypeConnectionBudget represents the condition type for a migration waiting for free vCenter
+	// sessions or ESXi NFC connections
+	MigrationConditionTypeConnectionBudget corev1.PodConditionType = "ConnectionBudget"
+
+	// MigrationConditionTypeCutoverSlot represents the condition type for a migration waiting for a free cutover slot
+	MigrationConditionTypeCutoverSlot corev1.PodConditionType = "CutoverSlot"
+
+	// MigrationConditionTypeDataVerified represents the condition type for the data verification of the copied volumes
+	MigrationConditionTypeDataVerified corev1.PodConditionType = "DataVerified"
+
+	// MigrationConditionTypeHookPrefix prefixes the name of a hook in the type of the condition holding its result
+	MigrationConditionTypeHookPrefix = "Hook/"
+
+	// MigrationConditionTypeTestBoot represents the condition type for the test boot of the VM before its cutover
+	MigrationConditionTypeTestBoot corev1.PodConditionType = "TestBoot"
+
+	// MigrationConditionTypeRollbackTargetInstanceDeleted represents the rollback step deleting the OpenStack server
+	MigrationConditionTypeRollbackTargetInstanceDeleted corev1.PodConditionType = "RollbackTargetInstanceDeleted"
+	// MigrationConditionTypeRollbackTargetPortsDeleted represents the rollback step deleting the ports of the OpenStack server
+	MigrationConditionTypeRollbackTargetPortsDeleted corev1.PodConditionType = "RollbackTargetPortsDeleted"
+	// MigrationConditionTypeRollbackTargetVolumesDeleted represents the rollback step deleting the copied volumes
+	MigrationConditionTypeRollbackTargetVolumesDeleted corev1.PodConditionType = "RollbackTargetVolumesDeleted"
+	// MigrationConditionTypeRollbackSourceVMRestored represents the rollback step restoring the name and folder of the source VM
+	MigrationConditionTypeRollbackSourceVMRestored corev1.PodConditionType = "RollbackSourceVMRestored"
+	// MigrationConditionTypeRollbackSourceNetworkReconnected represents the rollback step reconnecting the source VM network
+	MigrationConditionTypeRollbackSourceNetworkReconnected corev1.PodConditionType = "RollbackSourceNetworkReconnected"
+	// MigrationConditionTypeRollbackSourceVMPoweredOn represents the rollback step powering on the source VM
+	MigrationConditionTypeRollbackSourceVMPoweredOn corev1.PodConditionType = "RollbackSourceVMPoweredOn"
+
 	// VMMigrationStatesEnum is a map of migration phase to state
 	VMMigrationStatesEnum = map[migratev1alpha1.VMMigrationPhase]int{
 		migratev1alpha1.VMMigrationPhasePending:                  0,
@@ -237,6 +345,8 @@ runcmd:
 		migratev1alpha1.VMMigrationPhaseAwaitingAdminCutOver:     8,
 		migratev1alpha1.VMMigrationPhaseSucceeded:                9,
 		migratev1alpha1.VMMigrationPhaseUnknown:                  10,
+		migratev1alpha1.VMMigrationPhaseRollingBack:              11,
+		migratev1alpha1.VMMigrationPhaseRolledBack:               12,
 	}
 
 	// MigrationJobTTL is the TTL for migration job
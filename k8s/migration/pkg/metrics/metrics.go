@@ -0,0 +1,109 @@
+// Package metrics provides the Prometheus metrics of the migration controllers. They are registered with the
+// controller-runtime registry and served on the metrics endpoint of the controller-manager.
+package metrics
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+)
+
+var (
+	migrationPhase = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "stellaris_migrate_migration_phase",
+		Help: "Current phase of a Migration. The series of the current phase is 1.",
+	}, []string{"namespace", "migration", "migration_plan", "vm", "phase"})
+
+	migrationPhaseTransition = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "stellaris_migrate_migration_phase_transition_timestamp_seconds",
+		Help: "Time the Migration entered its current phase, as observed by the controller.",
+	}, []string{"namespace", "migration"})
+
+	planMigrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "stellaris_migrate_plan_migrations_total",
+		Help: "Migrations of a MigrationPlan that finished, by result.",
+	}, []string{"namespace", "migration_plan", "result"})
+
+	esxiMigrationPhase = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "stellaris_migrate_esxi_migration_phase",
+		Help: "Current phase of an ESXIMigration. The series of the current phase is 1.",
+	}, []string{"namespace", "esxi_migration", "rolling_migration_plan", "phase"})
+
+	clusterMigrationPhase = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "stellaris_migrate_cluster_migration_phase",
+		Help: "Current phase of a ClusterMigration. The series of the current phase is 1.",
+	}, []string{"namespace", "cluster_migration", "rolling_migration_plan", "phase"})
+)
+
+// observedMigrations holds the Migrations whose phase transition time is known
+var observedMigrations sync.Map
+
+func init() {
+	metrics.Registry.MustRegister(migrationPhase, migrationPhaseTransition, planMigrations, esxiMigrationPhase, clusterMigrationPhase)
+}
+
+// ObserveMigration records the phase of a Migration. previousPhase is the phase the Migration had when
+// the reconcile started, a change counts the Migration in the results of its plan once it finishes.
+func ObserveMigration(migration *migratev1alpha1.Migration, previousPhase migratev1alpha1.VMMigrationPhase) {
+	phase := migration.Status.Phase
+	if phase == "" {
+		return
+	}
+	migrationPhase.DeletePartialMatch(prometheus.Labels{"namespace": migration.Namespace, "migration": migration.Name})
+	migrationPhase.WithLabelValues(migration.Namespace, migration.Name, migration.Spec.MigrationPlan, migration.Spec.VMName, string(phase)).Set(1)
+
+	// After a restart of the controller the time of the transition is only known from now on
+	key := migration.Namespace + "/" + migration.Name
+	if _, seen := observedMigrations.LoadOrStore(key, true); !seen || phase != previousPhase {
+		migrationPhaseTransition.WithLabelValues(migration.Namespace, migration.Name).SetToCurrentTime()
+	}
+
+	if phase == previousPhase {
+		return
+	}
+	switch phase {
+	case migratev1alpha1.VMMigrationPhaseSucceeded, migratev1alpha1.VMMigrationPhaseFailed, migratev1alpha1.VMMigrationPhaseRolledBack:
+		planMigrations.WithLabelValues(migration.Namespace, migration.Spec.MigrationPlan, strings.ToLower(string(phase))).Inc()
+	}
+}
+
+// DeleteMigration removes the metrics of a deleted Migration
+func DeleteMigration(namespace, name string) {
+	migrationPhase.DeletePartialMatch(prometheus.Labels{"namespace": namespace, "migration": name})
+	migrationPhaseTransition.DeleteLabelValues(namespace, name)
+	observedMigrations.Delete(namespace + "/" + name)
+}
+
+// ObserveESXIMigration records the phase of an ESXIMigration
+func ObserveESXIMigration(esxiMigration *migratev1alpha1.ESXIMigration) {
+	if esxiMigration.Status.Phase == "" {
+		return
+	}
+	esxiMigrationPhase.DeletePartialMatch(prometheus.Labels{"namespace": esxiMigration.Namespace, "esxi_migration": esxiMigration.Name})
+	esxiMigrationPhase.WithLabelValues(esxiMigration.Namespace, esxiMigration.Name,
+		esxiMigration.Spec.RollingMigrationPlanRef.Name, string(esxiMigration.Status.Phase)).Set(1)
+}
+
+// DeleteESXIMigration removes the metrics of a deleted ESXIMigration
+func DeleteESXIMigration(namespace, name string) {
+	esxiMigrationPhase.DeletePartialMatch(prometheus.Labels{"namespace": namespace, "esxi_migration": name})
+}
+
+// ObserveClusterMigration records the phase of a ClusterMigration
+func ObserveClusterMigration(clusterMigration *migratev1alpha1.ClusterMigration) {
+	if clusterMigration.Status.Phase == "" {
+		return
+	}
+	clusterMigrationPhase.DeletePartialMatch(prometheus.Labels{"namespace": clusterMigration.Namespace, "cluster_migration": clusterMigration.Name})
+	clusterMigrationPhase.WithLabelValues(clusterMigration.Namespace, clusterMigration.Name,
+		clusterMigration.Spec.RollingMigrationPlanRef.Name, string(clusterMigration.Status.Phase)).Set(1)
+}
+
+// DeleteClusterMigration removes the metrics of a deleted ClusterMigration
+func DeleteClusterMigration(namespace, name string) {
+	clusterMigrationPhase.DeletePartialMatch(prometheus.Labels{"namespace": namespace, "cluster_migration": name})
+}
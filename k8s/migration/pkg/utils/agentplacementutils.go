@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+)
+
+// MigrationAgent is a node that can run v2v-helper pods, with the migrations it runs
+type MigrationAgent struct {
+	// NodeName is the name of the Kubernetes node of the agent
+	NodeName string
+	// ActiveMigrations is the number of migrations placed on or running on the agent
+	ActiveMigrations int
+	// CopyBytesPerSecond is the copy throughput of all disks the agent is copying
+	CopyBytesPerSecond int64
+}
+
+// inactiveMigrationPhases are the phases of migrations that no longer use their agent
+var inactiveMigrationPhases = []migratev1alpha1.VMMigrationPhase{
+	migratev1alpha1.VMMigrationPhaseSucceeded,
+	migratev1alpha1.VMMigrationPhaseFailed,
+	migratev1alpha1.VMMigrationPhaseUnknown,
+	migratev1alpha1.VMMigrationPhaseRollingBack,
+	migratev1alpha1.VMMigrationPhaseRolledBack,
+}
+
+// GetK8sNodeNameForStellarisMigrateNode returns the name of the Kubernetes node of a StellarisMigrateNode.
+// Worker entries are named after their node, the master entry has a fixed name.
+func GetK8sNodeNameForStellarisMigrateNode(ctx context.Context, k3sclient client.Client,
+	vjNode *migratev1alpha1.StellarisMigrateNode) (string, error) {
+	if vjNode.Spec.NodeRole != constants.NodeRoleMaster {
+		return vjNode.Name, nil
+	}
+	masterNode, err := GetMasterK8sNode(ctx, k3sclient)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get master node")
+	}
+	return masterNode.Name, nil
+}
+
+// GetMigrationAgents returns the ready agents whose nodes match the node selector, with the migrations placed on them.
+// Migrations that have been placed but whose pod has not started yet count as well, so that a batch of VMs is
+// spread over the agents.
+func GetMigrationAgents(ctx context.Context, k3sclient client.Client, nodeSelector map[string]string) ([]MigrationAgent, error) {
+	vjNodes := &migratev1alpha1.StellarisMigrateNodeList{}
+	if err := k3sclient.List(ctx, vjNodes, client.InNamespace(constants.NamespaceMigrationSystem)); err != nil {
+		return nil, errors.Wrap(err, "failed to list stellaris-migrate nodes")
+	}
+	migrations := &migratev1alpha1.MigrationList{}
+	if err := k3sclient.List(ctx, migrations); err != nil {
+		return nil, errors.Wrap(err, "failed to list migrations")
+	}
+
+	selector := labels.SelectorFromSet(nodeSelector)
+	var agents []MigrationAgent
+	for i := range vjNodes.Items {
+		vjNode := &vjNodes.Items[i]
+		if vjNode.Status.Phase != constants.StellarisMigrateNodePhaseNodeReady || !vjNode.DeletionTimestamp.IsZero() {
+			continue
+		}
+		nodeName, err := GetK8sNodeNameForStellarisMigrateNode(ctx, k3sclient, vjNode)
+		if err != nil {
+			return nil, err
+		}
+		node, err := GetNodeByName(ctx, k3sclient, nodeName)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to get node of stellaris-migrate node %s", vjNode.Name)
+		}
+		if node.Spec.Unschedulable || !isNodeReady(node) || !selector.Matches(labels.Set(node.Labels)) {
+			continue
+		}
+
+		agent := MigrationAgent{NodeName: nodeName}
+		for j := range migrations.Items {
+			migration := &migrations.Items[j]
+			if migration.Status.AgentName != nodeName || !IsMigrationOnAgent(migration) {
+				continue
+			}
+			agent.ActiveMigrations++
+			if migration.Status.Progress != nil {
+				for _, disk := range migration.Status.Progress.Disks {
+					agent.CopyBytesPerSecond += disk.BytesPerSecond
+				}
+			}
+		}
+		agents = append(agents, agent)
+	}
+	return agents, nil
+}
+
+// IsMigrationOnAgent reports whether a migration is placed on an agent and still uses it
+func IsMigrationOnAgent(migration *migratev1alpha1.Migration) bool {
+	return migration.Status.AgentName != "" && !slices.Contains(inactiveMigrationPhases, migration.Status.Phase)
+}
+
+// IsMigrationQueued reports whether a migration waits for a free agent
+func IsMigrationQueued(migration *migratev1alpha1.Migration) bool {
+	if slices.Contains(inactiveMigrationPhases, migration.Status.Phase) {
+		return false
+	}
+	for _, condition := range migration.Status.Conditions {
+		if condition.Type == constants.MigrationConditionTypeAgentPlacement {
+			return condition.Status == corev1.ConditionFalse
+		}
+	}
+	return false
+}
+
+// PickMigrationAgent returns the agent to place the next migration on, or nil if every agent already runs
+// maxMigrations migrations. A maxMigrations of 0 means no limit. Ties are broken by the node name so that the
+// placement is stable.
+func PickMigrationAgent(agents []MigrationAgent, strategy string, maxMigrations int) *MigrationAgent {
+	var picked *MigrationAgent
+	for i := range agents {
+		agent := &agents[i]
+		if maxMigrations > 0 && agent.ActiveMigrations >= maxMigrations {
+			continue
+		}
+		if picked == nil || lessLoaded(agent, picked, strategy) {
+			picked = agent
+		}
+	}
+	return picked
+}
+
+// lessLoaded reports whether agent a should get the next migration before agent b. The free bandwidth of an agent
+// is not known, MostFreeBandwidth takes the agent with the lowest copy throughput, which has the most free bandwidth
+// if all agents have the same. Migrations that are placed but not copying yet add no throughput, agents with the
+// same throughput are compared by their migrations.
+func lessLoaded(a, b *MigrationAgent, strategy string) bool {
+	if strategy == constants.PlacementStrategyMostFreeBandwidth && a.CopyBytesPerSecond != b.CopyBytesPerSecond {
+		return a.CopyBytesPerSecond < b.CopyBytesPerSecond
+	}
+	if a.ActiveMigrations != b.ActiveMigrations {
+		return a.ActiveMigrations < b.ActiveMigrations
+	}
+	return a.NodeName < b.NodeName
+}
+
+// isNodeReady reports whether the Ready condition of a node is true
+func isNodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+)
+
+var _ = ginkgo.Describe("Agent placement", func() {
+	ctx := context.Background()
+
+	ginkgo.Describe("GetMigrationAgents", func() {
+		agentNode := func(name string, ready bool, nodeLabels map[string]string) []client.Object {
+			status := corev1.ConditionTrue
+			if !ready {
+				status = corev1.ConditionFalse
+			}
+			return []client.Object{
+				&migratev1alpha1.StellarisMigrateNode{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: constants.NamespaceMigrationSystem},
+					Spec:       migratev1alpha1.StellarisMigrateNodeSpec{NodeRole: constants.NodeRoleWorker},
+					Status:     migratev1alpha1.StellarisMigrateNodeStatus{Phase: constants.StellarisMigrateNodePhaseNodeReady},
+				},
+				&corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nodeLabels},
+					Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}}},
+				},
+			}
+		}
+		migration := func(name, agent string, phase migratev1alpha1.VMMigrationPhase, bytesPerSecond ...int64) client.Object {
+			migration := &migratev1alpha1.Migration{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: constants.NamespaceMigrationSystem},
+				Status:     migratev1alpha1.MigrationStatus{Phase: phase, AgentName: agent},
+			}
+			if len(bytesPerSecond) > 0 {
+				migration.Status.Progress = &migratev1alpha1.MigrationProgress{}
+				for _, rate := range bytesPerSecond {
+					migration.Status.Progress.Disks = append(migration.Status.Progress.Disks,
+						migratev1alpha1.DiskProgress{BytesPerSecond: rate})
+				}
+			}
+			return migration
+		}
+
+		ginkgo.It("returns the ready agents matching the node selector with their migrations", func() {
+			objects := []client.Object{
+				migration("copying", "agent-1", migratev1alpha1.VMMigrationPhaseCopying, 100, 50),
+				migration("placed", "agent-1", migratev1alpha1.VMMigrationPhasePending),
+				migration("done", "agent-1", migratev1alpha1.VMMigrationPhaseSucceeded, 1000),
+				migration("other", "agent-2", migratev1alpha1.VMMigrationPhaseCopying, 10),
+			}
+			objects = append(objects, agentNode("agent-1", true, map[string]string{"zone": "a"})...)
+			objects = append(objects, agentNode("agent-2", true, map[string]string{"zone": "b"})...)
+			objects = append(objects, agentNode("agent-3", false, map[string]string{"zone": "a"})...)
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+			agents, err := GetMigrationAgents(ctx, k8sClient, map[string]string{"zone": "a"})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(agents).To(gomega.Equal([]MigrationAgent{
+				{NodeName: "agent-1", ActiveMigrations: 2, CopyBytesPerSecond: 150},
+			}))
+		})
+	})
+
+	ginkgo.Describe("PickMigrationAgent", func() {
+		agents := func() []MigrationAgent {
+			return []MigrationAgent{
+				{NodeName: "agent-b", ActiveMigrations: 1, CopyBytesPerSecond: 500},
+				{NodeName: "agent-a", ActiveMigrations: 1, CopyBytesPerSecond: 800},
+				{NodeName: "agent-c", ActiveMigrations: 2, CopyBytesPerSecond: 100},
+			}
+		}
+
+		ginkgo.It("picks the agent running the fewest migrations, then by name", func() {
+			gomega.Expect(PickMigrationAgent(agents(), constants.PlacementStrategyLeastMigrations, 0).NodeName).
+				To(gomega.Equal("agent-a"))
+		})
+
+		ginkgo.It("picks the agent with the lowest copy throughput", func() {
+			gomega.Expect(PickMigrationAgent(agents(), constants.PlacementStrategyMostFreeBandwidth, 0).NodeName).
+				To(gomega.Equal("agent-c"))
+		})
+
+		ginkgo.It("compares agents with the same throughput by their migrations", func() {
+			idle := []MigrationAgent{{NodeName: "agent-a", ActiveMigrations: 3}, {NodeName: "agent-b", ActiveMigrations: 1}}
+			gomega.Expect(PickMigrationAgent(idle, constants.PlacementStrategyMostFreeBandwidth, 0).NodeName).
+				To(gomega.Equal("agent-b"))
+		})
+
+		ginkgo.It("skips the agents running the maximum number of migrations", func() {
+			gomega.Expect(PickMigrationAgent(agents(), constants.PlacementStrategyMostFreeBandwidth, 2).NodeName).
+				To(gomega.Equal("agent-b"))
+			gomega.Expect(PickMigrationAgent(agents(), constants.PlacementStrategyLeastMigrations, 1)).To(gomega.BeNil())
+		})
+	})
+})
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+	openstackconst "github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	migrationutils "github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
+)
+
+// GetCopyCheckpointVolumes returns the volumes recorded in the copy checkpoint of the migration of a VMwareMachine.
+// The v2v-helper keeps these volumes when an attempt fails, to resume the disk copy when the migration is retried.
+func GetCopyCheckpointVolumes(ctx context.Context, k3sclient client.Client, vmK8sName string) ([]string, error) {
+	configMap := &corev1.ConfigMap{}
+	err := k3sclient.Get(ctx, types.NamespacedName{
+		Name:      migrationutils.CopyCheckpointConfigMapName(vmK8sName),
+		Namespace: constants.NamespaceMigrationSystem,
+	}, configMap)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to get checkpoint configmap")
+	}
+	data := configMap.Data[openstackconst.CopyCheckpointKey]
+	if data == "" {
+		return nil, nil
+	}
+	checkpoint := &migrationutils.CopyCheckpoint{}
+	if err := json.Unmarshal([]byte(data), checkpoint); err != nil {
+		return nil, errors.Wrap(err, "failed to parse copy checkpoint")
+	}
+	var volumeIDs []string
+	for _, disk := range checkpoint.Disks {
+		if disk.VolumeID != "" {
+			volumeIDs = append(volumeIDs, disk.VolumeID)
+		}
+	}
+	return volumeIDs, nil
+}
+
+// DeleteCopyCheckpoint deletes the copy checkpoint of the migration of a VMwareMachine
+func DeleteCopyCheckpoint(ctx context.Context, k3sclient client.Client, vmK8sName string) error {
+	configMap := &corev1.ConfigMap{}
+	configMap.Name = migrationutils.CopyCheckpointConfigMapName(vmK8sName)
+	configMap.Namespace = constants.NamespaceMigrationSystem
+	if err := k3sclient.Delete(ctx, configMap); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete checkpoint configmap")
+	}
+	return nil
+}
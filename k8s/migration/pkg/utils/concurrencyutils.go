@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+)
+
+// CountRunningMigrationJobs returns the number of unfinished v2v-helper jobs of all migration plans and of the
+// given migration plan
+func CountRunningMigrationJobs(ctx context.Context, k3sclient client.Client,
+	migrationplan *migratev1alpha1.MigrationPlan) (total, plan int, err error) {
+	jobs := &batchv1.JobList{}
+	if err := k3sclient.List(ctx, jobs, client.HasLabels{constants.MigrationPlanLabel}); err != nil {
+		return 0, 0, errors.Wrap(err, "failed to list v2v-helper jobs")
+	}
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if isJobFinished(job) {
+			continue
+		}
+		total++
+		if job.Namespace == migrationplan.Namespace && job.Labels[constants.MigrationPlanLabel] == migrationplan.Name {
+			plan++
+		}
+	}
+	return total, plan, nil
+}
+
+// GetMigrationPlanQueuePosition returns the position of a migration plan among the plans waiting for a free
+// migration slot. Plans with a higher priority come first, plans with the same priority in the order they were
+// created.
+func GetMigrationPlanQueuePosition(ctx context.Context, k3sclient client.Client, migrationplan *migratev1alpha1.MigrationPlan) (int, error) {
+	migrationplans := &migratev1alpha1.MigrationPlanList{}
+	if err := k3sclient.List(ctx, migrationplans); err != nil {
+		return 0, errors.Wrap(err, "failed to list migration plans")
+	}
+	waiting := []*migratev1alpha1.MigrationPlan{migrationplan}
+	for i := range migrationplans.Items {
+		plan := &migrationplans.Items[i]
+		if plan.UID == migrationplan.UID || plan.Status.QueuePosition == 0 || !plan.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if plan.Status.MigrationStatus == corev1.PodSucceeded || plan.Status.MigrationStatus == corev1.PodFailed ||
+			plan.Status.MigrationStatus == "Paused" {
+			continue
+		}
+		waiting = append(waiting, plan)
+	}
+	slices.SortFunc(waiting, func(a, b *migratev1alpha1.MigrationPlan) int {
+		return cmp.Or(
+			cmp.Compare(b.Spec.Priority, a.Spec.Priority),
+			a.CreationTimestamp.Compare(b.CreationTimestamp.Time),
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+	return slices.Index(waiting, migrationplan) + 1, nil
+}
+
+// CutoverSlots are the cutover slots taken by migrations that have not finished yet. The slots are recorded in a
+// ConfigMap keyed by migration UID, so that grants are serialized: a slot is only granted by an update of the
+// ConfigMap, which conflicts if another grant changed it since it was read.
+type CutoverSlots struct {
+	configMap *corev1.ConfigMap
+	// Total is the number of slots taken by the other migrations of all migration plans
+	Total int
+	// Plan is the number of slots taken by the other migrations of the migration plan of the migration
+	Plan int
+	// Held is set if the migration already took a slot
+	Held bool
+}
+
+// GetCutoverSlots returns the cutover slots taken by the migrations that have not finished yet, seen from the given
+// migration. Migrations that were given a slot before the slots were recorded count as well.
+func GetCutoverSlots(ctx context.Context, k3sclient client.Client, migration *migratev1alpha1.Migration) (*CutoverSlots, error) {
+	configMap := &corev1.ConfigMap{}
+	err := k3sclient.Get(ctx, k8stypes.NamespacedName{Name: constants.CutoverSlotsConfigMapName,
+		Namespace: constants.NamespaceMigrationSystem}, configMap)
+	if apierrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:      constants.CutoverSlotsConfigMapName,
+			Namespace: constants.NamespaceMigrationSystem,
+		}}
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to get configmap '%s'", constants.CutoverSlotsConfigMapName)
+	}
+	migrations := &migratev1alpha1.MigrationList{}
+	if err := k3sclient.List(ctx, migrations); err != nil {
+		return nil, errors.Wrap(err, "failed to list migrations")
+	}
+
+	slots := &CutoverSlots{configMap: configMap}
+	active := map[string]bool{}
+	for i := range migrations.Items {
+		other := &migrations.Items[i]
+		if slices.Contains(inactiveMigrationPhases, other.Status.Phase) {
+			continue
+		}
+		active[string(other.UID)] = true
+		if _, recorded := configMap.Data[string(other.UID)]; !recorded && !HasCutoverSlot(other) {
+			continue
+		}
+		if other.UID == migration.UID {
+			slots.Held = true
+			continue
+		}
+		slots.Total++
+		if other.Namespace == migration.Namespace && other.Spec.MigrationPlan == migration.Spec.MigrationPlan {
+			slots.Plan++
+		}
+	}
+	// The slots of finished and deleted migrations are free again
+	for uid := range configMap.Data {
+		if !active[uid] {
+			delete(configMap.Data, uid)
+		}
+	}
+	return slots, nil
+}
+
+// Take records the cutover slot of a migration. It fails with a conflict if a slot was granted to another
+// migration since the slots were read, in which case the slots must be read again.
+func (s *CutoverSlots) Take(ctx context.Context, k3sclient client.Client, migration *migratev1alpha1.Migration) error {
+	if s.configMap.Data == nil {
+		s.configMap.Data = map[string]string{}
+	}
+	s.configMap.Data[string(migration.UID)] = migration.Namespace + "/" + migration.Name
+	if s.configMap.ResourceVersion == "" {
+		if err := k3sclient.Create(ctx, s.configMap); err != nil {
+			return errors.Wrapf(err, "failed to create configmap '%s'", constants.CutoverSlotsConfigMapName)
+		}
+		return nil
+	}
+	if err := k3sclient.Update(ctx, s.configMap); err != nil {
+		return errors.Wrapf(err, "failed to update configmap '%s'", constants.CutoverSlotsConfigMapName)
+	}
+	return nil
+}
+
+// HasCutoverSlot reports whether a migration was given a cutover slot
+func HasCutoverSlot(migration *migratev1alpha1.Migration) bool {
+	for _, condition := range migration.Status.Conditions {
+		if condition.Type == constants.MigrationConditionTypeCutoverSlot {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// IsCutoverLimited reports whether the cutovers of a migration plan are limited, either by the settings or by the plan
+func IsCutoverLimited(settings *VjailbreakSettings, migrationplan *migratev1alpha1.MigrationPlan) bool {
+	return settings.MaxConcurrentCutovers > 0 || migrationplan.Spec.MaxConcurrentCutovers > 0
+}
+
+// IsCutoverGated reports whether the v2v-helper pod of a migration waits for a free cutover slot before cutting over
+func IsCutoverGated(pod *corev1.Pod) bool {
+	for _, container := range pod.Spec.Containers {
+		for _, env := range container.Env {
+			if env.Name == constants.CutoverGatedEnvVar {
+				return env.Value == trueString
+			}
+		}
+	}
+	return false
+}
+
+// isJobFinished reports whether a job has completed or failed
+func isJobFinished(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+)
+
+var _ = ginkgo.Describe("Concurrency", func() {
+	ctx := context.Background()
+	namespace := constants.NamespaceMigrationSystem
+
+	ginkgo.Describe("CountRunningMigrationJobs", func() {
+		job := func(name, plan string, finished bool) client.Object {
+			job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{constants.MigrationPlanLabel: plan},
+			}}
+			if finished {
+				job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+			}
+			return job
+		}
+
+		ginkgo.It("counts the unfinished jobs of all plans and of the plan", func() {
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				job("a-1", "a", false), job("a-2", "a", true), job("b-1", "b", false), job("b-2", "b", false),
+				&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: namespace}},
+			).Build()
+			plan := &migratev1alpha1.MigrationPlan{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: namespace}}
+
+			total, planJobs, err := CountRunningMigrationJobs(ctx, k8sClient, plan)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(total).To(gomega.Equal(3))
+			gomega.Expect(planJobs).To(gomega.Equal(1))
+		})
+	})
+
+	ginkgo.Describe("GetMigrationPlanQueuePosition", func() {
+		now := time.Now()
+		plan := func(name string, priority int32, created time.Duration, position int) *migratev1alpha1.MigrationPlan {
+			migrationplan := &migratev1alpha1.MigrationPlan{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              name,
+					Namespace:         namespace,
+					UID:               k8stypes.UID("uid-" + name),
+					CreationTimestamp: metav1.NewTime(now.Add(created)),
+				},
+				Status: migratev1alpha1.MigrationPlanStatus{QueuePosition: position},
+			}
+			migrationplan.Spec.Priority = priority
+			return migrationplan
+		}
+
+		ginkgo.It("queues plans by priority, then by age", func() {
+			succeeded := plan("succeeded", 10, -time.Hour, 1)
+			succeeded.Status.MigrationStatus = corev1.PodSucceeded
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				plan("old", 0, -time.Hour, 1), plan("urgent", 5, time.Hour, 2), plan("running", 10, 0, 0), succeeded,
+			).Build()
+
+			position, err := GetMigrationPlanQueuePosition(ctx, k8sClient, plan("new", 0, 0, 0))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(position).To(gomega.Equal(3))
+			position, err = GetMigrationPlanQueuePosition(ctx, k8sClient, plan("new", 5, 0, 0))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(position).To(gomega.Equal(1))
+		})
+	})
+
+	ginkgo.Describe("CutoverSlots", func() {
+		var k8sClient client.Client
+		migration := func(name, plan string, phase migratev1alpha1.VMMigrationPhase) *migratev1alpha1.Migration {
+			return &migratev1alpha1.Migration{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, UID: k8stypes.UID("uid-" + name)},
+				Spec:       migratev1alpha1.MigrationSpec{MigrationPlan: plan},
+				Status:     migratev1alpha1.MigrationStatus{Phase: phase},
+			}
+		}
+		awaiting := migratev1alpha1.VMMigrationPhaseAwaitingAdminCutOver
+
+		ginkgo.BeforeEach(func() {
+			slotted := migration("slotted", "b", awaiting)
+			slotted.Status.Conditions = []corev1.PodCondition{
+				{Type: constants.MigrationConditionTypeCutoverSlot, Status: corev1.ConditionTrue},
+			}
+			k8sClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				migration("a-1", "a", awaiting), migration("a-2", "a", awaiting), migration("a-3", "a", awaiting),
+				migration("done", "a", migratev1alpha1.VMMigrationPhaseSucceeded), slotted,
+			).Build()
+		})
+
+		ginkgo.It("counts the recorded slots and the slots of the conditions", func() {
+			slots, err := GetCutoverSlots(ctx, k8sClient, migration("a-1", "a", awaiting))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(slots.Total).To(gomega.Equal(1))
+			gomega.Expect(slots.Plan).To(gomega.BeZero())
+			gomega.Expect(slots.Take(ctx, k8sClient, migration("a-1", "a", awaiting))).To(gomega.Succeed())
+
+			slots, err = GetCutoverSlots(ctx, k8sClient, migration("a-2", "a", awaiting))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(slots.Total).To(gomega.Equal(2))
+			gomega.Expect(slots.Plan).To(gomega.Equal(1))
+			gomega.Expect(slots.Held).To(gomega.BeFalse())
+
+			slots, err = GetCutoverSlots(ctx, k8sClient, migration("a-1", "a", awaiting))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(slots.Held).To(gomega.BeTrue())
+		})
+
+		ginkgo.It("lets only one of two concurrent grants take a slot", func() {
+			first, err := GetCutoverSlots(ctx, k8sClient, migration("a-1", "a", awaiting))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(first.Take(ctx, k8sClient, migration("a-1", "a", awaiting))).To(gomega.Succeed())
+
+			second, err := GetCutoverSlots(ctx, k8sClient, migration("a-2", "a", awaiting))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			third, err := GetCutoverSlots(ctx, k8sClient, migration("a-3", "a", awaiting))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(second.Take(ctx, k8sClient, migration("a-2", "a", awaiting))).To(gomega.Succeed())
+			gomega.Expect(apierrors.IsConflict(third.Take(ctx, k8sClient, migration("a-3", "a", awaiting)))).To(gomega.BeTrue())
+		})
+
+		ginkgo.It("frees the slots of finished migrations", func() {
+			slots, err := GetCutoverSlots(ctx, k8sClient, migration("done", "a", awaiting))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(slots.Take(ctx, k8sClient, migration("done", "a", awaiting))).To(gomega.Succeed())
+
+			slots, err = GetCutoverSlots(ctx, k8sClient, migration("a-1", "a", awaiting))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(slots.Plan).To(gomega.BeZero())
+			gomega.Expect(slots.Take(ctx, k8sClient, migration("a-1", "a", awaiting))).To(gomega.Succeed())
+			configMap := &corev1.ConfigMap{}
+			gomega.Expect(k8sClient.Get(ctx, k8stypes.NamespacedName{Name: constants.CutoverSlotsConfigMapName,
+				Namespace: namespace}, configMap)).To(gomega.Succeed())
+			gomega.Expect(configMap.Data).To(gomega.HaveKey("uid-a-1"))
+			gomega.Expect(configMap.Data).NotTo(gomega.HaveKey("uid-done"))
+		})
+	})
+})
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+)
+
+// VCenterConnectionUsage is the number of vCenter sessions and ESXi NFC connections held by running migrations
+type VCenterConnectionUsage struct {
+	// Sessions is the number of sessions per vCenter
+	Sessions map[string]int
+	// NFCConnections is the number of NFC connections per ESXi host, keyed by vCenter and host
+	NFCConnections map[string]int
+}
+
+// GetVCenterConnectionUsage returns the connections held by the migrations that have not finished. A migration
+// holds its session until it finishes, and its NFC connections until its disks are copied.
+func GetVCenterConnectionUsage(ctx context.Context, k3sclient client.Client) (*VCenterConnectionUsage, error) {
+	migrations := &migratev1alpha1.MigrationList{}
+	if err := k3sclient.List(ctx, migrations); err != nil {
+		return nil, errors.Wrap(err, "failed to list migrations")
+	}
+	usage := &VCenterConnectionUsage{Sessions: map[string]int{}, NFCConnections: map[string]int{}}
+	for i := range migrations.Items {
+		migration := &migrations.Items[i]
+		if migration.Status.Connections == nil || slices.Contains(inactiveMigrationPhases, migration.Status.Phase) {
+			continue
+		}
+		connections := *migration.Status.Connections
+		if constants.VMMigrationStatesEnum[migration.Status.Phase] >= constants.VMMigrationStatesEnum[migratev1alpha1.VMMigrationPhaseConvertingDisk] {
+			connections.NFCConnections = 0
+		}
+		usage.Add(&connections)
+	}
+	return usage, nil
+}
+
+// VMNFCConnections returns the number of NFC connections the v2v-helper pod of a VM opens, one per disk it copies.
+// Disks excluded by the disk override of the VM are not copied, nor are shared disks another owner VM copies.
+func VMNFCConnections(vminfo *migratev1alpha1.VMInfo, override *migratev1alpha1.VMDiskOverride,
+	sharedDisks []migratev1alpha1.SharedDiskAssignment) int {
+	count := 0
+	for _, disk := range vminfo.Disks {
+		if override != nil && slices.ContainsFunc(override.Disks, func(o migratev1alpha1.DiskOverride) bool {
+			return o.Name == disk && o.Exclude
+		}) {
+			continue
+		}
+		if slices.ContainsFunc(sharedDisks, func(s migratev1alpha1.SharedDiskAssignment) bool {
+			return s.DiskName == disk && !s.Copy
+		}) {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// Add counts the connections of a migration
+func (u *VCenterConnectionUsage) Add(connections *migratev1alpha1.MigrationConnections) {
+	u.Sessions[connections.VCenter]++
+	if connections.ESXiHost != "" {
+		u.NFCConnections[nfcHostKey(connections)] += connections.NFCConnections
+	}
+}
+
+// Remove stops counting the connections of a migration
+func (u *VCenterConnectionUsage) Remove(connections *migratev1alpha1.MigrationConnections) {
+	u.Sessions[connections.VCenter]--
+	if connections.ESXiHost != "" {
+		u.NFCConnections[nfcHostKey(connections)] -= connections.NFCConnections
+	}
+}
+
+// Exceeds returns the reason and message of the budget the connections of a migration would exceed, or empty
+// strings if they fit. A migration always fits on an ESXi host no other migration reads from, so that VMs with
+// more disks than the budget still migrate.
+func (u *VCenterConnectionUsage) Exceeds(connections *migratev1alpha1.MigrationConnections,
+	settings *VjailbreakSettings) (reason, message string) {
+	if settings.MaxVCenterSessions > 0 && u.Sessions[connections.VCenter] >= settings.MaxVCenterSessions {
+		return "VCenterSessionsExhausted", fmt.Sprintf("Queued until fewer than %d v2v-helper sessions are open on vCenter %s",
+			settings.MaxVCenterSessions, connections.VCenter)
+	}
+	if settings.MaxNFCConnectionsPerHost > 0 && connections.ESXiHost != "" {
+		used := u.NFCConnections[nfcHostKey(connections)]
+		if used > 0 && used+connections.NFCConnections > settings.MaxNFCConnectionsPerHost {
+			return "NFCConnectionsExhausted", fmt.Sprintf("Queued until %d of the %d NFC connections of ESXi host %s are free, %d are open",
+				connections.NFCConnections, settings.MaxNFCConnectionsPerHost, connections.ESXiHost, used)
+		}
+	}
+	return "", ""
+}
+
+// nfcHostKey returns the key of the ESXi host of a migration, ESXi hosts of different vCenters may share a name
+func nfcHostKey(connections *migratev1alpha1.MigrationConnections) string {
+	return connections.VCenter + "/" + connections.ESXiHost
+}
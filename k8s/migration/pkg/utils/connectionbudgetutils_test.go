@@ -0,0 +1,125 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+)
+
+var _ = ginkgo.Describe("Connection budget", func() {
+	ctx := context.Background()
+	connections := func(vcenter, host string, nfc int) *migratev1alpha1.MigrationConnections {
+		return &migratev1alpha1.MigrationConnections{VCenter: vcenter, ESXiHost: host, NFCConnections: nfc}
+	}
+	migration := func(name string, phase migratev1alpha1.VMMigrationPhase,
+		conns *migratev1alpha1.MigrationConnections) client.Object {
+		return &migratev1alpha1.Migration{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: constants.NamespaceMigrationSystem},
+			Status:     migratev1alpha1.MigrationStatus{Phase: phase, Connections: conns},
+		}
+	}
+
+	ginkgo.Describe("GetVCenterConnectionUsage", func() {
+		ginkgo.It("counts the connections of unfinished migrations", func() {
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				migration("copying", migratev1alpha1.VMMigrationPhaseCopying, connections("vc1", "esx1", 2)),
+				migration("pending", migratev1alpha1.VMMigrationPhasePending, connections("vc1", "esx1", 3)),
+				migration("converting", migratev1alpha1.VMMigrationPhaseConvertingDisk, connections("vc1", "esx1", 4)),
+				migration("other-vcenter", migratev1alpha1.VMMigrationPhaseCopying, connections("vc2", "esx1", 1)),
+				migration("succeeded", migratev1alpha1.VMMigrationPhaseSucceeded, connections("vc1", "esx1", 5)),
+				migration("failed", migratev1alpha1.VMMigrationPhaseFailed, connections("vc1", "esx2", 5)),
+				migration("unrecorded", migratev1alpha1.VMMigrationPhaseCopying, nil),
+			).Build()
+
+			usage, err := GetVCenterConnectionUsage(ctx, k8sClient)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(usage.Sessions).To(gomega.Equal(map[string]int{"vc1": 3, "vc2": 1}))
+			// The disks of converting migrations are copied, their NFC connections are closed
+			gomega.Expect(usage.NFCConnections).To(gomega.Equal(map[string]int{"vc1/esx1": 5, "vc2/esx1": 1}))
+		})
+	})
+
+	ginkgo.Describe("Exceeds", func() {
+		settings := &VjailbreakSettings{MaxVCenterSessions: 2, MaxNFCConnectionsPerHost: 4}
+		var usage *VCenterConnectionUsage
+
+		ginkgo.BeforeEach(func() {
+			usage = &VCenterConnectionUsage{Sessions: map[string]int{}, NFCConnections: map[string]int{}}
+			usage.Add(connections("vc1", "esx1", 3))
+		})
+
+		ginkgo.It("fits within the budgets", func() {
+			reason, _ := usage.Exceeds(connections("vc1", "esx1", 1), settings)
+			gomega.Expect(reason).To(gomega.BeEmpty())
+		})
+
+		ginkgo.It("queues once the vCenter sessions are exhausted", func() {
+			usage.Add(connections("vc1", "esx2", 1))
+			reason, _ := usage.Exceeds(connections("vc1", "esx3", 1), settings)
+			gomega.Expect(reason).To(gomega.Equal("VCenterSessionsExhausted"))
+			reason, _ = usage.Exceeds(connections("vc2", "esx3", 1), settings)
+			gomega.Expect(reason).To(gomega.BeEmpty())
+		})
+
+		ginkgo.It("queues once the NFC connections of the host are exhausted", func() {
+			reason, _ := usage.Exceeds(connections("vc1", "esx1", 2), settings)
+			gomega.Expect(reason).To(gomega.Equal("NFCConnectionsExhausted"))
+			// Hosts of different vCenters may share a name
+			reason, _ = usage.Exceeds(connections("vc2", "esx1", 2), settings)
+			gomega.Expect(reason).To(gomega.BeEmpty())
+		})
+
+		ginkgo.It("fits VMs with more disks than the budget on a free host", func() {
+			reason, _ := usage.Exceeds(connections("vc1", "esx2", 10), settings)
+			gomega.Expect(reason).To(gomega.BeEmpty())
+		})
+
+		ginkgo.It("frees the connections that are removed", func() {
+			usage.Remove(connections("vc1", "esx1", 3))
+			reason, _ := usage.Exceeds(connections("vc1", "esx1", 2), settings)
+			gomega.Expect(reason).To(gomega.BeEmpty())
+		})
+	})
+
+	ginkgo.Describe("VMNFCConnections", func() {
+		vminfo := &migratev1alpha1.VMInfo{Disks: []string{"Hard disk 1", "Hard disk 2", "Hard disk 3"}}
+
+		ginkgo.It("counts one connection per disk", func() {
+			gomega.Expect(VMNFCConnections(vminfo, nil, nil)).To(gomega.Equal(3))
+		})
+
+		ginkgo.It("leaves out the excluded disks and the shared disks other VMs copy", func() {
+			override := &migratev1alpha1.VMDiskOverride{Disks: []migratev1alpha1.DiskOverride{
+				{Name: "Hard disk 1", Exclude: true},
+			}}
+			sharedDisks := []migratev1alpha1.SharedDiskAssignment{
+				{DiskName: "Hard disk 2", Copy: false},
+				{DiskName: "Hard disk 3", Copy: true},
+			}
+			gomega.Expect(VMNFCConnections(vminfo, override, sharedDisks)).To(gomega.Equal(1))
+		})
+	})
+})
@@ -34,6 +34,7 @@ import (
 	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
 	scope "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/scope"
 	migrationutils "github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
+	vmutils "github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/property"
@@ -305,31 +306,11 @@ func GetOpenstackInfo(ctx context.Context, k3sclient client.Client, openstackcre
 		openstacknetworks = append(openstacknetworks, allNetworks[i].Name)
 	}
 
-	credsInfo, err := GetOpenstackCredentialsFromSecret(ctx, k3sclient, openstackcreds.Spec.SecretRef.Name)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get openstack credentials for project lookup")
-	}
-
-	identityClient, err := openstack.NewIdentityV3(openstackClients.BlockStorageClient.ProviderClient, gophercloud.EndpointOpts{})
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create identity client")
-	}
-
-	listOpts := projects.ListOpts{Name: credsInfo.TenantName}
-	allPages, err := projects.List(identityClient, listOpts).AllPages()
+	projectID, err := GetOpenstackProjectID(ctx, k3sclient, openstackcreds, openstackClients)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to list projects with name %s", credsInfo.TenantName)
+		return nil, err
 	}
 
-	allProjects, err := projects.ExtractProjects(allPages)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to extract projects")
-	}
-	if len(allProjects) == 0 {
-		return nil, fmt.Errorf("no project found with name %s", credsInfo.TenantName)
-	}
-	projectID := allProjects[0].ID
-
 	allSecGroupPages, err := groups.List(openstackClients.NetworkingClient, groups.ListOpts{
 		TenantID: projectID,
 	}).AllPages()
@@ -365,6 +346,35 @@ func GetOpenstackInfo(ctx context.Context, k3sclient client.Client, openstackcre
 	}, nil
 }
 
+// GetOpenstackProjectID returns the ID of the project of the openstack credentials
+func GetOpenstackProjectID(ctx context.Context, k3sclient client.Client, openstackcreds *migratev1alpha1.OpenstackCreds,
+	openstackClients *OpenStackClients) (string, error) {
+	credsInfo, err := GetOpenstackCredentialsFromSecret(ctx, k3sclient, openstackcreds.Spec.SecretRef.Name)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get openstack credentials for project lookup")
+	}
+
+	identityClient, err := openstack.NewIdentityV3(openstackClients.BlockStorageClient.ProviderClient, gophercloud.EndpointOpts{})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create identity client")
+	}
+
+	listOpts := projects.ListOpts{Name: credsInfo.TenantName}
+	allPages, err := projects.List(identityClient, listOpts).AllPages()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list projects with name %s", credsInfo.TenantName)
+	}
+
+	allProjects, err := projects.ExtractProjects(allPages)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to extract projects")
+	}
+	if len(allProjects) == 0 {
+		return "", fmt.Errorf("no project found with name %s", credsInfo.TenantName)
+	}
+	return allProjects[0].ID, nil
+}
+
 // GetOpenStackClients is a function to create openstack clients
 func GetOpenStackClients(ctx context.Context, k3sclient client.Client, openstackcreds *migratev1alpha1.OpenstackCreds) (*OpenStackClients, error) {
 	if openstackcreds == nil {
@@ -612,6 +622,40 @@ func GetVMwDatastore(ctx context.Context, k3sclient client.Client, vmwcreds *mig
 	return datastores, nil
 }
 
+// GetVMwDiskInfo returns the sizes in bytes of the disks of the vm that are copied to volumes, which excludes
+// RDM disks, and whether changed block tracking is enabled on the vm
+func GetVMwDiskInfo(ctx context.Context, k3sclient client.Client, vmwcreds *migratev1alpha1.VMwareCreds,
+	datacenter, vmname string) (diskSizes []int64, cbtEnabled bool, err error) {
+	_, finder, err := getFinderForVMwareCreds(ctx, k3sclient, vmwcreds, datacenter)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get finder: %w", err)
+	}
+
+	vm, err := finder.VirtualMachine(ctx, vmname)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to find vm: %w", err)
+	}
+
+	var vmProps mo.VirtualMachine
+	err = vm.Properties(ctx, vm.Reference(), []string{"config"}, &vmProps)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get VM properties: %w", err)
+	}
+
+	for _, device := range vmProps.Config.Hardware.Device {
+		disk, ok := device.(*types.VirtualDisk)
+		if !ok {
+			continue
+		}
+		if _, ok := disk.Backing.(*types.VirtualDiskRawDiskMappingVer1BackingInfo); ok {
+			continue
+		}
+		diskSizes = append(diskSizes, disk.CapacityInBytes)
+	}
+	cbtEnabled = vmProps.Config.ChangeTrackingEnabled != nil && *vmProps.Config.ChangeTrackingEnabled
+	return diskSizes, cbtEnabled, nil
+}
+
 // GetAllVMs gets all the VMs in a datacenter.
 //
 //nolint:gocyclo // GetAllVMs is complex but intentional due to VM discovery logic
@@ -671,6 +715,10 @@ func GetAllVMs(ctx context.Context, scope *scope.VMwareCredsScope, datacenter st
 	// Close the semaphore channel after all goroutines have completed
 	close(semaphore)
 
+	if err := SyncSharedDisks(ctx, scope, vminfo); err != nil {
+		return nil, fmt.Errorf("failed to sync shared disks: %w", err)
+	}
+
 	if len(vmErrors) > 0 {
 		log.Error(fmt.Errorf("failed to get (%d) VMs", len(vmErrors)), "failed to get VMs")
 		// Print individual VM errors for better debugging
@@ -731,6 +779,7 @@ func ExtractVirtualNICs(vmProps *mo.VirtualMachine) ([]migratev1alpha1.NIC, erro
 // reported by VMware Tools. Returns MAC, IP, DNS, and origin for each NIC in the guest.
 func ExtractGuestNetworkInfo(vmProps *mo.VirtualMachine) ([]migratev1alpha1.GuestNetwork, error) {
 	guestNetworks := []migratev1alpha1.GuestNetwork{}
+	gateways := extractDefaultGateways(vmProps)
 
 	for i, guestNet := range vmProps.Guest.Net {
 		if guestNet.IpConfig == nil {
@@ -750,6 +799,7 @@ func ExtractGuestNetworkInfo(vmProps *mo.VirtualMachine) ([]migratev1alpha1.Gues
 				PrefixLength: ip.PrefixLength,
 				DNS:          dnsConfigList,
 				Device:       fmt.Sprintf("%d", i),
+				Gateway:      gateways[fmt.Sprintf("%d", i)],
 			})
 		}
 	}
@@ -757,6 +807,26 @@ func ExtractGuestNetworkInfo(vmProps *mo.VirtualMachine) ([]migratev1alpha1.Gues
 	return guestNetworks, nil
 }
 
+// extractDefaultGateways returns the IPv4 default gateways reported by VMware Tools,
+// keyed by the index of the NIC in guest.net
+func extractDefaultGateways(vmProps *mo.VirtualMachine) map[string]string {
+	gateways := map[string]string{}
+	for _, ipStack := range vmProps.Guest.IpStack {
+		if ipStack.IpRouteConfig == nil {
+			continue
+		}
+		for _, route := range ipStack.IpRouteConfig.IpRoute {
+			if route.Network != "0.0.0.0" || route.PrefixLength != 0 || route.Gateway.IpAddress == "" {
+				continue
+			}
+			if _, found := gateways[route.Gateway.Device]; !found {
+				gateways[route.Gateway.Device] = route.Gateway.IpAddress
+			}
+		}
+	}
+	return gateways
+}
+
 // processVMDisk processes a single virtual disk device and updates the disk information
 // it returns the datastore reference, RDM disk info, a skip flag, and any error encountered
 // It checks if the disk is backed by a shared SCSI controller and skips the VM.
@@ -1093,6 +1163,9 @@ func DeleteDependantObjectsForVMwareCreds(ctx context.Context, scope *scope.VMwa
 	if err := DeleteVMwareClustersForVMwareCreds(ctx, scope); err != nil {
 		return errors.Wrap(err, "Error deleting clusters")
 	}
+	if err := DeleteSharedDisksForVMwareCreds(ctx, scope); err != nil {
+		return errors.Wrap(err, "Error deleting shared disks")
+	}
 
 	if err := DeleteVMwarecredsSecret(ctx, scope); err != nil {
 		return errors.Wrap(err, "Error deleting secret")
@@ -1383,6 +1456,8 @@ func processSingleVM(ctx context.Context, scope *scope.VMwareCredsScope, vm *obj
 	var datastores []string
 	networks := make([]string, 0, 4) // Pre-allocate with estimated capacity
 	disks := make([]string, 0, 8)    // Pre-allocate with estimated capacity
+	diskSizes := make([]int64, 0, 8) // Pre-allocate with estimated capacity
+	var sharedDisks []migratev1alpha1.SharedDiskInfo
 	var clusterName string
 	log := scope.Logger
 	err := vm.Properties(ctx, vm.Reference(), []string{
@@ -1450,6 +1525,19 @@ func processSingleVM(ctx context.Context, scope *scope.VMwareCredsScope, vm *obj
 
 		datastores = AppendUnique(datastores, ds.Name)
 		disks = append(disks, disk.DeviceInfo.GetDescription().Label)
+		diskSizes = append(diskSizes, disk.CapacityInBytes)
+		if fileName, ok := multiWriterFileName(disk); ok {
+			name, err := SharedDiskName(fileName, scope.Name())
+			if err != nil {
+				appendToVMErrorsThreadSafe(errMu, vmErrors, vm.Name(), fmt.Errorf("failed to get shared disk name: %w", err))
+				return
+			}
+			sharedDisks = append(sharedDisks, migratev1alpha1.SharedDiskInfo{
+				DiskName:   disk.DeviceInfo.GetDescription().Label,
+				FileName:   fileName,
+				SharedDisk: name,
+			})
+		}
 	}
 
 	// Get the host name and parent (cluster) information
@@ -1574,6 +1662,10 @@ func processSingleVM(ctx context.Context, scope *scope.VMwareCredsScope, vm *obj
 		Name:              vmProps.Config.Name,
 		Datastores:        datastores,
 		Disks:             disks,
+		BootDisk:          vmutils.BootDisk(vmProps.Config),
+		DiskSizes:         diskSizes,
+		VTPM:              vmutils.HasVTPM(vmProps.Config),
+		Encrypted:         vmutils.IsEncrypted(vmProps.Config),
 		Networks:          networks,
 		IPAddress:         vmProps.Guest.IpAddress,
 		VMState:           vmProps.Guest.GuestState,
@@ -1583,6 +1675,7 @@ func processSingleVM(ctx context.Context, scope *scope.VMwareCredsScope, vm *obj
 		ESXiName:          host.Name,
 		ClusterName:       clusterName,
 		RDMDisks:          rdmDiskInfos,
+		SharedDisks:       sharedDisks,
 		NetworkInterfaces: nicList,
 		GuestNetworks:     guestNetworks,
 	}
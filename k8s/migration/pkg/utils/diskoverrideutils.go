@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+)
+
+// GetVMDiskOverride returns the disk override of a VM in the migration plan
+func GetVMDiskOverride(migrationplan *migratev1alpha1.MigrationPlan, vm string) *migratev1alpha1.VMDiskOverride {
+	for i := range migrationplan.Spec.DiskOverrides {
+		if migrationplan.Spec.DiskOverrides[i].VMName == vm {
+			return &migrationplan.Spec.DiskOverrides[i]
+		}
+	}
+	return nil
+}
+
+// ValidateDiskOverride checks the disk override of a VM against the disks of the VM and the volume types in
+// OpenStack. The boot disk of the VM cannot be excluded.
+func ValidateDiskOverride(ctx context.Context, k3sclient client.Client, openstackcreds *migratev1alpha1.OpenstackCreds,
+	override *migratev1alpha1.VMDiskOverride, vminfo *migratev1alpha1.VMInfo) error {
+	var volumeTypes []string
+	for _, disk := range override.Disks {
+		if !slices.Contains(vminfo.Disks, disk.Name) {
+			return errors.Errorf("disk '%s' not found on VM '%s'", disk.Name, override.VMName)
+		}
+		if disk.Exclude && disk.Name == vminfo.BootDisk {
+			return errors.Errorf("disk '%s' is the boot disk of VM '%s' and cannot be excluded", disk.Name, override.VMName)
+		}
+		if disk.VolumeType != "" && !slices.Contains(volumeTypes, disk.VolumeType) {
+			volumeTypes = append(volumeTypes, disk.VolumeType)
+		}
+	}
+	if len(volumeTypes) > 0 {
+		if err := VerifyStorage(ctx, k3sclient, openstackcreds, volumeTypes); err != nil {
+			return errors.Wrap(err, "failed to verify volume types of disk overrides")
+		}
+	}
+	return nil
+}
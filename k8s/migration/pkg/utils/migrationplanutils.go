@@ -10,6 +10,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"maps"
 	"reflect"
 	"regexp"
 	"strings"
@@ -20,6 +21,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/validation"
 )
 
@@ -114,3 +117,78 @@ func GenerateSha256Hash(input string) string {
 
 	return hashStr[:len(hashStr)-1] + string(replacement)
 }
+
+// GetMigrationJobOptions returns the options of the v2v-helper pods of a migration plan. The options of the plan
+// take precedence over those of its template, which take precedence over the defaults of the settings.
+func GetMigrationJobOptions(settings *VjailbreakSettings, migrationtemplate *migratev1alpha1.MigrationTemplate,
+	migrationplan *migratev1alpha1.MigrationPlan) (*migratev1alpha1.MigrationJobOptions, error) {
+	opts := &migratev1alpha1.MigrationJobOptions{
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{},
+			Limits:   corev1.ResourceList{},
+		},
+		PriorityClassName: settings.V2VHelperPriorityClassName,
+		VDDKPath:          settings.VDDKPath,
+		VirtioWinPath:     settings.VirtioWinPath,
+	}
+	for _, setting := range []struct {
+		list  corev1.ResourceList
+		name  corev1.ResourceName
+		key   string
+		value string
+	}{
+		{opts.Resources.Requests, corev1.ResourceCPU, "V2V_HELPER_CPU_REQUEST", settings.V2VHelperCPURequest},
+		{opts.Resources.Limits, corev1.ResourceCPU, "V2V_HELPER_CPU_LIMIT", settings.V2VHelperCPULimit},
+		{opts.Resources.Requests, corev1.ResourceMemory, "V2V_HELPER_MEMORY_REQUEST", settings.V2VHelperMemoryRequest},
+		{opts.Resources.Limits, corev1.ResourceMemory, "V2V_HELPER_MEMORY_LIMIT", settings.V2VHelperMemoryLimit},
+		{opts.Resources.Requests, corev1.ResourceEphemeralStorage, "V2V_HELPER_EPHEMERAL_STORAGE", settings.V2VHelperEphemeralStorage},
+		{opts.Resources.Limits, corev1.ResourceEphemeralStorage, "V2V_HELPER_EPHEMERAL_STORAGE", settings.V2VHelperEphemeralStorage},
+	} {
+		quantity, err := resource.ParseQuantity(setting.value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s setting", setting.key)
+		}
+		setting.list[setting.name] = quantity
+	}
+	if settings.V2VHelperNodeSelector != "" {
+		nodeSelector, err := labels.ConvertSelectorToLabelsMap(settings.V2VHelperNodeSelector)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid V2V_HELPER_NODE_SELECTOR setting")
+		}
+		opts.NodeSelector = nodeSelector
+	}
+
+	for _, override := range []*migratev1alpha1.MigrationJobOptions{migrationtemplate.Spec.JobOptions, migrationplan.Spec.JobOptions} {
+		if override == nil {
+			continue
+		}
+		maps.Copy(opts.Resources.Requests, override.Resources.Requests)
+		maps.Copy(opts.Resources.Limits, override.Resources.Limits)
+		if override.NodeSelector != nil {
+			opts.NodeSelector = override.NodeSelector
+		}
+		if override.Affinity != nil {
+			opts.Affinity = override.Affinity
+		}
+		if override.Tolerations != nil {
+			opts.Tolerations = override.Tolerations
+		}
+		if override.PriorityClassName != "" {
+			opts.PriorityClassName = override.PriorityClassName
+		}
+		if override.VDDKPath != "" {
+			opts.VDDKPath = override.VDDKPath
+		}
+		if override.VirtioWinPath != "" {
+			opts.VirtioWinPath = override.VirtioWinPath
+		}
+	}
+
+	// A request raised above the default limit, such as more memory for a large conversion, raises the limit too
+	for name, request := range opts.Resources.Requests {
+		if limit, ok := opts.Resources.Limits[name]; ok && limit.Cmp(request) < 0 {
+			opts.Resources.Limits[name] = request
+		}
+	}
+	return opts, nil
+}
@@ -6,12 +6,16 @@
 package utils
 
 import (
+	"encoding/json"
+	"fmt"
 	"slices"
 	"sort"
 	"strings"
 
 	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
 	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+	openstackconst "github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -27,6 +31,15 @@ type MigrationUtils interface {
 	// CreateMigratingCondition creates a migrated condition for the migration.
 	CreateMigratingCondition(migration *migratev1alpha1.Migration, eventList *corev1.EventList) []corev1.PodCondition
 
+	// CreateDataVerifiedCondition creates a data verified condition for the migration.
+	CreateDataVerifiedCondition(migration *migratev1alpha1.Migration, eventList *corev1.EventList) []corev1.PodCondition
+
+	// CreateHookConditions creates a condition for the result of each hook of the migration.
+	CreateHookConditions(migration *migratev1alpha1.Migration, results []migratev1alpha1.MigrationHookResult) []corev1.PodCondition
+
+	// CreateTestBootCondition creates a test boot condition for the migration.
+	CreateTestBootCondition(migration *migratev1alpha1.Migration, result *migratev1alpha1.TestBootResult) []corev1.PodCondition
+
 	// SetCutoverLabel sets the cutover label based on the initiateCutover flag.
 	SetCutoverLabel(initiateCutover bool, currentLabel string) string
 
@@ -123,6 +136,41 @@ func CreateMigratingCondition(migration *migratev1alpha1.Migration, eventList *c
 	return existingConditions
 }
 
+// CreateDataVerifiedCondition creates a data verified condition for a migration from the result of the
+// data verification of the copied volumes
+func CreateDataVerifiedCondition(migration *migratev1alpha1.Migration, eventList *corev1.EventList) []corev1.PodCondition {
+	existingConditions := migration.Status.Conditions
+	for i := 0; i < len(eventList.Items); i++ {
+		if eventList.Items[i].Reason != constants.MigrationReason {
+			continue
+		}
+		var status corev1.ConditionStatus
+		switch {
+		case strings.Contains(eventList.Items[i].Message, openstackconst.EventMessageDiskDataVerified):
+			status = corev1.ConditionTrue
+		case strings.Contains(eventList.Items[i].Message, openstackconst.EventMessageDiskDataMismatch):
+			status = corev1.ConditionFalse
+		default:
+			continue
+		}
+
+		idx := GetConditonIndex(existingConditions, constants.MigrationConditionTypeDataVerified, constants.MigrationReason)
+		statuscondition := GeneratePodCondition(constants.MigrationConditionTypeDataVerified,
+			status,
+			constants.MigrationReason,
+			eventList.Items[i].Message,
+			eventList.Items[i].LastTimestamp)
+
+		if idx == -1 {
+			existingConditions = append(existingConditions, *statuscondition)
+		} else {
+			existingConditions[idx] = *statuscondition
+		}
+		break
+	}
+	return existingConditions
+}
+
 // CreateFailedCondition creates or updates a failed condition for a migration based on events.
 // It analyzes event logs to identify failure reasons and updates the migration's status conditions accordingly.
 func CreateFailedCondition(migration *migratev1alpha1.Migration, eventList *corev1.EventList) []corev1.PodCondition {
@@ -148,6 +196,145 @@ func CreateFailedCondition(migration *migratev1alpha1.Migration, eventList *core
 	return existingConditions
 }
 
+// GetMigrationProgress returns the progress the v2v-helper published in the annotation of its pod, or nil if it
+// has not published any. Older helpers only report their progress through events.
+func GetMigrationProgress(pod *corev1.Pod) (*migratev1alpha1.MigrationProgress, error) {
+	value := pod.Annotations[openstackconst.MigrationProgressAnnotation]
+	if value == "" {
+		return nil, nil
+	}
+	progress := &migratev1alpha1.MigrationProgress{}
+	if err := json.Unmarshal([]byte(value), progress); err != nil {
+		return nil, errors.Wrapf(err, "invalid progress in annotation %s of pod %s", openstackconst.MigrationProgressAnnotation, pod.Name)
+	}
+	return progress, nil
+}
+
+// GetMigrationHookResults returns the results of the hooks the v2v-helper published in the annotation of its pod
+func GetMigrationHookResults(pod *corev1.Pod) ([]migratev1alpha1.MigrationHookResult, error) {
+	value := pod.Annotations[openstackconst.MigrationHookResultsAnnotation]
+	if value == "" {
+		return nil, nil
+	}
+	var results []migratev1alpha1.MigrationHookResult
+	if err := json.Unmarshal([]byte(value), &results); err != nil {
+		return nil, errors.Wrapf(err, "invalid hook results in annotation %s of pod %s", openstackconst.MigrationHookResultsAnnotation, pod.Name)
+	}
+	return results, nil
+}
+
+// CreateHookConditions creates or updates a condition for the result of each hook of a migration
+func CreateHookConditions(migration *migratev1alpha1.Migration, results []migratev1alpha1.MigrationHookResult) []corev1.PodCondition {
+	existingConditions := migration.Status.Conditions
+	for _, result := range results {
+		status, reason := corev1.ConditionTrue, "Succeeded"
+		switch {
+		case result.Ignored:
+			status, reason = corev1.ConditionFalse, "Ignored"
+		case !result.Succeeded:
+			status, reason = corev1.ConditionFalse, "Failed"
+		}
+		conditionType := corev1.PodConditionType(constants.MigrationConditionTypeHookPrefix + result.Name)
+		statuscondition := GeneratePodCondition(conditionType, status, reason,
+			fmt.Sprintf("%s hook: %s", result.Point, result.Message), result.CompletionTime)
+
+		idx := GetConditonIndex(existingConditions, conditionType, "Succeeded", "Ignored", "Failed")
+		if idx == -1 {
+			existingConditions = append(existingConditions, *statuscondition)
+		} else {
+			existingConditions[idx] = *statuscondition
+		}
+	}
+	return existingConditions
+}
+
+// GetMigrationTestBoot returns the result of the test boot the v2v-helper published in the annotation of its pod
+func GetMigrationTestBoot(pod *corev1.Pod) (*migratev1alpha1.TestBootResult, error) {
+	value := pod.Annotations[openstackconst.MigrationTestBootAnnotation]
+	if value == "" {
+		return nil, nil
+	}
+	result := &migratev1alpha1.TestBootResult{}
+	if err := json.Unmarshal([]byte(value), result); err != nil {
+		return nil, errors.Wrapf(err, "invalid test boot result in annotation %s of pod %s", openstackconst.MigrationTestBootAnnotation, pod.Name)
+	}
+	return result, nil
+}
+
+// CreateTestBootCondition creates or updates the test boot condition of a migration
+func CreateTestBootCondition(migration *migratev1alpha1.Migration, result *migratev1alpha1.TestBootResult) []corev1.PodCondition {
+	existingConditions := migration.Status.Conditions
+	status, reason, message := corev1.ConditionTrue, "Succeeded", "The copy of the VM booted and passed the health checks"
+	if !result.Succeeded {
+		status, reason, message = corev1.ConditionFalse, "Failed", "The copy of the VM did not pass the test boot"
+		for _, check := range result.Checks {
+			if check.Result == migratev1alpha1.PreflightCheckFailed {
+				message = fmt.Sprintf("Test boot check %s: %s", check.Name, check.Message)
+				break
+			}
+		}
+	}
+	statuscondition := GeneratePodCondition(constants.MigrationConditionTypeTestBoot, status, reason, message, result.CompletionTime)
+
+	idx := GetConditonIndex(existingConditions, constants.MigrationConditionTypeTestBoot, "Succeeded", "Failed")
+	if idx == -1 {
+		existingConditions = append(existingConditions, *statuscondition)
+	} else {
+		existingConditions[idx] = *statuscondition
+	}
+	return existingConditions
+}
+
+// GetMigrationSnapshots returns the snapshots of the source VM the v2v-helper published in the annotation of its pod
+func GetMigrationSnapshots(pod *corev1.Pod) ([]migratev1alpha1.MigrationSnapshot, error) {
+	value := pod.Annotations[openstackconst.MigrationSnapshotsAnnotation]
+	if value == "" {
+		return nil, nil
+	}
+	var snapshots []migratev1alpha1.MigrationSnapshot
+	if err := json.Unmarshal([]byte(value), &snapshots); err != nil {
+		return nil, errors.Wrapf(err, "invalid snapshots in annotation %s of pod %s", openstackconst.MigrationSnapshotsAnnotation, pod.Name)
+	}
+	return snapshots, nil
+}
+
+// GetMigrationVolumeExtensions returns the volume extensions the v2v-helper published in the annotation of its pod
+func GetMigrationVolumeExtensions(pod *corev1.Pod) ([]migratev1alpha1.VolumeExtension, error) {
+	value := pod.Annotations[openstackconst.MigrationVolumeExtensionsAnnotation]
+	if value == "" {
+		return nil, nil
+	}
+	var extensions []migratev1alpha1.VolumeExtension
+	if err := json.Unmarshal([]byte(value), &extensions); err != nil {
+		return nil, errors.Wrapf(err, "invalid volume extensions in annotation %s of pod %s", openstackconst.MigrationVolumeExtensionsAnnotation, pod.Name)
+	}
+	return extensions, nil
+}
+
+// GetMigrationSharedDiskVolumes returns the Cinder volumes of the shared disks the v2v-helper copied, by SharedDisk,
+// published in the annotation of its pod
+func GetMigrationSharedDiskVolumes(pod *corev1.Pod) (map[string]string, error) {
+	value := pod.Annotations[openstackconst.MigrationSharedDisksAnnotation]
+	if value == "" {
+		return nil, nil
+	}
+	var volumes map[string]string
+	if err := json.Unmarshal([]byte(value), &volumes); err != nil {
+		return nil, errors.Wrapf(err, "invalid shared disks in annotation %s of pod %s", openstackconst.MigrationSharedDisksAnnotation, pod.Name)
+	}
+	return volumes, nil
+}
+
+// GetVMSnapshotConsistency returns the snapshot consistency of a VM in the migration plan
+func GetVMSnapshotConsistency(migrationplan *migratev1alpha1.MigrationPlan, vm string) string {
+	for _, override := range migrationplan.Spec.SnapshotOverrides {
+		if override.VMName == vm {
+			return override.SnapshotConsistency
+		}
+	}
+	return migrationplan.Spec.MigrationStrategy.SnapshotConsistency
+}
+
 // SetCutoverLabel sets the cutover label for a migration
 func SetCutoverLabel(initiateCutover bool, currentLabel string) string {
 	// If initiateCutover is true, return the current label
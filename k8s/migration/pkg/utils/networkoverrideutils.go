@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+)
+
+// GetVMNetworkOverrides returns the NIC overrides of a VM in the migration plan
+func GetVMNetworkOverrides(migrationplan *migratev1alpha1.MigrationPlan, vm string) []migratev1alpha1.NICNetworkOverride {
+	for _, override := range migrationplan.Spec.NetworkOverrides {
+		if override.VMName == vm {
+			return override.NICs
+		}
+	}
+	return nil
+}
+
+// ResolveNetworkOverrides validates the NIC overrides of a VM against Neutron. It returns the target
+// networks with the overridden networks replaced, and the overrides with the network and subnet
+// set to the resolved network name and subnet ID.
+func ResolveNetworkOverrides(ctx context.Context, k3sclient client.Client, openstackcreds *migratev1alpha1.OpenstackCreds,
+	vm string, overrides []migratev1alpha1.NICNetworkOverride, targetnetworks []string) ([]string, []migratev1alpha1.NICNetworkOverride, error) {
+	if len(overrides) == 0 {
+		return targetnetworks, nil, nil
+	}
+	openstackClients, err := GetOpenStackClients(ctx, k3sclient, openstackcreds)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get openstack clients")
+	}
+	allPages, err := networks.List(openstackClients.NetworkingClient, nil).AllPages()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list networks")
+	}
+	allNetworks, err := networks.ExtractNetworks(allPages)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to extract all networks")
+	}
+	allPages, err = subnets.List(openstackClients.NetworkingClient, nil).AllPages()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list subnets")
+	}
+	allSubnets, err := subnets.ExtractSubnets(allPages)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to extract all subnets")
+	}
+
+	resolvednws := append([]string{}, targetnetworks...)
+	resolved := make([]migratev1alpha1.NICNetworkOverride, 0, len(overrides))
+	seenIndexes := map[int]bool{}
+	seenIPs := map[string]bool{}
+	for _, override := range overrides {
+		if override.Index < 0 || override.Index >= len(targetnetworks) {
+			return nil, nil, fmt.Errorf("NIC %d of VM '%s' does not exist, the VM has %d NICs", override.Index, vm, len(targetnetworks))
+		}
+		if seenIndexes[override.Index] {
+			return nil, nil, fmt.Errorf("NIC %d of VM '%s' is overridden more than once", override.Index, vm)
+		}
+		seenIndexes[override.Index] = true
+
+		networkName := override.Network
+		if networkName == "" {
+			networkName = targetnetworks[override.Index]
+		}
+		network := findNetworkByName(allNetworks, networkName)
+		if network == nil {
+			return nil, nil, fmt.Errorf("network '%s' for NIC %d of VM '%s' not found in OpenStack", networkName, override.Index, vm)
+		}
+
+		var ip net.IP
+		if override.FixedIP != "" {
+			if ip = net.ParseIP(override.FixedIP); ip == nil {
+				return nil, nil, fmt.Errorf("fixed IP '%s' for NIC %d of VM '%s' is not a valid IP address", override.FixedIP, override.Index, vm)
+			}
+			if seenIPs[ip.String()] {
+				return nil, nil, fmt.Errorf("fixed IP '%s' is assigned to more than one NIC of VM '%s'", override.FixedIP, vm)
+			}
+			seenIPs[ip.String()] = true
+		}
+
+		subnet, err := findOverrideSubnet(allSubnets, network, override.Subnet, ip)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid subnet for NIC %d of VM '%s'", override.Index, vm)
+		}
+
+		if ip != nil {
+			if err := verifyFixedIP(openstackClients, subnet, ip, vm); err != nil {
+				return nil, nil, errors.Wrapf(err, "invalid fixed IP for NIC %d of VM '%s'", override.Index, vm)
+			}
+		}
+
+		resolvednws[override.Index] = network.Name
+		override.Network = network.Name
+		override.Subnet = subnet.ID
+		resolved = append(resolved, override)
+	}
+	return resolvednws, resolved, nil
+}
+
+// findNetworkByName returns the network with the given name, or nil if there is none
+func findNetworkByName(allNetworks []networks.Network, name string) *networks.Network {
+	for i := range allNetworks {
+		if allNetworks[i].Name == name {
+			return &allNetworks[i]
+		}
+	}
+	return nil
+}
+
+// findOverrideSubnet returns the subnet of the network matching nameOrID. Without nameOrID it returns
+// the subnet containing ip, or the first subnet of the network when ip is nil.
+func findOverrideSubnet(allSubnets []subnets.Subnet, network *networks.Network, nameOrID string, ip net.IP) (*subnets.Subnet, error) {
+	var candidates []*subnets.Subnet
+	for i := range allSubnets {
+		if allSubnets[i].NetworkID != network.ID {
+			continue
+		}
+		if nameOrID != "" && allSubnets[i].ID != nameOrID && allSubnets[i].Name != nameOrID {
+			continue
+		}
+		candidates = append(candidates, &allSubnets[i])
+	}
+	switch {
+	case nameOrID != "" && len(candidates) == 0:
+		return nil, fmt.Errorf("subnet '%s' not found in network '%s'", nameOrID, network.Name)
+	case nameOrID != "" && len(candidates) > 1:
+		return nil, fmt.Errorf("subnet name '%s' is ambiguous in network '%s', use the subnet ID", nameOrID, network.Name)
+	case len(candidates) == 0:
+		return nil, fmt.Errorf("no subnets found in network '%s'", network.Name)
+	case nameOrID != "":
+		return candidates[0], nil
+	case ip == nil:
+		// Ports without an override also use the first subnet of the network
+		for _, candidate := range candidates {
+			if len(network.Subnets) > 0 && candidate.ID == network.Subnets[0] {
+				return candidate, nil
+			}
+		}
+		return candidates[0], nil
+	}
+	for _, candidate := range candidates {
+		if _, cidr, err := net.ParseCIDR(candidate.CIDR); err == nil && cidr.Contains(ip) {
+			return candidate, nil
+		}
+	}
+	return nil, fmt.Errorf("no subnet of network '%s' contains IP '%s'", network.Name, ip)
+}
+
+// verifyFixedIP checks that ip can be assigned to a new port of the VM in subnet. Ports left
+// behind by an earlier attempt to migrate the same VM may already hold the IP.
+func verifyFixedIP(openstackClients *OpenStackClients, subnet *subnets.Subnet, ip net.IP, vm string) error {
+	_, cidr, err := net.ParseCIDR(subnet.CIDR)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse CIDR of subnet '%s'", subnet.Name)
+	}
+	if !cidr.Contains(ip) {
+		return fmt.Errorf("IP '%s' is not in subnet '%s' (%s)", ip, subnet.Name, subnet.CIDR)
+	}
+	if subnet.GatewayIP != "" && net.ParseIP(subnet.GatewayIP).Equal(ip) {
+		return fmt.Errorf("IP '%s' is the gateway of subnet '%s'", ip, subnet.Name)
+	}
+	allPages, err := ports.List(openstackClients.NetworkingClient, ports.ListOpts{
+		FixedIPs: []ports.FixedIPOpts{{SubnetID: subnet.ID, IPAddress: ip.String()}},
+	}).AllPages()
+	if err != nil {
+		return errors.Wrap(err, "failed to list ports")
+	}
+	allPorts, err := ports.ExtractPorts(allPages)
+	if err != nil {
+		return errors.Wrap(err, "failed to extract all ports")
+	}
+	for _, port := range allPorts {
+		if port.Name != "port-"+vm {
+			return fmt.Errorf("IP '%s' is already used by port %s", ip, port.ID)
+		}
+	}
+	return nil
+}
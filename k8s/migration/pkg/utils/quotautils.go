@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	blockstoragequotasets "github.com/gophercloud/gophercloud/openstack/blockstorage/extensions/quotasets"
+	computequotasets "github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/quotasets"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+)
+
+// UnlimitedQuota is the headroom of a resource without a quota limit
+const UnlimitedQuota = -1
+
+// MigrationQuota is an amount of the Cinder and Nova quota of a project that migrations use
+type MigrationQuota struct {
+	Volumes   int
+	Gigabytes int
+	Instances int
+	Cores     int
+	// RAM is in MB
+	RAM int
+}
+
+// Add adds other to the quota
+func (q *MigrationQuota) Add(other MigrationQuota) {
+	q.Volumes += other.Volumes
+	q.Gigabytes += other.Gigabytes
+	q.Instances += other.Instances
+	q.Cores += other.Cores
+	q.RAM += other.RAM
+}
+
+// Shortfalls describes each resource of need that does not fit in the headroom
+func (q *MigrationQuota) Shortfalls(need MigrationQuota) []string {
+	var shortfalls []string
+	check := func(resource string, left, needed int) {
+		if left != UnlimitedQuota && left < needed {
+			shortfalls = append(shortfalls, fmt.Sprintf("%s: %d needed, %d left", resource, needed, left))
+		}
+	}
+	check("volumes", q.Volumes, need.Volumes)
+	check("gigabytes", q.Gigabytes, need.Gigabytes)
+	check("instances", q.Instances, need.Instances)
+	check("cores", q.Cores, need.Cores)
+	check("ram (MB)", q.RAM, need.RAM)
+	return shortfalls
+}
+
+// Take takes need off the headroom. Unlimited resources stay unlimited.
+func (q *MigrationQuota) Take(need MigrationQuota) {
+	take := func(left *int, needed int) {
+		if *left != UnlimitedQuota {
+			*left = max(*left-needed, 0)
+		}
+	}
+	take(&q.Volumes, need.Volumes)
+	take(&q.Gigabytes, need.Gigabytes)
+	take(&q.Instances, need.Instances)
+	take(&q.Cores, need.Cores)
+	take(&q.RAM, need.RAM)
+}
+
+// GetQuotaHeadroom returns the Cinder and Nova quota left in the project of the openstack credentials
+func GetQuotaHeadroom(ctx context.Context, k3sclient client.Client, openstackcreds *migratev1alpha1.OpenstackCreds) (*MigrationQuota, error) {
+	openstackClients, err := GetOpenStackClients(ctx, k3sclient, openstackcreds)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get openstack clients")
+	}
+	projectID, err := GetOpenstackProjectID(ctx, k3sclient, openstackcreds, openstackClients)
+	if err != nil {
+		return nil, err
+	}
+	cinderUsage, err := blockstoragequotasets.GetUsage(openstackClients.BlockStorageClient, projectID).Extract()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get cinder quota usage of project %s", projectID)
+	}
+	novaUsage, err := computequotasets.GetDetail(openstackClients.ComputeClient, projectID).Extract()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get nova quota usage of project %s", projectID)
+	}
+	return &MigrationQuota{
+		Volumes:   quotaHeadroom(cinderUsage.Volumes.Limit, cinderUsage.Volumes.InUse, cinderUsage.Volumes.Reserved),
+		Gigabytes: quotaHeadroom(cinderUsage.Gigabytes.Limit, cinderUsage.Gigabytes.InUse, cinderUsage.Gigabytes.Reserved),
+		Instances: quotaHeadroom(novaUsage.Instances.Limit, novaUsage.Instances.InUse, novaUsage.Instances.Reserved),
+		Cores:     quotaHeadroom(novaUsage.Cores.Limit, novaUsage.Cores.InUse, novaUsage.Cores.Reserved),
+		RAM:       quotaHeadroom(novaUsage.RAM.Limit, novaUsage.RAM.InUse, novaUsage.RAM.Reserved),
+	}, nil
+}
+
+// quotaHeadroom returns how much of a quota is left, or UnlimitedQuota if the quota has no limit
+func quotaHeadroom(limit, inUse, reserved int) int {
+	if limit < 0 {
+		return UnlimitedQuota
+	}
+	return max(limit-inUse-reserved, 0)
+}
+
+// VMQuotaNeed returns the quota the migration of a VM uses. Cores and RAM come from the flavor,
+// or from the VM itself for flavorless migrations where flavor is nil. Disks excluded by the disk override of the
+// VM are left out. The gigabytes of disks whose size is not known are left out as well.
+func VMQuotaNeed(vminfo *migratev1alpha1.VMInfo, override *migratev1alpha1.VMDiskOverride, flavor *flavors.Flavor) MigrationQuota {
+	need := MigrationQuota{
+		Volumes:   len(vminfo.RDMDisks),
+		Instances: 1,
+		Cores:     vminfo.CPU,
+		RAM:       vminfo.Memory,
+	}
+	for idx, disk := range vminfo.Disks {
+		if override != nil && slices.ContainsFunc(override.Disks, func(o migratev1alpha1.DiskOverride) bool {
+			return o.Name == disk && o.Exclude
+		}) {
+			continue
+		}
+		need.Volumes++
+		if idx < len(vminfo.DiskSizes) {
+			// Volumes are created with 1GB of extra space
+			need.Gigabytes += VolumeGigabytes(vminfo.DiskSizes[idx]) + 1
+		}
+	}
+	for _, rdmDisk := range vminfo.RDMDisks {
+		need.Gigabytes += VolumeGigabytes(rdmDisk.DiskSize)
+	}
+	if flavor != nil {
+		need.Cores = flavor.VCPUs
+		need.RAM = flavor.RAM
+	}
+	return need
+}
+
+// VolumeGigabytes returns the size in GB of a volume holding size bytes
+func VolumeGigabytes(size int64) int {
+	return int((size + (1 << 30) - 1) >> 30)
+}
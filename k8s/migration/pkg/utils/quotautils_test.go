@@ -0,0 +1,59 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+)
+
+var _ = ginkgo.Describe("VMQuotaNeed", func() {
+	const gb = int64(1) << 30
+	vminfo := &migratev1alpha1.VMInfo{
+		CPU:       2,
+		Memory:    4096,
+		Disks:     []string{"Hard disk 1", "Hard disk 2", "Hard disk 3"},
+		DiskSizes: []int64{10 * gb, 20 * gb, 30 * gb},
+		RDMDisks:  []migratev1alpha1.RDMDiskInfo{{DiskSize: 5 * gb}},
+	}
+
+	ginkgo.It("counts the disks, the RDM disks and the flavor", func() {
+		gomega.Expect(VMQuotaNeed(vminfo, nil, &flavors.Flavor{VCPUs: 4, RAM: 8192})).To(gomega.Equal(MigrationQuota{
+			Volumes: 4, Gigabytes: 11 + 21 + 31 + 5, Instances: 1, Cores: 4, RAM: 8192,
+		}))
+	})
+
+	ginkgo.It("leaves out the excluded disks", func() {
+		override := &migratev1alpha1.VMDiskOverride{Disks: []migratev1alpha1.DiskOverride{
+			{Name: "Hard disk 2", Exclude: true},
+			{Name: "Hard disk 3", VolumeType: "fast"},
+		}}
+		gomega.Expect(VMQuotaNeed(vminfo, override, nil)).To(gomega.Equal(MigrationQuota{
+			Volumes: 3, Gigabytes: 11 + 31 + 5, Instances: 1, Cores: 2, RAM: 4096,
+		}))
+	})
+
+	ginkgo.It("counts the volumes of disks whose size is not known", func() {
+		unsized := *vminfo
+		unsized.DiskSizes = nil
+		unsized.RDMDisks = nil
+		gomega.Expect(VMQuotaNeed(&unsized, nil, nil).Volumes).To(gomega.Equal(3))
+	})
+})
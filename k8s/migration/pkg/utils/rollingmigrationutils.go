@@ -525,6 +525,12 @@ func convertBatchToMigrationPlan(ctx context.Context, scope *scope.ClusterMigrat
 				MigrationStrategy: rollingMigrationPlan.Spec.MigrationStrategy,
 				// Copy advanced options if needed
 				AdvancedOptions: rollingMigrationPlan.Spec.AdvancedOptions,
+				JobOptions:      rollingMigrationPlan.Spec.JobOptions,
+				// Batches share the priority and limits of the rolling plan
+				Priority:                rollingMigrationPlan.Spec.Priority,
+				MaxConcurrentMigrations: rollingMigrationPlan.Spec.MaxConcurrentMigrations,
+				MaxConcurrentCutovers:   rollingMigrationPlan.Spec.MaxConcurrentCutovers,
+				Hooks:                   rollingMigrationPlan.Spec.Hooks,
 			},
 			// Include VM batch as a single group for migration
 			VirtualMachines: [][]string{batch},
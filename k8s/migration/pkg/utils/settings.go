@@ -29,6 +29,40 @@ type VjailbreakSettings struct {
 	CleanupVolumesAfterConvertFailure bool
 	// PopulateVMwareMachineFlavors is whether to automatically populate VMwareMachine objects with OpenStack flavors
 	PopulateVMwareMachineFlavors bool
+	// DiskCopyConcurrencyLimit is the max number of disks of a vm to copy at the same time
+	DiskCopyConcurrencyLimit int
+	// NodeBandwidthLimitMbps is the max bandwidth in Mbps shared by all migrations running on an agent node, 0 for no limit
+	NodeBandwidthLimitMbps int
+	// V2VHelperCPURequest is the default CPU request of the v2v-helper pods
+	V2VHelperCPURequest string
+	// V2VHelperCPULimit is the default CPU limit of the v2v-helper pods
+	V2VHelperCPULimit string
+	// V2VHelperMemoryRequest is the default memory request of the v2v-helper pods
+	V2VHelperMemoryRequest string
+	// V2VHelperMemoryLimit is the default memory limit of the v2v-helper pods
+	V2VHelperMemoryLimit string
+	// V2VHelperEphemeralStorage is the default ephemeral storage request and limit of the v2v-helper pods
+	V2VHelperEphemeralStorage string
+	// V2VHelperNodeSelector is the default node selector of the v2v-helper pods, as comma separated key=value pairs
+	V2VHelperNodeSelector string
+	// V2VHelperPriorityClassName is the default priority class of the v2v-helper pods
+	V2VHelperPriorityClassName string
+	// VDDKPath is the default directory on the nodes holding the VMware VDDK libraries
+	VDDKPath string
+	// VirtioWinPath is the default directory on the nodes where the virtio-win drivers are cached
+	VirtioWinPath string
+	// MigrationPlacementStrategy is how the agent of a migration is picked: LeastMigrations, MostFreeBandwidth or Scheduler
+	MigrationPlacementStrategy string
+	// MaxMigrationsPerAgent is the max number of migrations running on an agent at the same time, 0 for no limit
+	MaxMigrationsPerAgent int
+	// MaxConcurrentMigrations is the max number of v2v-helper jobs running across all migration plans, 0 for no limit
+	MaxConcurrentMigrations int
+	// MaxConcurrentCutovers is the max number of migrations cutting over across all migration plans, 0 for no limit
+	MaxConcurrentCutovers int
+	// MaxVCenterSessions is the max number of v2v-helper sessions open on a vCenter at the same time, 0 for no limit
+	MaxVCenterSessions int
+	// MaxNFCConnectionsPerHost is the max number of NFC connections open on an ESXi host at the same time, 0 for no limit
+	MaxNFCConnectionsPerHost int
 }
 
 // atoi is a helper function to convert string to int with a default value of 0
@@ -90,6 +124,35 @@ func GetMigrateSettings(ctx context.Context, k8sClient client.Client) (*Vjailbre
 		vjailbreakSettingsCM.Data["POPULATE_VMWARE_MACHINE_FLAVORS"] = trueString
 	}
 
+	if atoi(vjailbreakSettingsCM.Data["DISK_COPY_CONCURRENCY_LIMIT"]) < 1 {
+		vjailbreakSettingsCM.Data["DISK_COPY_CONCURRENCY_LIMIT"] = "1"
+	}
+
+	if vjailbreakSettingsCM.Data["NODE_BANDWIDTH_LIMIT_MBPS"] == "" {
+		vjailbreakSettingsCM.Data["NODE_BANDWIDTH_LIMIT_MBPS"] = "0"
+	}
+
+	defaults := getDefaultSettings()
+	for key, value := range map[string]string{
+		"V2V_HELPER_CPU_REQUEST":       defaults.V2VHelperCPURequest,
+		"V2V_HELPER_CPU_LIMIT":         defaults.V2VHelperCPULimit,
+		"V2V_HELPER_MEMORY_REQUEST":    defaults.V2VHelperMemoryRequest,
+		"V2V_HELPER_MEMORY_LIMIT":      defaults.V2VHelperMemoryLimit,
+		"V2V_HELPER_EPHEMERAL_STORAGE": defaults.V2VHelperEphemeralStorage,
+		"VDDK_PATH":                    defaults.VDDKPath,
+		"VIRTIO_WIN_PATH":              defaults.VirtioWinPath,
+		"MIGRATION_PLACEMENT_STRATEGY": defaults.MigrationPlacementStrategy,
+		"MAX_MIGRATIONS_PER_AGENT":     strconv.Itoa(defaults.MaxMigrationsPerAgent),
+		"MAX_CONCURRENT_MIGRATIONS":    strconv.Itoa(defaults.MaxConcurrentMigrations),
+		"MAX_CONCURRENT_CUTOVERS":      strconv.Itoa(defaults.MaxConcurrentCutovers),
+		"MAX_VCENTER_SESSIONS":         strconv.Itoa(defaults.MaxVCenterSessions),
+		"MAX_NFC_CONNECTIONS_PER_HOST": strconv.Itoa(defaults.MaxNFCConnectionsPerHost),
+	} {
+		if vjailbreakSettingsCM.Data[key] == "" {
+			vjailbreakSettingsCM.Data[key] = value
+		}
+	}
+
 	return &VjailbreakSettings{
 		ChangedBlocksCopyIterationThreshold: atoi(vjailbreakSettingsCM.Data["CHANGED_BLOCKS_COPY_ITERATION_THRESHOLD"]),
 		VMActiveWaitIntervalSeconds:         atoi(vjailbreakSettingsCM.Data["VM_ACTIVE_WAIT_INTERVAL_SECONDS"]),
@@ -98,6 +161,23 @@ func GetMigrateSettings(ctx context.Context, k8sClient client.Client) (*Vjailbre
 		VCenterScanConcurrencyLimit:         atoi(vjailbreakSettingsCM.Data["VCENTER_SCAN_CONCURRENCY_LIMIT"]),
 		CleanupVolumesAfterConvertFailure:   vjailbreakSettingsCM.Data["CLEANUP_VOLUMES_AFTER_CONVERT_FAILURE"] == "true",
 		PopulateVMwareMachineFlavors:        vjailbreakSettingsCM.Data["POPULATE_VMWARE_MACHINE_FLAVORS"] == "true",
+		DiskCopyConcurrencyLimit:            atoi(vjailbreakSettingsCM.Data["DISK_COPY_CONCURRENCY_LIMIT"]),
+		NodeBandwidthLimitMbps:              atoi(vjailbreakSettingsCM.Data["NODE_BANDWIDTH_LIMIT_MBPS"]),
+		V2VHelperCPURequest:                 vjailbreakSettingsCM.Data["V2V_HELPER_CPU_REQUEST"],
+		V2VHelperCPULimit:                   vjailbreakSettingsCM.Data["V2V_HELPER_CPU_LIMIT"],
+		V2VHelperMemoryRequest:              vjailbreakSettingsCM.Data["V2V_HELPER_MEMORY_REQUEST"],
+		V2VHelperMemoryLimit:                vjailbreakSettingsCM.Data["V2V_HELPER_MEMORY_LIMIT"],
+		V2VHelperEphemeralStorage:           vjailbreakSettingsCM.Data["V2V_HELPER_EPHEMERAL_STORAGE"],
+		V2VHelperNodeSelector:               vjailbreakSettingsCM.Data["V2V_HELPER_NODE_SELECTOR"],
+		V2VHelperPriorityClassName:          vjailbreakSettingsCM.Data["V2V_HELPER_PRIORITY_CLASS_NAME"],
+		VDDKPath:                            vjailbreakSettingsCM.Data["VDDK_PATH"],
+		VirtioWinPath:                       vjailbreakSettingsCM.Data["VIRTIO_WIN_PATH"],
+		MigrationPlacementStrategy:          vjailbreakSettingsCM.Data["MIGRATION_PLACEMENT_STRATEGY"],
+		MaxMigrationsPerAgent:               atoi(vjailbreakSettingsCM.Data["MAX_MIGRATIONS_PER_AGENT"]),
+		MaxConcurrentMigrations:             atoi(vjailbreakSettingsCM.Data["MAX_CONCURRENT_MIGRATIONS"]),
+		MaxConcurrentCutovers:               atoi(vjailbreakSettingsCM.Data["MAX_CONCURRENT_CUTOVERS"]),
+		MaxVCenterSessions:                  atoi(vjailbreakSettingsCM.Data["MAX_VCENTER_SESSIONS"]),
+		MaxNFCConnectionsPerHost:            atoi(vjailbreakSettingsCM.Data["MAX_NFC_CONNECTIONS_PER_HOST"]),
 	}, nil
 }
 
@@ -111,5 +191,20 @@ func getDefaultSettings() *VjailbreakSettings {
 		VCenterScanConcurrencyLimit:         10,
 		CleanupVolumesAfterConvertFailure:   false,
 		PopulateVMwareMachineFlavors:        true,
+		DiskCopyConcurrencyLimit:            1,
+		NodeBandwidthLimitMbps:              0,
+		V2VHelperCPURequest:                 "1000m",
+		V2VHelperCPULimit:                   "2000m",
+		V2VHelperMemoryRequest:              "1Gi",
+		V2VHelperMemoryLimit:                "3Gi",
+		V2VHelperEphemeralStorage:           "3Gi",
+		VDDKPath:                            constants.DefaultVDDKPath,
+		VirtioWinPath:                       constants.DefaultVirtioWinPath,
+		MigrationPlacementStrategy:          constants.PlacementStrategyLeastMigrations,
+		MaxMigrationsPerAgent:               0,
+		MaxConcurrentMigrations:             0,
+		MaxConcurrentCutovers:               0,
+		MaxVCenterSessions:                  0,
+		MaxNFCConnectionsPerHost:            0,
 	}
 }
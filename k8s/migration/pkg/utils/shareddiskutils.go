@@ -0,0 +1,305 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/types"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+	scope "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/scope"
+)
+
+// multiWriterFileName returns the VMDK of a disk that is shared with other VMs in multi-writer mode
+func multiWriterFileName(disk *types.VirtualDisk) (string, bool) {
+	backing, ok := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo)
+	if !ok || backing.Sharing != string(types.VirtualDiskSharingSharingMultiWriter) {
+		return "", false
+	}
+	return backing.FileName, true
+}
+
+// SharedDiskName returns the name of the SharedDisk of a VMDK. The hash of the full path keeps VMDKs with the same
+// name on different datastores apart.
+func SharedDiskName(fileName, credsName string) (string, error) {
+	k8sName, err := ConvertToK8sName(strings.TrimSuffix(path.Base(fileName), ".vmdk"))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to convert disk name to k8s name")
+	}
+	hash := GenerateSha256Hash(fmt.Sprintf("%s-%s", fileName, credsName))[:constants.HashSuffixLength]
+	return fmt.Sprintf("%s-%s", k8sName[:min(len(k8sName), constants.VMNameMaxLength)], hash), nil
+}
+
+// SyncSharedDisks creates or updates a SharedDisk for every disk the scanned VMs share in multi-writer mode, with
+// the VMs it is attached to as its owners. SharedDisks of the credentials that are no longer found are deleted,
+// unless they were copied already.
+func SyncSharedDisks(ctx context.Context, scope *scope.VMwareCredsScope, vminfo []migratev1alpha1.VMInfo) error {
+	specs := map[string]*migratev1alpha1.SharedDiskSpec{}
+	for _, vm := range vminfo {
+		for _, disk := range vm.SharedDisks {
+			spec, ok := specs[disk.SharedDisk]
+			if !ok {
+				spec = &migratev1alpha1.SharedDiskSpec{FileName: disk.FileName}
+				if idx := slices.Index(vm.Disks, disk.DiskName); idx >= 0 && idx < len(vm.DiskSizes) {
+					spec.DiskSize = vm.DiskSizes[idx]
+				}
+				specs[disk.SharedDisk] = spec
+			}
+			spec.OwnerVMs = append(spec.OwnerVMs, vm.Name)
+		}
+	}
+
+	for name, spec := range specs {
+		slices.Sort(spec.OwnerVMs)
+		sharedDisk := &migratev1alpha1.SharedDisk{}
+		err := scope.Client.Get(ctx, k8stypes.NamespacedName{Name: name, Namespace: scope.Namespace()}, sharedDisk)
+		switch {
+		case apierrors.IsNotFound(err):
+			sharedDisk = &migratev1alpha1.SharedDisk{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: scope.Namespace(),
+					Labels:    map[string]string{constants.VMwareCredsLabel: scope.Name()},
+				},
+				Spec: *spec,
+			}
+			if err := scope.Client.Create(ctx, sharedDisk); err != nil {
+				return errors.Wrapf(err, "failed to create SharedDisk '%s'", name)
+			}
+			sharedDisk.Status.Phase = migratev1alpha1.SharedDiskPhaseAvailable
+			if err := scope.Client.Status().Update(ctx, sharedDisk); err != nil {
+				return errors.Wrapf(err, "failed to update status of SharedDisk '%s'", name)
+			}
+		case err != nil:
+			return errors.Wrapf(err, "failed to get SharedDisk '%s'", name)
+		case sharedDisk.Spec.FileName != spec.FileName || sharedDisk.Spec.DiskSize != spec.DiskSize ||
+			!slices.Equal(sharedDisk.Spec.OwnerVMs, spec.OwnerVMs):
+			sharedDisk.Spec = *spec
+			if err := scope.Client.Update(ctx, sharedDisk); err != nil {
+				return errors.Wrapf(err, "failed to update SharedDisk '%s'", name)
+			}
+		}
+	}
+
+	sharedDisks := &migratev1alpha1.SharedDiskList{}
+	if err := scope.Client.List(ctx, sharedDisks, client.InNamespace(scope.Namespace()),
+		client.MatchingLabels{constants.VMwareCredsLabel: scope.Name()}); err != nil {
+		return errors.Wrap(err, "failed to list SharedDisks")
+	}
+	for i := range sharedDisks.Items {
+		sharedDisk := &sharedDisks.Items[i]
+		if _, ok := specs[sharedDisk.Name]; ok || sharedDisk.Status.Phase == migratev1alpha1.SharedDiskPhaseCopied {
+			continue
+		}
+		if err := scope.Client.Delete(ctx, sharedDisk); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete SharedDisk '%s'", sharedDisk.Name)
+		}
+	}
+	return nil
+}
+
+// DeleteSharedDisksForVMwareCreds removes all SharedDisk objects associated with a VMwareCreds resource
+func DeleteSharedDisksForVMwareCreds(ctx context.Context, scope *scope.VMwareCredsScope) error {
+	sharedDisks := &migratev1alpha1.SharedDiskList{}
+	if err := scope.Client.List(ctx, sharedDisks, client.InNamespace(scope.Namespace()),
+		client.MatchingLabels{constants.VMwareCredsLabel: scope.Name()}); err != nil {
+		return errors.Wrap(err, "failed to list SharedDisks")
+	}
+	for i := range sharedDisks.Items {
+		if err := scope.Client.Delete(ctx, &sharedDisks.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete SharedDisk '%s'", sharedDisks.Items[i].Name)
+		}
+	}
+	return nil
+}
+
+// GetVMSharedDisks returns how the migration of a VM handles the disks it shares with other VMs. All owner VMs of
+// a shared disk must be migrated together, in the same group of the migration plan, and the first of them copies
+// the disk.
+func GetVMSharedDisks(ctx context.Context, k3sclient client.Client, migrationplan *migratev1alpha1.MigrationPlan,
+	vm string, vminfo *migratev1alpha1.VMInfo) ([]migratev1alpha1.SharedDiskAssignment, error) {
+	var group []string
+	for _, vms := range migrationplan.Spec.VirtualMachines {
+		if slices.Contains(vms, vm) {
+			group = vms
+			break
+		}
+	}
+	assignments := make([]migratev1alpha1.SharedDiskAssignment, 0, len(vminfo.SharedDisks))
+	for _, disk := range vminfo.SharedDisks {
+		sharedDisk := &migratev1alpha1.SharedDisk{}
+		if err := k3sclient.Get(ctx, k8stypes.NamespacedName{Name: disk.SharedDisk, Namespace: migrationplan.Namespace}, sharedDisk); err != nil {
+			return nil, errors.Wrapf(err, "failed to get SharedDisk '%s' of disk '%s'", disk.SharedDisk, disk.DiskName)
+		}
+		var missing []string
+		for _, owner := range sharedDisk.Spec.OwnerVMs {
+			if !slices.Contains(group, owner) {
+				missing = append(missing, owner)
+			}
+		}
+		if len(missing) > 0 {
+			return nil, errors.Errorf("disk '%s' is shared with VMs %v that are not migrated in the same group as VM '%s'",
+				disk.DiskName, missing, vm)
+		}
+		assignments = append(assignments, migratev1alpha1.SharedDiskAssignment{
+			DiskName:   disk.DiskName,
+			SharedDisk: sharedDisk.Name,
+			OwnerVMs:   sharedDisk.Spec.OwnerVMs,
+			Copy:       sharedDisk.Spec.OwnerVMs[0] == vm,
+		})
+	}
+	return assignments, nil
+}
+
+// SharedDiskCutover is the cutover of the other VMs sharing disks with the VM of a migration. The owner VMs of a
+// shared disk cut over together, as one unit.
+type SharedDiskCutover struct {
+	// NotReady are the VMs that do not wait for the cutover yet
+	NotReady []string
+	// SlotHeld is set once one of the VMs was given a cutover slot, the others cut over with it
+	SlotHeld bool
+	// Started is set once the cutover of one of the VMs was started
+	Started bool
+}
+
+// GetSharedDiskCutover returns the cutover of the other VMs sharing disks with the VM of a migration. It returns
+// nil if the VM shares no disks.
+func GetSharedDiskCutover(ctx context.Context, k3sclient client.Client, migration *migratev1alpha1.Migration) (*SharedDiskCutover, error) {
+	vmwvm, err := getVMwareMachineForMigration(ctx, k3sclient, migration)
+	if err != nil {
+		return nil, err
+	}
+	if len(vmwvm.Spec.VMInfo.SharedDisks) == 0 {
+		return nil, nil
+	}
+	var owners []string
+	for _, disk := range vmwvm.Spec.VMInfo.SharedDisks {
+		sharedDisk := &migratev1alpha1.SharedDisk{}
+		if err := k3sclient.Get(ctx, k8stypes.NamespacedName{Name: disk.SharedDisk, Namespace: migration.Namespace}, sharedDisk); err != nil {
+			return nil, errors.Wrapf(err, "failed to get SharedDisk '%s'", disk.SharedDisk)
+		}
+		for _, owner := range sharedDisk.Spec.OwnerVMs {
+			if owner != migration.Spec.VMName && !slices.Contains(owners, owner) {
+				owners = append(owners, owner)
+			}
+		}
+	}
+
+	migrations := &migratev1alpha1.MigrationList{}
+	if err := k3sclient.List(ctx, migrations, client.InNamespace(migration.Namespace)); err != nil {
+		return nil, errors.Wrap(err, "failed to list migrations")
+	}
+	cutover := &SharedDiskCutover{}
+	for _, owner := range owners {
+		ready := false
+		for i := range migrations.Items {
+			other := &migrations.Items[i]
+			if other.Spec.MigrationPlan != migration.Spec.MigrationPlan || other.Spec.VMName != owner {
+				continue
+			}
+			phase := other.Status.Phase
+			if other.Status.Progress != nil && other.Status.Progress.Phase != "" {
+				phase = other.Status.Progress.Phase
+			}
+			slot := HasCutoverSlot(other)
+			started, err := isCutoverStarted(ctx, k3sclient, other)
+			if err != nil {
+				return nil, err
+			}
+			cutover.SlotHeld = cutover.SlotHeld || slot
+			cutover.Started = cutover.Started || started
+			ready = phase == migratev1alpha1.VMMigrationPhaseAwaitingAdminCutOver || slot || started
+			break
+		}
+		if !ready {
+			cutover.NotReady = append(cutover.NotReady, owner)
+		}
+	}
+	return cutover, nil
+}
+
+// isCutoverStarted reports whether the v2v-helper pod of a migration was told to cut over
+func isCutoverStarted(ctx context.Context, k3sclient client.Client, migration *migratev1alpha1.Migration) (bool, error) {
+	if migration.Spec.PodRef == "" {
+		return false, nil
+	}
+	pod := &corev1.Pod{}
+	err := k3sclient.Get(ctx, k8stypes.NamespacedName{Name: migration.Spec.PodRef, Namespace: migration.Namespace}, pod)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "failed to get pod '%s'", migration.Spec.PodRef)
+	}
+	return pod.Labels["startCutover"] == constants.StartCutOverYes, nil
+}
+
+// getVMwareMachineForMigration returns the VMwareMachine of the VM of a migration
+func getVMwareMachineForMigration(ctx context.Context, k3sclient client.Client, migration *migratev1alpha1.Migration) (*migratev1alpha1.VMwareMachine, error) {
+	vmwareCredsName, err := GetVMwareCredsNameFromMigration(ctx, k3sclient, migration)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get vmware credentials name")
+	}
+	name, err := GetK8sCompatibleVMWareObjectName(migration.Spec.VMName, vmwareCredsName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get vmware machine name")
+	}
+	vmwvm := &migratev1alpha1.VMwareMachine{}
+	if err := k3sclient.Get(ctx, k8stypes.NamespacedName{Name: name, Namespace: migration.Namespace}, vmwvm); err != nil {
+		return nil, errors.Wrap(err, "failed to get vmware machine")
+	}
+	return vmwvm, nil
+}
+
+// SetSharedDisksCopied records the Cinder volumes the shared disks were copied into by a migration
+func SetSharedDisksCopied(ctx context.Context, k3sclient client.Client, migration *migratev1alpha1.Migration, volumes map[string]string) error {
+	for name, volumeID := range volumes {
+		sharedDisk := &migratev1alpha1.SharedDisk{}
+		if err := k3sclient.Get(ctx, k8stypes.NamespacedName{Name: name, Namespace: migration.Namespace}, sharedDisk); err != nil {
+			return errors.Wrapf(err, "failed to get SharedDisk '%s'", name)
+		}
+		if sharedDisk.Status.Phase == migratev1alpha1.SharedDiskPhaseCopied && sharedDisk.Status.CinderVolumeID == volumeID &&
+			sharedDisk.Status.CopiedByUID == migration.UID {
+			continue
+		}
+		sharedDisk.Status.Phase = migratev1alpha1.SharedDiskPhaseCopied
+		sharedDisk.Status.CopiedBy = migration.Name
+		sharedDisk.Status.CopiedByUID = migration.UID
+		sharedDisk.Status.CinderVolumeID = volumeID
+		if err := k3sclient.Status().Update(ctx, sharedDisk); err != nil {
+			return errors.Wrapf(err, "failed to update status of SharedDisk '%s'", name)
+		}
+	}
+	return nil
+}
+
+// ResetSharedDisks makes the shared disks copied by a migration available again, so that the migrations of the
+// other owner VMs do not attach a copy that is rolled back, failed or left behind by an earlier attempt. The next
+// attempt of the copying migration copies the disks again.
+func ResetSharedDisks(ctx context.Context, k3sclient client.Client, migration *migratev1alpha1.Migration) error {
+	sharedDisks := &migratev1alpha1.SharedDiskList{}
+	if err := k3sclient.List(ctx, sharedDisks, client.InNamespace(migration.Namespace)); err != nil {
+		return errors.Wrap(err, "failed to list SharedDisks")
+	}
+	for i := range sharedDisks.Items {
+		sharedDisk := &sharedDisks.Items[i]
+		if sharedDisk.Status.Phase != migratev1alpha1.SharedDiskPhaseCopied || sharedDisk.Status.CopiedByUID != migration.UID {
+			continue
+		}
+		sharedDisk.Status = migratev1alpha1.SharedDiskStatus{Phase: migratev1alpha1.SharedDiskPhaseAvailable}
+		if err := k3sclient.Status().Update(ctx, sharedDisk); err != nil {
+			return errors.Wrapf(err, "failed to reset status of SharedDisk '%s'", sharedDisk.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+	scope "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/scope"
+)
+
+var _ = ginkgo.Describe("Shared disks", func() {
+	ctx := context.Background()
+	namespace := constants.NamespaceMigrationSystem
+	var k8sClient client.Client
+
+	sharedDisk := func(name string, owners ...string) *migratev1alpha1.SharedDisk {
+		return &migratev1alpha1.SharedDisk{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{constants.VMwareCredsLabel: "creds"},
+			},
+			Spec:   migratev1alpha1.SharedDiskSpec{FileName: "[ds] " + name + ".vmdk", OwnerVMs: owners},
+			Status: migratev1alpha1.SharedDiskStatus{Phase: migratev1alpha1.SharedDiskPhaseAvailable},
+		}
+	}
+	vmwareMachine := func(vm string, disks ...string) *migratev1alpha1.VMwareMachine {
+		name, err := GetK8sCompatibleVMWareObjectName(vm, "creds")
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		vmwvm := &migratev1alpha1.VMwareMachine{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		vmwvm.Spec.VMInfo.Name = vm
+		for _, disk := range disks {
+			vmwvm.Spec.VMInfo.SharedDisks = append(vmwvm.Spec.VMInfo.SharedDisks,
+				migratev1alpha1.SharedDiskInfo{DiskName: "Hard disk 2", SharedDisk: disk})
+		}
+		return vmwvm
+	}
+	migration := func(vm string, phase migratev1alpha1.VMMigrationPhase) *migratev1alpha1.Migration {
+		return &migratev1alpha1.Migration{
+			ObjectMeta: metav1.ObjectMeta{Name: "migration-" + vm, Namespace: namespace, UID: k8stypes.UID("uid-" + vm)},
+			Spec:       migratev1alpha1.MigrationSpec{MigrationPlan: "plan", VMName: vm, PodRef: "pod-" + vm},
+			Status:     migratev1alpha1.MigrationStatus{Phase: phase},
+		}
+	}
+	pod := func(vm, startCutover string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-" + vm,
+			Namespace: namespace,
+			Labels:    map[string]string{"startCutover": startCutover},
+		}}
+	}
+
+	ginkgo.BeforeEach(func() {
+		k8sClient = fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&migratev1alpha1.SharedDisk{}, &migratev1alpha1.Migration{}).
+			WithObjects(
+				&migratev1alpha1.MigrationPlan{
+					ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: namespace},
+					Spec: migratev1alpha1.MigrationPlanSpec{
+						MigrationPlanSpecPerVM: migratev1alpha1.MigrationPlanSpecPerVM{MigrationTemplate: "template"},
+						VirtualMachines:        [][]string{{"vm-1", "vm-2"}, {"vm-3"}},
+					},
+				},
+				&migratev1alpha1.MigrationTemplate{
+					ObjectMeta: metav1.ObjectMeta{Name: "template", Namespace: namespace},
+					Spec: migratev1alpha1.MigrationTemplateSpec{
+						Source: migratev1alpha1.MigrationTemplateSource{VMwareRef: "creds"},
+					},
+				},
+			).Build()
+	})
+
+	ginkgo.Describe("GetVMSharedDisks", func() {
+		plan := func() *migratev1alpha1.MigrationPlan {
+			migrationplan := &migratev1alpha1.MigrationPlan{}
+			gomega.Expect(k8sClient.Get(ctx, k8stypes.NamespacedName{Name: "plan", Namespace: namespace}, migrationplan)).To(gomega.Succeed())
+			return migrationplan
+		}
+
+		ginkgo.It("has the first owner VM copy the disk", func() {
+			gomega.Expect(k8sClient.Create(ctx, sharedDisk("data", "vm-1", "vm-2"))).To(gomega.Succeed())
+			vminfo := &vmwareMachine("vm-2", "data").Spec.VMInfo
+
+			assignments, err := GetVMSharedDisks(ctx, k8sClient, plan(), "vm-2", vminfo)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(assignments).To(gomega.Equal([]migratev1alpha1.SharedDiskAssignment{{
+				DiskName: "Hard disk 2", SharedDisk: "data", OwnerVMs: []string{"vm-1", "vm-2"}, Copy: false,
+			}}))
+
+			vminfo = &vmwareMachine("vm-1", "data").Spec.VMInfo
+			assignments, err = GetVMSharedDisks(ctx, k8sClient, plan(), "vm-1", vminfo)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(assignments[0].Copy).To(gomega.BeTrue())
+		})
+
+		ginkgo.It("rejects owner VMs migrated in another group", func() {
+			gomega.Expect(k8sClient.Create(ctx, sharedDisk("data", "vm-1", "vm-3"))).To(gomega.Succeed())
+
+			_, err := GetVMSharedDisks(ctx, k8sClient, plan(), "vm-1", &vmwareMachine("vm-1", "data").Spec.VMInfo)
+			gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("[vm-3] that are not migrated in the same group")))
+		})
+	})
+
+	ginkgo.Describe("GetSharedDiskCutover", func() {
+		ginkgo.BeforeEach(func() {
+			gomega.Expect(k8sClient.Create(ctx, sharedDisk("data", "vm-1", "vm-2"))).To(gomega.Succeed())
+			gomega.Expect(k8sClient.Create(ctx, vmwareMachine("vm-1", "data"))).To(gomega.Succeed())
+			gomega.Expect(k8sClient.Create(ctx, vmwareMachine("vm-3"))).To(gomega.Succeed())
+		})
+
+		ginkgo.It("returns nil for a VM that shares no disks", func() {
+			cutover, err := GetSharedDiskCutover(ctx, k8sClient, migration("vm-3", migratev1alpha1.VMMigrationPhaseAwaitingAdminCutOver))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(cutover).To(gomega.BeNil())
+		})
+
+		ginkgo.It("waits for owner VMs without a migration or still copying", func() {
+			cutover, err := GetSharedDiskCutover(ctx, k8sClient, migration("vm-1", migratev1alpha1.VMMigrationPhaseAwaitingAdminCutOver))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(cutover.NotReady).To(gomega.Equal([]string{"vm-2"}))
+
+			gomega.Expect(k8sClient.Create(ctx, migration("vm-2", migratev1alpha1.VMMigrationPhaseCopying))).To(gomega.Succeed())
+			cutover, err = GetSharedDiskCutover(ctx, k8sClient, migration("vm-1", migratev1alpha1.VMMigrationPhaseAwaitingAdminCutOver))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(cutover.NotReady).To(gomega.Equal([]string{"vm-2"}))
+		})
+
+		ginkgo.It("reports the slot and the cutover of the other owner VMs", func() {
+			other := migration("vm-2", migratev1alpha1.VMMigrationPhaseAwaitingAdminCutOver)
+			gomega.Expect(k8sClient.Create(ctx, other)).To(gomega.Succeed())
+			gomega.Expect(k8sClient.Create(ctx, pod("vm-2", constants.StartCutOverNo))).To(gomega.Succeed())
+
+			cutover, err := GetSharedDiskCutover(ctx, k8sClient, migration("vm-1", migratev1alpha1.VMMigrationPhaseAwaitingAdminCutOver))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(*cutover).To(gomega.Equal(SharedDiskCutover{}))
+
+			other.Status.Conditions = []corev1.PodCondition{{Type: constants.MigrationConditionTypeCutoverSlot, Status: corev1.ConditionTrue}}
+			gomega.Expect(k8sClient.Status().Update(ctx, other)).To(gomega.Succeed())
+			cutover, err = GetSharedDiskCutover(ctx, k8sClient, migration("vm-1", migratev1alpha1.VMMigrationPhaseAwaitingAdminCutOver))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(*cutover).To(gomega.Equal(SharedDiskCutover{SlotHeld: true}))
+
+			gomega.Expect(k8sClient.Update(ctx, pod("vm-2", constants.StartCutOverYes))).To(gomega.Succeed())
+			cutover, err = GetSharedDiskCutover(ctx, k8sClient, migration("vm-1", migratev1alpha1.VMMigrationPhaseAwaitingAdminCutOver))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(*cutover).To(gomega.Equal(SharedDiskCutover{SlotHeld: true, Started: true}))
+		})
+	})
+
+	ginkgo.Describe("SetSharedDisksCopied and ResetSharedDisks", func() {
+		ginkgo.It("records the attempt that copied the disk and resets only its copies", func() {
+			gomega.Expect(k8sClient.Create(ctx, sharedDisk("data", "vm-1", "vm-2"))).To(gomega.Succeed())
+			gomega.Expect(k8sClient.Create(ctx, sharedDisk("logs", "vm-1", "vm-2"))).To(gomega.Succeed())
+			copier := migration("vm-1", migratev1alpha1.VMMigrationPhaseCopying)
+			gomega.Expect(SetSharedDisksCopied(ctx, k8sClient, copier, map[string]string{"data": "volume-1"})).To(gomega.Succeed())
+
+			disk := &migratev1alpha1.SharedDisk{}
+			gomega.Expect(k8sClient.Get(ctx, k8stypes.NamespacedName{Name: "data", Namespace: namespace}, disk)).To(gomega.Succeed())
+			gomega.Expect(disk.Status).To(gomega.Equal(migratev1alpha1.SharedDiskStatus{
+				Phase:          migratev1alpha1.SharedDiskPhaseCopied,
+				CopiedBy:       "migration-vm-1",
+				CopiedByUID:    "uid-vm-1",
+				CinderVolumeID: "volume-1",
+			}))
+
+			retried := migration("vm-1", migratev1alpha1.VMMigrationPhaseFailed)
+			retried.UID = "uid-vm-1-retry"
+			gomega.Expect(ResetSharedDisks(ctx, k8sClient, retried)).To(gomega.Succeed())
+			gomega.Expect(k8sClient.Get(ctx, k8stypes.NamespacedName{Name: "data", Namespace: namespace}, disk)).To(gomega.Succeed())
+			gomega.Expect(disk.Status.Phase).To(gomega.Equal(migratev1alpha1.SharedDiskPhaseCopied))
+
+			gomega.Expect(ResetSharedDisks(ctx, k8sClient, copier)).To(gomega.Succeed())
+			gomega.Expect(k8sClient.Get(ctx, k8stypes.NamespacedName{Name: "data", Namespace: namespace}, disk)).To(gomega.Succeed())
+			gomega.Expect(disk.Status).To(gomega.Equal(migratev1alpha1.SharedDiskStatus{Phase: migratev1alpha1.SharedDiskPhaseAvailable}))
+		})
+	})
+
+	ginkgo.Describe("SyncSharedDisks", func() {
+		var credsScope *scope.VMwareCredsScope
+
+		ginkgo.BeforeEach(func() {
+			var err error
+			credsScope, err = scope.NewVMwareCredsScope(scope.VMwareCredsScopeParams{
+				Client:      k8sClient,
+				VMwareCreds: &migratev1alpha1.VMwareCreds{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: namespace}},
+			})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		})
+
+		vminfo := func(vm string, disks ...string) migratev1alpha1.VMInfo {
+			info := migratev1alpha1.VMInfo{Name: vm, Disks: []string{"Hard disk 1", "Hard disk 2"}, DiskSizes: []int64{10, 20}}
+			for _, disk := range disks {
+				info.SharedDisks = append(info.SharedDisks,
+					migratev1alpha1.SharedDiskInfo{DiskName: "Hard disk 2", FileName: "[ds] " + disk + ".vmdk", SharedDisk: disk})
+			}
+			return info
+		}
+
+		ginkgo.It("creates the shared disks of the scanned VMs with their owners", func() {
+			gomega.Expect(SyncSharedDisks(ctx, credsScope, []migratev1alpha1.VMInfo{
+				vminfo("vm-2", "data"), vminfo("vm-1", "data"), vminfo("vm-3"),
+			})).To(gomega.Succeed())
+
+			disk := &migratev1alpha1.SharedDisk{}
+			gomega.Expect(k8sClient.Get(ctx, k8stypes.NamespacedName{Name: "data", Namespace: namespace}, disk)).To(gomega.Succeed())
+			gomega.Expect(disk.Spec).To(gomega.Equal(migratev1alpha1.SharedDiskSpec{
+				FileName: "[ds] data.vmdk", DiskSize: 20, OwnerVMs: []string{"vm-1", "vm-2"},
+			}))
+			gomega.Expect(disk.Labels).To(gomega.HaveKeyWithValue(constants.VMwareCredsLabel, "creds"))
+			gomega.Expect(disk.Status.Phase).To(gomega.Equal(migratev1alpha1.SharedDiskPhaseAvailable))
+		})
+
+		ginkgo.It("updates the owners and deletes stale shared disks unless they were copied", func() {
+			gomega.Expect(k8sClient.Create(ctx, sharedDisk("data", "vm-1", "vm-2"))).To(gomega.Succeed())
+			gomega.Expect(k8sClient.Create(ctx, sharedDisk("stale", "vm-1", "vm-2"))).To(gomega.Succeed())
+			copied := sharedDisk("copied", "vm-1", "vm-2")
+			gomega.Expect(k8sClient.Create(ctx, copied)).To(gomega.Succeed())
+			copied.Status.Phase = migratev1alpha1.SharedDiskPhaseCopied
+			gomega.Expect(k8sClient.Status().Update(ctx, copied)).To(gomega.Succeed())
+
+			gomega.Expect(SyncSharedDisks(ctx, credsScope, []migratev1alpha1.VMInfo{
+				vminfo("vm-1", "data"), vminfo("vm-2", "data"), vminfo("vm-3", "data"),
+			})).To(gomega.Succeed())
+
+			disk := &migratev1alpha1.SharedDisk{}
+			gomega.Expect(k8sClient.Get(ctx, k8stypes.NamespacedName{Name: "data", Namespace: namespace}, disk)).To(gomega.Succeed())
+			gomega.Expect(disk.Spec.OwnerVMs).To(gomega.Equal([]string{"vm-1", "vm-2", "vm-3"}))
+			err := k8sClient.Get(ctx, k8stypes.NamespacedName{Name: "stale", Namespace: namespace}, disk)
+			gomega.Expect(apierrors.IsNotFound(err)).To(gomega.BeTrue())
+			gomega.Expect(k8sClient.Get(ctx, k8stypes.NamespacedName{Name: "copied", Namespace: namespace}, disk)).To(gomega.Succeed())
+		})
+	})
+})
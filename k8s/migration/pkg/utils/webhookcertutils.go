@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/kashyapshashankv/stellaris-migrate/k8s/migration/pkg/constants"
+	"github.com/pkg/errors"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctxlog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// webhookCAKey is the key of the CA in the webhook certificate secret, as used by cert-manager
+const webhookCAKey = "ca.crt"
+
+// webhookServiceDNSName is the name the API server uses to reach the webhooks
+var webhookServiceDNSName = fmt.Sprintf("%s.%s.svc", constants.WebhookServiceName, constants.NamespaceMigrationSystem)
+
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations;validatingwebhookconfigurations,verbs=get;list;watch;update;patch
+
+// EnsureWebhookCertificates makes sure the webhook server has a serving certificate the API server trusts.
+// The appliance does not run cert-manager, so a self-signed CA and serving certificate are kept in a secret,
+// regenerated when they are about to expire, written to certDir and injected into the webhook configurations.
+// reader must not be backed by the cache, as the manager is not started yet.
+func EnsureWebhookCertificates(ctx context.Context, reader client.Reader, k8sClient client.Client, certDir string) error {
+	log := ctxlog.FromContext(ctx)
+
+	secret := &corev1.Secret{}
+	err := reader.Get(ctx, k8stypes.NamespacedName{
+		Name:      constants.WebhookCertSecretName,
+		Namespace: constants.NamespaceMigrationSystem,
+	}, secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to get webhook certificate secret")
+	}
+	notFound := apierrors.IsNotFound(err)
+
+	if notFound || !webhookCertificateValid(secret.Data[corev1.TLSCertKey], time.Now()) {
+		log.Info("Generating webhook serving certificate", "secret", constants.WebhookCertSecretName)
+		caPEM, certPEM, keyPEM, err := generateWebhookCertificates(time.Now())
+		if err != nil {
+			return errors.Wrap(err, "failed to generate webhook certificates")
+		}
+		data := map[string][]byte{
+			webhookCAKey:            caPEM,
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+		}
+		if notFound {
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      constants.WebhookCertSecretName,
+					Namespace: constants.NamespaceMigrationSystem,
+				},
+				Type: corev1.SecretTypeTLS,
+				Data: data,
+			}
+			if err := k8sClient.Create(ctx, secret); err != nil {
+				return errors.Wrap(err, "failed to create webhook certificate secret")
+			}
+		} else {
+			secret.Data = data
+			if err := k8sClient.Update(ctx, secret); err != nil {
+				return errors.Wrap(err, "failed to update webhook certificate secret")
+			}
+		}
+	}
+
+	if err := os.MkdirAll(certDir, 0o700); err != nil {
+		return errors.Wrapf(err, "failed to create webhook certificate directory %s", certDir)
+	}
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		if err := os.WriteFile(filepath.Join(certDir, key), secret.Data[key], 0o600); err != nil {
+			return errors.Wrapf(err, "failed to write webhook %s", key)
+		}
+	}
+
+	return injectWebhookCABundle(ctx, reader, k8sClient, secret.Data[webhookCAKey])
+}
+
+// webhookCertificateValid checks that the serving certificate is for the webhook service and does not expire soon
+func webhookCertificateValid(certPEM []byte, now time.Time) bool {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(cert.DNSNames, webhookServiceDNSName) &&
+		now.Add(constants.WebhookCertRenewBefore).Before(cert.NotAfter)
+}
+
+// generateWebhookCertificates generates a CA and a serving certificate for the webhook service signed by it
+func generateWebhookCertificates(now time.Time) (caPEM, certPEM, keyPEM []byte, err error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to generate CA key")
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(now.UnixNano()),
+		Subject:               pkix.Name{CommonName: "stellaris-migrate-webhook-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(constants.WebhookCertValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to create CA certificate")
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to parse CA certificate")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to generate serving key")
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(now.UnixNano() + 1),
+		Subject:      pkix.Name{CommonName: webhookServiceDNSName},
+		DNSNames: []string{
+			constants.WebhookServiceName,
+			fmt.Sprintf("%s.%s", constants.WebhookServiceName, constants.NamespaceMigrationSystem),
+			webhookServiceDNSName,
+			webhookServiceDNSName + ".cluster.local",
+		},
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(constants.WebhookCertValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to create serving certificate")
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to encode serving key")
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		nil
+}
+
+// injectWebhookCABundle sets the CA of the webhooks in the mutating and validating webhook configurations
+func injectWebhookCABundle(ctx context.Context, reader client.Reader, k8sClient client.Client, caPEM []byte) error {
+	log := ctxlog.FromContext(ctx)
+
+	mutating := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	if err := reader.Get(ctx, k8stypes.NamespacedName{Name: constants.MutatingWebhookConfigurationName}, mutating); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "failed to get mutating webhook configuration")
+		}
+		log.Info("Mutating webhook configuration not found, skipping CA injection", "name", constants.MutatingWebhookConfigurationName)
+	} else {
+		changed := false
+		for i := range mutating.Webhooks {
+			if !bytes.Equal(mutating.Webhooks[i].ClientConfig.CABundle, caPEM) {
+				mutating.Webhooks[i].ClientConfig.CABundle = caPEM
+				changed = true
+			}
+		}
+		if changed {
+			if err := k8sClient.Update(ctx, mutating); err != nil {
+				return errors.Wrap(err, "failed to inject CA into mutating webhook configuration")
+			}
+		}
+	}
+
+	validating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := reader.Get(ctx, k8stypes.NamespacedName{Name: constants.ValidatingWebhookConfigurationName}, validating); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "failed to get validating webhook configuration")
+		}
+		log.Info("Validating webhook configuration not found, skipping CA injection", "name", constants.ValidatingWebhookConfigurationName)
+		return nil
+	}
+	changed := false
+	for i := range validating.Webhooks {
+		if !bytes.Equal(validating.Webhooks[i].ClientConfig.CABundle, caPEM) {
+			validating.Webhooks[i].ClientConfig.CABundle = caPEM
+			changed = true
+		}
+	}
+	if changed {
+		if err := k8sClient.Update(ctx, validating); err != nil {
+			return errors.Wrap(err, "failed to inject CA into validating webhook configuration")
+		}
+	}
+	return nil
+}
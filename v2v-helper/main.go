@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/nbd"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/openstack"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/metrics"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/reporter"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vcenter"
@@ -50,14 +52,29 @@ func main() {
 		return
 	}
 
+	// Serve the migration metrics, Prometheus finds the port in the pod annotations
+	metricsPort, err := metrics.Serve(":0")
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to serve metrics: %v", err))
+	} else if err := eventReporter.SetPodAnnotation(constants.PrometheusPortAnnotation, strconv.Itoa(metricsPort)); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to publish metrics port: %v", err))
+	} else {
+		utils.PrintLog(fmt.Sprintf("Serving metrics on port %d", metricsPort))
+		// Give Prometheus a chance to scrape the final values before the pod exits. Every exit after this point
+		// returns from main, including the ones reporting an error.
+		defer time.Sleep(constants.MetricsScrapeGracePeriod)
+	}
+
 	client, err := utils.GetInclusterClient()
 	if err != nil {
 		handleError(fmt.Sprintf("Failed to get in-cluster client: %v", err))
+		return
 	}
 
 	migrationparams, err := utils.GetMigrationParams(ctx, client)
 	if err != nil {
 		handleError(fmt.Sprintf("Failed to get migration parameters: %v", err))
+		return
 	}
 
 	utils.WriteToLogFile(fmt.Sprintf("-----	 Migration started at %s for VM %s -----", time.Now().Format(time.RFC3339), migrationparams.SourceVMName))
@@ -78,11 +95,52 @@ func main() {
 	starttime, _ := time.Parse(time.RFC3339, migrationparams.DataCopyStart)
 	cutstart, _ := time.Parse(time.RFC3339, migrationparams.VMcutoverStart)
 	cutend, _ := time.Parse(time.RFC3339, migrationparams.VMcutoverEnd)
+	copyWindow, err := migrate.ParseCopyWindow(migrationparams.CopyWindowStart, migrationparams.CopyWindowEnd, migrationparams.CopyWindowTimeZone)
+	if err != nil {
+		handleError(fmt.Sprintf("Failed to parse copy window: %v", err))
+		return
+	}
+	cutoverWindow, err := migrate.ParseCutoverWindow(migrationparams.CutoverWindowStart, migrationparams.CutoverWindowEnd,
+		migrationparams.CutoverWindowTimeZone, migrationparams.CutoverWindowDays)
+	if err != nil {
+		handleError(fmt.Sprintf("Failed to parse cutover window: %v", err))
+		return
+	}
+	// Plans created before the finalize duration existed keep no time free
+	cutoverFinalize, _ := time.ParseDuration(migrationparams.CutoverFinalize)
+	networkOverrides, err := migrate.ParseNetworkOverrides(migrationparams.NetworkOverrides)
+	if err != nil {
+		handleError(fmt.Sprintf("Failed to parse network overrides: %v", err))
+		return
+	}
+	diskOverride, err := migrate.ParseDiskOverride(migrationparams.DiskOverride)
+	if err != nil {
+		handleError(fmt.Sprintf("Failed to parse disk override: %v", err))
+		return
+	}
+	sharedDisks, err := migrate.ParseSharedDisks(migrationparams.SharedDisks)
+	if err != nil {
+		handleError(fmt.Sprintf("Failed to parse shared disks: %v", err))
+		return
+	}
+	hooks, err := migrate.ParseHooks(migrationparams.Hooks)
+	if err != nil {
+		handleError(fmt.Sprintf("Failed to parse hooks: %v", err))
+		return
+	}
+	// Plans created before quiesced snapshots existed fall back to the default timeout
+	quiesceTimeout, _ := time.ParseDuration(migrationparams.QuiesceTimeout)
+	testBoot, err := migrate.ParseTestBoot(migrationparams.TestBoot, migrationparams.TestBootCIDR, migrationparams.TestBootTimeout)
+	if err != nil {
+		handleError(fmt.Sprintf("Failed to parse test boot: %v", err))
+		return
+	}
 
 	// Validate vCenter connection
 	vcclient, err := vcenter.VCenterClientBuilder(ctx, vCenterUserName, vCenterPassword, vCenterURL, vCenterInsecure)
 	if err != nil {
 		handleError(fmt.Sprintf("Failed to validate vCenter connection: %v", err))
+		return
 	}
 	utils.PrintLog(fmt.Sprintf("Connected to vCenter: %s\n", vCenterURL))
 	defer vcclient.VCClient.CloseIdleConnections()
@@ -90,6 +148,7 @@ func main() {
 	openstackclients, err := openstack.NewOpenStackClients(openstackInsecure)
 	if err != nil {
 		handleError(fmt.Sprintf("Failed to validate OpenStack connection: %v", err))
+		return
 	}
 	utils.PrintLog("Connected to OpenStack")
 
@@ -97,6 +156,7 @@ func main() {
 	thumbprint, err := vcenter.GetThumbprint(vCenterURL)
 	if err != nil {
 		handleError(fmt.Sprintf("Failed to get thumbprint: %s", err))
+		return
 	}
 	utils.PrintLog(fmt.Sprintf("VCenter Thumbprint: %s\n", thumbprint))
 
@@ -104,6 +164,7 @@ func main() {
 	vmops, err := vm.VMOpsBuilder(ctx, *vcclient, migrationparams.SourceVMName, client)
 	if err != nil {
 		handleError(fmt.Sprintf("Failed to get source VM: %v", err))
+		return
 	}
 
 	migrationobj := migrate.Migrate{
@@ -131,17 +192,32 @@ func main() {
 			VMCutoverStart: cutstart,
 			VMCutoverEnd:   cutend,
 		},
-		MigrationType:          migrationparams.MigrationType,
-		PerformHealthChecks:    migrationparams.PerformHealthChecks,
-		HealthCheckPort:        migrationparams.HealthCheckPort,
-		K8sClient:              client,
-		TargetFlavorId:         migrationparams.TARGET_FLAVOR_ID,
-		TargetAvailabilityZone: migrationparams.TargetAvailabilityZone,
-		AssignedIP:             migrationparams.AssignedIP,
-		SecurityGroups:         utils.RemoveEmptyStrings(strings.Split(migrationparams.SecurityGroups, ",")),
-		UseFlavorless:          os.Getenv("USE_FLAVORLESS") == "true",
-		TenantName:             openstackProjectName,
-		Reporter:               eventReporter,
+		MigrationType:           migrationparams.MigrationType,
+		PerformHealthChecks:     migrationparams.PerformHealthChecks,
+		HealthCheckPort:         migrationparams.HealthCheckPort,
+		K8sClient:               client,
+		TargetFlavorId:          migrationparams.TARGET_FLAVOR_ID,
+		TargetAvailabilityZone:  migrationparams.TargetAvailabilityZone,
+		AssignedIP:              migrationparams.AssignedIP,
+		NetworkOverrides:        networkOverrides,
+		ReconfigureGuestNetwork: migrationparams.ReconfigureGuestNetwork,
+		SecurityGroups:          utils.RemoveEmptyStrings(strings.Split(migrationparams.SecurityGroups, ",")),
+		UseFlavorless:           os.Getenv("USE_FLAVORLESS") == "true",
+		TenantName:              openstackProjectName,
+		Reporter:                eventReporter,
+		BandwidthLimitMbps:      migrationparams.BandwidthLimitMbps,
+		CopyWindow:              copyWindow,
+		DataVerification:        migrationparams.DataVerification,
+		VTPMPolicy:              migrationparams.VTPMPolicy,
+		CutoverGated:            os.Getenv("CUTOVER_GATED") == "true",
+		CutoverWindow:           cutoverWindow,
+		CutoverFinalizeDuration: cutoverFinalize,
+		Hooks:                   hooks,
+		TestBoot:                testBoot,
+		DiskOverride:            diskOverride,
+		SharedDisks:             sharedDisks,
+		SnapshotConsistency:     migrationparams.SnapshotConsistency,
+		QuiesceTimeout:          quiesceTimeout,
 	}
 
 	if err := migrationobj.MigrateVM(ctx); err != nil {
@@ -0,0 +1,359 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/volumes"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils/migrateutils"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// allocatedAreasChangeID makes QueryChangedDiskAreas return all allocated areas of the disk
+const allocatedAreasChangeID = "*"
+
+// loadCopyCheckpoint reads the copy progress saved by an earlier attempt of this migration
+func (migobj *Migrate) loadCopyCheckpoint(ctx context.Context) {
+	if migobj.K8sClient == nil {
+		return
+	}
+	checkpoint, err := utils.GetCopyCheckpoint(ctx, migobj.K8sClient)
+	if err != nil {
+		migobj.logMessage(fmt.Sprintf("Failed to read copy checkpoint, disks will be copied from scratch: %v", err))
+		checkpoint = &utils.CopyCheckpoint{}
+	}
+	if len(checkpoint.Disks) > 0 {
+		migobj.logMessage(fmt.Sprintf("Found copy checkpoint for %d disk(s) from an earlier attempt", len(checkpoint.Disks)))
+	}
+	migobj.checkpointMu.Lock()
+	defer migobj.checkpointMu.Unlock()
+	migobj.checkpoint = checkpoint
+}
+
+// updateCopyCheckpoint applies update to the copy progress and persists it. Disks are copied
+// concurrently, so updates are serialized. Failing to save a checkpoint only means a later
+// attempt copies more data, so errors are logged and not returned.
+func (migobj *Migrate) updateCopyCheckpoint(ctx context.Context, update func(checkpoint *utils.CopyCheckpoint)) {
+	migobj.checkpointMu.Lock()
+	defer migobj.checkpointMu.Unlock()
+	if migobj.checkpoint == nil {
+		return
+	}
+	update(migobj.checkpoint)
+	if err := utils.SaveCopyCheckpoint(ctx, migobj.K8sClient, migobj.checkpoint); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to save copy checkpoint: %v", err))
+	}
+}
+
+// getDiskCheckpoint returns a copy of the progress recorded for a disk
+func (migobj *Migrate) getDiskCheckpoint(name string) (utils.DiskCheckpoint, bool) {
+	migobj.checkpointMu.Lock()
+	defer migobj.checkpointMu.Unlock()
+	if migobj.checkpoint == nil {
+		return utils.DiskCheckpoint{}, false
+	}
+	diskCheckpoint := migobj.checkpoint.GetDisk(name)
+	if diskCheckpoint == nil {
+		return utils.DiskCheckpoint{}, false
+	}
+	return *diskCheckpoint, true
+}
+
+// hasCopyCheckpoint reports whether a disk has recorded copy progress that a later attempt can resume from, which
+// is what keeping the volumes of a failed attempt is worth
+func (migobj *Migrate) hasCopyCheckpoint() bool {
+	migobj.checkpointMu.Lock()
+	defer migobj.checkpointMu.Unlock()
+	if migobj.checkpoint == nil {
+		return false
+	}
+	for _, disk := range migobj.checkpoint.Disks {
+		if disk.FullCopyCompleted || len(disk.CompletedRanges) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// clearCopyCheckpoint removes the copy progress once the volumes no longer match the source
+// snapshot, i.e. after the copy has finished or the volumes have been deleted
+func (migobj *Migrate) clearCopyCheckpoint(ctx context.Context) {
+	migobj.checkpointMu.Lock()
+	defer migobj.checkpointMu.Unlock()
+	if migobj.checkpoint == nil {
+		return
+	}
+	migobj.checkpoint = nil
+	if err := utils.DeleteCopyCheckpoint(ctx, migobj.K8sClient); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to delete copy checkpoint: %v", err))
+	}
+}
+
+// getCheckpointVolume returns the volume recorded for the disk by an earlier attempt,
+// if it still exists and can hold the disk
+func (migobj *Migrate) getCheckpointVolume(vmdisk vm.VMDisk) *volumes.Volume {
+	diskCheckpoint, ok := migobj.getDiskCheckpoint(vmdisk.Name)
+	if !ok || diskCheckpoint.VolumeID == "" {
+		return nil
+	}
+	openstackops := migobj.Openstackclients
+	volume, err := openstackops.GetVolume(diskCheckpoint.VolumeID)
+	if err != nil {
+		migobj.logMessage(fmt.Sprintf("Volume %s from checkpoint of disk %s not usable: %v", diskCheckpoint.VolumeID, vmdisk.Name, err))
+		return nil
+	}
+	if volume.Status == "error" || int64(volume.Size) < int64(math.Ceil(float64(vmdisk.Size)/(1024*1024*1024))) {
+		migobj.logMessage(fmt.Sprintf("Volume %s from checkpoint of disk %s not usable: status %s, size %dGB", volume.ID, vmdisk.Name, volume.Status, volume.Size))
+		return nil
+	}
+	// The volume may still be attached to the helper VM of the earlier attempt
+	instanceID, err := migrateutils.GetCurrentInstanceUUID()
+	if err != nil {
+		migobj.logMessage(fmt.Sprintf("Failed to get instance ID, not reusing volume %s: %v", volume.ID, err))
+		return nil
+	}
+	for _, attachment := range volume.Attachments {
+		if attachment.ServerID == instanceID {
+			continue
+		}
+		if err := openstackops.DetachVolumeFromServer(attachment.ServerID, volume.ID); err != nil {
+			migobj.logMessage(fmt.Sprintf("Failed to release volume %s from server %s, not reusing it: %v", volume.ID, attachment.ServerID, err))
+			return nil
+		}
+	}
+	return volume
+}
+
+// isDiskResumable reports whether an earlier attempt copied data to the volume of the disk
+func (migobj *Migrate) isDiskResumable(vmdisk vm.VMDisk) bool {
+	diskCheckpoint, ok := migobj.getDiskCheckpoint(vmdisk.Name)
+	if !ok || vmdisk.OpenstackVol == nil || diskCheckpoint.VolumeID != vmdisk.OpenstackVol.ID {
+		return false
+	}
+	if diskCheckpoint.FullCopyCompleted {
+		return diskCheckpoint.ChangeID != ""
+	}
+	return diskCheckpoint.BaseChangeID != ""
+}
+
+// markFullCopyStarted records the change ID of the snapshot the full copy of a disk is taken from
+func (migobj *Migrate) markFullCopyStarted(ctx context.Context, vmdisk vm.VMDisk) {
+	if vmdisk.OpenstackVol == nil {
+		return
+	}
+	migobj.updateCopyCheckpoint(ctx, func(checkpoint *utils.CopyCheckpoint) {
+		checkpoint.SetDisk(utils.DiskCheckpoint{
+			Name:         vmdisk.Name,
+			VolumeID:     vmdisk.OpenstackVol.ID,
+			BaseChangeID: vmdisk.ChangeID,
+		})
+	})
+}
+
+// markDiskSynced records that the volume of a disk matches the snapshot with the disk's change ID
+func (migobj *Migrate) markDiskSynced(ctx context.Context, vmdisk vm.VMDisk) {
+	if vmdisk.OpenstackVol == nil {
+		return
+	}
+	migobj.updateCopyCheckpoint(ctx, func(checkpoint *utils.CopyCheckpoint) {
+		checkpoint.SetDisk(utils.DiskCheckpoint{
+			Name:              vmdisk.Name,
+			VolumeID:          vmdisk.OpenstackVol.ID,
+			FullCopyCompleted: true,
+			ChangeID:          vmdisk.ChangeID,
+		})
+	})
+}
+
+// resumeDiskCopy brings the volume of a disk in line with the current migration snapshot,
+// starting from the progress recorded by an earlier attempt instead of copying the whole disk.
+// It returns the number of bytes copied.
+func (migobj *Migrate) resumeDiskCopy(ctx context.Context, vminfo vm.VMInfo, idx int) (int64, error) {
+	vmdisk := vminfo.VMDisks[idx]
+	diskCheckpoint, ok := migobj.getDiskCheckpoint(vmdisk.Name)
+	if !ok {
+		return 0, errors.Errorf("no checkpoint found for disk %s", vmdisk.Name)
+	}
+
+	snapshot, err := migobj.getSnapshot(constants.MigrationSnapshotName)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get snapshot")
+	}
+
+	var extents []types.DiskChangeExtent
+	if diskCheckpoint.FullCopyCompleted {
+		migobj.logMessage(fmt.Sprintf("Disk %d: full copy completed by an earlier attempt, copying blocks changed since %s", idx, diskCheckpoint.ChangeID))
+		changedAreas, err := migobj.queryChangedDiskAreas(diskCheckpoint.ChangeID, snapshot, vmdisk.Disk)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to get changed disk areas")
+		}
+		extents = changedAreas.ChangedArea
+	} else {
+		// Everything allocated that is not yet on the volume, plus whatever changed on the
+		// source since the interrupted copy started, which covers stale completed ranges
+		migobj.logMessage(fmt.Sprintf("Disk %d: resuming interrupted full copy, %d range(s) already copied", idx, len(diskCheckpoint.CompletedRanges)))
+		allocatedAreas, err := migobj.queryChangedDiskAreas(allocatedAreasChangeID, snapshot, vmdisk.Disk)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to get allocated disk areas")
+		}
+		changedAreas, err := migobj.queryChangedDiskAreas(diskCheckpoint.BaseChangeID, snapshot, vmdisk.Disk)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to get changed disk areas")
+		}
+		extents = subtractRanges(allocatedAreas.ChangedArea, diskCheckpoint.CompletedRanges)
+		extents = mergeExtents(append(extents, changedAreas.ChangedArea...))
+	}
+
+	copied, err := migobj.copyDiskExtents(ctx, vmdisk, idx, extents, diskCheckpoint)
+	if err != nil {
+		return copied, err
+	}
+	migobj.markDiskSynced(ctx, vmdisk)
+	return copied, nil
+}
+
+// copyDiskExtents copies extents of a disk to its volume in batches and returns the number of bytes copied. Until
+// the full copy of the disk has completed, the copied ranges are recorded in its checkpoint after each batch, so
+// that an interrupted copy resumes after them.
+func (migobj *Migrate) copyDiskExtents(ctx context.Context, vmdisk vm.VMDisk, idx int, extents []types.DiskChangeExtent, diskCheckpoint utils.DiskCheckpoint) (int64, error) {
+	var total, copied int64
+	for _, extent := range extents {
+		total += extent.Length
+	}
+	for _, batch := range batchExtents(extents, constants.CopyCheckpointBatchSize) {
+		changeInfo := types.DiskChangeInfo{ChangedArea: batch}
+		for _, extent := range batch {
+			changeInfo.Length += extent.Length
+		}
+		migobj.setDiskProgressWindow(idx, copied, changeInfo.Length, total)
+		if err := migobj.Nbdops[idx].CopyChangedBlocks(ctx, changeInfo, vmdisk.Path, idx); err != nil {
+			return copied, errors.Wrap(err, "failed to copy blocks")
+		}
+		copied += changeInfo.Length
+		if !diskCheckpoint.FullCopyCompleted {
+			for _, extent := range batch {
+				diskCheckpoint.CompletedRanges = append(diskCheckpoint.CompletedRanges, utils.ByteRange{Start: extent.Start, Length: extent.Length})
+			}
+			diskCheckpoint.CompletedRanges = mergeRanges(diskCheckpoint.CompletedRanges)
+			migobj.updateCopyCheckpoint(ctx, func(checkpoint *utils.CopyCheckpoint) {
+				checkpoint.SetDisk(diskCheckpoint)
+			})
+		}
+	}
+	return copied, nil
+}
+
+// copyAllocatedExtents performs the full copy of a disk by copying its allocated areas through
+// copyDiskExtents, so that the progress of the copy is checkpointed, and returns the number of bytes copied.
+// It returns false without copying anything if the allocated areas of the disk cannot be queried.
+func (migobj *Migrate) copyAllocatedExtents(ctx context.Context, vmdisk vm.VMDisk, idx int) (bool, int64, error) {
+	diskCheckpoint, ok := migobj.getDiskCheckpoint(vmdisk.Name)
+	if !ok {
+		return false, 0, nil
+	}
+	snapshot, err := migobj.getSnapshot(constants.MigrationSnapshotName)
+	if err != nil {
+		migobj.logMessage(fmt.Sprintf("Disk %d: failed to get snapshot, copying the disk without checkpoints: %v", idx, err))
+		return false, 0, nil
+	}
+	allocatedAreas, err := migobj.queryChangedDiskAreas(allocatedAreasChangeID, snapshot, vmdisk.Disk)
+	if err != nil {
+		migobj.logMessage(fmt.Sprintf("Disk %d: failed to get allocated disk areas, copying the disk without checkpoints: %v", idx, err))
+		return false, 0, nil
+	}
+	copied, err := migobj.copyDiskExtents(ctx, vmdisk, idx, mergeExtents(allocatedAreas.ChangedArea), diskCheckpoint)
+	return true, copied, err
+}
+
+// mergeExtents sorts extents and merges the ones that overlap or touch
+func mergeExtents(extents []types.DiskChangeExtent) []types.DiskChangeExtent {
+	if len(extents) == 0 {
+		return extents
+	}
+	sorted := append([]types.DiskChangeExtent{}, extents...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	merged := []types.DiskChangeExtent{sorted[0]}
+	for _, extent := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if extent.Start <= last.Start+last.Length {
+			if end := extent.Start + extent.Length; end > last.Start+last.Length {
+				last.Length = end - last.Start
+			}
+			continue
+		}
+		merged = append(merged, extent)
+	}
+	return merged
+}
+
+// mergeRanges sorts byte ranges and merges the ones that overlap or touch
+func mergeRanges(ranges []utils.ByteRange) []utils.ByteRange {
+	extents := make([]types.DiskChangeExtent, 0, len(ranges))
+	for _, r := range ranges {
+		extents = append(extents, types.DiskChangeExtent{Start: r.Start, Length: r.Length})
+	}
+	merged := make([]utils.ByteRange, 0, len(ranges))
+	for _, extent := range mergeExtents(extents) {
+		merged = append(merged, utils.ByteRange{Start: extent.Start, Length: extent.Length})
+	}
+	return merged
+}
+
+// subtractRanges returns the parts of extents not covered by ranges
+func subtractRanges(extents []types.DiskChangeExtent, ranges []utils.ByteRange) []types.DiskChangeExtent {
+	covered := mergeRanges(ranges)
+	var result []types.DiskChangeExtent
+	for _, extent := range mergeExtents(extents) {
+		start, end := extent.Start, extent.Start+extent.Length
+		for _, r := range covered {
+			if r.Start+r.Length <= start || r.Start >= end {
+				continue
+			}
+			if r.Start > start {
+				result = append(result, types.DiskChangeExtent{Start: start, Length: r.Start - start})
+			}
+			start = r.Start + r.Length
+			if start >= end {
+				break
+			}
+		}
+		if start < end {
+			result = append(result, types.DiskChangeExtent{Start: start, Length: end - start})
+		}
+	}
+	return result
+}
+
+// batchExtents splits extents into batches of roughly batchSize bytes, splitting extents
+// that are larger than a batch
+func batchExtents(extents []types.DiskChangeExtent, batchSize int64) [][]types.DiskChangeExtent {
+	var batches [][]types.DiskChangeExtent
+	var batch []types.DiskChangeExtent
+	size := int64(0)
+	for _, extent := range extents {
+		for extent.Length > 0 {
+			length := extent.Length
+			if size+length > batchSize {
+				length = batchSize - size
+			}
+			batch = append(batch, types.DiskChangeExtent{Start: extent.Start, Length: length})
+			size += length
+			extent.Start += length
+			extent.Length -= length
+			if size >= batchSize {
+				batches = append(batches, batch)
+				batch, size = nil, 0
+			}
+		}
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
@@ -0,0 +1,200 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/nbd"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/volumes"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// diskBytesCopied returns the bytes copied of a disk recorded in the metrics
+func diskBytesCopied(t *testing.T, vmName, disk string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	assert.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "stellaris_migrate_disk_bytes_copied_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["vm"] == vmName && labels["disk"] == disk {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestMergeExtents(t *testing.T) {
+	merged := mergeExtents([]types.DiskChangeExtent{
+		{Start: 100, Length: 50},
+		{Start: 0, Length: 10},
+		{Start: 10, Length: 20},
+		{Start: 120, Length: 10},
+	})
+	assert.Equal(t, []types.DiskChangeExtent{
+		{Start: 0, Length: 30},
+		{Start: 100, Length: 50},
+	}, merged)
+}
+
+func TestSubtractRanges(t *testing.T) {
+	extents := []types.DiskChangeExtent{
+		{Start: 0, Length: 100},
+		{Start: 200, Length: 100},
+	}
+	completed := []utils.ByteRange{
+		{Start: 0, Length: 20},
+		{Start: 50, Length: 10},
+		{Start: 90, Length: 150},
+	}
+	assert.Equal(t, []types.DiskChangeExtent{
+		{Start: 20, Length: 30},
+		{Start: 60, Length: 30},
+		{Start: 240, Length: 60},
+	}, subtractRanges(extents, completed))
+	assert.Empty(t, subtractRanges(extents, []utils.ByteRange{{Start: 0, Length: 300}}))
+}
+
+func TestBatchExtents(t *testing.T) {
+	batches := batchExtents([]types.DiskChangeExtent{
+		{Start: 0, Length: 30},
+		{Start: 100, Length: 5},
+	}, 20)
+	assert.Equal(t, [][]types.DiskChangeExtent{
+		{{Start: 0, Length: 20}},
+		{{Start: 20, Length: 10}, {Start: 100, Length: 5}},
+	}, batches)
+}
+
+func TestIsDiskResumable(t *testing.T) {
+	disk := vm.VMDisk{Name: "disk1", OpenstackVol: &volumes.Volume{ID: "id1"}}
+	migobj := Migrate{}
+	assert.False(t, migobj.isDiskResumable(disk))
+
+	migobj.checkpoint = &utils.CopyCheckpoint{}
+	migobj.checkpoint.SetDisk(utils.DiskCheckpoint{Name: "disk1", VolumeID: "id1"})
+	assert.False(t, migobj.isDiskResumable(disk))
+
+	migobj.checkpoint.SetDisk(utils.DiskCheckpoint{Name: "disk1", VolumeID: "id1", BaseChangeID: "52 3c/4"})
+	assert.True(t, migobj.isDiskResumable(disk))
+
+	migobj.checkpoint.SetDisk(utils.DiskCheckpoint{Name: "disk1", VolumeID: "id2", FullCopyCompleted: true, ChangeID: "52 3c/8"})
+	assert.False(t, migobj.isDiskResumable(disk))
+	assert.Len(t, migobj.checkpoint.Disks, 1)
+}
+
+func TestResumeInterruptedFullCopy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	const batch = constants.CopyCheckpointBatchSize
+	snapshot := &types.ManagedObjectReference{Type: "VirtualMachineSnapshot", Value: "snapshot-1"}
+	disk := &types.VirtualDisk{}
+	vminfo := vm.VMInfo{Name: "resume-vm", VMDisks: []vm.VMDisk{{
+		Name:         "disk1",
+		Size:         3 * batch,
+		Path:         "/dev/vdb",
+		ChangeID:     "52 3c/4",
+		Disk:         disk,
+		OpenstackVol: &volumes.Volume{ID: "id1"},
+	}}}
+	allocated := types.DiskChangeInfo{ChangedArea: []types.DiskChangeExtent{{Start: 0, Length: 2 * batch}}}
+
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	mockNBD := nbd.NewMockNBDOperations(ctrl)
+	migobj := Migrate{VMops: mockVMOps, Nbdops: []nbd.NBDOperations{mockNBD}, checkpoint: &utils.CopyCheckpoint{}}
+	mockVMOps.EXPECT().GetSnapshot(constants.MigrationSnapshotName).Return(snapshot, nil).Times(2)
+	mockVMOps.EXPECT().CustomQueryChangedDiskAreas(allocatedAreasChangeID, snapshot, disk, int64(0)).Return(allocated, nil).Times(2)
+
+	// The first attempt copies the first batch and is interrupted during the second one
+	gomock.InOrder(
+		mockNBD.EXPECT().CopyChangedBlocks(gomock.Any(), types.DiskChangeInfo{
+			ChangedArea: []types.DiskChangeExtent{{Start: 0, Length: batch}}, Length: batch,
+		}, "/dev/vdb", 0).Return(nil),
+		mockNBD.EXPECT().CopyChangedBlocks(gomock.Any(), types.DiskChangeInfo{
+			ChangedArea: []types.DiskChangeExtent{{Start: batch, Length: batch}}, Length: batch,
+		}, "/dev/vdb", 0).Return(context.Canceled),
+	)
+	err := migobj.copyFullDisk(context.TODO(), vminfo, 0)
+	assert.ErrorIs(t, errors.Cause(err), context.Canceled)
+	assert.Zero(t, diskBytesCopied(t, "resume-vm", "0"))
+	assert.True(t, migobj.hasCopyCheckpoint())
+	assert.True(t, migobj.isDiskResumable(vminfo.VMDisks[0]))
+
+	// The retry only copies what was not copied, plus what changed on the source since the first attempt
+	mockVMOps.EXPECT().CustomQueryChangedDiskAreas("52 3c/4", snapshot, disk, int64(0)).Return(types.DiskChangeInfo{
+		ChangedArea: []types.DiskChangeExtent{{Start: 100, Length: 50}},
+	}, nil)
+	gomock.InOrder(
+		mockNBD.EXPECT().CopyChangedBlocks(gomock.Any(), types.DiskChangeInfo{
+			ChangedArea: []types.DiskChangeExtent{{Start: 100, Length: 50}, {Start: batch, Length: batch - 50}}, Length: batch,
+		}, "/dev/vdb", 0).Return(nil),
+		mockNBD.EXPECT().CopyChangedBlocks(gomock.Any(), types.DiskChangeInfo{
+			ChangedArea: []types.DiskChangeExtent{{Start: 2*batch - 50, Length: 50}}, Length: 50,
+		}, "/dev/vdb", 0).Return(nil),
+	)
+	vminfo.VMDisks[0].ChangeID = "52 3c/8"
+	assert.NoError(t, migobj.copyFullDisk(context.TODO(), vminfo, 0))
+
+	diskCheckpoint, ok := migobj.getDiskCheckpoint("disk1")
+	assert.True(t, ok)
+	assert.True(t, diskCheckpoint.FullCopyCompleted)
+	assert.Equal(t, "52 3c/8", diskCheckpoint.ChangeID)
+	// Only the bytes copied by the retry are recorded, not the size of the disk
+	assert.Equal(t, float64(batch+50), diskBytesCopied(t, "resume-vm", "0"))
+}
+
+func TestCopyFullDiskMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	snapshot := &types.ManagedObjectReference{Type: "VirtualMachineSnapshot", Value: "snapshot-1"}
+	disk := &types.VirtualDisk{}
+	vminfo := vm.VMInfo{Name: "metrics-vm", VMDisks: []vm.VMDisk{{
+		Name:         "disk1",
+		Size:         10 << 30,
+		Path:         "/dev/vdb",
+		Disk:         disk,
+		OpenstackVol: &volumes.Volume{ID: "id1"},
+	}}}
+	allocated := types.DiskChangeInfo{ChangedArea: []types.DiskChangeExtent{{Start: 0, Length: 1 << 20}, {Start: 4 << 20, Length: 1 << 20}}}
+
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	mockNBD := nbd.NewMockNBDOperations(ctrl)
+	migobj := Migrate{VMops: mockVMOps, Nbdops: []nbd.NBDOperations{mockNBD}, checkpoint: &utils.CopyCheckpoint{}}
+	mockVMOps.EXPECT().GetSnapshot(constants.MigrationSnapshotName).Return(snapshot, nil)
+	mockVMOps.EXPECT().CustomQueryChangedDiskAreas(allocatedAreasChangeID, snapshot, disk, int64(0)).Return(allocated, nil)
+	mockNBD.EXPECT().CopyChangedBlocks(gomock.Any(), gomock.Any(), "/dev/vdb", 0).Return(nil)
+
+	assert.NoError(t, migobj.copyFullDisk(context.TODO(), vminfo, 0))
+	// The allocated areas are copied, not the whole disk
+	assert.Equal(t, float64(2<<20), diskBytesCopied(t, "metrics-vm", "0"))
+}
+
+func TestHasCopyCheckpoint(t *testing.T) {
+	migobj := Migrate{}
+	assert.False(t, migobj.hasCopyCheckpoint())
+
+	// A checkpoint without progress, such as the one of a copy that has just started, is not worth the volumes
+	migobj.checkpoint = &utils.CopyCheckpoint{}
+	migobj.checkpoint.SetDisk(utils.DiskCheckpoint{Name: "disk1", VolumeID: "id1", BaseChangeID: "52 3c/4"})
+	assert.False(t, migobj.hasCopyCheckpoint())
+
+	migobj.checkpoint.SetDisk(utils.DiskCheckpoint{Name: "disk1", VolumeID: "id1", BaseChangeID: "52 3c/4",
+		CompletedRanges: []utils.ByteRange{{Start: 0, Length: 10}}})
+	assert.True(t, migobj.hasCopyCheckpoint())
+}
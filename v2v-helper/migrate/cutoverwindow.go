@@ -0,0 +1,160 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/pkg/errors"
+)
+
+// cutoverWindowDays maps the days of a cutover window to weekdays
+var cutoverWindowDays = map[string]time.Weekday{
+	"Sun": time.Sunday,
+	"Mon": time.Monday,
+	"Tue": time.Tuesday,
+	"Wed": time.Wednesday,
+	"Thu": time.Thursday,
+	"Fri": time.Friday,
+	"Sat": time.Saturday,
+}
+
+// CutoverWindow is a recurring time window in which the VM is cut over
+type CutoverWindow struct {
+	startMinute int
+	endMinute   int
+	// days are the weekdays on which the window opens, every day if empty
+	days     map[time.Weekday]bool
+	location *time.Location
+}
+
+// ParseCutoverWindow parses a cutover window given as HH:MM start and end times in an IANA time zone, opening on
+// the comma separated days. It returns nil if no window is set.
+func ParseCutoverWindow(start, end, timezone, days string) (*CutoverWindow, error) {
+	if start == "" && end == "" {
+		return nil, nil
+	}
+	copyWindow, err := ParseCopyWindow(start, end, timezone)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid cutover window")
+	}
+	if copyWindow.startMinute == copyWindow.endMinute {
+		return nil, errors.Errorf("cutover window %s-%s is empty", start, end)
+	}
+	window := &CutoverWindow{
+		startMinute: copyWindow.startMinute,
+		endMinute:   copyWindow.endMinute,
+		days:        map[time.Weekday]bool{},
+		location:    copyWindow.location,
+	}
+	for _, day := range strings.Split(days, ",") {
+		day = strings.TrimSpace(day)
+		if day == "" {
+			continue
+		}
+		weekday, ok := cutoverWindowDays[day]
+		if !ok {
+			return nil, errors.Errorf("invalid cutover window day %s", day)
+		}
+		window.days[weekday] = true
+	}
+	return window, nil
+}
+
+// occurrences returns the openings and closings of the window on the days around now, in order
+func (w *CutoverWindow) occurrences(now time.Time) (openings, closings []time.Time) {
+	now = now.In(w.location)
+	for offset := -1; offset <= 7; offset++ {
+		day := time.Date(now.Year(), now.Month(), now.Day()+offset, 0, 0, 0, 0, w.location)
+		if len(w.days) > 0 && !w.days[day.Weekday()] {
+			continue
+		}
+		opening := time.Date(day.Year(), day.Month(), day.Day(), 0, w.startMinute, 0, 0, w.location)
+		closing := time.Date(day.Year(), day.Month(), day.Day(), 0, w.endMinute, 0, 0, w.location)
+		if w.endMinute < w.startMinute {
+			// The window spans midnight
+			closing = time.Date(day.Year(), day.Month(), day.Day()+1, 0, w.endMinute, 0, 0, w.location)
+		}
+		openings = append(openings, opening)
+		closings = append(closings, closing)
+	}
+	return openings, closings
+}
+
+// closing returns the time the window closes if it is open at now
+func (w *CutoverWindow) closing(now time.Time) (time.Time, bool) {
+	openings, closings := w.occurrences(now)
+	for i := range openings {
+		if !now.Before(openings[i]) && now.Before(closings[i]) {
+			return closings[i], true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextOpening returns the time the window opens next after now
+func (w *CutoverWindow) nextOpening(now time.Time) time.Time {
+	openings, _ := w.occurrences(now)
+	for _, opening := range openings {
+		if opening.After(now) {
+			return opening
+		}
+	}
+	return time.Time{}
+}
+
+// fits reports whether a cutover taking duration and starting at now ends before the window closes
+func (w *CutoverWindow) fits(now time.Time, duration time.Duration) bool {
+	closing, open := w.closing(now)
+	return open && !now.Add(duration).After(closing)
+}
+
+// cutoverFits reports whether the final sync, estimated from the last sync, and the instance creation finish at the
+// latest when the cutover window closes and when the VM cutover end time is reached. It fails once the VM cutover
+// end time has passed, as the VM can no longer be cut over.
+func (migobj *Migrate) cutoverFits(now time.Time, estimate time.Duration) (bool, error) {
+	end := migobj.MigrationTimes.VMCutoverEnd
+	if !end.IsZero() {
+		if end.Before(now) {
+			return false, errors.New("VM Cutover End time has already passed")
+		}
+		if now.Add(estimate).After(end) {
+			return false, nil
+		}
+	}
+	return migobj.CutoverWindow == nil || migobj.CutoverWindow.fits(now, estimate), nil
+}
+
+// deferCutover reports whether the cutover has to wait, because the cutover window is closed or because the final
+// sync, estimated from the last sync, and the instance creation would not finish before the window closes or before
+// the VM cutover end time. The VM is not cut over in the meantime: deferCutover waits until the next window opens or
+// until the changed blocks are due to be synced again, whichever comes first. A shorter sync may then fit.
+func (migobj *Migrate) deferCutover(ctx context.Context, lastSync time.Duration) (bool, error) {
+	now := time.Now()
+	estimate := lastSync + migobj.CutoverFinalizeDuration
+	fits, err := migobj.cutoverFits(now, estimate)
+	if err != nil || fits {
+		return false, err
+	}
+	wait := constants.CutoverWindowSyncInterval
+	if migobj.CutoverWindow != nil && !migobj.CutoverWindow.fits(now, estimate) {
+		opening := migobj.CutoverWindow.nextOpening(now)
+		migobj.logMessage(fmt.Sprintf("%s: the final sync and instance creation need about %s and do not fit in the cutover window, deferring the cutover to the window opening at %s",
+			constants.EventMessageWaitingForCutOverStart, estimate.Round(time.Second), opening.Format(time.RFC3339)))
+		wait = min(time.Until(opening), wait)
+	} else {
+		migobj.logMessage(fmt.Sprintf("%s: the final sync and instance creation need about %s and would not finish before the VM Cutover End time %s, syncing the changed blocks again",
+			constants.EventMessageWaitingForCutOverStart, estimate.Round(time.Second), migobj.MigrationTimes.VMCutoverEnd.Format(time.RFC3339)))
+	}
+	migobj.setProgressPhase(migratev1alpha1.VMMigrationPhaseAwaitingCutOverStartTime, migobj.progressTracker().iteration())
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-time.After(wait):
+	}
+	return true, nil
+}
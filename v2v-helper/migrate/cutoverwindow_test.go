@@ -0,0 +1,76 @@
+package migrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCutoverWindow(t *testing.T) {
+	window, err := ParseCutoverWindow("", "", "", "")
+	assert.NoError(t, err)
+	assert.Nil(t, window)
+
+	window, err = ParseCutoverWindow("01:00", "04:00", "Europe/Berlin", "Mon,Tue, Wed")
+	assert.NoError(t, err)
+	assert.Equal(t, 60, window.startMinute)
+	assert.Equal(t, 4*60, window.endMinute)
+	assert.Equal(t, map[time.Weekday]bool{time.Monday: true, time.Tuesday: true, time.Wednesday: true}, window.days)
+
+	_, err = ParseCutoverWindow("01:00", "01:00", "", "")
+	assert.Error(t, err)
+	_, err = ParseCutoverWindow("01:00", "04:00", "", "Someday")
+	assert.Error(t, err)
+}
+
+func TestCutoverWindow(t *testing.T) {
+	// March 10 2025 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	weekdays, err := ParseCutoverWindow("01:00", "04:00", "", "Mon,Tue,Wed,Thu,Fri")
+	assert.NoError(t, err)
+	assert.True(t, weekdays.fits(at(10, 1, 30), time.Hour))
+	assert.False(t, weekdays.fits(at(10, 1, 30), 3*time.Hour))
+	assert.False(t, weekdays.fits(at(10, 5, 0), time.Minute))
+	assert.Equal(t, at(11, 1, 0), weekdays.nextOpening(at(10, 1, 30)))
+	assert.Equal(t, at(17, 1, 0), weekdays.nextOpening(at(14, 5, 0)))
+
+	saturdayNight, err := ParseCutoverWindow("23:00", "02:00", "", "Sat")
+	assert.NoError(t, err)
+	assert.True(t, saturdayNight.fits(at(16, 1, 0), 30*time.Minute))
+	assert.False(t, saturdayNight.fits(at(16, 23, 0), 30*time.Minute))
+	assert.Equal(t, at(22, 23, 0), saturdayNight.nextOpening(at(16, 1, 0)))
+}
+
+func TestCutoverFits(t *testing.T) {
+	// March 10 2025 is a Monday
+	now := time.Date(2025, time.March, 10, 1, 30, 0, 0, time.UTC)
+
+	migobj := Migrate{}
+	fits, err := migobj.cutoverFits(now, 10*time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, fits)
+
+	migobj.MigrationTimes.VMCutoverEnd = now.Add(time.Hour)
+	fits, err = migobj.cutoverFits(now, 30*time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, fits)
+	// Overrunning the VM cutover end time defers the cutover rather than failing it
+	fits, err = migobj.cutoverFits(now, 2*time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, fits)
+	_, err = migobj.cutoverFits(now.Add(2*time.Hour), time.Minute)
+	assert.Error(t, err)
+
+	migobj.CutoverWindow, err = ParseCutoverWindow("01:00", "02:00", "", "")
+	assert.NoError(t, err)
+	fits, err = migobj.cutoverFits(now, 15*time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, fits)
+	fits, err = migobj.cutoverFits(now, 45*time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, fits)
+}
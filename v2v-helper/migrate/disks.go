@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
+	"github.com/pkg/errors"
+)
+
+// ParseDiskOverride parses the disk override of the VM. It returns nil if the VM has none.
+func ParseDiskOverride(override string) (*migratev1alpha1.VMDiskOverride, error) {
+	if override == "" {
+		return nil, nil
+	}
+	var diskOverride migratev1alpha1.VMDiskOverride
+	if err := json.Unmarshal([]byte(override), &diskOverride); err != nil {
+		return nil, errors.Wrap(err, "invalid disk override")
+	}
+	return &diskOverride, nil
+}
+
+// diskOverride returns the override of the disk with the given label, or nil if it has none
+func (migobj *Migrate) diskOverride(name string) *migratev1alpha1.DiskOverride {
+	if migobj.DiskOverride == nil {
+		return nil
+	}
+	for i := range migobj.DiskOverride.Disks {
+		if migobj.DiskOverride.Disks[i].Name == name {
+			return &migobj.DiskOverride.Disks[i]
+		}
+	}
+	return nil
+}
+
+// selectDisks drops the disks of the VM that are not migrated, together with their volume types, and applies the
+// volume type and multiattach of the disk overrides to the others. The boot disk is always migrated.
+func (migobj *Migrate) selectDisks(vminfo vm.VMInfo) (vm.VMInfo, error) {
+	if migobj.DiskOverride == nil {
+		return vminfo, nil
+	}
+	var disks []vm.VMDisk
+	var volumetypes []string
+	for idx, disk := range vminfo.VMDisks {
+		override := migobj.diskOverride(disk.Name)
+		independent := migobj.DiskOverride.SkipIndependentNonPersistent && disk.Disk != nil && vm.IsIndependentNonPersistent(disk.Disk)
+		if (override != nil && override.Exclude) || independent {
+			if disk.Name == vminfo.BootDisk {
+				return vminfo, errors.Errorf("disk %s is the boot disk of the VM and cannot be excluded", disk.Name)
+			}
+			reason := "excluded"
+			if independent {
+				reason = "independent non-persistent"
+			}
+			migobj.logMessage(fmt.Sprintf("Skipping disk %s (%s)", disk.Name, reason))
+			continue
+		}
+		volumetype := migobj.Volumetypes[idx]
+		if override != nil {
+			if override.VolumeType != "" {
+				volumetype = override.VolumeType
+			}
+			disk.Multiattach = override.Multiattach
+		}
+		disks = append(disks, disk)
+		volumetypes = append(volumetypes, volumetype)
+	}
+	if len(disks) == 0 {
+		return vminfo, errors.New("all disks of the VM are excluded")
+	}
+	vminfo.VMDisks = disks
+	migobj.Volumetypes = volumetypes
+	return vminfo, nil
+}
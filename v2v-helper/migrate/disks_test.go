@@ -0,0 +1,52 @@
+package migrate
+
+import (
+	"testing"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestSelectDisks(t *testing.T) {
+	scratch := &types.VirtualDisk{
+		VirtualDevice: types.VirtualDevice{
+			Backing: &types.VirtualDiskFlatVer2BackingInfo{DiskMode: string(types.VirtualDiskModeIndependent_nonpersistent)},
+		},
+	}
+	vminfo := vm.VMInfo{
+		BootDisk: "Hard disk 1",
+		VMDisks: []vm.VMDisk{
+			{Name: "Hard disk 1"},
+			{Name: "Hard disk 2"},
+			{Name: "Hard disk 3", Disk: scratch},
+			{Name: "Hard disk 4"},
+		},
+	}
+	migobj := Migrate{
+		Volumetypes: []string{"voltype-1", "voltype-2", "voltype-3", "voltype-4"},
+		DiskOverride: &migratev1alpha1.VMDiskOverride{
+			SkipIndependentNonPersistent: true,
+			Disks: []migratev1alpha1.DiskOverride{
+				{Name: "Hard disk 2", Exclude: true},
+				{Name: "Hard disk 4", VolumeType: "shared", Multiattach: true},
+			},
+		},
+	}
+
+	selected, err := migobj.selectDisks(vminfo)
+	assert.NoError(t, err)
+	assert.Len(t, selected.VMDisks, 2)
+	assert.Equal(t, "Hard disk 1", selected.VMDisks[0].Name)
+	assert.False(t, selected.VMDisks[0].Multiattach)
+	assert.Equal(t, "Hard disk 4", selected.VMDisks[1].Name)
+	assert.True(t, selected.VMDisks[1].Multiattach)
+	assert.Equal(t, []string{"voltype-1", "shared"}, migobj.Volumetypes)
+
+	// The boot disk is always migrated
+	migobj.Volumetypes = []string{"voltype-1", "voltype-2", "voltype-3", "voltype-4"}
+	migobj.DiskOverride.Disks = []migratev1alpha1.DiskOverride{{Name: "Hard disk 1", Exclude: true}}
+	_, err = migobj.selectDisks(vminfo)
+	assert.ErrorContains(t, err, "boot disk")
+}
@@ -0,0 +1,102 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// extendVolume extends the volume of a disk that grew on the source since the last iteration and waits until the
+// attached device has the new size, so that the changed blocks past the old end of the disk can be written
+func (migobj *Migrate) extendVolume(ctx context.Context, vmdisk vm.VMDisk, idx, iteration int) error {
+	volume := vmdisk.OpenstackVol
+	// The same headroom as the volume was created with
+	size := int(math.Ceil(float64(vmdisk.Size)/(1024*1024*1024))) + 1
+	if volume.Size >= size {
+		utils.PrintLog(fmt.Sprintf("Disk %d grew to %d bytes, volume %s of %dGB still holds it", idx, vmdisk.Size, volume.ID, volume.Size))
+		return nil
+	}
+	migobj.logMessage(fmt.Sprintf("Disk %d grew to %d bytes, extending volume %s from %dGB to %dGB", idx, vmdisk.Size, volume.ID, volume.Size, size))
+	if err := migobj.Openstackclients.ExtendVolume(volume.ID, size); err != nil {
+		return errors.Wrapf(err, "failed to extend volume of disk %d", idx)
+	}
+	if err := waitForDeviceSize(ctx, vmdisk.Path, vmdisk.Size); err != nil {
+		return errors.Wrapf(err, "failed to resize device of disk %d", idx)
+	}
+	migobj.recordVolumeExtension(migratev1alpha1.VolumeExtension{
+		Disk:          vmdisk.Name,
+		VolumeID:      volume.ID,
+		Iteration:     iteration,
+		OldSizeGB:     volume.Size,
+		NewSizeGB:     size,
+		ExtensionTime: metav1.Now(),
+	})
+	volume.Size = size
+	migobj.logMessage(fmt.Sprintf("Extended volume %s of disk %d to %dGB", volume.ID, idx, size))
+	return nil
+}
+
+// waitForDeviceSize waits until the block device at path holds at least size bytes
+func waitForDeviceSize(ctx context.Context, path string, size int64) error {
+	ctx, cancel := context.WithTimeout(ctx, constants.DeviceResizeTimeout)
+	defer cancel()
+	for {
+		current, err := deviceSize(path)
+		if err != nil {
+			return err
+		}
+		if current >= size {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Errorf("device %s has %d bytes after %s, expected %d", path, current, constants.DeviceResizeTimeout, size)
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// deviceSize returns the size of the block device at path
+func deviceSize(path string) (int64, error) {
+	device, err := os.Open(path)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to open device %s", path)
+	}
+	defer device.Close()
+	size, err := device.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get size of device %s", path)
+	}
+	return size, nil
+}
+
+// recordVolumeExtension keeps a volume extension and publishes the extensions in an annotation of the pod, from
+// where the controller copies them to the status of the Migration. Disks are copied concurrently, so extensions
+// are serialized.
+func (migobj *Migrate) recordVolumeExtension(extension migratev1alpha1.VolumeExtension) {
+	migobj.volumeExtensionsMu.Lock()
+	defer migobj.volumeExtensionsMu.Unlock()
+	migobj.volumeExtensions = append(migobj.volumeExtensions, extension)
+	if !migobj.InPod || migobj.Reporter == nil {
+		return
+	}
+	extensions, err := json.Marshal(migobj.volumeExtensions)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to encode volume extensions: %v", err))
+		return
+	}
+	if err := migobj.Reporter.SetPodAnnotation(constants.MigrationVolumeExtensionsAnnotation, string(extensions)); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to publish volume extensions: %v", err))
+	}
+}
@@ -0,0 +1,42 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/volumes"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/openstack"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtendVolume(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// A file stands in for the attached device, already grown to the new size
+	const gb = 1024 * 1024 * 1024
+	device := filepath.Join(t.TempDir(), "vdb")
+	assert.NoError(t, os.WriteFile(device, nil, 0o600))
+	assert.NoError(t, os.Truncate(device, 4*gb))
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	migobj := Migrate{Openstackclients: mockOpenStackOps}
+	volume := &volumes.Volume{ID: "id1", Size: 3}
+
+	// The volume still holds the disk with the headroom it was created with
+	err := migobj.extendVolume(t.Context(), vm.VMDisk{Name: "disk1", Size: 2 * gb, OpenstackVol: volume, Path: device}, 0, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, migobj.volumeExtensions)
+
+	mockOpenStackOps.EXPECT().ExtendVolume("id1", 5).Return(nil)
+	err = migobj.extendVolume(t.Context(), vm.VMDisk{Name: "disk1", Size: 4 * gb, OpenstackVol: volume, Path: device}, 0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, volume.Size)
+	assert.Len(t, migobj.volumeExtensions, 1)
+	assert.Equal(t, 3, migobj.volumeExtensions[0].OldSizeGB)
+	assert.Equal(t, 5, migobj.volumeExtensions[0].NewSizeGB)
+	assert.Equal(t, 2, migobj.volumeExtensions[0].Iteration)
+}
@@ -0,0 +1,298 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/guest"
+	vimtypes "github.com/vmware/govmomi/vim25/types"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// HookRequest is the body of the calls of HTTP hooks
+type HookRequest struct {
+	VMName    string                             `json:"vmName"`
+	Migration string                             `json:"migration"`
+	Hook      string                             `json:"hook"`
+	Point     migratev1alpha1.MigrationHookPoint `json:"point"`
+}
+
+// ParseHooks parses the hooks of the migration plan
+func ParseHooks(hooks string) ([]migratev1alpha1.MigrationHook, error) {
+	if hooks == "" {
+		return nil, nil
+	}
+	var parsed []migratev1alpha1.MigrationHook
+	if err := json.Unmarshal([]byte(hooks), &parsed); err != nil {
+		return nil, errors.Wrap(err, "invalid hooks")
+	}
+	return parsed, nil
+}
+
+// runHooks runs the hooks of the VM at point in order and records their results. It returns an error as soon as a
+// hook whose failure policy is Fail fails, the hooks after it are not run.
+func (migobj *Migrate) runHooks(ctx context.Context, vmName string, point migratev1alpha1.MigrationHookPoint) error {
+	for _, hook := range migobj.Hooks {
+		if hook.Point != point {
+			continue
+		}
+		migobj.logMessage(fmt.Sprintf("Running %s hook %s", point, hook.Name))
+		err := migobj.runHook(ctx, vmName, hook)
+		result := migratev1alpha1.MigrationHookResult{
+			Name:           hook.Name,
+			Point:          point,
+			Succeeded:      err == nil,
+			Message:        "Hook succeeded",
+			CompletionTime: metav1.Now(),
+		}
+		if err != nil {
+			result.Message = err.Error()
+			result.Ignored = hook.FailurePolicy == migratev1alpha1.MigrationHookFailurePolicyIgnore
+		}
+		migobj.recordHookResult(result)
+		if err == nil {
+			migobj.logMessage(fmt.Sprintf("%s hook %s succeeded", point, hook.Name))
+			continue
+		}
+		if result.Ignored {
+			// The error is not part of the event, which would mark the migration as failed
+			utils.PrintLog(fmt.Sprintf("%s hook %s: %v", point, hook.Name, err))
+			migobj.logMessage(fmt.Sprintf("%s hook %s did not succeed, carrying on as its failure policy is Ignore", point, hook.Name))
+			continue
+		}
+		return errors.Wrapf(err, "%s hook %s failed", point, hook.Name)
+	}
+	return nil
+}
+
+// runHook runs a single hook within its timeout
+func (migobj *Migrate) runHook(ctx context.Context, vmName string, hook migratev1alpha1.MigrationHook) error {
+	timeout := hook.Timeout.Duration
+	if timeout <= 0 {
+		timeout = constants.DefaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The migration name is only known in the pod
+	migrationName, _ := utils.GetMigrationObjectName()
+	request := HookRequest{VMName: vmName, Migration: migrationName, Hook: hook.Name, Point: hook.Point}
+	var err error
+	switch {
+	case hook.Job != nil:
+		err = migobj.runJobHook(ctx, request, hook.Job)
+	case hook.HTTP != nil:
+		err = migobj.runHTTPHook(ctx, request, hook.HTTP)
+	case hook.GuestCommand != nil:
+		err = migobj.runGuestCommandHook(ctx, hook.GuestCommand)
+	default:
+		err = errors.New("hook has no action")
+	}
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return errors.Errorf("timed out after %s: %v", timeout, err)
+	}
+	return err
+}
+
+// runJobHook runs the image of a hook as a Job and waits for it to finish. The Job is deleted once it finished.
+func (migobj *Migrate) runJobHook(ctx context.Context, request HookRequest, hook *migratev1alpha1.MigrationHookJob) error {
+	if migobj.K8sClient == nil {
+		return errors.New("no Kubernetes client to run the hook Job")
+	}
+	backoffLimit := int32(0)
+	env := []corev1.EnvVar{
+		{Name: "VM_NAME", Value: request.VMName},
+		{Name: "MIGRATION_NAME", Value: request.Migration},
+		{Name: "HOOK_POINT", Value: string(request.Point)},
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("hook-%s-", request.Hook),
+			Namespace:    constants.NamespaceMigrationSystem,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "hook",
+							Image:   hook.Image,
+							Command: hook.Command,
+							Args:    hook.Args,
+							Env:     append(env, hook.Env...),
+						},
+					},
+				},
+			},
+		},
+	}
+	if err := migobj.K8sClient.Create(ctx, job); err != nil {
+		return errors.Wrap(err, "failed to create hook Job")
+	}
+	defer func() {
+		propagation := metav1.DeletePropagationBackground
+		if err := migobj.K8sClient.Delete(context.Background(), job, &client.DeleteOptions{PropagationPolicy: &propagation}); err != nil {
+			utils.PrintLog(fmt.Sprintf("Failed to delete hook Job %s: %v", job.Name, err))
+		}
+	}()
+
+	for {
+		if err := migobj.K8sClient.Get(ctx, types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, job); err != nil {
+			return errors.Wrap(err, "failed to get hook Job")
+		}
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				return nil
+			case batchv1.JobFailed:
+				return errors.Errorf("hook Job %s failed: %s", job.Name, condition.Message)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Errorf("hook Job %s did not finish", job.Name)
+		case <-time.After(constants.HookPollInterval):
+		}
+	}
+}
+
+// runHTTPHook calls the webhook of a hook, any 2xx response is a success
+func (migobj *Migrate) runHTTPHook(ctx context.Context, request HookRequest, hook *migratev1alpha1.MigrationHookHTTP) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode hook request")
+	}
+	method := hook.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	var reader io.Reader
+	if method != http.MethodGet {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, hook.URL, reader)
+	if err != nil {
+		return errors.Wrap(err, "failed to build hook request")
+	}
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if hook.HeadersSecretRef != nil {
+		secret, err := migobj.getHookSecret(ctx, hook.HeadersSecretRef.Name)
+		if err != nil {
+			return err
+		}
+		for key, value := range secret.Data {
+			req.Header.Set(key, string(value))
+		}
+	}
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			// #nosec G402 -- only skipped when the hook asks for it
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: hook.InsecureSkipVerify},
+		},
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to call hook")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("hook returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// runGuestCommandHook runs the program of a hook in the source VM through VMware Tools and waits for it to exit
+func (migobj *Migrate) runGuestCommandHook(ctx context.Context, hook *migratev1alpha1.MigrationHookGuestCommand) error {
+	secret, err := migobj.getHookSecret(ctx, hook.CredentialsSecretRef.Name)
+	if err != nil {
+		return err
+	}
+	auth := &vimtypes.NamePasswordAuthentication{
+		Username: string(secret.Data["username"]),
+		Password: string(secret.Data["password"]),
+	}
+	vmObj := migobj.VMops.GetVMObj()
+	processManager, err := guest.NewOperationsManager(vmObj.Client(), vmObj.Reference()).ProcessManager(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get guest process manager, are VMware Tools running?")
+	}
+	pid, err := processManager.StartProgram(ctx, auth, &vimtypes.GuestProgramSpec{
+		ProgramPath: hook.Path,
+		Arguments:   hook.Args,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to start guest command")
+	}
+	for {
+		processes, err := processManager.ListProcesses(ctx, auth, []int64{pid})
+		if err != nil {
+			return errors.Wrap(err, "failed to get guest command status")
+		}
+		if len(processes) == 1 && processes[0].EndTime != nil {
+			if processes[0].ExitCode != 0 {
+				return errors.Errorf("guest command %s exited with code %d", hook.Path, processes[0].ExitCode)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Errorf("guest command %s did not exit", hook.Path)
+		case <-time.After(constants.HookPollInterval):
+		}
+	}
+}
+
+// getHookSecret returns a secret referenced by a hook
+func (migobj *Migrate) getHookSecret(ctx context.Context, name string) (*corev1.Secret, error) {
+	if migobj.K8sClient == nil {
+		return nil, errors.Errorf("no Kubernetes client to read secret %s", name)
+	}
+	secret := &corev1.Secret{}
+	err := migobj.K8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: constants.NamespaceMigrationSystem}, secret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get secret %s", name)
+	}
+	return secret, nil
+}
+
+// recordHookResult keeps the result of a hook and publishes the results in an annotation of the pod, from where the
+// controller records them as conditions of the Migration
+func (migobj *Migrate) recordHookResult(result migratev1alpha1.MigrationHookResult) {
+	migobj.hookResults = append(migobj.hookResults, result)
+	if !migobj.InPod || migobj.Reporter == nil {
+		return
+	}
+	results, err := json.Marshal(migobj.hookResults)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to encode hook results: %v", err))
+		return
+	}
+	if err := migobj.Reporter.SetPodAnnotation(constants.MigrationHookResultsAnnotation, string(results)); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to publish hook results: %v", err))
+	}
+}
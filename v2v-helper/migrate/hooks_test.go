@@ -0,0 +1,59 @@
+package migrate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunHooks(t *testing.T) {
+	var requests []HookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request HookRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		if request.Hook == "cmdb" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	migobj := Migrate{
+		Hooks: []migratev1alpha1.MigrationHook{
+			{
+				Name:  "dns",
+				Point: migratev1alpha1.MigrationHookPostTargetBoot,
+				HTTP:  &migratev1alpha1.MigrationHookHTTP{URL: server.URL},
+			},
+			{
+				Name:          "cmdb",
+				Point:         migratev1alpha1.MigrationHookPostTargetBoot,
+				HTTP:          &migratev1alpha1.MigrationHookHTTP{URL: server.URL},
+				FailurePolicy: migratev1alpha1.MigrationHookFailurePolicyIgnore,
+			},
+			{
+				Name:  "loadbalancer",
+				Point: migratev1alpha1.MigrationHookPostHealthCheck,
+				HTTP:  &migratev1alpha1.MigrationHookHTTP{URL: server.URL},
+			},
+		},
+	}
+
+	err := migobj.runHooks(t.Context(), "vm1", migratev1alpha1.MigrationHookPostTargetBoot)
+	assert.NoError(t, err)
+	assert.Len(t, requests, 2)
+	assert.Equal(t, HookRequest{VMName: "vm1", Hook: "dns", Point: migratev1alpha1.MigrationHookPostTargetBoot}, requests[0])
+	assert.Len(t, migobj.hookResults, 2)
+	assert.True(t, migobj.hookResults[0].Succeeded)
+	assert.False(t, migobj.hookResults[1].Succeeded)
+	assert.True(t, migobj.hookResults[1].Ignored)
+
+	// A failing hook with the Fail policy blocks the cutover
+	migobj.Hooks[1].FailurePolicy = migratev1alpha1.MigrationHookFailurePolicyFail
+	err = migobj.runHooks(t.Context(), "vm1", migratev1alpha1.MigrationHookPostTargetBoot)
+	assert.ErrorContains(t, err, "PostTargetBoot hook cmdb failed")
+}
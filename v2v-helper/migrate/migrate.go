@@ -9,16 +9,21 @@ import (
 	"os"
 	"os/signal"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
 	"github.com/pkg/errors"
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/nbd"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/openstack"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/metrics"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils/migrateutils"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/reporter"
@@ -28,6 +33,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	probing "github.com/prometheus-community/pro-bing"
+	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
@@ -59,10 +65,37 @@ type Migrate struct {
 	TargetFlavorId          string
 	TargetAvailabilityZone  string
 	AssignedIP              string
+	NetworkOverrides        []migratev1alpha1.NICNetworkOverride
+	ReconfigureGuestNetwork bool
 	SecurityGroups          []string
 	UseFlavorless           bool
 	TenantName              string
 	Reporter                *reporter.Reporter
+	BandwidthLimitMbps      int
+	CopyWindow              *CopyWindow
+	DataVerification        string
+	VTPMPolicy              string
+	CutoverGated            bool
+	CutoverWindow           *CutoverWindow
+	CutoverFinalizeDuration time.Duration
+	Hooks                   []migratev1alpha1.MigrationHook
+	hookResults             []migratev1alpha1.MigrationHookResult
+	TestBoot                *TestBootOptions
+	DiskOverride            *migratev1alpha1.VMDiskOverride
+	SharedDisks             []migratev1alpha1.SharedDiskAssignment
+	SnapshotConsistency     string
+	QuiesceTimeout          time.Duration
+	snapshots               []migratev1alpha1.MigrationSnapshot
+	volumeExtensions        []migratev1alpha1.VolumeExtension
+	volumeExtensionsMu      sync.Mutex
+	checkpoint              *utils.CopyCheckpoint
+	checkpointMu            sync.Mutex
+	generatedMACs           map[int]string
+	progress                *progressTracker
+	progressMu              sync.Mutex
+	vmopsMu                 sync.Mutex
+	// bandwidth splits the bandwidth limit between the disks being copied, nil if there is no limit
+	bandwidth *bandwidthThrottle
 }
 
 type MigrationTimes struct {
@@ -96,22 +129,70 @@ func (migobj *Migrate) logMessage(message string) {
 	utils.PrintLog(message)
 }
 
+// powerOffSource powers off the source VM for its final copy, running the hooks before the final snapshot and
+// after the power off. A VM copying shared disks also waits for the other VMs sharing them to power off.
+func (migobj *Migrate) powerOffSource(ctx context.Context, vminfo vm.VMInfo) error {
+	if err := migobj.runHooks(ctx, vminfo.Name, migratev1alpha1.MigrationHookPreFinalSnapshot); err != nil {
+		return err
+	}
+	if err := migobj.VMops.VMPowerOff(); err != nil {
+		return errors.Wrap(err, "failed to power off VM")
+	}
+	if err := migobj.waitForSharedDiskOwners(ctx, vminfo); err != nil {
+		return err
+	}
+	return migobj.runHooks(ctx, vminfo.Name, migratev1alpha1.MigrationHookPostSourcePowerOff)
+}
+
+// ValidateVMEncryption refuses VMs whose disks cannot be read or whose TPM cannot be recreated.
+// Encrypted disks cannot be read through nbdkit and VMs with a vTPM are only migrated if the
+// vTPM policy allows recreating the TPM on the target, as the keys sealed to it are lost.
+func (migobj *Migrate) ValidateVMEncryption(vminfo vm.VMInfo) error {
+	if vminfo.Encrypted {
+		return errors.New("VM is encrypted, encrypted disks cannot be migrated. Decrypt the VM in vCenter and retry")
+	}
+	if !vminfo.VTPM {
+		return nil
+	}
+	if migobj.VTPMPolicy != constants.VTPMPolicyRecreate {
+		return errors.New("VM has a vTPM, set the vTPM policy of the migration plan to recreate to migrate it " +
+			"with an emulated TPM. Keys sealed to the source TPM, such as BitLocker keys, are not carried over")
+	}
+	migobj.logMessage(fmt.Sprintf("VM has a vTPM, the target will use an emulated TPM %s (%s). "+
+		"Keys sealed to the source TPM are not carried over, keep BitLocker recovery keys at hand", constants.TPMVersion, constants.TPMModel))
+	return nil
+}
+
 // This function creates volumes in OpenStack and attaches them to the helper vm
 func (migobj *Migrate) CreateVolumes(vminfo vm.VMInfo) (vm.VMInfo, error) {
 	openstackops := migobj.Openstackclients
 	migobj.logMessage("Creating volumes in OpenStack")
 	for idx, vmdisk := range vminfo.VMDisks {
-		volume, err := openstackops.CreateVolume(vminfo.Name+"-"+vmdisk.Name, vmdisk.Size, vminfo.OSType, vminfo.UEFI, migobj.Volumetypes[idx])
+		if volume := migobj.getCheckpointVolume(vmdisk); volume != nil {
+			migobj.logMessage(fmt.Sprintf("Reusing volume %s of disk %s from an earlier attempt", volume.ID, vmdisk.Name))
+			vminfo.VMDisks[idx].OpenstackVol = volume
+			continue
+		}
+		volume, err := openstackops.CreateVolume(vminfo.Name+"-"+vmdisk.Name, vmdisk.Size, vminfo.OSType, vminfo.UEFI, migobj.Volumetypes[idx], vmdisk.Multiattach)
 		if err != nil {
 			return vminfo, errors.Wrap(err, "failed to create volume")
 		}
 		vminfo.VMDisks[idx].OpenstackVol = volume
+		migobj.updateCopyCheckpoint(context.TODO(), func(checkpoint *utils.CopyCheckpoint) {
+			checkpoint.SetDisk(utils.DiskCheckpoint{Name: vmdisk.Name, VolumeID: volume.ID})
+		})
 		if vminfo.VMDisks[idx].Boot {
 			err = openstackops.SetVolumeBootable(volume)
 			if err != nil {
 				return vminfo, errors.Wrap(err, "failed to set volume as bootable")
 			}
 		}
+		if vminfo.VTPM {
+			err = openstackops.SetVolumeTPM(volume)
+			if err != nil {
+				return vminfo, errors.Wrap(err, "failed to set volume tpm")
+			}
+		}
 	}
 	migobj.logMessage("Volumes created successfully")
 	return vminfo, nil
@@ -216,22 +297,25 @@ func (migobj *Migrate) EnableCBTWrapper() error {
 	return nil
 }
 
+// WaitforCutover waits for the VM cutover start time. Whether the cutover finishes before the VM cutover end time
+// is checked by deferCutover.
 func (migobj *Migrate) WaitforCutover() error {
 	var zerotime time.Time
 	if !migobj.MigrationTimes.VMCutoverStart.Equal(zerotime) && migobj.MigrationTimes.VMCutoverStart.After(time.Now()) {
 		migobj.logMessage("Waiting for VM Cutover start time")
+		migobj.setProgressPhase(migratev1alpha1.VMMigrationPhaseAwaitingCutOverStartTime, migobj.progressTracker().iteration())
 		time.Sleep(time.Until(migobj.MigrationTimes.VMCutoverStart))
 		migobj.logMessage("VM Cutover start time reached")
-	} else {
-		if !migobj.MigrationTimes.VMCutoverEnd.Equal(zerotime) && migobj.MigrationTimes.VMCutoverEnd.Before(time.Now()) {
-			return errors.New("VM Cutover End time has already passed")
-		}
 	}
 	return nil
 }
 
 func (migobj *Migrate) WaitforAdminCutover() error {
+	if migobj.CutoverGated {
+		utils.PrintLog("Waiting for a free cutover slot")
+	}
 	migobj.logMessage("Waiting for Admin Cutover conditions to be met")
+	migobj.setProgressPhase(migratev1alpha1.VMMigrationPhaseAwaitingAdminCutOver, migobj.progressTracker().iteration())
 	for {
 		label := <-migobj.PodLabelWatcher
 		migobj.logMessage(fmt.Sprintf("Label: %s", label))
@@ -244,6 +328,11 @@ func (migobj *Migrate) WaitforAdminCutover() error {
 }
 
 func (migobj *Migrate) CheckIfAdminCutoverSelected() bool {
+	// A gated cutover starts with the label set to no as well, but waits for a free cutover slot after copying
+	// the changed blocks rather than for the admin
+	if migobj.CutoverGated {
+		return false
+	}
 	value, err := migobj.Reporter.GetCutoverLabel()
 	if err != nil {
 		utils.PrintLog(fmt.Sprintf("Failed to get pod labels: %v", err))
@@ -265,8 +354,8 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 	thumbprint := migobj.Thumbprint
 
 	if migobj.MigrationType == "cold" {
-		if err := vmops.VMPowerOff(); err != nil {
-			return vminfo, errors.Wrap(err, "failed to power off VM")
+		if err := migobj.powerOffSource(ctx, vminfo); err != nil {
+			return vminfo, err
 		}
 	}
 
@@ -278,9 +367,9 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 	}
 
 	utils.PrintLog("Starting NBD server")
-	err = vmops.TakeSnapshot(constants.MigrationSnapshotName)
+	err = migobj.takeMigrationSnapshot(0, migobj.MigrationType == "cold")
 	if err != nil {
-		return vminfo, errors.Wrap(err, "failed to take snapshot of source VM")
+		return vminfo, err
 	}
 
 	err = vmops.UpdateDisksInfo(&vminfo)
@@ -315,30 +404,34 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 	// Check if migration has admin cutover if so don't copy any more changed blocks
 	adminInitiatedCutover := migobj.CheckIfAdminCutoverSelected()
 
+	diskCopyConcurrency := vcenterSettings.DiskCopyConcurrencyLimit
+	if diskCopyConcurrency > len(vminfo.VMDisks) {
+		diskCopyConcurrency = len(vminfo.VMDisks)
+	}
+	utils.PrintLog(fmt.Sprintf("Copying up to %d disk(s) concurrently", diskCopyConcurrency))
+
 	incrementalCopyCount := 0
+	// cutoverGranted is set once the cutover slot or the admin granted the cutover
+	cutoverGranted := false
 	for {
 		// If its the first copy, copy the entire disk
 		if incrementalCopyCount == 0 {
-			for idx := range vminfo.VMDisks {
-				startTime := time.Now()
-				migobj.logMessage(fmt.Sprintf("Starting full disk copy of disk %d ", idx))
-
-				err = nbdops[idx].CopyDisk(ctx, vminfo.VMDisks[idx].Path, idx)
-				if err != nil {
-					return vminfo, errors.Wrap(err, "failed to copy disk")
-				}
-				duration := time.Since(startTime)
-				migobj.logMessage(fmt.Sprintf("Disk %d (%s) copied successfully in %s, copying changed blocks now", idx, vminfo.VMDisks[idx].Path, duration))
+			migobj.setProgressPhase(migratev1alpha1.VMMigrationPhaseCopying, 0)
+			err = migobj.copyDisksConcurrently(ctx, vminfo, diskCopyConcurrency, func(ctx context.Context, idx int) error {
+				return migobj.copyFullDisk(ctx, vminfo, idx)
+			})
+			if err != nil {
+				return vminfo, err
 			}
+			migobj.testBoot(ctx, vminfo)
 			if adminInitiatedCutover {
 				utils.PrintLog("Admin initiated cutover detected, skipping changed blocks copy")
 				if err := migobj.WaitforAdminCutover(); err != nil {
 					return vminfo, errors.Wrap(err, "failed to start VM Cutover")
 				}
 				utils.PrintLog("Shutting down source VM and performing final copy")
-				err = vmops.VMPowerOff()
-				if err != nil {
-					return vminfo, errors.Wrap(err, "failed to power off VM")
+				if err := migobj.powerOffSource(ctx, vminfo); err != nil {
+					return vminfo, err
 				}
 				final = true
 			}
@@ -347,78 +440,63 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 			if err != nil {
 				return vminfo, errors.Wrap(err, "failed to get snapshot")
 			}
-
-			var changedAreas types.DiskChangeInfo
-			done := true
-
-			for idx := range vminfo.VMDisks {
-				changedAreas, err = vmops.CustomQueryChangedDiskAreas(vminfo.VMDisks[idx].ChangeID, migration_snapshot, vminfo.VMDisks[idx].Disk, 0)
-				if err != nil {
-					return vminfo, errors.Wrap(err, "failed to get changed disk areas")
-				}
-
-				if len(changedAreas.ChangedArea) == 0 {
-					migobj.logMessage(fmt.Sprintf("Disk %d: No changed blocks found. Skipping copy", idx))
-				} else {
-					migobj.logMessage(fmt.Sprintf("Disk %d: Blocks have Changed.", idx))
-
-					utils.PrintLog("Restarting NBD server")
-					err = nbdops[idx].StopNBDServer()
-					if err != nil {
-						return vminfo, errors.Wrap(err, "failed to stop NBD server")
-					}
-
-					err = nbdops[idx].StartNBDServer(vmops.GetVMObj(), envURL, envUserName, envPassword, thumbprint, vminfo.VMDisks[idx].Snapname, vminfo.VMDisks[idx].SnapBackingDisk, migobj.EventReporter)
-					if err != nil {
-						return vminfo, errors.Wrap(err, "failed to start NBD server")
-					}
-					// sleep for 2 seconds to allow the NBD server to start
-					time.Sleep(2 * time.Second)
-
-					// 11. Copy Changed Blocks over
-					done = false
-					changedBlockCopySuccess := true
-					migobj.logMessage("Copying changed blocks")
-
-					// incremental block copy
-
-					startTime := time.Now()
-					migobj.logMessage(fmt.Sprintf("Starting incremental block copy for disk %d at %s", idx, startTime))
-
-					err = nbdops[idx].CopyChangedBlocks(ctx, changedAreas, vminfo.VMDisks[idx].Path)
-					if err != nil {
-						changedBlockCopySuccess = false
-					}
-
-					duration := time.Since(startTime)
-
-					migobj.logMessage(fmt.Sprintf("Incremental block copy for disk %d completed in %s", idx, duration))
-
-					err = vmops.UpdateDiskInfo(&vminfo, vminfo.VMDisks[idx], changedBlockCopySuccess)
-					if err != nil {
-						return vminfo, errors.Wrap(err, "failed to update disk info")
-					}
-					if !changedBlockCopySuccess {
-						migobj.logMessage(fmt.Sprintf("Failed to copy changed blocks: %s", err))
-						migobj.logMessage(fmt.Sprintf("Since full copy has completed, Retrying copy of changed blocks for disk: %d", idx))
-					}
-					migobj.logMessage(fmt.Sprintf("Finished copying and syncing changed blocks for disk %d in %s [Progress: %d/20]", idx, duration, incrementalCopyCount))
+			migobj.setProgressPhase(migratev1alpha1.VMMigrationPhaseCopyingChangedBlocks, incrementalCopyCount)
+
+			syncStart := time.Now()
+			var changedDisks atomic.Int32
+			// Each disk is updated on its own copy, the copies are merged once all disks are done
+			disks := slices.Clone(vminfo.VMDisks)
+			err = migobj.copyDisksConcurrently(ctx, vminfo, diskCopyConcurrency, func(ctx context.Context, idx int) error {
+				disk, changed, err := migobj.copyChangedBlocksOfDisk(ctx, vminfo, idx, migration_snapshot, incrementalCopyCount, final)
+				disks[idx] = disk
+				if changed {
+					changedDisks.Add(1)
 				}
+				return err
+			})
+			vminfo.VMDisks = disks
+			if err != nil {
+				return vminfo, err
 			}
+			metrics.IncChangedBlockIterations(vminfo.Name)
+			done := changedDisks.Load() == 0
+
 			if final {
 				break
 			}
-			if done || incrementalCopyCount > vcenterSettings.ChangedBlocksCopyIterationThreshold {
+			lastSync := time.Since(syncStart)
+			cutover := done || incrementalCopyCount > vcenterSettings.ChangedBlocksCopyIterationThreshold
+			if cutover {
 				if err := migobj.WaitforCutover(); err != nil {
 					return vminfo, errors.Wrap(err, "failed to start VM Cutover")
 				}
+				// Keep syncing the changed blocks until the cutover fits in a cutover window and before the VM
+				// cutover end time
+				deferred, err := migobj.deferCutover(ctx, lastSync)
+				if err != nil {
+					return vminfo, errors.Wrap(err, "failed to wait for cutover window")
+				}
+				cutover = !deferred
+			}
+			if cutover && !cutoverGranted {
 				if err := migobj.WaitforAdminCutover(); err != nil {
 					return vminfo, errors.Wrap(err, "failed to start Admin initated Cutover")
 				}
+				cutoverGranted = true
+				// A free cutover slot may take long, the window may have closed in the meantime. The slot is kept
+				// while the changed blocks are synced again.
+				if migobj.CutoverGated {
+					deferred, err := migobj.deferCutover(ctx, lastSync)
+					if err != nil {
+						return vminfo, errors.Wrap(err, "failed to wait for cutover window")
+					}
+					cutover = !deferred
+				}
+			}
+			if cutover {
 				utils.PrintLog("Shutting down source VM and performing final copy")
-				err = vmops.VMPowerOff()
-				if err != nil {
-					return vminfo, errors.Wrap(err, "failed to power off VM")
+				if err := migobj.powerOffSource(ctx, vminfo); err != nil {
+					return vminfo, err
 				}
 				final = true
 			}
@@ -432,9 +510,15 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 		if err != nil {
 			return vminfo, errors.Wrap(err, "failed to cleanup snapshot of source VM")
 		}
-		err = vmops.TakeSnapshot(constants.MigrationSnapshotName)
+		// The final sync runs with the VM powered off, so it is never held back by the copy window
+		if !final {
+			if err := migobj.waitForCopyWindow(ctx); err != nil {
+				return vminfo, errors.Wrap(err, "failed to wait for copy window")
+			}
+		}
+		err = migobj.takeMigrationSnapshot(incrementalCopyCount+1, final)
 		if err != nil {
-			return vminfo, errors.Wrap(err, "failed to take snapshot of source VM")
+			return vminfo, err
 		}
 
 		incrementalCopyCount += 1
@@ -463,8 +547,222 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 	return vminfo, nil
 }
 
+// copyDisksConcurrently runs copyFn for every disk of the VM with at most limit disks in flight.
+// The first failing disk cancels the copies still running and its error is returned.
+func (migobj *Migrate) copyDisksConcurrently(ctx context.Context, vminfo vm.VMInfo, limit int, copyFn func(ctx context.Context, idx int) error) error {
+	if limit < 1 {
+		limit = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	semaphore := make(chan struct{}, limit)
+	for idx := range vminfo.VMDisks {
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			if ctx.Err() != nil {
+				migobj.logMessage(fmt.Sprintf("Disk %d: copy cancelled", idx))
+				return
+			}
+			migobj.copyStarted()
+			err := copyFn(ctx, idx)
+			migobj.copyFinished()
+			if err != nil {
+				errMu.Lock()
+				defer errMu.Unlock()
+				if firstErr != nil {
+					migobj.logMessage(fmt.Sprintf("Disk %d: copy cancelled: %s", idx, err))
+					return
+				}
+				migobj.logMessage(fmt.Sprintf("Disk %d: copy failed: %s", idx, err))
+				firstErr = errors.Wrapf(err, "disk %d", idx)
+				cancel()
+			}
+		}(idx)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// copyFullDisk performs the initial copy of a disk, resuming from the checkpoint of an earlier attempt if there is one
+func (migobj *Migrate) copyFullDisk(ctx context.Context, vminfo vm.VMInfo, idx int) error {
+	startTime := time.Now()
+	if migobj.isDiskResumable(vminfo.VMDisks[idx]) {
+		// The volume already holds data, so it cannot be the target of a full copy
+		copiedBytes, err := migobj.resumeDiskCopy(ctx, vminfo, idx)
+		if err != nil {
+			configMapName, _ := utils.GetCopyCheckpointConfigMapName()
+			return errors.Wrapf(err, "failed to resume copy of disk %d from checkpoint, delete configmap %s to copy it from scratch", idx, configMapName)
+		}
+		duration := time.Since(startTime)
+		metrics.ObserveDiskCopy(vminfo.Name, idx, copiedBytes, duration)
+		migobj.logMessage(fmt.Sprintf("Disk %d (%s) resumed from checkpoint and copied in %s", idx, vminfo.VMDisks[idx].Path, duration))
+		return nil
+	}
+	migobj.logMessage(fmt.Sprintf("Starting full disk copy of disk %d ", idx))
+	migobj.markFullCopyStarted(ctx, vminfo.VMDisks[idx])
+	migobj.startDiskProgress(idx, vminfo.VMDisks[idx].Size)
+
+	copied, copiedBytes, err := migobj.copyAllocatedExtents(ctx, vminfo.VMDisks[idx], idx)
+	if err != nil {
+		return errors.Wrap(err, "failed to copy disk")
+	}
+	if !copied {
+		if err := migobj.Nbdops[idx].CopyDisk(ctx, vminfo.VMDisks[idx].Path, idx); err != nil {
+			return errors.Wrap(err, "failed to copy disk")
+		}
+		// nbdcopy does not report how much it copied, the size of the disk is the most it can have copied
+		copiedBytes = vminfo.VMDisks[idx].Size
+	}
+	migobj.updateDiskProgress(idx, 1)
+	duration := time.Since(startTime)
+	metrics.ObserveDiskCopy(vminfo.Name, idx, copiedBytes, duration)
+	migobj.markDiskSynced(ctx, vminfo.VMDisks[idx])
+	migobj.logMessage(fmt.Sprintf("Disk %d (%s) copied successfully in %s, copying changed blocks now", idx, vminfo.VMDisks[idx].Path, duration))
+	return nil
+}
+
+// copyChangedBlocksOfDisk copies the blocks of a disk that changed since its last synced change ID and reports
+// whether there were any. It returns the disk with its change ID, size and snapshot updated. Unless it is the final
+// sync, the copy stops when the copy window closes and the blocks are copied again in the next sync.
+func (migobj *Migrate) copyChangedBlocksOfDisk(ctx context.Context, vminfo vm.VMInfo, idx int, snapshot *types.ManagedObjectReference, incrementalCopyCount int, final bool) (vm.VMDisk, bool, error) {
+	nbdops := migobj.Nbdops
+	disk := vminfo.VMDisks[idx]
+
+	// The disk may have been extended since the last iteration, its volume has to hold the blocks past the old end
+	size := disk.Size
+	disk, err := migobj.updateDiskInfo(disk, false)
+	if err != nil {
+		return disk, false, errors.Wrap(err, "failed to update disk info")
+	}
+	if disk.Size > size {
+		if err := migobj.extendVolume(ctx, disk, idx, incrementalCopyCount); err != nil {
+			return disk, false, err
+		}
+	}
+
+	changedAreas, err := migobj.queryChangedDiskAreas(disk.ChangeID, snapshot, disk.Disk)
+	if err != nil {
+		return disk, false, errors.Wrap(err, "failed to get changed disk areas")
+	}
+
+	if len(changedAreas.ChangedArea) == 0 {
+		migobj.logMessage(fmt.Sprintf("Disk %d: No changed blocks found. Skipping copy", idx))
+		return disk, false, nil
+	}
+	migobj.logMessage(fmt.Sprintf("Disk %d: Blocks have Changed.", idx))
+
+	utils.PrintLog(fmt.Sprintf("Restarting NBD server for disk %d", idx))
+	err = nbdops[idx].StopNBDServer()
+	if err != nil {
+		return disk, true, errors.Wrap(err, "failed to stop NBD server")
+	}
+
+	err = nbdops[idx].StartNBDServer(migobj.vmObj(), migobj.URL, migobj.UserName, migobj.Password, migobj.Thumbprint, disk.Snapname, disk.SnapBackingDisk, migobj.EventReporter)
+	if err != nil {
+		return disk, true, errors.Wrap(err, "failed to start NBD server")
+	}
+	// sleep for 2 seconds to allow the NBD server to start
+	time.Sleep(2 * time.Second)
+
+	// 11. Copy Changed Blocks over
+	migobj.logMessage("Copying changed blocks")
+
+	// incremental block copy
+
+	var changedBytes int64
+	for _, area := range changedAreas.ChangedArea {
+		changedBytes += area.Length
+	}
+	startTime := time.Now()
+	migobj.logMessage(fmt.Sprintf("Starting incremental block copy for disk %d at %s", idx, startTime))
+	migobj.startDiskProgress(idx, changedBytes)
+
+	copyCtx, cancel := migobj.copyWindowContext(ctx, final)
+	copyErr := nbdops[idx].CopyChangedBlocks(copyCtx, changedAreas, disk.Path, idx)
+	windowClosed := copyErr != nil && ctx.Err() == nil && errors.Is(copyCtx.Err(), context.DeadlineExceeded)
+	cancel()
+	changedBlockCopySuccess := copyErr == nil
+
+	duration := time.Since(startTime)
+
+	migobj.logMessage(fmt.Sprintf("Incremental block copy for disk %d completed in %s", idx, duration))
+
+	disk, err = migobj.updateDiskInfo(disk, changedBlockCopySuccess)
+	if err != nil {
+		return disk, true, errors.Wrap(err, "failed to update disk info")
+	}
+	if changedBlockCopySuccess {
+		migobj.updateDiskProgress(idx, 1)
+		metrics.ObserveDiskCopy(vminfo.Name, idx, changedBytes, duration)
+		migobj.markDiskSynced(ctx, disk)
+	} else if windowClosed {
+		migobj.logMessage(fmt.Sprintf("Copy window closed, stopped copying changed blocks for disk %d until it opens again", idx))
+	} else {
+		migobj.logMessage(fmt.Sprintf("Failed to copy changed blocks: %s", copyErr))
+		migobj.logMessage(fmt.Sprintf("Since full copy has completed, Retrying copy of changed blocks for disk: %d", idx))
+	}
+	migobj.logMessage(fmt.Sprintf("Finished copying and syncing changed blocks for disk %d in %s [Progress: %d/20]", idx, duration, incrementalCopyCount))
+	return disk, true, nil
+}
+
+// The disks are copied concurrently while the VM operations share the reference to the VM, which they refresh when
+// the vCenter session expired. The calls made for the copies of the disks are serialized.
+
+// getSnapshot returns the snapshot of the VM with the given name
+func (migobj *Migrate) getSnapshot(name string) (*types.ManagedObjectReference, error) {
+	migobj.vmopsMu.Lock()
+	defer migobj.vmopsMu.Unlock()
+	return migobj.VMops.GetSnapshot(name)
+}
+
+// queryChangedDiskAreas returns the areas of a disk that changed in snapshot since changeID
+func (migobj *Migrate) queryChangedDiskAreas(changeID string, snapshot *types.ManagedObjectReference, disk *types.VirtualDisk) (types.DiskChangeInfo, error) {
+	migobj.vmopsMu.Lock()
+	defer migobj.vmopsMu.Unlock()
+	return migobj.VMops.CustomQueryChangedDiskAreas(changeID, snapshot, disk, 0)
+}
+
+// updateDiskInfo returns a copy of disk with the change ID, size and snapshot of the current snapshot of the VM.
+// The change ID is only updated if blockCopySuccess is set.
+func (migobj *Migrate) updateDiskInfo(disk vm.VMDisk, blockCopySuccess bool) (vm.VMDisk, error) {
+	migobj.vmopsMu.Lock()
+	defer migobj.vmopsMu.Unlock()
+	diskinfo := vm.VMInfo{VMDisks: []vm.VMDisk{disk}}
+	if err := migobj.VMops.UpdateDiskInfo(&diskinfo, disk, blockCopySuccess); err != nil {
+		return disk, err
+	}
+	return diskinfo.VMDisks[0], nil
+}
+
+// vmObj returns the reference to the VM
+func (migobj *Migrate) vmObj() *object.VirtualMachine {
+	migobj.vmopsMu.Lock()
+	defer migobj.vmopsMu.Unlock()
+	return migobj.VMops.GetVMObj()
+}
+
 func (migobj *Migrate) ConvertVolumes(ctx context.Context, vminfo vm.VMInfo) error {
 	migobj.logMessage("Converting disk")
+	migobj.setProgressPhase(migratev1alpha1.VMMigrationPhaseConvertingDisk, migobj.progressTracker().iteration())
+	if err := migobj.convertVolumes(ctx, vminfo, migobj.ReconfigureGuestNetwork); err != nil {
+		return err
+	}
+	migobj.logMessage("Successfully converted disk")
+	return nil
+}
+
+// convertVolumes attaches the volumes of the VM, converts its boot disk and detaches them again. The guest network
+// is configured with the addresses of the VM if reconfigureGuestNetwork is set, and with DHCP otherwise.
+func (migobj *Migrate) convertVolumes(ctx context.Context, vminfo vm.VMInfo, reconfigureGuestNetwork bool) error {
 
 	var (
 		osRelease                   = ""
@@ -596,6 +894,15 @@ func (migobj *Migrate) ConvertVolumes(ctx context.Context, vminfo vm.VMInfo) err
 	// save the index of bootVolume
 	utils.PrintLog(fmt.Sprintf("Setting up boot volume as: %s", vminfo.VMDisks[bootVolumeIndex].Name))
 	vminfo.VMDisks[bootVolumeIndex].Boot = true
+
+	var guestNICs []virtv2v.GuestNICConfig
+	if reconfigureGuestNetwork {
+		guestNICs, err = migobj.guestNICConfigs(vminfo)
+		if err != nil {
+			return errors.Wrap(err, "failed to get guest network configuration")
+		}
+	}
+
 	if migobj.Convert {
 		firstbootscripts := []string{}
 		// Fix NTFS
@@ -604,9 +911,20 @@ func (migobj *Migrate) ConvertVolumes(ctx context.Context, vminfo vm.VMInfo) err
 			if err != nil {
 				return errors.Wrap(err, "failed to run ntfsfix")
 			}
+			if reconfigureGuestNetwork {
+				// Windows is configured on first boot, once the virtio network driver is installed
+				firstbootscriptname := "windows_configure_network"
+				firstbootscripts = append(firstbootscripts, firstbootscriptname)
+				err = virtv2v.AddFirstBootScript(virtv2v.RenderWindowsNetworkScript(guestNICs), firstbootscriptname)
+				if err != nil {
+					return errors.Wrap(err, "failed to add first boot script")
+				}
+				utils.PrintLog("Guest network first boot script added successfully")
+			}
 		}
 
-		if virtv2v.IsRHELFamily(osRelease) {
+		// The DHCP script would replace the static configuration written into the guest
+		if virtv2v.IsRHELFamily(osRelease) && !reconfigureGuestNetwork {
 			// If RHEL family, we need to inject a script to make interface come up with DHCP,
 			// We preserve the ip because we have a port created with the same IP
 			// If NM is present, we inject a script to force neutron DHCP on first boot.
@@ -644,7 +962,18 @@ func (migobj *Migrate) ConvertVolumes(ctx context.Context, vminfo vm.VMInfo) err
 		}
 	}
 
-	if strings.ToLower(vminfo.OSType) == constants.OSFamilyLinux {
+	if strings.ToLower(vminfo.OSType) == constants.OSFamilyWindows && reconfigureGuestNetwork && !migobj.Convert {
+		utils.PrintLog("Warning: guest network of Windows VMs is only reconfigured when the disks are converted")
+	}
+
+	if strings.ToLower(vminfo.OSType) == constants.OSFamilyLinux && reconfigureGuestNetwork {
+		utils.PrintLog("Reconfiguring guest network")
+		err = virtv2v.ReconfigureGuestNetwork(vminfo.VMDisks, useSingleDisk, vminfo.VMDisks[bootVolumeIndex].Path, osRelease, guestNICs)
+		if err != nil {
+			return errors.Wrap(err, "failed to reconfigure guest network")
+		}
+		utils.PrintLog("Guest network reconfigured successfully")
+	} else if strings.ToLower(vminfo.OSType) == constants.OSFamilyLinux {
 		if strings.Contains(osRelease, "ubuntu") {
 			// Check if netplan is supported
 			versionID := parseVersionID(osRelease)
@@ -717,7 +1046,6 @@ func (migobj *Migrate) ConvertVolumes(ctx context.Context, vminfo vm.VMInfo) err
 	if err != nil {
 		return errors.Wrap(err, "Failed to detach all volumes from VM")
 	}
-	migobj.logMessage("Successfully converted disk")
 	return nil
 }
 
@@ -765,34 +1093,68 @@ func DetectAndHandleNetwork(diskPath string, osRelease string, vmInfo vm.VMInfo)
 	return nil
 }
 
-func (migobj *Migrate) CreateTargetInstance(vminfo vm.VMInfo) error {
-	migobj.logMessage("Creating target instance")
-	openstackops := migobj.Openstackclients
-	networknames := migobj.Networknames
-	var flavor *flavors.Flavor
-	var err error
+// saveTargetResources records the server, ports and volumes created by the migration so that
+// a rollback can delete them. RDM volumes are managed outside the migration and are left out.
+func (migobj *Migrate) saveTargetResources(serverID string, portIDs []string, vminfo vm.VMInfo) {
+	if migobj.K8sClient == nil {
+		return
+	}
+	resources := &migratev1alpha1.MigrationTargetResources{
+		ServerID: serverID,
+		PortIDs:  portIDs,
+	}
+	for _, disk := range vminfo.VMDisks {
+		// A shared disk is attached to the instances of the other owner VMs as well, a rollback must not delete it
+		if assignment := migobj.sharedDisk(disk.Name); assignment != nil && assignment.Copy {
+			continue
+		}
+		if disk.OpenstackVol != nil {
+			resources.VolumeIDs = append(resources.VolumeIDs, disk.OpenstackVol.ID)
+		}
+	}
+	if err := utils.SaveMigrationTargetResources(context.Background(), migobj.K8sClient, resources); err != nil {
+		utils.PrintLog(fmt.Sprintf("failed to record target resources of the migration: %v", err))
+	}
+}
 
+// targetFlavor returns the flavor the OpenStack instance of the VM is created with
+func (migobj *Migrate) targetFlavor(vminfo vm.VMInfo) (*flavors.Flavor, error) {
+	openstackops := migobj.Openstackclients
 	if migobj.UseFlavorless {
 		if migobj.TargetFlavorId == "" {
-			err = fmt.Errorf("flavorless creation is enabled, but TargetFlavorId in vmwaremachine %s is empty. Please set it to the ID of your base flavor (e.g., '0-0-x')", vminfo.Name)
-			return errors.Wrap(err, "failed to create target instance")
+			err := fmt.Errorf("flavorless creation is enabled, but TargetFlavorId in vmwaremachine %s is empty. Please set it to the ID of your base flavor (e.g., '0-0-x')", vminfo.Name)
+			return nil, errors.Wrap(err, "failed to create target instance")
 		}
 		migobj.logMessage(fmt.Sprintf("Using flavorless creation with base flavor ID: %s", migobj.TargetFlavorId))
-		flavor, err = openstackops.GetFlavor(migobj.TargetFlavorId)
+		flavor, err := openstackops.GetFlavor(migobj.TargetFlavorId)
 		if err != nil {
-			return errors.Wrap(err, "failed to get the specified base flavor for flavorless creation")
+			return nil, errors.Wrap(err, "failed to get the specified base flavor for flavorless creation")
 		}
-	} else if migobj.TargetFlavorId != "" {
-		flavor, err = openstackops.GetFlavor(migobj.TargetFlavorId)
-		if err != nil {
-			return errors.Wrap(err, "failed to get OpenStack flavor")
-		}
-	} else {
-		flavor, err = openstackops.GetClosestFlavour(vminfo.CPU, vminfo.Memory)
+		return flavor, nil
+	}
+	if migobj.TargetFlavorId != "" {
+		flavor, err := openstackops.GetFlavor(migobj.TargetFlavorId)
 		if err != nil {
-			return errors.Wrap(err, "failed to get closest OpenStack flavor")
+			return nil, errors.Wrap(err, "failed to get OpenStack flavor")
 		}
-		utils.PrintLog(fmt.Sprintf("Closest OpenStack flavor: %s: CPU: %dvCPUs\tMemory: %dMB\n", flavor.Name, flavor.VCPUs, flavor.RAM))
+		return flavor, nil
+	}
+	flavor, err := openstackops.GetClosestFlavour(vminfo.CPU, vminfo.Memory)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get closest OpenStack flavor")
+	}
+	utils.PrintLog(fmt.Sprintf("Closest OpenStack flavor: %s: CPU: %dvCPUs\tMemory: %dMB\n", flavor.Name, flavor.VCPUs, flavor.RAM))
+	return flavor, nil
+}
+
+func (migobj *Migrate) CreateTargetInstance(vminfo vm.VMInfo) error {
+	migobj.logMessage("Creating target instance")
+	openstackops := migobj.Openstackclients
+	networknames := migobj.Networknames
+
+	flavor, err := migobj.targetFlavor(vminfo)
+	if err != nil {
+		return err
 	}
 
 	securityGroupIDs, err := openstackops.GetSecurityGroupIDs(migobj.SecurityGroups, migobj.TenantName)
@@ -804,6 +1166,8 @@ func (migobj *Migrate) CreateTargetInstance(vminfo vm.VMInfo) error {
 	networkids := []string{}
 	ipaddresses := []string{}
 	portids := []string{}
+	// Ports given in the migration plan are not owned by the migration and are not recorded
+	createdPortIDs := []string{}
 
 	if len(migobj.Networkports) != 0 {
 		if len(migobj.Networkports) != len(networknames) {
@@ -831,6 +1195,18 @@ func (migobj *Migrate) CreateTargetInstance(vminfo vm.VMInfo) error {
 				return errors.Errorf("network not found")
 			}
 
+			if nic := migobj.networkOverride(idx); nic != nil {
+				port, err := migobj.createOverridePort(network, nic, vminfo, securityGroupIDs)
+				if err != nil {
+					return err
+				}
+				networkids = append(networkids, network.ID)
+				portids = append(portids, port.ID)
+				createdPortIDs = append(createdPortIDs, port.ID)
+				ipaddresses = append(ipaddresses, port.FixedIPs[0].IPAddress)
+				continue
+			}
+
 			ip := ""
 			if len(vminfo.Mac) != len(vminfo.IPs) {
 				ip = ""
@@ -849,6 +1225,7 @@ func (migobj *Migrate) CreateTargetInstance(vminfo vm.VMInfo) error {
 			utils.PrintLog(fmt.Sprintf("Port created successfully: MAC:%s IP:%s\n", port.MACAddress, port.FixedIPs[0].IPAddress))
 			networkids = append(networkids, network.ID)
 			portids = append(portids, port.ID)
+			createdPortIDs = append(createdPortIDs, port.ID)
 			ipaddresses = append(ipaddresses, port.FixedIPs[0].IPAddress)
 		}
 	}
@@ -864,6 +1241,7 @@ func (migobj *Migrate) CreateTargetInstance(vminfo vm.VMInfo) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to create VM")
 	}
+	migobj.saveTargetResources(newVM.ID, createdPortIDs, vminfo)
 
 	// Wait for VM to become active
 	for i := 0; i < migrateSettings.VMActiveWaitRetryLimit; i++ {
@@ -883,18 +1261,28 @@ func (migobj *Migrate) CreateTargetInstance(vminfo vm.VMInfo) error {
 
 	migobj.logMessage(fmt.Sprintf("VM created successfully: ID: %s", newVM.ID))
 
-	if migobj.PerformHealthChecks {
-		err = migobj.HealthCheck(vminfo, ipaddresses)
-		if err != nil {
-			migobj.logMessage(fmt.Sprintf("Health Check failed: %s", err))
-		}
-	} else {
-		migobj.logMessage("Skipping Health Checks")
+	if err := migobj.runHooks(context.Background(), vminfo.Name, migratev1alpha1.MigrationHookPostTargetBoot); err != nil {
+		return err
 	}
 
+	if migobj.PerformHealthChecks {
+		return migobj.checkTargetHealth(vminfo, ipaddresses)
+	}
+	migobj.logMessage("Skipping Health Checks")
 	return nil
 }
 
+// checkTargetHealth runs the health checks of the target VM and, only once they pass, the PostHealthCheck hooks.
+// A failed health check does not fail the migration, it is reported with an event the controller rolls the
+// migration back on if the migration plan asks for it.
+func (migobj *Migrate) checkTargetHealth(vminfo vm.VMInfo, ips []string) error {
+	if err := migobj.HealthCheck(vminfo, ips); err != nil {
+		migobj.logMessage(fmt.Sprintf("%s: %s", constants.EventMessageHealthCheckFailed, err))
+		return nil
+	}
+	return migobj.runHooks(context.Background(), vminfo.Name, migratev1alpha1.MigrationHookPostHealthCheck)
+}
+
 // parseVersionID parses the VERSION_ID from /etc/os-release or /etc/redhat-release format.
 // It returns the version ID as a string, or an empty string if not found.
 func parseVersionID(osRelease string) string {
@@ -1030,17 +1418,26 @@ func (migobj *Migrate) tryConnection(client *http.Client, url string) error {
 	return nil
 }
 
+// healthCheckAttempts and healthCheckRetryInterval bound how long the health checks of the target VM are retried
+var (
+	healthCheckAttempts      = 10
+	healthCheckRetryInterval = 60 * time.Second
+)
+
+// HealthCheck pings the target VM and checks that it answers HTTP GET requests on the health check port. The
+// checks are retried until they pass, it returns an error naming the checks that still fail after the retries.
 func (migobj *Migrate) HealthCheck(vminfo vm.VMInfo, ips []string) error {
 	migobj.logMessage("Performing Health Checks")
+	checkNames := []string{"Ping", "HTTP Get"}
 	healthChecks := make(map[string]bool)
 	healthChecks["Ping"] = false
 	healthChecks["HTTP Get"] = false
-	for i := 0; i < len(vminfo.IPs); i++ {
+	for i := 0; i < len(vminfo.IPs) && i < len(ips); i++ {
 		if ips[i] != vminfo.IPs[i] {
 			migobj.logMessage(fmt.Sprintf("VM has been assigned a new IP: %s instead of the original IP %s. Using the new IP for tests", ips[i], vminfo.IPs[i]))
 		}
 	}
-	for i := 0; i < 10; i++ {
+	for i := 0; i < healthCheckAttempts; i++ {
 		migobj.logMessage(fmt.Sprintf("Health Check Attempt %d", i+1))
 		// 1. Ping
 		if !healthChecks["Ping"] {
@@ -1063,16 +1460,24 @@ func (migobj *Migrate) HealthCheck(vminfo vm.VMInfo, ips []string) error {
 		if healthChecks["Ping"] && healthChecks["HTTP Get"] {
 			break
 		}
-		migobj.logMessage("Waiting for 60 seconds before retrying health checks")
-		time.Sleep(60 * time.Second)
+		if i == healthCheckAttempts-1 {
+			break
+		}
+		migobj.logMessage(fmt.Sprintf("Waiting for %s before retrying health checks", healthCheckRetryInterval))
+		time.Sleep(healthCheckRetryInterval)
 	}
-	for key, value := range healthChecks {
-		if !value {
+	var failed []string
+	for _, key := range checkNames {
+		if !healthChecks[key] {
 			migobj.logMessage(fmt.Sprintf("Health Check %s failed", key))
+			failed = append(failed, key)
 		} else {
 			migobj.logMessage(fmt.Sprintf("Health Check %s succeeded", key))
 		}
 	}
+	if len(failed) > 0 {
+		return errors.Errorf("%s still failing after %d attempts", strings.Join(failed, ", "), healthCheckAttempts)
+	}
 	return nil
 }
 
@@ -1083,18 +1488,28 @@ func (migobj *Migrate) gracefulTerminate(vminfo vm.VMInfo, cancel context.Cancel
 	<-gracefulShutdown
 	migobj.logMessage("Gracefully terminating")
 	cancel()
-	migobj.cleanup(vminfo, "Migration terminated")
+	if migobj.hasCopyCheckpoint() {
+		migobj.keepVolumesForResume(vminfo, "Migration terminated")
+	} else {
+		migobj.cleanup(vminfo, "Migration terminated")
+	}
 	os.Exit(0)
 }
 
-func (migobj *Migrate) MigrateVM(ctx context.Context) error {
+func (migobj *Migrate) MigrateVM(ctx context.Context) (reterr error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
+	defer func() {
+		if reterr != nil {
+			migobj.setProgressPhase(migratev1alpha1.VMMigrationPhaseFailed, migobj.progressTracker().iteration())
+		}
+	}()
 
 	// Wait until the data copy start time
 	var zerotime time.Time
 	if !migobj.MigrationTimes.DataCopyStart.Equal(zerotime) && migobj.MigrationTimes.DataCopyStart.After(time.Now()) {
 		migobj.logMessage("Waiting for data copy start time")
+		migobj.setProgressPhase(migratev1alpha1.VMMigrationPhaseAwaitingDataCopyStart, 0)
 		time.Sleep(time.Until(migobj.MigrationTimes.DataCopyStart))
 		migobj.logMessage("Data copy start time reached")
 	}
@@ -1110,6 +1525,20 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 	if len(vminfo.Mac) != len(migobj.Networknames) {
 		return errors.Errorf("number of mac addresses does not match number of network names mac(%d) network(%d)", len(vminfo.Mac), len(migobj.Networknames))
 	}
+	vminfo, err = migobj.selectDisks(vminfo)
+	if err != nil {
+		return errors.Wrap(err, "failed to select disks")
+	}
+	vminfo, err = migobj.selectSharedDisks(vminfo)
+	if err != nil {
+		return errors.Wrap(err, "failed to select shared disks")
+	}
+	if err := migobj.ValidateVMEncryption(vminfo); err != nil {
+		return err
+	}
+	// Pick up the copy progress of an earlier attempt, if any
+	migobj.loadCopyCheckpoint(ctx)
+
 	// Graceful Termination clean-up volumes and snapshots
 	go migobj.gracefulTerminate(vminfo, cancel)
 
@@ -1125,20 +1554,46 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 		return errors.Wrap(err, "CBT Failure")
 	}
 
+	// Limit the rate at which the disks are read from VMware
+	rateFile, err := migobj.startBandwidthThrottle(ctx)
+	if err != nil {
+		migobj.cleanup(vminfo, fmt.Sprintf("failed to set up bandwidth limit: %s", err))
+		return errors.Wrap(err, "failed to set up bandwidth limit")
+	}
+
 	// Create NBD servers
 	for range vminfo.VMDisks {
-		migobj.Nbdops = append(migobj.Nbdops, &nbd.NBDServer{})
+		migobj.Nbdops = append(migobj.Nbdops, &nbd.NBDServer{RateFile: rateFile, Progress: migobj.updateDiskProgress})
 	}
 
 	// Live Replicate Disks
+	phaseStart := time.Now()
 	vminfo, err = migobj.LiveReplicateDisks(ctx, vminfo)
+	metrics.ObservePhaseDuration(vminfo.Name, metrics.PhaseCopy, time.Since(phaseStart))
 	if err != nil {
+		if migobj.hasCopyCheckpoint() {
+			migobj.keepVolumesForResume(vminfo, fmt.Sprintf("failed to live replicate disks: %s", err))
+			return errors.Wrap(err, "failed to live replicate disks")
+		}
 		if cleanuperror := migobj.cleanup(vminfo, fmt.Sprintf("failed to live replicate disks: %s", err)); cleanuperror != nil {
 			// combine both errors
 			return errors.Wrapf(err, "failed to cleanup disks: %s", cleanuperror)
 		}
 		return errors.Wrap(err, "failed to live replicate disks")
 	}
+	// The volumes are modified from here on, so an earlier checkpoint no longer applies
+	migobj.clearCopyCheckpoint(ctx)
+
+	// Compare the volumes with the source disks before they are converted
+	err = migobj.VerifyDisks(ctx, vminfo)
+	if err != nil {
+		if cleanuperror := migobj.cleanup(vminfo, fmt.Sprintf("failed to verify disk data: %s", err)); cleanuperror != nil {
+			// combine both errors
+			return errors.Wrapf(err, "failed to cleanup disks: %s", cleanuperror)
+		}
+		return errors.Wrap(err, "failed to verify disk data")
+	}
+
 	// Import LUN and MigrateRDM disk
 	for idx, rdmDisk := range vminfo.RDMDisks {
 		volumeID, err := migobj.cinderManage(rdmDisk)
@@ -1155,7 +1610,9 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 	}
 
 	// Convert the Boot Disk to raw format
+	phaseStart = time.Now()
 	err = migobj.ConvertVolumes(ctx, vminfo)
+	metrics.ObservePhaseDuration(vminfo.Name, metrics.PhaseConvert, time.Since(phaseStart))
 	if err != nil {
 		if !vcenterSettings.CleanupVolumesAfterConvertFailure {
 			migobj.logMessage("Cleanup volumes after convert failure is disabled, detaching volumes and cleaning up snapshots")
@@ -1178,7 +1635,17 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 		return errors.Wrap(err, "failed to convert disks")
 	}
 
+	if err := migobj.attachSharedDisks(ctx, &vminfo); err != nil {
+		if cleanuperror := migobj.cleanup(vminfo, fmt.Sprintf("failed to attach shared disks: %s", err)); cleanuperror != nil {
+			// combine both errors
+			return errors.Wrapf(err, "failed to cleanup disks: %s", cleanuperror)
+		}
+		return errors.Wrap(err, "failed to attach shared disks")
+	}
+
+	phaseStart = time.Now()
 	err = migobj.CreateTargetInstance(vminfo)
+	metrics.ObservePhaseDuration(vminfo.Name, metrics.PhaseCreateInstance, time.Since(phaseStart))
 	if err != nil {
 		if cleanuperror := migobj.cleanup(vminfo, fmt.Sprintf("failed to create target instance: %s", err)); cleanuperror != nil {
 			// combine both errors
@@ -1186,6 +1653,8 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 		}
 		return errors.Wrap(err, "failed to create target instance")
 	}
+	migobj.publishSharedDisks(vminfo)
+	migobj.setProgressPhase(migratev1alpha1.VMMigrationPhaseSucceeded, migobj.progressTracker().iteration())
 
 	if err := migobj.DisconnectSourceNetworkIfRequested(); err != nil {
 		migobj.logMessage(fmt.Sprintf("Warning: Failed to disconnect source VM network interfaces: %v", err))
@@ -1204,6 +1673,7 @@ func (migobj *Migrate) cleanup(vminfo vm.VMInfo, message string) error {
 	if err != nil {
 		utils.PrintLog(fmt.Sprintf("Failed to delete all volumes from host: %s\n", err))
 	}
+	migobj.clearCopyCheckpoint(context.TODO())
 	err = migobj.VMops.CleanUpSnapshots(true)
 	if err != nil {
 		utils.PrintLog(fmt.Sprintf("Failed to cleanup snapshot of source VM: %s\n", err))
@@ -1212,6 +1682,20 @@ func (migobj *Migrate) cleanup(vminfo vm.VMInfo, message string) error {
 	return nil
 }
 
+// keepVolumesForResume detaches the volumes and removes the migration snapshots, but keeps
+// the volumes and the copy checkpoint so that a retry of the migration can resume the copy
+func (migobj *Migrate) keepVolumesForResume(vminfo vm.VMInfo, message string) {
+	migobj.logMessage(fmt.Sprintf("%s. Trying to perform cleanup, keeping volumes to resume the copy on retry", message))
+	err := migobj.DetachAllVolumes(vminfo)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to detach all volumes from VM: %s\n", err))
+	}
+	err = migobj.VMops.CleanUpSnapshots(true)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to cleanup snapshot of source VM: %s\n", err))
+	}
+}
+
 // cinderManage imports a LUN into OpenStack Cinder and returns the volume ID.
 func (migobj *Migrate) cinderManage(rdmDisk vm.RDMDisk) (string, error) {
 	openstackops := migobj.Openstackclients
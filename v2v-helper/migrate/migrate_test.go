@@ -2,10 +2,17 @@ package migrate
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/nbd"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/openstack"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
 
@@ -15,6 +22,7 @@ import (
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
@@ -38,11 +46,11 @@ func TestCreateVolumes(t *testing.T) {
 
 	gomock.InOrder(
 		mockOpenStackOps.EXPECT().
-			CreateVolume(inputvminfo.Name+"-"+inputvminfo.VMDisks[0].Name, inputvminfo.VMDisks[0].Size, "linux", false, "voltype-1").
+			CreateVolume(inputvminfo.Name+"-"+inputvminfo.VMDisks[0].Name, inputvminfo.VMDisks[0].Size, "linux", false, "voltype-1", false).
 			Return(&volumes.Volume{ID: "id1", Name: "test-vm-disk1"}, nil).
 			AnyTimes(),
 		mockOpenStackOps.EXPECT().
-			CreateVolume(inputvminfo.Name+"-"+inputvminfo.VMDisks[1].Name, inputvminfo.VMDisks[1].Size, "linux", false, "voltype-2").
+			CreateVolume(inputvminfo.Name+"-"+inputvminfo.VMDisks[1].Name, inputvminfo.VMDisks[1].Size, "linux", false, "voltype-2", false).
 			Return(&volumes.Volume{ID: "id2", Name: "test-vm-disk2"}, nil).
 			AnyTimes(),
 	)
@@ -236,7 +244,7 @@ func TestLiveReplicateDisks(t *testing.T) {
 			AnyTimes(),
 		mockOpenStackOps.EXPECT().AttachVolumeToVM("id1").Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().FindDevice("id1").Return("/dev/sda", nil).AnyTimes(),
-		mockNBD.EXPECT().CopyChangedBlocks(context.TODO(), changedAreasexample, "/dev/sda").Return(nil).AnyTimes(),
+		mockNBD.EXPECT().CopyChangedBlocks(context.TODO(), changedAreasexample, "/dev/sda", 0).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().DetachVolumeFromVM(gomock.Any()).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().WaitForVolume(gomock.Any()).Return(nil).AnyTimes(),
 		// Incremental Copy Disk 2
@@ -256,7 +264,7 @@ func TestLiveReplicateDisks(t *testing.T) {
 			AnyTimes(),
 		mockOpenStackOps.EXPECT().AttachVolumeToVM("id2").Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().FindDevice("id2").Return("/dev/sdb", nil).AnyTimes(),
-		mockNBD.EXPECT().CopyChangedBlocks(context.TODO(), changedAreasexample, "/dev/sdb").Return(nil).AnyTimes(),
+		mockNBD.EXPECT().CopyChangedBlocks(context.TODO(), changedAreasexample, "/dev/sdb", 1).Return(nil).AnyTimes(),
 		// 2. Only Disk 1 Changes
 		mockVMOps.EXPECT().
 			UpdateDiskInfo(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes(),
@@ -280,7 +288,7 @@ func TestLiveReplicateDisks(t *testing.T) {
 		mockNBD.EXPECT().StartNBDServer(&object.VirtualMachine{}, envURL, envUserName, envPassword, thumbprint, "migration-snap", "[ds1] test_vm/test_vm.vmdk", dummychan).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().AttachVolumeToVM("id1").Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().FindDevice("id1").Return("/dev/sda", nil).AnyTimes(),
-		mockNBD.EXPECT().CopyChangedBlocks(context.TODO(), changedAreasexample, "/dev/sda").Return(nil).AnyTimes(),
+		mockNBD.EXPECT().CopyChangedBlocks(context.TODO(), changedAreasexample, "/dev/sda", 0).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().DetachVolumeFromVM(gomock.Any()).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().WaitForVolume(gomock.Any()).Return(nil).AnyTimes(),
 		// No copy for Disk 2
@@ -567,3 +575,152 @@ func TestCreateTargetInstance_AdvancedMapping_InsufficientPorts(t *testing.T) {
 	err := migobj.CreateTargetInstance(inputvminfo)
 	assert.Contains(t, err.Error(), "number of network ports does not match number of network names")
 }
+
+func TestCopyDisksConcurrently(t *testing.T) {
+	migobj := Migrate{}
+	vminfo := vm.VMInfo{VMDisks: []vm.VMDisk{{Name: "disk1"}, {Name: "disk2"}, {Name: "disk3"}, {Name: "disk4"}}}
+
+	var running, maxRunning atomic.Int32
+	err := migobj.copyDisksConcurrently(context.TODO(), vminfo, 2, func(ctx context.Context, idx int) error {
+		cur := running.Add(1)
+		defer running.Add(-1)
+		for {
+			prev := maxRunning.Load()
+			if cur <= prev || maxRunning.CompareAndSwap(prev, cur) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), maxRunning.Load())
+
+	var copied atomic.Int32
+	err = migobj.copyDisksConcurrently(context.TODO(), vminfo, 1, func(ctx context.Context, idx int) error {
+		copied.Add(1)
+		if idx == 1 {
+			return errors.New("nbdcopy failed")
+		}
+		return nil
+	})
+	assert.ErrorContains(t, err, "disk 1: nbdcopy failed")
+	assert.Equal(t, int32(2), copied.Load())
+}
+
+func TestCopyChangedBlocksOfDisksConcurrently(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	vminfo := vm.VMInfo{
+		Name: "test-vm",
+		VMDisks: []vm.VMDisk{
+			{Name: "disk1", Size: int64(1024), Path: "/dev/sda", Disk: &types.VirtualDisk{}, ChangeID: "1"},
+			{Name: "disk2", Size: int64(1024), Path: "/dev/sdb", Disk: &types.VirtualDisk{}, ChangeID: "1"},
+			{Name: "disk3", Size: int64(1024), Path: "/dev/sdc", Disk: &types.VirtualDisk{}, ChangeID: "1"},
+		},
+	}
+	changedAreas := types.DiskChangeInfo{ChangedArea: []types.DiskChangeExtent{{Start: 0, Length: 10}}}
+
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	mockVMOps.EXPECT().CustomQueryChangedDiskAreas("1", gomock.Any(), gomock.Any(), int64(0)).Return(changedAreas, nil).Times(3)
+	mockVMOps.EXPECT().GetVMObj().Return(&object.VirtualMachine{}).Times(3)
+	mockVMOps.EXPECT().UpdateDiskInfo(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(vminfo *vm.VMInfo, disk vm.VMDisk, blockCopySuccess bool) error {
+			assert.Len(t, vminfo.VMDisks, 1)
+			vminfo.VMDisks[0].Snapname = "migration-snap"
+			if blockCopySuccess {
+				vminfo.VMDisks[0].ChangeID = "2-" + disk.Name
+			}
+			return nil
+		}).Times(6)
+
+	var nbdops []nbd.NBDOperations
+	for _, disk := range vminfo.VMDisks {
+		mockNBD := nbd.NewMockNBDOperations(ctrl)
+		mockNBD.EXPECT().StopNBDServer().Return(nil)
+		mockNBD.EXPECT().StartNBDServer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "migration-snap", gomock.Any(), gomock.Any()).Return(nil)
+		mockNBD.EXPECT().CopyChangedBlocks(gomock.Any(), changedAreas, disk.Path, gomock.Any()).Return(nil)
+		nbdops = append(nbdops, mockNBD)
+	}
+	migobj := Migrate{VMops: mockVMOps, Nbdops: nbdops}
+
+	disks := make([]vm.VMDisk, len(vminfo.VMDisks))
+	err := migobj.copyDisksConcurrently(context.TODO(), vminfo, len(vminfo.VMDisks), func(ctx context.Context, idx int) error {
+		disk, changed, err := migobj.copyChangedBlocksOfDisk(ctx, vminfo, idx, &types.ManagedObjectReference{}, 1, false)
+		assert.True(t, changed)
+		disks[idx] = disk
+		return err
+	})
+	assert.NoError(t, err)
+	for idx, disk := range disks {
+		assert.Equal(t, vminfo.VMDisks[idx].Name, disk.Name)
+		assert.Equal(t, "2-"+disk.Name, disk.ChangeID)
+	}
+}
+
+// failingHealthCheckTarget returns the IP and port of a target whose HTTP health check fails, and makes the health
+// checks give up after one attempt
+func failingHealthCheckTarget(t *testing.T) (string, string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+	attempts := healthCheckAttempts
+	healthCheckAttempts = 1
+	t.Cleanup(func() { healthCheckAttempts = attempts })
+	serverURL, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+	return serverURL.Hostname(), serverURL.Port()
+}
+
+// reportedEvents returns the events reported so far
+func reportedEvents(events chan string) []string {
+	var messages []string
+	for {
+		select {
+		case message := <-events:
+			messages = append(messages, message)
+		default:
+			return messages
+		}
+	}
+}
+
+func TestHealthCheckFailed(t *testing.T) {
+	ip, port := failingHealthCheckTarget(t)
+	events := make(chan string, 100)
+	migobj := Migrate{InPod: true, EventReporter: events, HealthCheckPort: port}
+
+	err := migobj.HealthCheck(vm.VMInfo{IPs: []string{ip}}, []string{ip})
+	assert.ErrorContains(t, err, "HTTP Get")
+
+	// The failure is reported with the event the controller rolls the migration back on
+	assert.NoError(t, migobj.checkTargetHealth(vm.VMInfo{Name: "vm1", IPs: []string{ip}}, []string{ip}))
+	failed := false
+	for _, message := range reportedEvents(events) {
+		failed = failed || strings.HasPrefix(message, constants.EventMessageHealthCheckFailed+": ")
+	}
+	assert.True(t, failed)
+}
+
+func TestCheckTargetHealthSkipsHooksOnFailure(t *testing.T) {
+	ip, port := failingHealthCheckTarget(t)
+	var hookCalls atomic.Int32
+	hookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hookCalls.Add(1)
+	}))
+	defer hookServer.Close()
+	migobj := Migrate{
+		HealthCheckPort: port,
+		Hooks: []migratev1alpha1.MigrationHook{{
+			Name:  "loadbalancer",
+			Point: migratev1alpha1.MigrationHookPostHealthCheck,
+			HTTP:  &migratev1alpha1.MigrationHookHTTP{URL: hookServer.URL},
+		}},
+	}
+
+	assert.NoError(t, migobj.checkTargetHealth(vm.VMInfo{Name: "vm1", IPs: []string{ip}}, []string{ip}))
+	assert.Zero(t, hookCalls.Load())
+	assert.Empty(t, migobj.hookResults)
+}
@@ -0,0 +1,147 @@
+package migrate
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/virtv2v"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
+	"github.com/pkg/errors"
+)
+
+// ParseNetworkOverrides parses the NIC overrides of the VM. The controller has already resolved
+// the subnets to IDs and validated them against Neutron.
+func ParseNetworkOverrides(overrides string) ([]migratev1alpha1.NICNetworkOverride, error) {
+	if overrides == "" {
+		return nil, nil
+	}
+	var nics []migratev1alpha1.NICNetworkOverride
+	if err := json.Unmarshal([]byte(overrides), &nics); err != nil {
+		return nil, errors.Wrap(err, "invalid network overrides")
+	}
+	return nics, nil
+}
+
+// networkOverride returns the override of the NIC at idx, or nil if it has none
+func (migobj *Migrate) networkOverride(idx int) *migratev1alpha1.NICNetworkOverride {
+	for i := range migobj.NetworkOverrides {
+		if migobj.NetworkOverrides[i].Index == idx {
+			return &migobj.NetworkOverrides[i]
+		}
+	}
+	return nil
+}
+
+// createOverridePort creates the port of a NIC with an override in the subnet of the override
+func (migobj *Migrate) createOverridePort(network *networks.Network, nic *migratev1alpha1.NICNetworkOverride, vminfo vm.VMInfo, securityGroupIDs []string) (*ports.Port, error) {
+	mac, err := migobj.targetMAC(nic.Index, vminfo)
+	if err != nil {
+		return nil, err
+	}
+	utils.PrintLog(fmt.Sprintf("Using network override for NIC %d: network %s, subnet %s, IP '%s', MAC %s",
+		nic.Index, network.Name, nic.Subnet, nic.FixedIP, mac))
+	// Each NIC has its own port name, so that a retry finds the port it created before
+	port, err := migobj.Openstackclients.CreatePortInSubnet(network, nic.Subnet, mac, nic.FixedIP,
+		fmt.Sprintf("%s-%d", vminfo.Name, nic.Index), securityGroupIDs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create port for NIC %d", nic.Index)
+	}
+	return port, nil
+}
+
+// targetMAC returns the MAC address of the NIC at idx on the target. It is the source MAC unless an
+// override drops it, in which case a MAC is generated once so that the port and the guest agree.
+func (migobj *Migrate) targetMAC(idx int, vminfo vm.VMInfo) (string, error) {
+	nic := migobj.networkOverride(idx)
+	if nic == nil || nic.PreserveMAC == nil || *nic.PreserveMAC {
+		return vminfo.Mac[idx], nil
+	}
+	if mac, ok := migobj.generatedMACs[idx]; ok {
+		return mac, nil
+	}
+	buf := make([]byte, 3)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "failed to generate MAC address")
+	}
+	// fa:16:3e is the prefix Neutron uses for the MAC addresses it generates
+	mac := fmt.Sprintf("fa:16:3e:%02x:%02x:%02x", buf[0], buf[1], buf[2])
+	if migobj.generatedMACs == nil {
+		migobj.generatedMACs = map[int]string{}
+	}
+	migobj.generatedMACs[idx] = mac
+	return mac, nil
+}
+
+// guestNICConfigs returns the network configuration to write into the guest for each NIC. NICs keep
+// the static configuration reported by VMware Tools unless an override moves them to another subnet,
+// in which case a fixed IP takes its prefix, gateway and DNS from the subnet and otherwise DHCP is used.
+func (migobj *Migrate) guestNICConfigs(vminfo vm.VMInfo) ([]virtv2v.GuestNICConfig, error) {
+	nics := make([]virtv2v.GuestNICConfig, 0, len(vminfo.Mac))
+	for idx, sourceMAC := range vminfo.Mac {
+		mac, err := migobj.targetMAC(idx, vminfo)
+		if err != nil {
+			return nil, err
+		}
+		nic := virtv2v.GuestNICConfig{MAC: mac, DHCP: true}
+		var source *migratev1alpha1.GuestNetwork
+		for i := range vminfo.GuestNetworks {
+			// Ignore the IPv6 addresses
+			if strings.EqualFold(vminfo.GuestNetworks[i].MAC, sourceMAC) && !strings.Contains(vminfo.GuestNetworks[i].IP, ":") {
+				source = &vminfo.GuestNetworks[i]
+				break
+			}
+		}
+		if source != nil && source.IP != "" && source.PrefixLength > 0 && !strings.EqualFold(source.Origin, "dhcp") {
+			nic = virtv2v.GuestNICConfig{
+				MAC:          mac,
+				Address:      source.IP,
+				PrefixLength: int(source.PrefixLength),
+				Gateway:      source.Gateway,
+				DNS:          source.DNS,
+			}
+		}
+
+		if override := migobj.networkOverride(idx); override != nil {
+			if override.FixedIP == "" {
+				nic = virtv2v.GuestNICConfig{MAC: mac, DHCP: true}
+			} else {
+				subnet, err := migobj.Openstackclients.GetSubnet(override.Subnet)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to get subnet of NIC %d", idx)
+				}
+				_, cidr, err := net.ParseCIDR(subnet.CIDR)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to parse CIDR of subnet %s", subnet.ID)
+				}
+				prefixLength, _ := cidr.Mask.Size()
+				dns := subnet.DNSNameservers
+				if len(dns) == 0 && source != nil {
+					dns = source.DNS
+				}
+				nic = virtv2v.GuestNICConfig{
+					MAC:          mac,
+					Address:      override.FixedIP,
+					PrefixLength: prefixLength,
+					Gateway:      subnet.GatewayIP,
+					DNS:          dns,
+				}
+			}
+		}
+		nic.SourceMAC = strings.ToLower(sourceMAC)
+		if nic.DHCP {
+			utils.PrintLog(fmt.Sprintf("Guest network of NIC %d (%s): DHCP", idx, nic.MAC))
+		} else {
+			utils.PrintLog(fmt.Sprintf("Guest network of NIC %d (%s): %s/%d, gateway '%s', DNS %v",
+				idx, nic.MAC, nic.Address, nic.PrefixLength, nic.Gateway, nic.DNS))
+		}
+		nics = append(nics, nic)
+	}
+	return nics, nil
+}
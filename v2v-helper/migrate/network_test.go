@@ -0,0 +1,137 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/openstack"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/virtv2v"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseNetworkOverrides(t *testing.T) {
+	nics, err := ParseNetworkOverrides("")
+	assert.NoError(t, err)
+	assert.Nil(t, nics)
+
+	nics, err = ParseNetworkOverrides(`[{"index":1,"network":"net-b","subnet":"subnet-id","fixedIP":"10.0.0.5","preserveMAC":false}]`)
+	assert.NoError(t, err)
+	assert.Len(t, nics, 1)
+	assert.Equal(t, 1, nics[0].Index)
+	assert.Equal(t, "subnet-id", nics[0].Subnet)
+	assert.False(t, *nics[0].PreserveMAC)
+
+	_, err = ParseNetworkOverrides("not json")
+	assert.Error(t, err)
+}
+
+func TestCreateTargetInstance_NetworkOverrides(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	mockOpenStackOps.EXPECT().GetFlavor("flavor-id").Return(&flavors.Flavor{VCPUs: 2, RAM: 2048}, nil).AnyTimes()
+	mockOpenStackOps.EXPECT().GetSecurityGroupIDs(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	mockOpenStackOps.EXPECT().GetNetwork("network-name-1").Return(&networks.Network{ID: "network-1"}, nil)
+	mockOpenStackOps.EXPECT().GetNetwork("network-name-2").Return(&networks.Network{ID: "network-2"}, nil)
+	// The first NIC has no override and keeps the source IP
+	mockOpenStackOps.EXPECT().CreatePort(gomock.Any(), "mac-address-1", "ip-address-1", "test-vm", gomock.Any()).Return(&ports.Port{
+		ID:       "port-1",
+		FixedIPs: []ports.IP{{IPAddress: "ip-address-1"}},
+	}, nil)
+	// The second NIC gets the fixed IP from the chosen subnet and a new MAC
+	mockOpenStackOps.EXPECT().CreatePortInSubnet(gomock.Any(), "subnet-id", gomock.Not("mac-address-2"), "10.0.0.5", "test-vm-1", gomock.Any()).Return(&ports.Port{
+		ID:       "port-2",
+		FixedIPs: []ports.IP{{IPAddress: "10.0.0.5"}},
+	}, nil)
+	mockOpenStackOps.EXPECT().CreateVM(gomock.Any(), []string{"network-1", "network-2"}, []string{"port-1", "port-2"},
+		gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&servers.Server{}, nil)
+	mockOpenStackOps.EXPECT().WaitUntilVMActive(gomock.Any()).Return(true, nil).AnyTimes()
+
+	preserveMAC := false
+	migobj := Migrate{
+		Openstackclients: mockOpenStackOps,
+		Networknames:     []string{"network-name-1", "network-name-2"},
+		NetworkOverrides: []migratev1alpha1.NICNetworkOverride{
+			{Index: 1, Subnet: "subnet-id", FixedIP: "10.0.0.5", PreserveMAC: &preserveMAC},
+		},
+		InPod:          false,
+		TargetFlavorId: "flavor-id",
+		K8sClient: fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      constants.StellarisMigrateSettingsConfigMapName,
+				Namespace: constants.NamespaceMigrationSystem,
+			},
+		}).Build(),
+	}
+	err := migobj.CreateTargetInstance(vm.VMInfo{
+		Name:   "test-vm",
+		OSType: "linux",
+		Mac:    []string{"mac-address-1", "mac-address-2"},
+		IPs:    []string{"ip-address-1", "ip-address-2"},
+	})
+	assert.NoError(t, err)
+}
+
+func TestGuestNICConfigs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	mockOpenStackOps.EXPECT().GetSubnet("subnet-id").Return(&subnets.Subnet{
+		ID:             "subnet-id",
+		CIDR:           "10.0.0.0/24",
+		GatewayIP:      "10.0.0.1",
+		DNSNameservers: []string{"10.0.0.2"},
+	}, nil)
+
+	preserveMAC := false
+	migobj := Migrate{
+		Openstackclients: mockOpenStackOps,
+		NetworkOverrides: []migratev1alpha1.NICNetworkOverride{
+			{Index: 1, Subnet: "subnet-id", FixedIP: "10.0.0.5", PreserveMAC: &preserveMAC},
+			{Index: 2, Subnet: "other-subnet-id"},
+		},
+	}
+	vminfo := vm.VMInfo{
+		Mac: []string{"00:50:56:00:00:01", "00:50:56:00:00:02", "00:50:56:00:00:03", "00:50:56:00:00:04"},
+		GuestNetworks: []migratev1alpha1.GuestNetwork{
+			{MAC: "00:50:56:00:00:01", IP: "fe80::1", PrefixLength: 64, Origin: "linklayer"},
+			{MAC: "00:50:56:00:00:01", IP: "192.168.1.10", PrefixLength: 24, Origin: "manual", Gateway: "192.168.1.1", DNS: []string{"192.168.1.2"}},
+			{MAC: "00:50:56:00:00:02", IP: "192.168.2.10", PrefixLength: 24, Origin: "manual", DNS: []string{"192.168.1.2"}},
+			{MAC: "00:50:56:00:00:03", IP: "192.168.3.10", PrefixLength: 24, Origin: "manual"},
+			{MAC: "00:50:56:00:00:04", IP: "192.168.4.10", PrefixLength: 24, Origin: "dhcp"},
+		},
+	}
+	nics, err := migobj.guestNICConfigs(vminfo)
+	assert.NoError(t, err)
+	assert.Len(t, nics, 4)
+	// The static configuration of the source is kept
+	assert.Equal(t, virtv2v.GuestNICConfig{
+		MAC: "00:50:56:00:00:01", SourceMAC: "00:50:56:00:00:01", Address: "192.168.1.10", PrefixLength: 24, Gateway: "192.168.1.1", DNS: []string{"192.168.1.2"},
+	}, nics[0])
+	// The fixed IP of the override takes its settings from the subnet and gets a new MAC
+	assert.Equal(t, "10.0.0.5", nics[1].Address)
+	assert.Equal(t, 24, nics[1].PrefixLength)
+	assert.Equal(t, "10.0.0.1", nics[1].Gateway)
+	assert.Equal(t, []string{"10.0.0.2"}, nics[1].DNS)
+	assert.Regexp(t, "^fa:16:3e:", nics[1].MAC)
+	// The generated MAC is also used for the port
+	mac, err := migobj.targetMAC(1, vminfo)
+	assert.NoError(t, err)
+	assert.Equal(t, nics[1].MAC, mac)
+	// A new subnet without a fixed IP and a DHCP source use DHCP
+	assert.True(t, nics[2].DHCP)
+	assert.True(t, nics[3].DHCP)
+}
@@ -0,0 +1,175 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// progressTracker collects the typed progress of the migration. It is published as JSON in an
+// annotation of the pod, from where the controller copies it to the status of the Migration.
+type progressTracker struct {
+	mu            sync.Mutex
+	progress      migratev1alpha1.MigrationProgress
+	diskStarts    map[int]time.Time
+	diskWindows   map[int]progressWindow
+	lastPublished time.Time
+	// publish writes the progress, it is nil when the helper does not run in a pod
+	publish func(progress string) error
+}
+
+// progressWindow is the part of the copy of a disk a batch copies, in bytes. The fractions reported while the batch
+// is copied are fractions of the batch.
+type progressWindow struct {
+	offset, length, total int64
+}
+
+// progressTracker returns the progress tracker of the migration, creating it on first use
+func (migobj *Migrate) progressTracker() *progressTracker {
+	migobj.progressMu.Lock()
+	defer migobj.progressMu.Unlock()
+	if migobj.progress == nil {
+		migobj.progress = &progressTracker{diskStarts: map[int]time.Time{}, diskWindows: map[int]progressWindow{}}
+		if migobj.InPod && migobj.Reporter != nil {
+			migobj.progress.publish = func(progress string) error {
+				return migobj.Reporter.SetPodAnnotation(constants.MigrationProgressAnnotation, progress)
+			}
+		}
+	}
+	return migobj.progress
+}
+
+// iteration returns the changed block copy iteration of the migration
+func (tracker *progressTracker) iteration() int {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	return tracker.progress.Iteration
+}
+
+// setProgressPhase publishes the phase the migration is in. Entering a copy iteration resets the
+// progress of the disks.
+func (migobj *Migrate) setProgressPhase(phase migratev1alpha1.VMMigrationPhase, iteration int) {
+	tracker := migobj.progressTracker()
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	if (phase == migratev1alpha1.VMMigrationPhaseCopying || phase == migratev1alpha1.VMMigrationPhaseCopyingChangedBlocks) &&
+		(tracker.progress.Phase != phase || tracker.progress.Iteration != iteration) {
+		tracker.progress.Disks = nil
+		tracker.progress.BytesDone = 0
+		tracker.progress.BytesTotal = 0
+		tracker.progress.ETA = nil
+		tracker.diskStarts = map[int]time.Time{}
+		tracker.diskWindows = map[int]progressWindow{}
+	}
+	tracker.progress.Phase = phase
+	tracker.progress.Iteration = iteration
+	tracker.publishLocked(true)
+}
+
+// startDiskProgress starts tracking the copy of total bytes of the disk at idx
+func (migobj *Migrate) startDiskProgress(idx int, total int64) {
+	tracker := migobj.progressTracker()
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.diskStarts[idx] = time.Now()
+	delete(tracker.diskWindows, idx)
+	tracker.setDiskLocked(idx, 0, total, time.Now())
+	tracker.publishLocked(false)
+}
+
+// updateDiskProgress records that fraction of the copy of the disk at idx is done
+func (migobj *Migrate) updateDiskProgress(idx int, fraction float64) {
+	tracker := migobj.progressTracker()
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	var total int64
+	for _, disk := range tracker.progress.Disks {
+		if disk.Index == idx {
+			total = disk.BytesTotal
+		}
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	if window, ok := tracker.diskWindows[idx]; ok && window.total > 0 {
+		fraction = (float64(window.offset) + fraction*float64(window.length)) / float64(window.total)
+	}
+	tracker.setDiskLocked(idx, int64(fraction*float64(total)), total, time.Now())
+	tracker.publishLocked(fraction == 1)
+}
+
+// setDiskProgressWindow tells that the fractions reported for the disk at idx until the next window are fractions
+// of length bytes starting at offset bytes of a copy of total bytes
+func (migobj *Migrate) setDiskProgressWindow(idx int, offset, length, total int64) {
+	tracker := migobj.progressTracker()
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.diskWindows[idx] = progressWindow{offset: offset, length: length, total: total}
+}
+
+// setDiskLocked sets the progress of a disk and recomputes the totals and ETAs
+func (tracker *progressTracker) setDiskLocked(idx int, done, total int64, now time.Time) {
+	disk := migratev1alpha1.DiskProgress{Index: idx, BytesDone: done, BytesTotal: total}
+	if start, ok := tracker.diskStarts[idx]; ok && done > 0 {
+		if elapsed := now.Sub(start).Seconds(); elapsed > 0 {
+			disk.BytesPerSecond = int64(float64(done) / elapsed)
+		}
+		if disk.BytesPerSecond > 0 {
+			eta := metav1.NewTime(now.Add(time.Duration(float64(total-done) / float64(disk.BytesPerSecond) * float64(time.Second))))
+			disk.ETA = &eta
+		}
+	}
+
+	replaced := false
+	for i := range tracker.progress.Disks {
+		if tracker.progress.Disks[i].Index == idx {
+			tracker.progress.Disks[i] = disk
+			replaced = true
+		}
+	}
+	if !replaced {
+		tracker.progress.Disks = append(tracker.progress.Disks, disk)
+		sort.Slice(tracker.progress.Disks, func(i, j int) bool {
+			return tracker.progress.Disks[i].Index < tracker.progress.Disks[j].Index
+		})
+	}
+
+	// The copy of the iteration completes with its slowest disk
+	tracker.progress.BytesDone, tracker.progress.BytesTotal, tracker.progress.ETA = 0, 0, nil
+	for _, d := range tracker.progress.Disks {
+		tracker.progress.BytesDone += d.BytesDone
+		tracker.progress.BytesTotal += d.BytesTotal
+		if d.ETA != nil && (tracker.progress.ETA == nil || d.ETA.After(tracker.progress.ETA.Time)) {
+			tracker.progress.ETA = d.ETA
+		}
+	}
+}
+
+// publishLocked publishes the progress, at most once per ProgressPublishInterval unless force is set
+func (tracker *progressTracker) publishLocked(force bool) {
+	if tracker.publish == nil {
+		return
+	}
+	now := time.Now()
+	if !force && now.Sub(tracker.lastPublished) < constants.ProgressPublishInterval {
+		return
+	}
+	tracker.progress.LastUpdateTime = metav1.NewTime(now)
+	progress, err := json.Marshal(tracker.progress)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to encode progress: %v", err))
+		return
+	}
+	if err := tracker.publish(string(progress)); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to publish progress: %v", err))
+		return
+	}
+	tracker.lastPublished = now
+}
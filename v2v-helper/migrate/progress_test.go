@@ -0,0 +1,51 @@
+package migrate
+
+import (
+	"encoding/json"
+	"testing"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressTracker(t *testing.T) {
+	var published []migratev1alpha1.MigrationProgress
+	migobj := Migrate{}
+	migobj.progressTracker().publish = func(progress string) error {
+		var p migratev1alpha1.MigrationProgress
+		assert.NoError(t, json.Unmarshal([]byte(progress), &p))
+		published = append(published, p)
+		return nil
+	}
+
+	migobj.setProgressPhase(migratev1alpha1.VMMigrationPhaseCopying, 0)
+	migobj.startDiskProgress(1, 2000)
+	migobj.startDiskProgress(0, 1000)
+	migobj.updateDiskProgress(0, 0.5)
+	// Completing a disk is always published
+	migobj.updateDiskProgress(1, 1)
+
+	last := published[len(published)-1]
+	assert.Equal(t, migratev1alpha1.VMMigrationPhaseCopying, last.Phase)
+	assert.Equal(t, int64(2500), last.BytesDone)
+	assert.Equal(t, int64(3000), last.BytesTotal)
+	assert.Len(t, last.Disks, 2)
+	assert.Equal(t, 0, last.Disks[0].Index)
+	assert.Equal(t, int64(500), last.Disks[0].BytesDone)
+	assert.Equal(t, int64(2000), last.Disks[1].BytesDone)
+
+	// A new iteration starts with no disk progress
+	migobj.setProgressPhase(migratev1alpha1.VMMigrationPhaseCopyingChangedBlocks, 1)
+	last = published[len(published)-1]
+	assert.Equal(t, 1, last.Iteration)
+	assert.Empty(t, last.Disks)
+	assert.Zero(t, last.BytesTotal)
+
+	// Waiting for the cutover keeps the iteration and the progress of the disks
+	migobj.startDiskProgress(0, 100)
+	migobj.setProgressPhase(migratev1alpha1.VMMigrationPhaseAwaitingAdminCutOver, migobj.progressTracker().iteration())
+	last = published[len(published)-1]
+	assert.Equal(t, migratev1alpha1.VMMigrationPhaseAwaitingAdminCutOver, last.Phase)
+	assert.Equal(t, 1, last.Iteration)
+	assert.Len(t, last.Disks, 1)
+}
@@ -0,0 +1,216 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
+	"github.com/pkg/errors"
+	vimtypes "github.com/vmware/govmomi/vim25/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ParseSharedDisks parses the shared disks of the VM
+func ParseSharedDisks(sharedDisks string) ([]migratev1alpha1.SharedDiskAssignment, error) {
+	if sharedDisks == "" {
+		return nil, nil
+	}
+	var assignments []migratev1alpha1.SharedDiskAssignment
+	if err := json.Unmarshal([]byte(sharedDisks), &assignments); err != nil {
+		return nil, errors.Wrap(err, "invalid shared disks")
+	}
+	return assignments, nil
+}
+
+// sharedDisk returns the assignment of the disk with the given label, or nil if the disk is not shared
+func (migobj *Migrate) sharedDisk(name string) *migratev1alpha1.SharedDiskAssignment {
+	for i := range migobj.SharedDisks {
+		if migobj.SharedDisks[i].DiskName == name {
+			return &migobj.SharedDisks[i]
+		}
+	}
+	return nil
+}
+
+// copiesSharedDisks tells whether the migration copies any of the shared disks of the VM
+func (migobj *Migrate) copiesSharedDisks() bool {
+	for _, assignment := range migobj.SharedDisks {
+		if assignment.Copy {
+			return true
+		}
+	}
+	return false
+}
+
+// selectSharedDisks copies the shared disks the migration is assigned into multiattach volumes and drops the others,
+// together with their volume types, from the disks of the VM. Those are attached to the instance once copied by the
+// migration of another owner VM.
+func (migobj *Migrate) selectSharedDisks(vminfo vm.VMInfo) (vm.VMInfo, error) {
+	if len(migobj.SharedDisks) == 0 {
+		return vminfo, nil
+	}
+	var disks []vm.VMDisk
+	var volumetypes []string
+	for idx, disk := range vminfo.VMDisks {
+		assignment := migobj.sharedDisk(disk.Name)
+		if assignment == nil {
+			disks = append(disks, disk)
+			volumetypes = append(volumetypes, migobj.Volumetypes[idx])
+			continue
+		}
+		if disk.Name == vminfo.BootDisk {
+			return vminfo, errors.Errorf("disk %s is the boot disk of the VM and cannot be shared", disk.Name)
+		}
+		if assignment.Copy {
+			migobj.logMessage(fmt.Sprintf("Copying disk %s shared with VMs %s", disk.Name, strings.Join(assignment.OwnerVMs, ", ")))
+			disk.Multiattach = true
+			disks = append(disks, disk)
+			volumetypes = append(volumetypes, migobj.Volumetypes[idx])
+			continue
+		}
+		migobj.logMessage(fmt.Sprintf("Disk %s is shared with VMs %s and copied by the migration of %s",
+			disk.Name, strings.Join(assignment.OwnerVMs, ", "), assignment.OwnerVMs[0]))
+		vminfo.SharedDisks = append(vminfo.SharedDisks, vm.SharedDisk{DiskName: disk.Name, SharedDisk: assignment.SharedDisk})
+	}
+	vminfo.VMDisks = disks
+	migobj.Volumetypes = volumetypes
+	return vminfo, nil
+}
+
+// waitForSharedDiskOwners waits until the other VMs sharing the disks the migration copies are powered off, so that
+// nothing writes to the disks during their final copy
+func (migobj *Migrate) waitForSharedDiskOwners(ctx context.Context, vminfo vm.VMInfo) error {
+	owners := map[string]bool{}
+	for _, assignment := range migobj.SharedDisks {
+		if !assignment.Copy {
+			continue
+		}
+		for _, owner := range assignment.OwnerVMs {
+			if owner != vminfo.Name {
+				owners[owner] = true
+			}
+		}
+	}
+	if len(owners) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, constants.SharedDiskCopyTimeout)
+	defer cancel()
+	for owner := range owners {
+		migobj.logMessage(fmt.Sprintf("Waiting for VM %s sharing disks to power off", owner))
+		for {
+			ownerVM, err := migobj.Vcclient.GetVMByName(ctx, owner)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get VM %s sharing disks", owner)
+			}
+			state, err := ownerVM.PowerState(ctx)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get power state of VM %s", owner)
+			}
+			if state == vimtypes.VirtualMachinePowerStatePoweredOff {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return errors.Errorf("VM %s sharing disks was not powered off", owner)
+			case <-time.After(constants.HookPollInterval):
+			}
+		}
+	}
+	return nil
+}
+
+// attachSharedDisks waits until the shared disks the migration attaches are copied by the migration of another owner
+// VM and sets the volumes they were copied into
+func (migobj *Migrate) attachSharedDisks(ctx context.Context, vminfo *vm.VMInfo) error {
+	if len(vminfo.SharedDisks) == 0 {
+		return nil
+	}
+	if migobj.K8sClient == nil {
+		return errors.New("no Kubernetes client to get shared disks")
+	}
+	ctx, cancel := context.WithTimeout(ctx, constants.SharedDiskCopyTimeout)
+	defer cancel()
+	migrationName, err := utils.GetMigrationObjectName()
+	if err != nil {
+		return errors.Wrap(err, "failed to get migration name")
+	}
+	migration := &migratev1alpha1.Migration{}
+	err = migobj.K8sClient.Get(ctx, types.NamespacedName{Name: migrationName, Namespace: constants.NamespaceMigrationSystem}, migration)
+	if err != nil {
+		return errors.Wrap(err, "failed to get migration")
+	}
+	for idx, disk := range vminfo.SharedDisks {
+		migobj.logMessage(fmt.Sprintf("Waiting for shared disk %s to be copied", disk.DiskName))
+		for {
+			sharedDisk := &migratev1alpha1.SharedDisk{}
+			err := migobj.K8sClient.Get(ctx, types.NamespacedName{Name: disk.SharedDisk, Namespace: constants.NamespaceMigrationSystem}, sharedDisk)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get shared disk %s", disk.SharedDisk)
+			}
+			copied, err := migobj.isSharedDiskCopied(ctx, sharedDisk, migration.Spec.MigrationPlan)
+			if err != nil {
+				return err
+			}
+			if copied {
+				vminfo.SharedDisks[idx].VolumeID = sharedDisk.Status.CinderVolumeID
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return errors.Errorf("shared disk %s was not copied", disk.DiskName)
+			case <-time.After(constants.HookPollInterval):
+			}
+		}
+	}
+	return nil
+}
+
+// isSharedDiskCopied tells whether a shared disk was copied by the current attempt of the migration of another owner
+// VM in the migration plan. A copy left behind by a failed attempt or by an earlier migration plan is not attached.
+func (migobj *Migrate) isSharedDiskCopied(ctx context.Context, sharedDisk *migratev1alpha1.SharedDisk, migrationPlan string) (bool, error) {
+	if sharedDisk.Status.Phase != migratev1alpha1.SharedDiskPhaseCopied || sharedDisk.Status.CinderVolumeID == "" {
+		return false, nil
+	}
+	copier := &migratev1alpha1.Migration{}
+	err := migobj.K8sClient.Get(ctx, types.NamespacedName{Name: sharedDisk.Status.CopiedBy, Namespace: constants.NamespaceMigrationSystem}, copier)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "failed to get migration %s that copied shared disk %s", sharedDisk.Status.CopiedBy, sharedDisk.Name)
+	}
+	return copier.UID == sharedDisk.Status.CopiedByUID && copier.Spec.MigrationPlan == migrationPlan &&
+		copier.Status.Phase != migratev1alpha1.VMMigrationPhaseFailed, nil
+}
+
+// publishSharedDisks publishes the volumes of the shared disks the migration copied in an annotation of the pod, from
+// where the controller sets them on the SharedDisks for the migrations of the other owner VMs
+func (migobj *Migrate) publishSharedDisks(vminfo vm.VMInfo) {
+	if !migobj.copiesSharedDisks() || !migobj.InPod || migobj.Reporter == nil {
+		return
+	}
+	volumes := map[string]string{}
+	for _, disk := range vminfo.VMDisks {
+		assignment := migobj.sharedDisk(disk.Name)
+		if assignment == nil || !assignment.Copy || disk.OpenstackVol == nil {
+			continue
+		}
+		volumes[assignment.SharedDisk] = disk.OpenstackVol.ID
+	}
+	value, err := json.Marshal(volumes)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to encode shared disks: %v", err))
+		return
+	}
+	if err := migobj.Reporter.SetPodAnnotation(constants.MigrationSharedDisksAnnotation, string(value)); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to publish shared disks: %v", err))
+	}
+}
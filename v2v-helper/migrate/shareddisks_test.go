@@ -0,0 +1,45 @@
+package migrate
+
+import (
+	"testing"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectSharedDisks(t *testing.T) {
+	vminfo := vm.VMInfo{
+		Name:     "rac-2",
+		BootDisk: "Hard disk 1",
+		VMDisks: []vm.VMDisk{
+			{Name: "Hard disk 1"},
+			{Name: "Hard disk 2"},
+			{Name: "Hard disk 3"},
+		},
+	}
+	migobj := Migrate{
+		Volumetypes: []string{"voltype-1", "voltype-2", "voltype-3"},
+		SharedDisks: []migratev1alpha1.SharedDiskAssignment{
+			{DiskName: "Hard disk 2", SharedDisk: "ocr", OwnerVMs: []string{"rac-1", "rac-2"}},
+			{DiskName: "Hard disk 3", SharedDisk: "data", OwnerVMs: []string{"rac-2", "rac-3"}, Copy: true},
+		},
+	}
+
+	selected, err := migobj.selectSharedDisks(vminfo)
+	assert.NoError(t, err)
+	assert.Len(t, selected.VMDisks, 2)
+	assert.Equal(t, "Hard disk 1", selected.VMDisks[0].Name)
+	assert.False(t, selected.VMDisks[0].Multiattach)
+	assert.Equal(t, "Hard disk 3", selected.VMDisks[1].Name)
+	assert.True(t, selected.VMDisks[1].Multiattach)
+	assert.Equal(t, []string{"voltype-1", "voltype-3"}, migobj.Volumetypes)
+	assert.Equal(t, []vm.SharedDisk{{DiskName: "Hard disk 2", SharedDisk: "ocr"}}, selected.SharedDisks)
+	assert.True(t, migobj.copiesSharedDisks())
+
+	// The boot disk cannot be shared
+	migobj.Volumetypes = []string{"voltype-1", "voltype-2", "voltype-3"}
+	migobj.SharedDisks = []migratev1alpha1.SharedDiskAssignment{{DiskName: "Hard disk 1", SharedDisk: "boot", OwnerVMs: []string{"rac-1", "rac-2"}}}
+	_, err = migobj.selectSharedDisks(vminfo)
+	assert.ErrorContains(t, err, "boot disk")
+}
@@ -0,0 +1,67 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// takeMigrationSnapshot takes the migration snapshot the disks of the iteration are synced from and records how it
+// was taken. With quiesced snapshots the guest is quiesced first, falling back to a crash-consistent snapshot if
+// quiescing fails or times out. A powered off VM is not quiesced.
+func (migobj *Migrate) takeMigrationSnapshot(iteration int, poweredOff bool) error {
+	vmops := migobj.VMops
+	snapshot := migratev1alpha1.MigrationSnapshot{Iteration: iteration, Mode: migratev1alpha1.SnapshotModeCrashConsistent}
+	switch {
+	case poweredOff:
+		snapshot.Mode = migratev1alpha1.SnapshotModePoweredOff
+	case migobj.SnapshotConsistency == constants.SnapshotConsistencyQuiesced:
+		timeout := migobj.QuiesceTimeout
+		if timeout <= 0 {
+			timeout = constants.DefaultQuiesceTimeout
+		}
+		err := vmops.TakeQuiescedSnapshot(constants.MigrationSnapshotName, timeout)
+		if err == nil {
+			snapshot.Mode = migratev1alpha1.SnapshotModeQuiesced
+			break
+		}
+		// The error is not part of the event, which would mark the migration as failed
+		utils.PrintLog(fmt.Sprintf("Quiesced snapshot of iteration %d: %v", iteration, err))
+		migobj.logMessage(fmt.Sprintf("Quiesced snapshot of iteration %d did not succeed, taking a crash-consistent snapshot", iteration))
+		snapshot.Message = err.Error()
+		// A quiesced snapshot that completed after all would be taken twice
+		if err := vmops.CleanUpSnapshots(false); err != nil {
+			return errors.Wrap(err, "failed to clean up snapshot of source VM")
+		}
+	}
+	if snapshot.Mode != migratev1alpha1.SnapshotModeQuiesced {
+		if err := vmops.TakeSnapshot(constants.MigrationSnapshotName); err != nil {
+			return errors.Wrap(err, "failed to take snapshot of source VM")
+		}
+	}
+	snapshot.CreationTime = metav1.Now()
+	migobj.recordSnapshot(snapshot)
+	return nil
+}
+
+// recordSnapshot keeps a snapshot and publishes the snapshots in an annotation of the pod, from where the controller
+// copies them to the status of the Migration
+func (migobj *Migrate) recordSnapshot(snapshot migratev1alpha1.MigrationSnapshot) {
+	migobj.snapshots = append(migobj.snapshots, snapshot)
+	if !migobj.InPod || migobj.Reporter == nil {
+		return
+	}
+	snapshots, err := json.Marshal(migobj.snapshots)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to encode snapshots: %v", err))
+		return
+	}
+	if err := migobj.Reporter.SetPodAnnotation(constants.MigrationSnapshotsAnnotation, string(snapshots)); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to publish snapshots: %v", err))
+	}
+}
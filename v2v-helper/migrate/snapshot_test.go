@@ -0,0 +1,46 @@
+package migrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTakeMigrationSnapshot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	migobj := Migrate{
+		VMops:               mockVMOps,
+		SnapshotConsistency: constants.SnapshotConsistencyQuiesced,
+		QuiesceTimeout:      time.Minute,
+	}
+
+	mockVMOps.EXPECT().TakeQuiescedSnapshot(constants.MigrationSnapshotName, time.Minute).Return(nil)
+	assert.NoError(t, migobj.takeMigrationSnapshot(0, false))
+
+	// A quiesced snapshot that times out falls back to a crash-consistent one
+	gomock.InOrder(
+		mockVMOps.EXPECT().TakeQuiescedSnapshot(constants.MigrationSnapshotName, time.Minute).Return(errors.New("quiesced snapshot timed out after 1m0s")),
+		mockVMOps.EXPECT().CleanUpSnapshots(false).Return(nil),
+		mockVMOps.EXPECT().TakeSnapshot(constants.MigrationSnapshotName).Return(nil),
+	)
+	assert.NoError(t, migobj.takeMigrationSnapshot(1, false))
+
+	// The powered off VM is not quiesced
+	mockVMOps.EXPECT().TakeSnapshot(constants.MigrationSnapshotName).Return(nil)
+	assert.NoError(t, migobj.takeMigrationSnapshot(2, true))
+
+	assert.Len(t, migobj.snapshots, 3)
+	assert.Equal(t, migratev1alpha1.SnapshotModeQuiesced, migobj.snapshots[0].Mode)
+	assert.Equal(t, migratev1alpha1.SnapshotModeCrashConsistent, migobj.snapshots[1].Mode)
+	assert.Equal(t, "quiesced snapshot timed out after 1m0s", migobj.snapshots[1].Message)
+	assert.Equal(t, migratev1alpha1.SnapshotModePoweredOff, migobj.snapshots[2].Mode)
+}
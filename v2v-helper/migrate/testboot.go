@@ -0,0 +1,367 @@
+package migrate
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"os/exec"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestBootOptions configures the test boot of the VM before its cutover
+type TestBootOptions struct {
+	// CIDR is the CIDR of the subnet of the isolated network, a random /24 of TestBootCIDRPool if empty
+	CIDR string
+	// Timeout is how long the copy of the VM may take to boot and pass the health checks
+	Timeout time.Duration
+}
+
+// ParseTestBoot parses the test boot options of the VM. It returns nil if the VM is not test booted.
+func ParseTestBoot(enabled bool, cidr, timeout string) (*TestBootOptions, error) {
+	if !enabled {
+		return nil, nil
+	}
+	options := &TestBootOptions{CIDR: cidr, Timeout: constants.DefaultTestBootTimeout}
+	if cidr != "" {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return nil, errors.Wrap(err, "invalid test boot CIDR")
+		}
+	}
+	if timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid test boot timeout")
+		}
+		if duration > 0 {
+			options.Timeout = duration
+		}
+	}
+	return options, nil
+}
+
+// subnet returns the CIDR of the subnet of the isolated network. Without a CIDR a random /24 of the pool is taken,
+// so that test boots running on the same agent are unlikely to share a subnet.
+func (options *TestBootOptions) subnet() string {
+	if options.CIDR != "" {
+		return options.CIDR
+	}
+	return randomSubnet(netip.MustParsePrefix(constants.TestBootCIDRPool), 24).String()
+}
+
+// randomSubnet returns a random subnet of the given size in pool
+func randomSubnet(pool netip.Prefix, bits int) netip.Prefix {
+	base := pool.Masked().Addr().As4()
+	subnets := uint32(1) << (bits - pool.Bits())
+	addr := binary.BigEndian.Uint32(base[:]) | rand.Uint32N(subnets)<<(32-bits)
+	var subnet [4]byte
+	binary.BigEndian.PutUint32(subnet[:], addr)
+	return netip.PrefixFrom(netip.AddrFrom4(subnet), bits)
+}
+
+// testBootResources are the OpenStack resources created for a test boot, deleted once it finished
+type testBootResources struct {
+	snapshotIDs       []string
+	volumeIDs         []string
+	attachedVolumeIDs []string
+	networkID         string
+	portIDs           []string
+	helperPortID      string
+	serverID          string
+}
+
+// testBoot boots a converted copy of the VM on an isolated network while the source VM keeps running and records
+// whether it booted and passed the health checks. The copy is made of clones of the volumes synced so far and is
+// deleted afterwards. A failing test boot does not fail the migration.
+func (migobj *Migrate) testBoot(ctx context.Context, vminfo vm.VMInfo) {
+	if migobj.TestBoot == nil {
+		return
+	}
+	if migobj.MigrationType == "cold" {
+		migobj.logMessage("Skipping the test boot, the source VM of a cold migration is powered off")
+		return
+	}
+	migobj.logMessage("Test booting a copy of the VM on an isolated network")
+	result := &migratev1alpha1.TestBootResult{}
+	resources := &testBootResources{}
+	migobj.runTestBoot(ctx, vminfo, resources, result)
+	if err := migobj.deleteTestBootResources(resources); err != nil {
+		addTestBootCheck(result, "Cleanup", err)
+	}
+	result.Succeeded = !slices.ContainsFunc(result.Checks, func(check migratev1alpha1.PreflightCheck) bool {
+		return check.Result == migratev1alpha1.PreflightCheckFailed
+	})
+	result.CompletionTime = metav1.Now()
+	migobj.publishTestBootResult(result)
+	if result.Succeeded {
+		migobj.logMessage("Test boot of the VM succeeded")
+	} else {
+		migobj.logMessage("Test boot of the VM did not succeed, see the test boot checks of the migration")
+	}
+}
+
+// runTestBoot clones and converts the volumes, boots the copy and runs the health checks, adding a check to result
+// for each step. It stops at the first step that fails.
+func (migobj *Migrate) runTestBoot(ctx context.Context, vminfo vm.VMInfo, resources *testBootResources,
+	result *migratev1alpha1.TestBootResult) {
+	openstackops := migobj.Openstackclients
+	name := vminfo.Name + "-testboot"
+
+	// The clones hold what was synced to the volumes so far
+	syscall.Sync()
+	testinfo := vminfo
+	testinfo.Name = name
+	testinfo.VMDisks = slices.Clone(vminfo.VMDisks)
+	// RDM disks are LUNs shared with the source VM and shared disks are in use by the instances of other VMs, the
+	// copy boots without them
+	testinfo.RDMDisks = nil
+	testinfo.SharedDisks = nil
+	err := func() error {
+		for idx, disk := range vminfo.VMDisks {
+			snapshot, err := openstackops.CreateVolumeSnapshot(disk.OpenstackVol.ID, fmt.Sprintf("%s-%d", name, idx))
+			if snapshot != nil {
+				resources.snapshotIDs = append(resources.snapshotIDs, snapshot.ID)
+			}
+			if err != nil {
+				return err
+			}
+			volume, err := openstackops.CreateVolumeFromSnapshot(fmt.Sprintf("%s-%d", name, idx), snapshot)
+			if volume != nil {
+				resources.volumeIDs = append(resources.volumeIDs, volume.ID)
+			}
+			if err != nil {
+				return err
+			}
+			testinfo.VMDisks[idx].OpenstackVol = volume
+			testinfo.VMDisks[idx].Path = ""
+		}
+		return nil
+	}()
+	if !addTestBootCheck(result, "CloneVolumes", err) {
+		return
+	}
+
+	// The guest takes its address from the isolated network, the addresses of the VM are not on it
+	err = migobj.convertVolumes(ctx, testinfo, false)
+	if !addTestBootCheck(result, "Convert", err) {
+		// The volumes are only detached once converted, the conversion sets the path of those it attached
+		for _, disk := range testinfo.VMDisks {
+			if disk.Path != "" {
+				resources.attachedVolumeIDs = append(resources.attachedVolumeIDs, disk.OpenstackVol.ID)
+			}
+		}
+		return
+	}
+
+	var networkIDs, portIDs, ips []string
+	err = func() error {
+		subnet := migobj.TestBoot.subnet()
+		network, err := openstackops.CreateIsolatedNetwork(name, subnet)
+		if network != nil {
+			resources.networkID = network.ID
+		}
+		if err != nil {
+			return err
+		}
+		for idx := range vminfo.Mac {
+			mac, err := migobj.targetMAC(idx, vminfo)
+			if err != nil {
+				return err
+			}
+			port, err := openstackops.CreatePortInSubnet(network, network.Subnets[0], mac, "", fmt.Sprintf("%s-%d", name, idx), nil)
+			if err != nil {
+				return err
+			}
+			resources.portIDs = append(resources.portIDs, port.ID)
+			networkIDs = append(networkIDs, network.ID)
+			portIDs = append(portIDs, port.ID)
+			ips = append(ips, port.FixedIPs[0].IPAddress)
+		}
+		if !migobj.PerformHealthChecks || len(ips) == 0 {
+			return nil
+		}
+		// The helper VM joins the isolated network to run the health checks
+		helperPort, err := openstackops.CreatePortInSubnet(network, network.Subnets[0], "", "", name+"-helper", nil)
+		if err != nil {
+			return err
+		}
+		resources.helperPortID = helperPort.ID
+		if err := openstackops.AttachPortToVM(helperPort.ID); err != nil {
+			return err
+		}
+		return configureTestBootInterface(ctx, helperPort, subnet)
+	}()
+	if !addTestBootCheck(result, "Network", err) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, migobj.TestBoot.Timeout)
+	defer cancel()
+	err = func() error {
+		flavor, err := migobj.targetFlavor(testinfo)
+		if err != nil {
+			return err
+		}
+		migrateSettings, err := utils.GetMigrateSettings(ctx, migobj.K8sClient)
+		if err != nil {
+			return errors.Wrap(err, "failed to get stellaris-migrate settings")
+		}
+		server, err := openstackops.CreateVM(flavor, networkIDs, portIDs, testinfo, migobj.TargetAvailabilityZone, nil, *migrateSettings, migobj.UseFlavorless)
+		if server != nil {
+			resources.serverID = server.ID
+		}
+		return err
+	}()
+	if !addTestBootCheck(result, "Boot", err) || !migobj.PerformHealthChecks || len(ips) == 0 {
+		return
+	}
+
+	var pingErr, httpErr error = errors.New("not run"), errors.New("not run")
+	for {
+		if pingErr != nil {
+			pingErr = migobj.pingVM(ips)
+		}
+		if httpErr != nil {
+			httpErr = migobj.checkHTTPGet(ips, migobj.HealthCheckPort)
+		}
+		if pingErr == nil && httpErr == nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(constants.TestBootPollInterval):
+			continue
+		}
+		break
+	}
+	addTestBootCheck(result, "Ping", pingErr)
+	addTestBootCheck(result, "HTTP Get", httpErr)
+}
+
+// addTestBootCheck adds the result of a step of the test boot and reports whether it passed
+func addTestBootCheck(result *migratev1alpha1.TestBootResult, name string, err error) bool {
+	check := migratev1alpha1.PreflightCheck{Name: name, Result: migratev1alpha1.PreflightCheckPassed}
+	if err != nil {
+		check.Result = migratev1alpha1.PreflightCheckFailed
+		check.Message = err.Error()
+		utils.PrintLog(fmt.Sprintf("Test boot step %s: %v", name, err))
+	}
+	result.Checks = append(result.Checks, check)
+	return err == nil
+}
+
+// configureTestBootInterface assigns the address of the helper port in subnet to the interface it was attached as.
+// The helper pod shares the network namespace of the helper VM.
+func configureTestBootInterface(ctx context.Context, port *ports.Port, subnet string) error {
+	if len(port.FixedIPs) == 0 {
+		return errors.Errorf("port %s has no address", port.ID)
+	}
+	var device string
+	for device == "" {
+		interfaces, err := net.Interfaces()
+		if err != nil {
+			return errors.Wrap(err, "failed to list network interfaces")
+		}
+		for _, iface := range interfaces {
+			if strings.EqualFold(iface.HardwareAddr.String(), port.MACAddress) {
+				device = iface.Name
+			}
+		}
+		if device != "" {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Errorf("interface of port %s did not appear", port.ID)
+		case <-time.After(time.Second):
+		}
+	}
+	prefix, err := netip.ParsePrefix(subnet)
+	if err != nil {
+		return errors.Wrapf(err, "invalid test boot CIDR %s", subnet)
+	}
+	address := fmt.Sprintf("%s/%d", port.FixedIPs[0].IPAddress, prefix.Bits())
+	commands := [][]string{
+		{"ip", "link", "set", "dev", device, "up"},
+		{"ip", "addr", "replace", address, "dev", device},
+	}
+	for _, command := range commands {
+		if out, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput(); err != nil {
+			return errors.Wrapf(err, "failed to run %s: %s", strings.Join(command, " "), strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// deleteTestBootResources deletes the resources of a test boot, carrying on when one cannot be deleted. Volumes a
+// failed conversion left attached to the helper VM are detached first.
+func (migobj *Migrate) deleteTestBootResources(resources *testBootResources) error {
+	openstackops := migobj.Openstackclients
+	var failed []string
+	record := func(err error) {
+		if err != nil {
+			utils.PrintLog(fmt.Sprintf("Failed to delete test boot resource: %v", err))
+			failed = append(failed, err.Error())
+		}
+	}
+	if resources.serverID != "" {
+		record(openstackops.DeleteServer(resources.serverID))
+	}
+	if resources.helperPortID != "" {
+		record(openstackops.DetachPortFromVM(resources.helperPortID))
+		record(openstackops.DeletePort(resources.helperPortID))
+	}
+	for _, portID := range resources.portIDs {
+		record(openstackops.DeletePort(portID))
+	}
+	if resources.networkID != "" {
+		record(openstackops.DeleteNetwork(resources.networkID))
+	}
+	for _, volumeID := range resources.attachedVolumeIDs {
+		if err := openstackops.DetachVolumeFromVM(volumeID); err != nil {
+			record(err)
+			continue
+		}
+		record(openstackops.WaitForVolume(volumeID))
+	}
+	for _, volumeID := range resources.volumeIDs {
+		record(openstackops.DeleteVolume(volumeID))
+	}
+	for _, snapshotID := range resources.snapshotIDs {
+		record(openstackops.DeleteVolumeSnapshot(snapshotID))
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("failed to delete test boot resources, delete them manually: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// publishTestBootResult publishes the result of the test boot in an annotation of the pod, from where the controller
+// copies it to the status of the Migration
+func (migobj *Migrate) publishTestBootResult(result *migratev1alpha1.TestBootResult) {
+	if !migobj.InPod || migobj.Reporter == nil {
+		return
+	}
+	value, err := json.Marshal(result)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to encode test boot result: %v", err))
+		return
+	}
+	if err := migobj.Reporter.SetPodAnnotation(constants.MigrationTestBootAnnotation, string(value)); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to publish test boot result: %v", err))
+	}
+}
@@ -0,0 +1,68 @@
+package migrate
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/openstack"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTestBoot(t *testing.T) {
+	options, err := ParseTestBoot(false, "", "")
+	assert.NoError(t, err)
+	assert.Nil(t, options)
+
+	options, err = ParseTestBoot(true, "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, constants.DefaultTestBootTimeout, options.Timeout)
+
+	options, err = ParseTestBoot(true, "192.168.50.0/24", "1h0m0s")
+	assert.NoError(t, err)
+	assert.Equal(t, &TestBootOptions{CIDR: "192.168.50.0/24", Timeout: time.Hour}, options)
+	assert.Equal(t, "192.168.50.0/24", options.subnet())
+
+	_, err = ParseTestBoot(true, "192.168.50.0", "")
+	assert.Error(t, err)
+	_, err = ParseTestBoot(true, "", "soon")
+	assert.Error(t, err)
+}
+
+func TestRandomSubnet(t *testing.T) {
+	pool := netip.MustParsePrefix(constants.TestBootCIDRPool)
+	for range 100 {
+		subnet := netip.MustParsePrefix((&TestBootOptions{}).subnet())
+		assert.Equal(t, 24, subnet.Bits())
+		assert.True(t, pool.Contains(subnet.Addr()))
+		assert.Equal(t, subnet.Masked(), subnet)
+	}
+}
+
+func TestDeleteTestBootResources(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	migobj := Migrate{Openstackclients: mockOpenStackOps}
+
+	// The conversion failed with both clones attached, they are detached before they are deleted
+	gomock.InOrder(
+		mockOpenStackOps.EXPECT().DetachVolumeFromVM("clone-1").Return(nil),
+		mockOpenStackOps.EXPECT().WaitForVolume("clone-1").Return(nil),
+		mockOpenStackOps.EXPECT().DetachVolumeFromVM("clone-2").Return(errors.New("detach failed")),
+		mockOpenStackOps.EXPECT().DeleteVolume("clone-1").Return(nil),
+		mockOpenStackOps.EXPECT().DeleteVolume("clone-2").Return(errors.New("volume is in-use")),
+		mockOpenStackOps.EXPECT().DeleteVolumeSnapshot("snapshot-1").Return(nil),
+		mockOpenStackOps.EXPECT().DeleteVolumeSnapshot("snapshot-2").Return(nil),
+	)
+	err := migobj.deleteTestBootResources(&testBootResources{
+		snapshotIDs:       []string{"snapshot-1", "snapshot-2"},
+		volumeIDs:         []string{"clone-1", "clone-2"},
+		attachedVolumeIDs: []string{"clone-1", "clone-2"},
+	})
+	assert.ErrorContains(t, err, "detach failed; volume is in-use")
+}
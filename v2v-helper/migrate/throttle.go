@@ -0,0 +1,254 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// CopyWindow is a daily time window in which changed blocks are synced
+type CopyWindow struct {
+	startMinute int
+	endMinute   int
+	location    *time.Location
+}
+
+// ParseCopyWindow parses a copy window given as HH:MM start and end times in an IANA time zone.
+// It returns nil if no window is set.
+func ParseCopyWindow(start, end, timezone string) (*CopyWindow, error) {
+	if start == "" && end == "" {
+		return nil, nil
+	}
+	location := time.UTC
+	if timezone != "" {
+		var err error
+		location, err = time.LoadLocation(timezone)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid copy window time zone %s", timezone)
+		}
+	}
+	startTime, err := time.Parse("15:04", start)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid copy window start %s", start)
+	}
+	endTime, err := time.Parse("15:04", end)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid copy window end %s", end)
+	}
+	return &CopyWindow{
+		startMinute: startTime.Hour()*60 + startTime.Minute(),
+		endMinute:   endTime.Hour()*60 + endTime.Minute(),
+		location:    location,
+	}, nil
+}
+
+// nextOpening returns the time the window opens next, or now if it is open
+func (w *CopyWindow) nextOpening(now time.Time) time.Time {
+	if w.startMinute == w.endMinute {
+		return now
+	}
+	now = now.In(w.location)
+	minute := now.Hour()*60 + now.Minute()
+	var open bool
+	if w.startMinute < w.endMinute {
+		open = minute >= w.startMinute && minute < w.endMinute
+	} else {
+		// The window spans midnight
+		open = minute >= w.startMinute || minute < w.endMinute
+	}
+	if open {
+		return now
+	}
+	day := now.Day()
+	if minute > w.startMinute {
+		day++
+	}
+	return time.Date(now.Year(), now.Month(), day, 0, w.startMinute, 0, 0, w.location)
+}
+
+// closing returns the time the window closes if it is open at now. A window that never closes has no closing time.
+func (w *CopyWindow) closing(now time.Time) (time.Time, bool) {
+	if w.startMinute == w.endMinute || !w.nextOpening(now).Equal(now) {
+		return time.Time{}, false
+	}
+	now = now.In(w.location)
+	day := now.Day()
+	if w.endMinute <= now.Hour()*60+now.Minute() {
+		// The window spans midnight and closes tomorrow
+		day++
+	}
+	return time.Date(now.Year(), now.Month(), day, 0, w.endMinute, 0, 0, w.location), true
+}
+
+// copyWindowContext returns a context for syncing changed blocks that is done when the copy window closes, or
+// right away if it is closed. The final sync runs with the VM powered off, so it is never held back by the window.
+func (migobj *Migrate) copyWindowContext(ctx context.Context, final bool) (context.Context, context.CancelFunc) {
+	if migobj.CopyWindow != nil && !final {
+		now := time.Now()
+		if closing, ok := migobj.CopyWindow.closing(now); ok {
+			return context.WithDeadline(ctx, closing)
+		}
+		if !migobj.CopyWindow.nextOpening(now).Equal(now) {
+			return context.WithDeadline(ctx, now)
+		}
+	}
+	return context.WithCancel(ctx)
+}
+
+// waitForCopyWindow blocks until the copy window is open
+func (migobj *Migrate) waitForCopyWindow(ctx context.Context) error {
+	if migobj.CopyWindow == nil {
+		return nil
+	}
+	opening := migobj.CopyWindow.nextOpening(time.Now())
+	wait := time.Until(opening)
+	if wait <= 0 {
+		return nil
+	}
+	migobj.logMessage(fmt.Sprintf("Outside the copy window, pausing changed blocks sync until %s", opening.Format(time.RFC3339)))
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+	}
+	migobj.logMessage("Copy window opened, resuming changed blocks sync")
+	return nil
+}
+
+// getBandwidthLimitMbps returns the bandwidth this migration may use, which is the lower of the limit
+// of the migration plan and its share of the limit of the node. 0 means no limit.
+func (migobj *Migrate) getBandwidthLimitMbps(ctx context.Context, nodeLimitMbps int) (int, error) {
+	limit := migobj.BandwidthLimitMbps
+	if nodeLimitMbps <= 0 {
+		return limit, nil
+	}
+	migrations, err := utils.CountMigrationsOnNode(ctx, migobj.K8sClient)
+	if err != nil {
+		return 0, err
+	}
+	share := max(nodeLimitMbps/migrations, 1)
+	if limit <= 0 || share < limit {
+		limit = share
+	}
+	return limit, nil
+}
+
+// bandwidthThrottle splits the bandwidth limit of the pod between the disks being copied. The nbdkit servers of
+// all disks read their rate from the same file, which is rewritten whenever the limit or the number of copies
+// changes, so that a single copy gets the whole limit.
+type bandwidthThrottle struct {
+	mu        sync.Mutex
+	rateFile  string
+	limitMbps int
+	copies    int
+}
+
+// setLimit changes the bandwidth limit of the pod and rewrites the rate file
+func (t *bandwidthThrottle) setLimit(limitMbps int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limitMbps = limitMbps
+	return writeRateFile(t.rateFile, t.limitMbps, max(t.copies, 1))
+}
+
+// addCopies changes the number of running copies by delta and rewrites the rate file
+func (t *bandwidthThrottle) addCopies(delta int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.copies += delta
+	return writeRateFile(t.rateFile, t.limitMbps, max(t.copies, 1))
+}
+
+// limit returns the bandwidth limit of the pod in Mbps
+func (t *bandwidthThrottle) limit() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.limitMbps
+}
+
+// copyStarted gives a share of the bandwidth limit to a disk copy, if a limit is set
+func (migobj *Migrate) copyStarted() {
+	if migobj.bandwidth == nil {
+		return
+	}
+	if err := migobj.bandwidth.addCopies(1); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to update bandwidth limit: %v", err))
+	}
+}
+
+// copyFinished returns the share of the bandwidth limit of a disk copy to the other copies
+func (migobj *Migrate) copyFinished() {
+	if migobj.bandwidth == nil {
+		return
+	}
+	if err := migobj.bandwidth.addCopies(-1); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to update bandwidth limit: %v", err))
+	}
+}
+
+// startBandwidthThrottle writes the rate file read by the nbdkit rate filter and keeps it up to date
+// until ctx is done. The limit applies to the whole pod and is split evenly between the disks being copied.
+// It returns an empty path if no bandwidth limit is set.
+func (migobj *Migrate) startBandwidthThrottle(ctx context.Context) (string, error) {
+	vcenterSettings, err := utils.GetMigrateSettings(ctx, migobj.K8sClient)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get vcenter settings")
+	}
+	if migobj.BandwidthLimitMbps <= 0 && vcenterSettings.NodeBandwidthLimitMbps <= 0 {
+		return "", nil
+	}
+	throttle := &bandwidthThrottle{rateFile: filepath.Join(os.TempDir(), "nbdkit-rate")}
+
+	limit, err := migobj.getBandwidthLimitMbps(ctx, vcenterSettings.NodeBandwidthLimitMbps)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get bandwidth limit")
+	}
+	if err := throttle.setLimit(limit); err != nil {
+		return "", err
+	}
+	migobj.bandwidth = throttle
+	migobj.logMessage(fmt.Sprintf("Limiting data copy to %d Mbps", limit))
+
+	go func() {
+		ticker := time.NewTicker(constants.BandwidthLimitRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			newLimit, err := migobj.getBandwidthLimitMbps(ctx, vcenterSettings.NodeBandwidthLimitMbps)
+			if err != nil {
+				utils.PrintLog(fmt.Sprintf("Failed to refresh bandwidth limit, keeping %d Mbps: %v", throttle.limit(), err))
+				continue
+			}
+			if newLimit == throttle.limit() {
+				continue
+			}
+			if err := throttle.setLimit(newLimit); err != nil {
+				utils.PrintLog(fmt.Sprintf("Failed to update bandwidth limit: %v", err))
+				continue
+			}
+			migobj.logMessage(fmt.Sprintf("Limiting data copy to %d Mbps", newLimit))
+		}
+	}()
+	return throttle.rateFile, nil
+}
+
+// writeRateFile writes the rate limit of a single nbdkit server in bits per second
+func writeRateFile(path string, limitMbps, nbdServers int) error {
+	rate := max(int64(limitMbps)*1000*1000/int64(nbdServers), 1)
+	if err := os.WriteFile(path, []byte(strconv.FormatInt(rate, 10)), 0644); err != nil {
+		return errors.Wrap(err, "failed to write nbdkit rate file")
+	}
+	return nil
+}
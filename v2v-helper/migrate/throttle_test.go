@@ -0,0 +1,101 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCopyWindow(t *testing.T) {
+	window, err := ParseCopyWindow("", "", "")
+	assert.NoError(t, err)
+	assert.Nil(t, window)
+
+	window, err = ParseCopyWindow("22:00", "06:30", "Europe/Berlin")
+	assert.NoError(t, err)
+	assert.Equal(t, 22*60, window.startMinute)
+	assert.Equal(t, 6*60+30, window.endMinute)
+	assert.Equal(t, "Europe/Berlin", window.location.String())
+
+	_, err = ParseCopyWindow("25:00", "06:00", "")
+	assert.Error(t, err)
+	_, err = ParseCopyWindow("22:00", "06:00", "Mars/Olympus")
+	assert.Error(t, err)
+}
+
+func TestCopyWindowNextOpening(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	daytime, err := ParseCopyWindow("09:00", "17:00", "")
+	assert.NoError(t, err)
+	assert.Equal(t, at(10, 9, 0), daytime.nextOpening(at(10, 9, 0)))
+	assert.Equal(t, at(10, 16, 59), daytime.nextOpening(at(10, 16, 59)))
+	assert.Equal(t, at(10, 9, 0), daytime.nextOpening(at(10, 3, 0)))
+	assert.Equal(t, at(11, 9, 0), daytime.nextOpening(at(10, 17, 0)))
+
+	overnight, err := ParseCopyWindow("22:00", "06:00", "")
+	assert.NoError(t, err)
+	assert.Equal(t, at(10, 23, 0), overnight.nextOpening(at(10, 23, 0)))
+	assert.Equal(t, at(10, 5, 0), overnight.nextOpening(at(10, 5, 0)))
+	assert.Equal(t, at(10, 22, 0), overnight.nextOpening(at(10, 12, 0)))
+
+	always, err := ParseCopyWindow("00:00", "00:00", "")
+	assert.NoError(t, err)
+	assert.Equal(t, at(10, 12, 0), always.nextOpening(at(10, 12, 0)))
+}
+
+func TestWriteRateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rate")
+	assert.NoError(t, writeRateFile(path, 100, 4))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "25000000", string(data))
+}
+
+func TestCopyWindowClosing(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, time.March, 10, hour, minute, 0, 0, time.UTC)
+	}
+
+	night, err := ParseCopyWindow("22:00", "06:00", "")
+	assert.NoError(t, err)
+	closing, open := night.closing(at(23, 0))
+	assert.True(t, open)
+	assert.Equal(t, at(6, 0).AddDate(0, 0, 1), closing)
+	closing, open = night.closing(at(5, 0))
+	assert.True(t, open)
+	assert.Equal(t, at(6, 0), closing)
+	_, open = night.closing(at(12, 0))
+	assert.False(t, open)
+
+	always, err := ParseCopyWindow("00:00", "00:00", "")
+	assert.NoError(t, err)
+	_, open = always.closing(at(12, 0))
+	assert.False(t, open)
+}
+
+func TestBandwidthThrottle(t *testing.T) {
+	throttle := &bandwidthThrottle{rateFile: filepath.Join(t.TempDir(), "rate")}
+	rate := func() string {
+		data, err := os.ReadFile(throttle.rateFile)
+		assert.NoError(t, err)
+		return string(data)
+	}
+
+	// A single copy gets the whole limit of the pod
+	assert.NoError(t, throttle.setLimit(100))
+	assert.Equal(t, "100000000", rate())
+	assert.NoError(t, throttle.addCopies(1))
+	assert.Equal(t, "100000000", rate())
+	assert.NoError(t, throttle.addCopies(1))
+	assert.Equal(t, "50000000", rate())
+	assert.NoError(t, throttle.setLimit(40))
+	assert.Equal(t, "20000000", rate())
+	assert.NoError(t, throttle.addCopies(-1))
+	assert.Equal(t, "40000000", rate())
+}
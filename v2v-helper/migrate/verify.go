@@ -0,0 +1,137 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// maxReportedExtents limits the number of mismatched extents listed per disk in the verification report
+const maxReportedExtents = 10
+
+// VerifyDisks compares the copied volumes with the source disks, which no longer change once the
+// final sync has run. It is a no-op unless data verification is enabled for the migration.
+func (migobj *Migrate) VerifyDisks(ctx context.Context, vminfo vm.VMInfo) error {
+	if migobj.DataVerification == "" || migobj.DataVerification == constants.DataVerificationNone {
+		return nil
+	}
+	vmops := migobj.VMops
+	migobj.logMessage(fmt.Sprintf("%s (%s)", constants.EventMessageVerifyingDiskData, migobj.DataVerification))
+
+	// The migration snapshot was removed after the final sync, take a new one to read the disks through
+	err := vmops.TakeSnapshot(constants.MigrationSnapshotName)
+	if err != nil {
+		return errors.Wrap(err, "failed to take snapshot of source VM")
+	}
+	defer func() {
+		if err := vmops.CleanUpSnapshots(true); err != nil {
+			utils.PrintLog(fmt.Sprintf("Failed to cleanup snapshot of source VM: %s", err))
+		}
+	}()
+	err = vmops.UpdateDisksInfo(&vminfo)
+	if err != nil {
+		return errors.Wrap(err, "failed to update disk info")
+	}
+
+	for idx, vmdisk := range vminfo.VMDisks {
+		vminfo.VMDisks[idx].Path, err = migobj.AttachVolume(vmdisk)
+		if err != nil {
+			return errors.Wrap(err, "failed to attach volume")
+		}
+	}
+	defer func() {
+		if err := migobj.DetachAllVolumes(vminfo); err != nil {
+			utils.PrintLog(fmt.Sprintf("Failed to detach all volumes from VM: %s", err))
+		}
+	}()
+
+	for idx, vmdisk := range vminfo.VMDisks {
+		err = migobj.Nbdops[idx].StartNBDServer(vmops.GetVMObj(), migobj.URL, migobj.UserName, migobj.Password, migobj.Thumbprint, vmdisk.Snapname, vmdisk.SnapBackingDisk, migobj.EventReporter)
+		if err != nil {
+			return errors.Wrap(err, "failed to start NBD server")
+		}
+	}
+	defer func() {
+		for _, nbdserver := range migobj.Nbdops {
+			if err := nbdserver.StopNBDServer(); err != nil {
+				utils.PrintLog(fmt.Sprintf("Failed to stop NBD server: %s", err))
+			}
+		}
+	}()
+	// sleep for 2 seconds to allow the NBD server to start
+	time.Sleep(2 * time.Second)
+
+	mismatches := map[int][]types.DiskChangeExtent{}
+	verified := int64(0)
+	for idx, vmdisk := range vminfo.VMDisks {
+		startTime := time.Now()
+		ranges := verificationRanges(vmdisk.Size, migobj.DataVerification)
+		mismatched, err := migobj.Nbdops[idx].VerifyDisk(ctx, ranges, vmdisk.Path, idx)
+		if err != nil {
+			return errors.Wrapf(err, "failed to verify disk %d", idx)
+		}
+		for _, extent := range ranges {
+			verified += extent.Length
+		}
+		if len(mismatched) > 0 {
+			mismatches[idx] = mismatched
+			utils.PrintLog(fmt.Sprintf("Disk %d (%s) differs from the source in %d extents", idx, vmdisk.Name, len(mismatched)))
+			continue
+		}
+		utils.PrintLog(fmt.Sprintf("Disk %d (%s) verified in %s", idx, vmdisk.Name, time.Since(startTime)))
+	}
+
+	if len(mismatches) > 0 {
+		report := mismatchReport(vminfo, mismatches)
+		migobj.logMessage(fmt.Sprintf("%s: %s", constants.EventMessageDiskDataMismatch, report))
+		return errors.Errorf("volumes do not match the source disks: %s", report)
+	}
+	migobj.logMessage(fmt.Sprintf("%s, compared %d bytes of %d disk(s)", constants.EventMessageDiskDataVerified, verified, len(vminfo.VMDisks)))
+	return nil
+}
+
+// verificationRanges returns the ranges of a disk of the given size checked by the verification mode.
+// Sampled verification checks ranges spread evenly over the disk, including its first and last bytes.
+func verificationRanges(size int64, mode string) []types.DiskChangeExtent {
+	if mode == constants.DataVerificationFull || size <= constants.VerifySampleCount*constants.VerifySampleSize {
+		return []types.DiskChangeExtent{{Start: 0, Length: size}}
+	}
+	ranges := make([]types.DiskChangeExtent, 0, constants.VerifySampleCount)
+	last := size - constants.VerifySampleSize
+	for i := int64(0); i < constants.VerifySampleCount; i++ {
+		start := last * i / (constants.VerifySampleCount - 1)
+		if i < constants.VerifySampleCount-1 {
+			// Keep the samples aligned to the sector size of the volume
+			start &^= 4095
+		}
+		ranges = append(ranges, types.DiskChangeExtent{Start: start, Length: constants.VerifySampleSize})
+	}
+	return ranges
+}
+
+// mismatchReport lists the extents of each disk that differ from the source
+func mismatchReport(vminfo vm.VMInfo, mismatches map[int][]types.DiskChangeExtent) string {
+	var disks []string
+	for idx, vmdisk := range vminfo.VMDisks {
+		extents, ok := mismatches[idx]
+		if !ok {
+			continue
+		}
+		var listed []string
+		for _, extent := range extents[:min(len(extents), maxReportedExtents)] {
+			listed = append(listed, fmt.Sprintf("%d+%d", extent.Start, extent.Length))
+		}
+		if len(extents) > maxReportedExtents {
+			listed = append(listed, fmt.Sprintf("and %d more", len(extents)-maxReportedExtents))
+		}
+		disks = append(disks, fmt.Sprintf("disk %d (%s) differs at offset+length %s", idx, vmdisk.Name, strings.Join(listed, " ")))
+	}
+	return strings.Join(disks, "; ")
+}
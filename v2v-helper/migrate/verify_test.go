@@ -0,0 +1,39 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestVerificationRanges(t *testing.T) {
+	size := int64(100 << 30)
+	assert.Equal(t, []types.DiskChangeExtent{{Start: 0, Length: size}}, verificationRanges(size, constants.DataVerificationFull))
+
+	small := int64(constants.VerifySampleCount * constants.VerifySampleSize)
+	assert.Equal(t, []types.DiskChangeExtent{{Start: 0, Length: small}}, verificationRanges(small, constants.DataVerificationSampled))
+
+	ranges := verificationRanges(size, constants.DataVerificationSampled)
+	assert.Len(t, ranges, constants.VerifySampleCount)
+	assert.Equal(t, int64(0), ranges[0].Start)
+	assert.Equal(t, size, ranges[len(ranges)-1].Start+ranges[len(ranges)-1].Length)
+	for i := 1; i < len(ranges); i++ {
+		assert.GreaterOrEqual(t, ranges[i].Start, ranges[i-1].Start+ranges[i-1].Length)
+		assert.Zero(t, ranges[i].Start%4096)
+	}
+}
+
+func TestMismatchReport(t *testing.T) {
+	vminfo := vm.VMInfo{VMDisks: []vm.VMDisk{{Name: "disk1"}, {Name: "disk2"}}}
+	var extents []types.DiskChangeExtent
+	for i := int64(0); i < 12; i++ {
+		extents = append(extents, types.DiskChangeExtent{Start: i * 4096, Length: 512})
+	}
+	report := mismatchReport(vminfo, map[int][]types.DiskChangeExtent{1: extents})
+	assert.Equal(t, "disk 1 (disk2) differs at offset+length 0+512 4096+512 8192+512 12288+512 16384+512 "+
+		"20480+512 24576+512 28672+512 32768+512 36864+512 and 2 more", report)
+}
@@ -5,12 +5,14 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"os/exec"
 	"strings"
 	"sync"
 	"syscall"
+	"unsafe"
 
 	"github.com/pkg/errors"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
@@ -28,10 +30,15 @@ type NBDOperations interface {
 	StartNBDServer(vm *object.VirtualMachine, server, username, password, thumbprint, snapref, file string, progchan chan string) error
 	StopNBDServer() error
 	CopyDisk(ctx context.Context, dest string, diskindex int) error
-	CopyChangedBlocks(ctx context.Context, changedAreas types.DiskChangeInfo, path string) error
+	CopyChangedBlocks(ctx context.Context, changedAreas types.DiskChangeInfo, path string, diskindex int) error
+	VerifyDisk(ctx context.Context, ranges []types.DiskChangeExtent, path string, diskindex int) ([]types.DiskChangeExtent, error)
 }
 
 type NBDServer struct {
+	// RateFile is the file holding the read rate limit in bits per second, empty for no limit
+	RateFile string
+	// Progress, if set, is called with the fraction of the copy of the disk that is done
+	Progress     func(diskindex int, fraction float64)
 	cmd          *exec.Cmd
 	tmp_dir      string
 	progresschan chan string
@@ -90,8 +97,7 @@ vixDiskLib.nfcAio.Session.BufCount=4`
 	socket := fmt.Sprintf("%s/nbdkit.sock", tmp_dir)
 	pidFile := fmt.Sprintf("%s/nbdkit.pid", tmp_dir)
 
-	cmd := exec.Command(
-		"nbdkit",
+	args := []string{
 		"--exit-with-parent",
 		"--readonly",
 		"--foreground",
@@ -100,6 +106,12 @@ vixDiskLib.nfcAio.Session.BufCount=4`
 		"--verbose",
 		"-D vddk.datapath=0",
 		"-D nbdkit.backend.datapath=0",
+	}
+	if nbdserver.RateFile != "" {
+		// The rate filter re-reads the file while running, so the limit can change during the copy
+		args = append(args, "--filter=rate")
+	}
+	args = append(args,
 		"vddk",
 		"libdir=/home/fedora/vmware-vix-disklib-distrib",
 		fmt.Sprintf("server=%s", server),
@@ -111,8 +123,13 @@ vixDiskLib.nfcAio.Session.BufCount=4`
 		"transports=file:nbdssl:nbd",
 		fmt.Sprintf("vm=moref=%s", vm.Reference().Value),
 		fmt.Sprintf("snapshot=%s", snapref),
-		file,
 	)
+	if nbdserver.RateFile != "" {
+		args = append(args, fmt.Sprintf("rate-file=%s", nbdserver.RateFile))
+	}
+	args = append(args, file)
+
+	cmd := exec.Command("nbdkit", args...)
 
 	// Log the command
 	cmdstring := ""
@@ -166,11 +183,14 @@ func (nbdserver *NBDServer) CopyDisk(ctx context.Context, dest string, diskindex
 		scanner := bufio.NewScanner(progressRead)
 		lastProgress := 0
 		for scanner.Scan() {
-			progressInt, _, err := utils.ParseFraction(scanner.Text())
+			progressInt, progressTotal, err := utils.ParseFraction(scanner.Text())
 			if err != nil {
 				utils.PrintLog(fmt.Sprintf("Error converting progress percent to int: %v", err))
 				continue
 			}
+			if nbdserver.Progress != nil && progressTotal > 0 {
+				nbdserver.Progress(diskindex, float64(progressInt)/float64(progressTotal))
+			}
 			msg := fmt.Sprintf("Copying disk %d, Completed: %d%%", diskindex, progressInt)
 			utils.PrintLog(msg)
 
@@ -365,7 +385,7 @@ func copyRange(fd *os.File, handle *libnbd.Libnbd, block *BlockStatusData) error
 	return nil
 }
 
-func (nbdserver *NBDServer) CopyChangedBlocks(ctx context.Context, changedAreas types.DiskChangeInfo, path string) error {
+func (nbdserver *NBDServer) CopyChangedBlocks(ctx context.Context, changedAreas types.DiskChangeInfo, path string, diskindex int) error {
 	// Copy the changed blocks from source to destination
 	handle, err := libnbd.Create()
 	if err != nil {
@@ -393,7 +413,11 @@ func (nbdserver *NBDServer) CopyChangedBlocks(ctx context.Context, changedAreas
 		totalsize += extent.Length
 	}
 
-	var wg sync.WaitGroup
+	var (
+		wg      sync.WaitGroup
+		errMu   sync.Mutex
+		copyErr error
+	)
 	semaphore := make(chan struct{}, 16)
 	incrementalcopyprogress := make(chan int64)
 
@@ -402,8 +426,11 @@ func (nbdserver *NBDServer) CopyChangedBlocks(ctx context.Context, changedAreas
 		copiedsize := int64(0)
 		for progress := range incrementalcopyprogress {
 			copiedsize += progress
-			prog := fmt.Sprintf("Progress: %.2f%%", float64(copiedsize)/float64(totalsize)*100.0)
+			prog := fmt.Sprintf("Disk %d Progress: %.2f%%", diskindex, float64(copiedsize)/float64(totalsize)*100.0)
 			utils.PrintLog(prog)
+			if nbdserver.Progress != nil && totalsize > 0 {
+				nbdserver.Progress(diskindex, float64(copiedsize)/float64(totalsize))
+			}
 			nbdserver.progresschan <- prog
 		}
 	}()
@@ -411,28 +438,129 @@ func (nbdserver *NBDServer) CopyChangedBlocks(ctx context.Context, changedAreas
 	for _, extent := range changedAreas.ChangedArea {
 		wg.Add(1)
 		go func(extent types.DiskChangeExtent) {
-			blocks := getBlockStatus(handle, extent)
+			defer wg.Done()
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
-			for _, block := range blocks {
+			// Extents left when the copy is cancelled or has failed are not copied
+			errMu.Lock()
+			failed := copyErr != nil
+			errMu.Unlock()
+			if failed || ctx.Err() != nil {
+				return
+			}
+			for _, block := range getBlockStatus(handle, extent) {
 				if err := copyRange(fd, handle, block); err != nil {
 					utils.PrintLog(fmt.Sprintf("Failed to copy block: %v", err))
+					errMu.Lock()
+					if copyErr == nil {
+						copyErr = err
+					}
+					errMu.Unlock()
+					return
 				}
 			}
-			// check if context is cancelled
-			select {
-			case <-ctx.Done():
-				if _, ok := <-incrementalcopyprogress; ok {
-					close(incrementalcopyprogress)
-				}
-				return
-			case incrementalcopyprogress <- extent.Length:
-				wg.Done()
-			}
+			incrementalcopyprogress <- extent.Length
 		}(extent)
 	}
 	wg.Wait()
 	close(incrementalcopyprogress)
+	// The changed blocks are only on the volume if every extent was copied
+	if copyErr != nil {
+		return errors.Wrap(copyErr, "failed to copy changed blocks")
+	}
+	return ctx.Err()
+}
+
+// VerifyDisk compares the given ranges of the source disk with the destination and returns the ranges that differ
+func (nbdserver *NBDServer) VerifyDisk(ctx context.Context, ranges []types.DiskChangeExtent, path string, diskindex int) ([]types.DiskChangeExtent, error) {
+	handle, err := libnbd.Create()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create libnbd handle: %v", err)
+	}
+	err = handle.ConnectUri(generateSockUrl(nbdserver.tmp_dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to source: %v", err)
+	}
+	defer handle.Close()
+
+	// The page cache may still hold the pages written by the copy, the volume is read with O_DIRECT to verify
+	// what was actually written to it
+	fd, err := os.OpenFile(path, os.O_RDONLY|unix.O_DIRECT, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer fd.Close()
+
+	totalsize := int64(0)
+	for _, extent := range ranges {
+		totalsize += extent.Length
+	}
+
+	var mismatched []types.DiskChangeExtent
+	source := make([]byte, MaxPreadLength)
+	dest := make([]byte, MaxPreadLength)
+	direct := alignedBuffer(MaxPreadLength + 2*directIOAlignment)
+	verified := int64(0)
+	lastProgress := 0
+	for _, extent := range ranges {
+		end := extent.Start + extent.Length
+		for offset := extent.Start; offset < end; offset += int64(MaxPreadLength) {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			length := min(int64(MaxPreadLength), end-offset)
+			if err := handle.Pread(source[:length], uint64(offset), nil); err != nil {
+				return nil, fmt.Errorf("error reading from source at offset %d: %v", offset, err)
+			}
+			if err := readDirect(fd, dest[:length], offset, direct); err != nil {
+				return nil, fmt.Errorf("error reading from destination at offset %d: %v", offset, err)
+			}
+			if !bytes.Equal(source[:length], dest[:length]) {
+				last := len(mismatched) - 1
+				if last >= 0 && mismatched[last].Start+mismatched[last].Length == offset {
+					mismatched[last].Length += length
+				} else {
+					mismatched = append(mismatched, types.DiskChangeExtent{Start: offset, Length: length})
+				}
+			}
+
+			verified += length
+			progress := int(float64(verified) / float64(totalsize) * 100.0)
+			if lastProgress <= progress-10 {
+				prog := fmt.Sprintf("Disk %d Verification Progress: %d%%", diskindex, progress)
+				utils.PrintLog(prog)
+				nbdserver.progresschan <- prog
+				lastProgress = progress
+			}
+		}
+	}
+	return mismatched, nil
+}
+
+// directIOAlignment is the alignment of the buffers, offsets and lengths of reads from a file opened with O_DIRECT
+const directIOAlignment = 4096
+
+// alignedBuffer returns a buffer of size bytes that starts at a multiple of directIOAlignment in memory
+func alignedBuffer(size int) []byte {
+	buf := make([]byte, size+directIOAlignment)
+	start := 0
+	if rem := int(uintptr(unsafe.Pointer(&buf[0])) % directIOAlignment); rem != 0 {
+		start = directIOAlignment - rem
+	}
+	return buf[start : start+size]
+}
+
+// readDirect reads len(p) bytes at offset from a file opened with O_DIRECT. The read is widened to aligned
+// offsets into buf, which has to be aligned and hold len(p) plus twice directIOAlignment bytes.
+func readDirect(fd *os.File, p []byte, offset int64, buf []byte) error {
+	start := offset &^ (directIOAlignment - 1)
+	end := (offset + int64(len(p)) + directIOAlignment - 1) &^ (directIOAlignment - 1)
+	n, err := fd.ReadAt(buf[:end-start], start)
+	// The aligned end may lie past the end of the file
+	if err != nil && (err != io.EOF || int64(n) < offset-start+int64(len(p))) {
+		return err
+	}
+	copy(p, buf[offset-start:])
 	return nil
 }
 
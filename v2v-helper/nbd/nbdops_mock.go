@@ -37,17 +37,17 @@ func (m *MockNBDOperations) EXPECT() *MockNBDOperationsMockRecorder {
 }
 
 // CopyChangedBlocks mocks base method.
-func (m *MockNBDOperations) CopyChangedBlocks(ctx context.Context, changedAreas types.DiskChangeInfo, path string) error {
+func (m *MockNBDOperations) CopyChangedBlocks(ctx context.Context, changedAreas types.DiskChangeInfo, path string, diskindex int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CopyChangedBlocks", ctx, changedAreas, path)
+	ret := m.ctrl.Call(m, "CopyChangedBlocks", ctx, changedAreas, path, diskindex)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CopyChangedBlocks indicates an expected call of CopyChangedBlocks.
-func (mr *MockNBDOperationsMockRecorder) CopyChangedBlocks(ctx, changedAreas, path interface{}) *gomock.Call {
+func (mr *MockNBDOperationsMockRecorder) CopyChangedBlocks(ctx, changedAreas, path, diskindex interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyChangedBlocks", reflect.TypeOf((*MockNBDOperations)(nil).CopyChangedBlocks), ctx, changedAreas, path)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyChangedBlocks", reflect.TypeOf((*MockNBDOperations)(nil).CopyChangedBlocks), ctx, changedAreas, path, diskindex)
 }
 
 // CopyDisk mocks base method.
@@ -91,3 +91,18 @@ func (mr *MockNBDOperationsMockRecorder) StopNBDServer() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopNBDServer", reflect.TypeOf((*MockNBDOperations)(nil).StopNBDServer))
 }
+
+// VerifyDisk mocks base method.
+func (m *MockNBDOperations) VerifyDisk(ctx context.Context, ranges []types.DiskChangeExtent, path string, diskindex int) ([]types.DiskChangeExtent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyDisk", ctx, ranges, path, diskindex)
+	ret0, _ := ret[0].([]types.DiskChangeExtent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyDisk indicates an expected call of VerifyDisk.
+func (mr *MockNBDOperationsMockRecorder) VerifyDisk(ctx, ranges, path, diskindex interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyDisk", reflect.TypeOf((*MockNBDOperations)(nil).VerifyDisk), ctx, ranges, path, diskindex)
+}
@@ -16,22 +16,27 @@ import (
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/snapshots"
 	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/volumes"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
 )
 
 //go:generate mockgen -source=../openstack/openstackops.go -destination=../openstack/openstackops_mock.go -package=openstack
 
 type OpenstackOperations interface {
-	CreateVolume(name string, size int64, ostype string, uefi bool, volumetype string) (*volumes.Volume, error)
+	CreateVolume(name string, size int64, ostype string, uefi bool, volumetype string, multiattach bool) (*volumes.Volume, error)
+	GetVolume(volumeID string) (*volumes.Volume, error)
 	WaitForVolume(volumeID string) error
 	AttachVolumeToVM(volumeID string) error
 	WaitForVolumeAttachment(volumeID string) error
 	DetachVolumeFromVM(volumeID string) error
+	DetachVolumeFromServer(serverID, volumeID string) error
 	SetVolumeUEFI(volume *volumes.Volume) error
+	SetVolumeTPM(volume *volumes.Volume) error
 	EnableQGA(volume *volumes.Volume) error
 	SetVolumeImageMetadata(volume *volumes.Volume) error
 	SetVolumeBootable(volume *volumes.Volume) error
@@ -39,13 +44,25 @@ type OpenstackOperations interface {
 	GetFlavor(flavorId string) (*flavors.Flavor, error)
 	GetNetwork(networkname string) (*networks.Network, error)
 	GetPort(portID string) (*ports.Port, error)
+	GetSubnet(subnetID string) (*subnets.Subnet, error)
 	CreatePort(networkid *networks.Network, mac, ip, vmname string, securityGroups []string) (*ports.Port, error)
+	CreatePortInSubnet(network *networks.Network, subnetID, mac, ip, name string, securityGroups []string) (*ports.Port, error)
 	CreateVM(flavor *flavors.Flavor, networkIDs, portIDs []string, vminfo vm.VMInfo, availabilityZone string, securityGroups []string, migrateSettings utils.VjailbreakSettings, useFlavorless bool) (*servers.Server, error)
 	GetSecurityGroupIDs(groupNames []string, projectName string) ([]string, error)
 	DeleteVolume(volumeID string) error
 	FindDevice(volumeID string) (string, error)
 	WaitUntilVMActive(vmID string) (bool, error)
 	CinderManage(rdmDisk vm.RDMDisk, openstackAPIVersion string) (*volumes.Volume, error)
+	CreateVolumeSnapshot(volumeID, name string) (*snapshots.Snapshot, error)
+	DeleteVolumeSnapshot(snapshotID string) error
+	CreateVolumeFromSnapshot(name string, snapshot *snapshots.Snapshot) (*volumes.Volume, error)
+	CreateIsolatedNetwork(name, cidr string) (*networks.Network, error)
+	DeleteNetwork(networkID string) error
+	DeletePort(portID string) error
+	AttachPortToVM(portID string) error
+	DetachPortFromVM(portID string) error
+	DeleteServer(serverID string) error
+	ExtendVolume(volumeID string, size int) error
 }
 
 func getCert(endpoint string) (*x509.Certificate, error) {
@@ -8,11 +8,13 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	snapshots "github.com/gophercloud/gophercloud/openstack/blockstorage/v3/snapshots"
 	volumes "github.com/gophercloud/gophercloud/openstack/blockstorage/v3/volumes"
 	flavors "github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
 	servers "github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
 	networks "github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
 	ports "github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	subnets "github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
 	utils "github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/utils"
 	vm "github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
 )
@@ -40,6 +42,20 @@ func (m *MockOpenstackOperations) EXPECT() *MockOpenstackOperationsMockRecorder
 	return m.recorder
 }
 
+// AttachPortToVM mocks base method.
+func (m *MockOpenstackOperations) AttachPortToVM(portID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AttachPortToVM", portID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AttachPortToVM indicates an expected call of AttachPortToVM.
+func (mr *MockOpenstackOperationsMockRecorder) AttachPortToVM(portID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttachPortToVM", reflect.TypeOf((*MockOpenstackOperations)(nil).AttachPortToVM), portID)
+}
+
 // AttachVolumeToVM mocks base method.
 func (m *MockOpenstackOperations) AttachVolumeToVM(volumeID string) error {
 	m.ctrl.T.Helper()
@@ -69,6 +85,21 @@ func (mr *MockOpenstackOperationsMockRecorder) CinderManage(rdmDisk, openstackAP
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CinderManage", reflect.TypeOf((*MockOpenstackOperations)(nil).CinderManage), rdmDisk, openstackAPIVersion)
 }
 
+// CreateIsolatedNetwork mocks base method.
+func (m *MockOpenstackOperations) CreateIsolatedNetwork(name, cidr string) (*networks.Network, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIsolatedNetwork", name, cidr)
+	ret0, _ := ret[0].(*networks.Network)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateIsolatedNetwork indicates an expected call of CreateIsolatedNetwork.
+func (mr *MockOpenstackOperationsMockRecorder) CreateIsolatedNetwork(name, cidr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIsolatedNetwork", reflect.TypeOf((*MockOpenstackOperations)(nil).CreateIsolatedNetwork), name, cidr)
+}
+
 // CreatePort mocks base method.
 func (m *MockOpenstackOperations) CreatePort(networkid *networks.Network, mac, ip, vmname string, securityGroups []string) (*ports.Port, error) {
 	m.ctrl.T.Helper()
@@ -84,6 +115,21 @@ func (mr *MockOpenstackOperationsMockRecorder) CreatePort(networkid, mac, ip, vm
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePort", reflect.TypeOf((*MockOpenstackOperations)(nil).CreatePort), networkid, mac, ip, vmname, securityGroups)
 }
 
+// CreatePortInSubnet mocks base method.
+func (m *MockOpenstackOperations) CreatePortInSubnet(network *networks.Network, subnetID, mac, ip, name string, securityGroups []string) (*ports.Port, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePortInSubnet", network, subnetID, mac, ip, name, securityGroups)
+	ret0, _ := ret[0].(*ports.Port)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePortInSubnet indicates an expected call of CreatePortInSubnet.
+func (mr *MockOpenstackOperationsMockRecorder) CreatePortInSubnet(network, subnetID, mac, ip, name, securityGroups interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePortInSubnet", reflect.TypeOf((*MockOpenstackOperations)(nil).CreatePortInSubnet), network, subnetID, mac, ip, name, securityGroups)
+}
+
 // CreateVM mocks base method.
 func (m *MockOpenstackOperations) CreateVM(flavor *flavors.Flavor, networkIDs, portIDs []string, vminfo vm.VMInfo, availabilityZone string, securityGroups []string, migrateSettings utils.VjailbreakSettings, useFlavorless bool) (*servers.Server, error) {
 	m.ctrl.T.Helper()
@@ -100,18 +146,90 @@ func (mr *MockOpenstackOperationsMockRecorder) CreateVM(flavor, networkIDs, port
 }
 
 // CreateVolume mocks base method.
-func (m *MockOpenstackOperations) CreateVolume(name string, size int64, ostype string, uefi bool, volumetype string) (*volumes.Volume, error) {
+func (m *MockOpenstackOperations) CreateVolume(name string, size int64, ostype string, uefi bool, volumetype string, multiattach bool) (*volumes.Volume, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateVolume", name, size, ostype, uefi, volumetype)
+	ret := m.ctrl.Call(m, "CreateVolume", name, size, ostype, uefi, volumetype, multiattach)
 	ret0, _ := ret[0].(*volumes.Volume)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateVolume indicates an expected call of CreateVolume.
-func (mr *MockOpenstackOperationsMockRecorder) CreateVolume(name, size, ostype, uefi, volumetype interface{}) *gomock.Call {
+func (mr *MockOpenstackOperationsMockRecorder) CreateVolume(name, size, ostype, uefi, volumetype, multiattach interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVolume", reflect.TypeOf((*MockOpenstackOperations)(nil).CreateVolume), name, size, ostype, uefi, volumetype, multiattach)
+}
+
+// CreateVolumeFromSnapshot mocks base method.
+func (m *MockOpenstackOperations) CreateVolumeFromSnapshot(name string, snapshot *snapshots.Snapshot) (*volumes.Volume, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateVolumeFromSnapshot", name, snapshot)
+	ret0, _ := ret[0].(*volumes.Volume)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateVolumeFromSnapshot indicates an expected call of CreateVolumeFromSnapshot.
+func (mr *MockOpenstackOperationsMockRecorder) CreateVolumeFromSnapshot(name, snapshot interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVolumeFromSnapshot", reflect.TypeOf((*MockOpenstackOperations)(nil).CreateVolumeFromSnapshot), name, snapshot)
+}
+
+// CreateVolumeSnapshot mocks base method.
+func (m *MockOpenstackOperations) CreateVolumeSnapshot(volumeID, name string) (*snapshots.Snapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateVolumeSnapshot", volumeID, name)
+	ret0, _ := ret[0].(*snapshots.Snapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateVolumeSnapshot indicates an expected call of CreateVolumeSnapshot.
+func (mr *MockOpenstackOperationsMockRecorder) CreateVolumeSnapshot(volumeID, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVolumeSnapshot", reflect.TypeOf((*MockOpenstackOperations)(nil).CreateVolumeSnapshot), volumeID, name)
+}
+
+// DeleteNetwork mocks base method.
+func (m *MockOpenstackOperations) DeleteNetwork(networkID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteNetwork", networkID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteNetwork indicates an expected call of DeleteNetwork.
+func (mr *MockOpenstackOperationsMockRecorder) DeleteNetwork(networkID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNetwork", reflect.TypeOf((*MockOpenstackOperations)(nil).DeleteNetwork), networkID)
+}
+
+// DeletePort mocks base method.
+func (m *MockOpenstackOperations) DeletePort(portID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePort", portID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePort indicates an expected call of DeletePort.
+func (mr *MockOpenstackOperationsMockRecorder) DeletePort(portID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePort", reflect.TypeOf((*MockOpenstackOperations)(nil).DeletePort), portID)
+}
+
+// DeleteServer mocks base method.
+func (m *MockOpenstackOperations) DeleteServer(serverID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteServer", serverID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteServer indicates an expected call of DeleteServer.
+func (mr *MockOpenstackOperationsMockRecorder) DeleteServer(serverID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVolume", reflect.TypeOf((*MockOpenstackOperations)(nil).CreateVolume), name, size, ostype, uefi, volumetype)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteServer", reflect.TypeOf((*MockOpenstackOperations)(nil).DeleteServer), serverID)
 }
 
 // DeleteVolume mocks base method.
@@ -128,6 +246,48 @@ func (mr *MockOpenstackOperationsMockRecorder) DeleteVolume(volumeID interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVolume", reflect.TypeOf((*MockOpenstackOperations)(nil).DeleteVolume), volumeID)
 }
 
+// DeleteVolumeSnapshot mocks base method.
+func (m *MockOpenstackOperations) DeleteVolumeSnapshot(snapshotID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteVolumeSnapshot", snapshotID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteVolumeSnapshot indicates an expected call of DeleteVolumeSnapshot.
+func (mr *MockOpenstackOperationsMockRecorder) DeleteVolumeSnapshot(snapshotID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVolumeSnapshot", reflect.TypeOf((*MockOpenstackOperations)(nil).DeleteVolumeSnapshot), snapshotID)
+}
+
+// DetachPortFromVM mocks base method.
+func (m *MockOpenstackOperations) DetachPortFromVM(portID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DetachPortFromVM", portID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DetachPortFromVM indicates an expected call of DetachPortFromVM.
+func (mr *MockOpenstackOperationsMockRecorder) DetachPortFromVM(portID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetachPortFromVM", reflect.TypeOf((*MockOpenstackOperations)(nil).DetachPortFromVM), portID)
+}
+
+// DetachVolumeFromServer mocks base method.
+func (m *MockOpenstackOperations) DetachVolumeFromServer(serverID, volumeID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DetachVolumeFromServer", serverID, volumeID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DetachVolumeFromServer indicates an expected call of DetachVolumeFromServer.
+func (mr *MockOpenstackOperationsMockRecorder) DetachVolumeFromServer(serverID, volumeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetachVolumeFromServer", reflect.TypeOf((*MockOpenstackOperations)(nil).DetachVolumeFromServer), serverID, volumeID)
+}
+
 // DetachVolumeFromVM mocks base method.
 func (m *MockOpenstackOperations) DetachVolumeFromVM(volumeID string) error {
 	m.ctrl.T.Helper()
@@ -156,6 +316,20 @@ func (mr *MockOpenstackOperationsMockRecorder) EnableQGA(volume interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableQGA", reflect.TypeOf((*MockOpenstackOperations)(nil).EnableQGA), volume)
 }
 
+// ExtendVolume mocks base method.
+func (m *MockOpenstackOperations) ExtendVolume(volumeID string, size int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExtendVolume", volumeID, size)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExtendVolume indicates an expected call of ExtendVolume.
+func (mr *MockOpenstackOperationsMockRecorder) ExtendVolume(volumeID, size interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtendVolume", reflect.TypeOf((*MockOpenstackOperations)(nil).ExtendVolume), volumeID, size)
+}
+
 // FindDevice mocks base method.
 func (m *MockOpenstackOperations) FindDevice(volumeID string) (string, error) {
 	m.ctrl.T.Helper()
@@ -246,6 +420,36 @@ func (mr *MockOpenstackOperationsMockRecorder) GetSecurityGroupIDs(groupNames, p
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecurityGroupIDs", reflect.TypeOf((*MockOpenstackOperations)(nil).GetSecurityGroupIDs), groupNames, projectName)
 }
 
+// GetSubnet mocks base method.
+func (m *MockOpenstackOperations) GetSubnet(subnetID string) (*subnets.Subnet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubnet", subnetID)
+	ret0, _ := ret[0].(*subnets.Subnet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubnet indicates an expected call of GetSubnet.
+func (mr *MockOpenstackOperationsMockRecorder) GetSubnet(subnetID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubnet", reflect.TypeOf((*MockOpenstackOperations)(nil).GetSubnet), subnetID)
+}
+
+// GetVolume mocks base method.
+func (m *MockOpenstackOperations) GetVolume(volumeID string) (*volumes.Volume, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVolume", volumeID)
+	ret0, _ := ret[0].(*volumes.Volume)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVolume indicates an expected call of GetVolume.
+func (mr *MockOpenstackOperationsMockRecorder) GetVolume(volumeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolume", reflect.TypeOf((*MockOpenstackOperations)(nil).GetVolume), volumeID)
+}
+
 // SetVolumeBootable mocks base method.
 func (m *MockOpenstackOperations) SetVolumeBootable(volume *volumes.Volume) error {
 	m.ctrl.T.Helper()
@@ -274,6 +478,20 @@ func (mr *MockOpenstackOperationsMockRecorder) SetVolumeImageMetadata(volume int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVolumeImageMetadata", reflect.TypeOf((*MockOpenstackOperations)(nil).SetVolumeImageMetadata), volume)
 }
 
+// SetVolumeTPM mocks base method.
+func (m *MockOpenstackOperations) SetVolumeTPM(volume *volumes.Volume) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetVolumeTPM", volume)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetVolumeTPM indicates an expected call of SetVolumeTPM.
+func (mr *MockOpenstackOperationsMockRecorder) SetVolumeTPM(volume interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVolumeTPM", reflect.TypeOf((*MockOpenstackOperations)(nil).SetVolumeTPM), volume)
+}
+
 // SetVolumeUEFI mocks base method.
 func (m *MockOpenstackOperations) SetVolumeUEFI(volume *volumes.Volume) error {
 	m.ctrl.T.Helper()
@@ -95,6 +95,10 @@ echo "$(date '+%Y-%m-%d %H:%M:%S') - Network fix script completed" >> "$LOG_FILE
 	EventMessageCopyingDisk                       = "Copying disk"
 	EventMessageFailed                            = "Failed to"
 	EventDisconnect                               = "Disconnected network interfaces"
+	EventMessageVerifyingDiskData                 = "Verifying disk data"
+	EventMessageDiskDataVerified                  = "Disk data verified"
+	EventMessageDiskDataMismatch                  = "Disk data mismatch"
+	EventMessageHealthCheckFailed                 = "Health Check failed"
 
 	OSFamilyWindows = "windowsguest"
 	OSFamilyLinux   = "linuxguest"
@@ -125,6 +129,115 @@ echo "$(date '+%Y-%m-%d %H:%M:%S') - Network fix script completed" >> "$LOG_FILE
 	// PopulateVMwareMachineFlavors is the default value for populating VMwareMachine objects with OpenStack flavors
 	PopulateVMwareMachineFlavors = true
 
+	// DiskCopyConcurrencyLimit is the default max number of disks of a vm copied at the same time
+	DiskCopyConcurrencyLimit = 1
+
+	// NodeBandwidthLimitMbps is the default bandwidth limit shared by all migrations on an agent node, 0 for no limit
+	NodeBandwidthLimitMbps = 0
+
+	// DataVerificationNone, DataVerificationSampled and DataVerificationFull are the data verification modes
+	DataVerificationNone    = "none"
+	DataVerificationSampled = "sampled"
+	DataVerificationFull    = "full"
+
+	// VTPMPolicyRefuse and VTPMPolicyRecreate are the ways VMs with a virtual TPM are handled. Refuse fails the
+	// migration, recreate boots the target with an emulated TPM 2.0 that does not hold the state of the source TPM
+	VTPMPolicyRefuse   = "refuse"
+	VTPMPolicyRecreate = "recreate"
+
+	// TPMVersion and TPMModel are the image properties requesting an emulated TPM from Nova
+	TPMVersion = "2.0"
+	TPMModel   = "tpm-crb"
+
+	// VerifySampleCount is the number of ranges of a disk checked by sampled data verification
+	VerifySampleCount = 1024
+
+	// VerifySampleSize is the size of each range checked by sampled data verification
+	VerifySampleSize = 1 << 20
+
+	// BandwidthLimitRefreshInterval is how often the bandwidth limit of a migration is recalculated
+	BandwidthLimitRefreshInterval = 30 * time.Second
+
+	// VMNameLabel is the label on the v2v-helper pods holding the name of the VM being migrated
+	VMNameLabel = "migrate.k8s.stellaris.io/vm-name"
+
 	// StellarisMigrateSettingsConfigMapName is the name of the stellaris-migrate settings configmap
 	StellarisMigrateSettingsConfigMapName = "stellaris-migrate-settings"
+
+	// CopyCheckpointKey is the key in the checkpoint configmap holding the disk copy progress
+	CopyCheckpointKey = "checkpoint"
+
+	// CopyCheckpointBatchSize is the amount of data copied between two checkpoints when resuming a disk copy
+	CopyCheckpointBatchSize = 4 << 30
+
+	// PrometheusScrapeAnnotation, PrometheusPathAnnotation and PrometheusPortAnnotation let Prometheus discover the
+	// metrics of the v2v-helper pods. The port is only known once the helper listens, so the helper sets it itself
+	PrometheusScrapeAnnotation = "prometheus.io/scrape"
+	PrometheusPathAnnotation   = "prometheus.io/path"
+	PrometheusPortAnnotation   = "prometheus.io/port"
+
+	// MetricsScrapeGracePeriod is how long the v2v-helper keeps serving metrics after the migration ends, so that
+	// the last values are scraped
+	MetricsScrapeGracePeriod = 30 * time.Second
+
+	// MigrationProgressAnnotation is the annotation on the v2v-helper pods holding the typed progress of the
+	// migration as JSON. The controller copies it to the status of the Migration
+	MigrationProgressAnnotation = "migrate.k8s.stellaris.io/progress"
+
+	// ProgressPublishInterval is the minimum interval between two publications of the copy progress
+	ProgressPublishInterval = 10 * time.Second
+
+	// CutoverWindowSyncInterval is how often changed blocks are synced while the cutover waits for the next
+	// cutover window
+	CutoverWindowSyncInterval = 15 * time.Minute
+
+	// MigrationHookResultsAnnotation is the annotation on the v2v-helper pods holding the results of the hooks run
+	// so far as JSON. The controller records them as conditions of the Migration
+	MigrationHookResultsAnnotation = "migrate.k8s.stellaris.io/hook-results"
+
+	// DefaultHookTimeout is how long a hook without a timeout may run
+	DefaultHookTimeout = 10 * time.Minute
+
+	// HookPollInterval is how often the v2v-helper checks whether a hook Job or guest command has finished
+	HookPollInterval = 5 * time.Second
+
+	// MigrationTestBootAnnotation is the annotation on the v2v-helper pods holding the result of the test boot of
+	// the VM as JSON. The controller copies it to the status of the Migration
+	MigrationTestBootAnnotation = "migrate.k8s.stellaris.io/test-boot"
+
+	// TestBootCIDRPool is the range the subnets of test boots without a CIDR are taken from
+	TestBootCIDRPool = "100.64.0.0/10"
+
+	// DefaultTestBootTimeout is how long the copy of the VM may take to boot and pass the health checks if the
+	// migration plan does not say
+	DefaultTestBootTimeout = 30 * time.Minute
+
+	// TestBootPollInterval is how often the health checks of a test boot are retried
+	TestBootPollInterval = 30 * time.Second
+
+	// SnapshotConsistencyCrashConsistent and SnapshotConsistencyQuiesced are the snapshot consistencies
+	SnapshotConsistencyCrashConsistent = "crashConsistent"
+	SnapshotConsistencyQuiesced        = "quiesced"
+
+	// DefaultQuiesceTimeout is how long a quiesced snapshot may take before it falls back to a crash-consistent one
+	DefaultQuiesceTimeout = 5 * time.Minute
+
+	// MigrationSnapshotsAnnotation is the annotation on the v2v-helper pods holding the snapshots the disks were
+	// synced from as JSON. The controller copies it to the status of the Migration
+	MigrationSnapshotsAnnotation = "migrate.k8s.stellaris.io/snapshots"
+
+	// MigrationVolumeExtensionsAnnotation is the annotation on the v2v-helper pods holding the volumes extended
+	// because their source disk grew as JSON. The controller copies it to the status of the Migration
+	MigrationVolumeExtensionsAnnotation = "migrate.k8s.stellaris.io/volume-extensions"
+
+	// MigrationSharedDisksAnnotation is the annotation on the v2v-helper pods holding the Cinder volumes of the
+	// shared disks the migration copied, by SharedDisk, as JSON. The controller copies it to the SharedDisks
+	MigrationSharedDisksAnnotation = "migrate.k8s.stellaris.io/shared-disks"
+
+	// SharedDiskCopyTimeout is how long a migration waits for the shared disks it attaches to be copied by the
+	// migration of another owner VM, and how long the copying migration waits for the other owner VMs to power off
+	SharedDiskCopyTimeout = 4 * time.Hour
+
+	// DeviceResizeTimeout is how long the helper VM may take to see the new size of an extended volume
+	DeviceResizeTimeout = 2 * time.Minute
 )
@@ -0,0 +1,81 @@
+// Package metrics exposes the Prometheus metrics of a migration run by the v2v-helper
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Phases of the migration whose duration is recorded
+const (
+	PhaseCopy           = "copy"
+	PhaseConvert        = "convert"
+	PhaseCreateInstance = "create_instance"
+)
+
+var (
+	diskBytesCopied = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "stellaris_migrate_disk_bytes_copied_total",
+		Help: "Bytes copied from the source disk to the target volume, including changed blocks.",
+	}, []string{"vm", "disk"})
+
+	diskCopyThroughput = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "stellaris_migrate_disk_copy_throughput_bytes_per_second",
+		Help: "Throughput of the last completed copy of the disk.",
+	}, []string{"vm", "disk"})
+
+	changedBlockIterations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "stellaris_migrate_changed_block_iterations_total",
+		Help: "Changed block copy iterations completed for the VM.",
+	}, []string{"vm"})
+
+	phaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "stellaris_migrate_phase_duration_seconds",
+		Help: "Duration of the copy, convert and create instance phases of the migration.",
+		// 30 seconds to about 17 hours
+		Buckets: prometheus.ExponentialBuckets(30, 2, 12),
+	}, []string{"vm", "phase"})
+)
+
+func init() {
+	prometheus.MustRegister(diskBytesCopied, diskCopyThroughput, changedBlockIterations, phaseDuration)
+}
+
+// ObserveDiskCopy records a copy of size bytes of the disk at idx that took duration
+func ObserveDiskCopy(vm string, idx int, size int64, duration time.Duration) {
+	disk := fmt.Sprint(idx)
+	diskBytesCopied.WithLabelValues(vm, disk).Add(float64(size))
+	if duration > 0 {
+		diskCopyThroughput.WithLabelValues(vm, disk).Set(float64(size) / duration.Seconds())
+	}
+}
+
+// IncChangedBlockIterations records a completed changed block copy iteration
+func IncChangedBlockIterations(vm string) {
+	changedBlockIterations.WithLabelValues(vm).Inc()
+}
+
+// ObservePhaseDuration records the duration of a phase of the migration
+func ObservePhaseDuration(vm, phase string, duration time.Duration) {
+	phaseDuration.WithLabelValues(vm, phase).Observe(duration.Seconds())
+}
+
+// Serve serves the metrics on addr in the background and returns the port it listens on. The
+// helper pods use the host network, so addr normally has port 0 to avoid clashes between pods.
+func Serve(addr string) (int, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return 0, fmt.Errorf("failed to listen on %s: %v", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		_ = http.Serve(listener, mux)
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserveDiskCopy(t *testing.T) {
+	ObserveDiskCopy("test-vm", 0, 1000, 2*time.Second)
+	ObserveDiskCopy("test-vm", 0, 500, 0)
+
+	var metric dto.Metric
+	assert.NoError(t, diskBytesCopied.WithLabelValues("test-vm", "0").Write(&metric))
+	assert.Equal(t, float64(1500), metric.GetCounter().GetValue())
+	// A copy without a duration leaves the throughput of the last copy
+	assert.NoError(t, diskCopyThroughput.WithLabelValues("test-vm", "0").Write(&metric))
+	assert.Equal(t, float64(500), metric.GetGauge().GetValue())
+}
+
+func TestObservePhaseDuration(t *testing.T) {
+	IncChangedBlockIterations("test-vm")
+	IncChangedBlockIterations("test-vm")
+	ObservePhaseDuration("test-vm", PhaseCopy, time.Minute)
+
+	var metric dto.Metric
+	assert.NoError(t, changedBlockIterations.WithLabelValues("test-vm").Write(&metric))
+	assert.Equal(t, float64(2), metric.GetCounter().GetValue())
+
+	histogram, err := phaseDuration.GetMetricWithLabelValues("test-vm", PhaseCopy)
+	assert.NoError(t, err)
+	assert.NoError(t, histogram.(prometheus.Metric).Write(&metric))
+	assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount())
+	assert.Equal(t, float64(60), metric.GetHistogram().GetSampleSum())
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"context"
+	"os"
+
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CountMigrationsOnNode returns the number of v2v-helper pods running on the node of this pod,
+// including this one
+func CountMigrationsOnNode(ctx context.Context, k8sClient client.Client) (int, error) {
+	nodeName := os.Getenv("NODE_NAME")
+	if nodeName == "" {
+		return 0, errors.New("NODE_NAME environment variable is not set")
+	}
+	pods := &corev1.PodList{}
+	err := k8sClient.List(ctx, pods, client.InNamespace(constants.NamespaceMigrationSystem), client.HasLabels{constants.VMNameLabel})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list migration pods")
+	}
+	count := 0
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == nodeName && pod.Status.Phase == corev1.PodRunning {
+			count++
+		}
+	}
+	if count == 0 {
+		// This pod is always running on the node
+		count = 1
+	}
+	return count, nil
+}
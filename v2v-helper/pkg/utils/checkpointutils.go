@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/pkg/constants"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ByteRange is a contiguous range of bytes on a disk
+type ByteRange struct {
+	Start  int64 `json:"start"`
+	Length int64 `json:"length"`
+}
+
+// DiskCheckpoint records how far the copy of a single disk has progressed
+type DiskCheckpoint struct {
+	// Name is the name of the source disk
+	Name string `json:"name"`
+	// VolumeID is the ID of the Cinder volume the disk is being copied to
+	VolumeID string `json:"volumeID"`
+	// FullCopyCompleted is true once the initial copy of the disk has finished
+	FullCopyCompleted bool `json:"fullCopyCompleted"`
+	// BaseChangeID is the CBT change ID of the snapshot the initial copy was started from
+	BaseChangeID string `json:"baseChangeID,omitempty"`
+	// ChangeID is the CBT change ID of the last snapshot fully synced to the volume
+	ChangeID string `json:"changeID,omitempty"`
+	// CompletedRanges are the byte ranges of an interrupted initial copy that are already on the volume
+	CompletedRanges []ByteRange `json:"completedRanges,omitempty"`
+}
+
+// CopyCheckpoint is the durable copy progress of a migration
+type CopyCheckpoint struct {
+	Disks []DiskCheckpoint `json:"disks"`
+}
+
+// GetDisk returns the checkpoint of the disk with the given name, or nil if there is none
+func (c *CopyCheckpoint) GetDisk(name string) *DiskCheckpoint {
+	for idx := range c.Disks {
+		if c.Disks[idx].Name == name {
+			return &c.Disks[idx]
+		}
+	}
+	return nil
+}
+
+// SetDisk adds or replaces the checkpoint of a disk
+func (c *CopyCheckpoint) SetDisk(disk DiskCheckpoint) {
+	if existing := c.GetDisk(disk.Name); existing != nil {
+		*existing = disk
+		return
+	}
+	c.Disks = append(c.Disks, disk)
+}
+
+// GetCopyCheckpointConfigMapName returns the name of the configmap holding the copy checkpoint
+func GetCopyCheckpointConfigMapName() (string, error) {
+	vmK8sName, err := GetVMwareMachineName()
+	if err != nil {
+		return "", err
+	}
+	return CopyCheckpointConfigMapName(vmK8sName), nil
+}
+
+// CopyCheckpointConfigMapName returns the name of the configmap holding the copy checkpoint of the migration of
+// the VMwareMachine with the given name
+func CopyCheckpointConfigMapName(vmK8sName string) string {
+	return fmt.Sprintf("migration-checkpoint-%s", vmK8sName)
+}
+
+// GetCopyCheckpoint returns the copy checkpoint of the migration, or an empty checkpoint if none was saved
+func GetCopyCheckpoint(ctx context.Context, k8sClient client.Client) (*CopyCheckpoint, error) {
+	checkpoint := &CopyCheckpoint{}
+	configMapName, err := GetCopyCheckpointConfigMapName()
+	if err != nil {
+		return nil, err
+	}
+	configMap := &corev1.ConfigMap{}
+	err = k8sClient.Get(ctx, types.NamespacedName{Name: configMapName, Namespace: constants.NamespaceMigrationSystem}, configMap)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return checkpoint, nil
+		}
+		return nil, errors.Wrap(err, "failed to get checkpoint configmap")
+	}
+	data := configMap.Data[constants.CopyCheckpointKey]
+	if data == "" {
+		return checkpoint, nil
+	}
+	if err := json.Unmarshal([]byte(data), checkpoint); err != nil {
+		return nil, errors.Wrap(err, "failed to parse copy checkpoint")
+	}
+	return checkpoint, nil
+}
+
+// SaveCopyCheckpoint writes the copy checkpoint of the migration. The configmap is owned by the
+// MigrationPlan so that it outlives the Migration when the migration is retried.
+func SaveCopyCheckpoint(ctx context.Context, k8sClient client.Client, checkpoint *CopyCheckpoint) error {
+	configMapName, err := GetCopyCheckpointConfigMapName()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal copy checkpoint")
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err = k8sClient.Get(ctx, types.NamespacedName{Name: configMapName, Namespace: constants.NamespaceMigrationSystem}, configMap)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "failed to get checkpoint configmap")
+		}
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      configMapName,
+				Namespace: constants.NamespaceMigrationSystem,
+			},
+			Data: map[string]string{constants.CopyCheckpointKey: string(data)},
+		}
+		if ownerRef, err := getMigrationPlanOwnerReference(ctx, k8sClient); err != nil {
+			PrintLog(fmt.Sprintf("Creating checkpoint configmap without owner: %v", err))
+		} else {
+			configMap.OwnerReferences = []metav1.OwnerReference{*ownerRef}
+		}
+		if err := k8sClient.Create(ctx, configMap); err != nil {
+			return errors.Wrap(err, "failed to create checkpoint configmap")
+		}
+		return nil
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[constants.CopyCheckpointKey] = string(data)
+	if err := k8sClient.Update(ctx, configMap); err != nil {
+		return errors.Wrap(err, "failed to update checkpoint configmap")
+	}
+	return nil
+}
+
+// DeleteCopyCheckpoint removes the copy checkpoint of the migration
+func DeleteCopyCheckpoint(ctx context.Context, k8sClient client.Client) error {
+	configMapName, err := GetCopyCheckpointConfigMapName()
+	if err != nil {
+		return err
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMapName,
+			Namespace: constants.NamespaceMigrationSystem,
+		},
+	}
+	if err := k8sClient.Delete(ctx, configMap); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete checkpoint configmap")
+	}
+	return nil
+}
+
+func getMigrationPlanOwnerReference(ctx context.Context, k8sClient client.Client) (*metav1.OwnerReference, error) {
+	migrationName, err := GetMigrationObjectName()
+	if err != nil {
+		return nil, err
+	}
+	migration := &migratev1alpha1.Migration{}
+	err = k8sClient.Get(ctx, types.NamespacedName{Name: migrationName, Namespace: constants.NamespaceMigrationSystem}, migration)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get migration")
+	}
+	migrationPlan := &migratev1alpha1.MigrationPlan{}
+	err = k8sClient.Get(ctx, types.NamespacedName{Name: migration.Spec.MigrationPlan, Namespace: constants.NamespaceMigrationSystem}, migrationPlan)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get migration plan")
+	}
+	return metav1.NewControllerRef(migrationPlan, migratev1alpha1.GroupVersion.WithKind("MigrationPlan")), nil
+}
@@ -21,15 +21,19 @@ import (
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
 	"github.com/gophercloud/gophercloud/openstack/blockstorage/extensions/volumeactions"
+	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/snapshots"
 	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/volumes"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/attachinterfaces"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/bootfromvolume"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/volumeattach"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
 	"github.com/gophercloud/gophercloud/openstack/identity/v3/projects"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/portsecurity"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
 )
 
 type OpenStackClients struct {
@@ -71,13 +75,14 @@ func GetCurrentInstanceUUID() (string, error) {
 }
 
 // create a new volume
-func (osclient *OpenStackClients) CreateVolume(name string, size int64, ostype string, uefi bool, volumetype string) (*volumes.Volume, error) {
+func (osclient *OpenStackClients) CreateVolume(name string, size int64, ostype string, uefi bool, volumetype string, multiattach bool) (*volumes.Volume, error) {
 	blockStorageClient := osclient.BlockStorageClient
 
 	opts := volumes.CreateOpts{
-		VolumeType: volumetype,
-		Size:       int(math.Ceil(float64(size) / (1024 * 1024 * 1024))),
-		Name:       name,
+		VolumeType:  volumetype,
+		Size:        int(math.Ceil(float64(size) / (1024 * 1024 * 1024))),
+		Name:        name,
+		Multiattach: multiattach,
 	}
 
 	// Add 1GB to the size to account for the extra space
@@ -97,6 +102,10 @@ func (osclient *OpenStackClients) CreateVolume(name string, size int64, ostype s
 		return nil, fmt.Errorf("failed to get volume: %s", err)
 	}
 	utils.PrintLog(fmt.Sprintf("Volume created successfully. current status %s", volume.Status))
+	// Multiattach is a property of the volume type with older Cinder versions, which ignore the request
+	if multiattach && !volume.Multiattach {
+		return nil, fmt.Errorf("volume %s is not multiattach, check that volume type %s allows it", volume.ID, volumetype)
+	}
 
 	if uefi {
 		err = osclient.SetVolumeUEFI(volume)
@@ -120,6 +129,15 @@ func (osclient *OpenStackClients) CreateVolume(name string, size int64, ostype s
 	return volume, nil
 }
 
+// GetVolume returns the volume with the given ID
+func (osclient *OpenStackClients) GetVolume(volumeID string) (*volumes.Volume, error) {
+	volume, err := volumes.Get(osclient.BlockStorageClient, volumeID).Extract()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volume: %s", err)
+	}
+	return volume, nil
+}
+
 func (osclient *OpenStackClients) DeleteVolume(volumeID string) error {
 	err := volumes.Delete(osclient.BlockStorageClient, volumeID, volumes.DeleteOpts{}).ExtractErr()
 	if err != nil {
@@ -248,6 +266,16 @@ func (osclient *OpenStackClients) DetachVolumeFromVM(volumeID string) error {
 	return nil
 }
 
+// DetachVolumeFromServer detaches a volume from a server other than the current instance,
+// for example a helper VM that ran an earlier attempt of the migration
+func (osclient *OpenStackClients) DetachVolumeFromServer(serverID, volumeID string) error {
+	err := volumeattach.Delete(osclient.ComputeClient, serverID, volumeID).ExtractErr()
+	if err != nil && !strings.Contains(err.Error(), "is not attached") {
+		return fmt.Errorf("failed to detach volume %s from server %s: %s", volumeID, serverID, err)
+	}
+	return osclient.WaitForVolume(volumeID)
+}
+
 func (osclient *OpenStackClients) EnableQGA(volume *volumes.Volume) error {
 	options := volumeactions.ImageMetadataOpts{
 		Metadata: map[string]string{
@@ -276,6 +304,21 @@ func (osclient *OpenStackClients) SetVolumeUEFI(volume *volumes.Volume) error {
 	return nil
 }
 
+// SetVolumeTPM requests an emulated TPM for the instance booted from the volume
+func (osclient *OpenStackClients) SetVolumeTPM(volume *volumes.Volume) error {
+	options := volumeactions.ImageMetadataOpts{
+		Metadata: map[string]string{
+			"hw_tpm_version": constants.TPMVersion,
+			"hw_tpm_model":   constants.TPMModel,
+		},
+	}
+	err := volumeactions.SetImageMetadata(osclient.BlockStorageClient, volume.ID, options).ExtractErr()
+	if err != nil {
+		return fmt.Errorf("failed to set volume image metadata hw_tpm_version and hw_tpm_model: %s", err)
+	}
+	return nil
+}
+
 func (osclient *OpenStackClients) SetVolumeImageMetadata(volume *volumes.Volume) error {
 	options := volumeactions.ImageMetadataOpts{
 		Metadata: map[string]string{
@@ -372,6 +415,15 @@ func (osclient *OpenStackClients) GetPort(portID string) (*ports.Port, error) {
 	return port, nil
 }
 
+// GetSubnet returns the subnet with the given ID
+func (osclient *OpenStackClients) GetSubnet(subnetID string) (*subnets.Subnet, error) {
+	subnet, err := subnets.Get(osclient.NetworkingClient, subnetID).Extract()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get subnet %s", subnetID)
+	}
+	return subnet, nil
+}
+
 func (osclient *OpenStackClients) CreatePort(network *networks.Network, mac, ip, vmname string, securityGroups []string) (*ports.Port, error) {
 	pages, err := ports.List(osclient.NetworkingClient, ports.ListOpts{
 		NetworkID:  network.ID,
@@ -434,6 +486,68 @@ func (osclient *OpenStackClients) CreatePort(network *networks.Network, mac, ip,
 	return port, nil
 }
 
+// CreatePortInSubnet creates a port named after name with an IP from the given subnet. An empty mac lets Neutron
+// generate one and an empty ip lets Neutron allocate one. A port of the same name that an earlier attempt to
+// migrate the VM left unattached is reused if its MAC and IP match and deleted otherwise, so that retries do not
+// leak ports, e.g. when the MAC is generated again. Unlike CreatePort it does not fall back to DHCP when the IP
+// cannot be assigned.
+func (osclient *OpenStackClients) CreatePortInSubnet(network *networks.Network, subnetID, mac, ip, name string, securityGroups []string) (*ports.Port, error) {
+	pages, err := ports.List(osclient.NetworkingClient, ports.ListOpts{
+		NetworkID: network.ID,
+		Name:      "port-" + name,
+	}).AllPages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ports: %s", err)
+	}
+	portList, err := ports.ExtractPorts(pages)
+	if err != nil {
+		return nil, err
+	}
+	for _, port := range portList {
+		// Attached ports belong to an instance
+		if port.DeviceID != "" || port.DeviceOwner != "" {
+			continue
+		}
+		if (mac == "" || strings.EqualFold(port.MACAddress, mac)) && portHasFixedIP(port, subnetID, ip) {
+			utils.PrintLog(fmt.Sprintf("Port in subnet %s already exists, ID: %s", subnetID, port.ID))
+			return &port, nil
+		}
+		utils.PrintLog(fmt.Sprintf("Deleting port %s left by an earlier attempt, its MAC %s or IP does not match", port.ID, port.MACAddress))
+		if err := ports.Delete(osclient.NetworkingClient, port.ID).ExtractErr(); err != nil {
+			return nil, errors.Wrapf(err, "failed to delete port %s", port.ID)
+		}
+	}
+
+	createOpts := ports.CreateOpts{
+		Name:           "port-" + name,
+		NetworkID:      network.ID,
+		MACAddress:     mac,
+		SecurityGroups: &securityGroups,
+		FixedIPs: []ports.IP{
+			{
+				SubnetID:  subnetID,
+				IPAddress: ip,
+			},
+		},
+	}
+	port, err := ports.Create(osclient.NetworkingClient, createOpts).Extract()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create port in subnet %s with IP '%s'", subnetID, ip)
+	}
+	utils.PrintLog(fmt.Sprintf("Port created in subnet %s with ID: %s", subnetID, port.ID))
+	return port, nil
+}
+
+// portHasFixedIP reports whether the port has an IP in the subnet, and that IP is ip if ip is set
+func portHasFixedIP(port ports.Port, subnetID, ip string) bool {
+	for _, fixedIP := range port.FixedIPs {
+		if fixedIP.SubnetID == subnetID && (ip == "" || fixedIP.IPAddress == ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func (osclient *OpenStackClients) CreateVM(flavor *flavors.Flavor, networkIDs, portIDs []string, vminfo vm.VMInfo, availabilityZone string, securityGroups []string, migrateSettings utils.VjailbreakSettings, useFlavorless bool) (*servers.Server, error) {
 	uuid := ""
 	bootableDiskIndex := 0
@@ -513,8 +627,15 @@ func (osclient *OpenStackClients) CreateVM(flavor *flavors.Flavor, networkIDs, p
 
 	utils.PrintLog(fmt.Sprintf("Server created with ID: %s, Attaching Additional Disks", server.ID))
 
+	// Multiattach volumes can only be attached with the compute API microversion 2.60
+	multiattachComputeClient := *osclient.ComputeClient
+	multiattachComputeClient.Microversion = "2.60"
 	for _, disk := range append(vminfo.VMDisks[:bootableDiskIndex], vminfo.VMDisks[bootableDiskIndex+1:]...) {
-		_, err := volumeattach.Create(osclient.ComputeClient, server.ID, volumeattach.CreateOpts{
+		computeClient := osclient.ComputeClient
+		if disk.Multiattach {
+			computeClient = &multiattachComputeClient
+		}
+		_, err := volumeattach.Create(computeClient, server.ID, volumeattach.CreateOpts{
 			VolumeID:            disk.OpenstackVol.ID,
 			DeleteOnTermination: false,
 		}).Extract()
@@ -531,6 +652,16 @@ func (osclient *OpenStackClients) CreateVM(flavor *flavors.Flavor, networkIDs, p
 			return nil, fmt.Errorf("failed to attach volume to VM: %s", err)
 		}
 	}
+	// Shared disks are attached to the instances of the other VMs sharing them as well
+	for _, disk := range vminfo.SharedDisks {
+		_, err := volumeattach.Create(&multiattachComputeClient, server.ID, volumeattach.CreateOpts{
+			VolumeID:            disk.VolumeID,
+			DeleteOnTermination: false,
+		}).Extract()
+		if err != nil {
+			return nil, fmt.Errorf("failed to attach shared disk %s to VM: %s", disk.DiskName, err)
+		}
+	}
 	return server, nil
 }
 
@@ -612,3 +743,174 @@ func (osclient *OpenStackClients) GetSecurityGroupIDs(groupNames []string, proje
 
 	return groupIDs, nil
 }
+
+// CreateVolumeSnapshot takes a snapshot of a volume, also while it is attached, and waits for it to become available
+func (osclient *OpenStackClients) CreateVolumeSnapshot(volumeID, name string) (*snapshots.Snapshot, error) {
+	snapshot, err := snapshots.Create(osclient.BlockStorageClient, snapshots.CreateOpts{
+		VolumeID: volumeID,
+		Name:     name,
+		Force:    true,
+	}).Extract()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create snapshot of volume %s", volumeID)
+	}
+	for i := 0; i < constants.MaxIntervalCount; i++ {
+		snapshot, err = snapshots.Get(osclient.BlockStorageClient, snapshot.ID).Extract()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get snapshot of volume %s", volumeID)
+		}
+		switch snapshot.Status {
+		case "available":
+			return snapshot, nil
+		case "error":
+			return snapshot, errors.Errorf("snapshot %s of volume %s is in error state", snapshot.ID, volumeID)
+		}
+		time.Sleep(5 * time.Second)
+	}
+	return snapshot, errors.Errorf("snapshot %s did not become available within %d seconds", snapshot.ID, constants.MaxIntervalCount*5)
+}
+
+// DeleteVolumeSnapshot deletes a snapshot of a volume
+func (osclient *OpenStackClients) DeleteVolumeSnapshot(snapshotID string) error {
+	err := snapshots.Delete(osclient.BlockStorageClient, snapshotID).ExtractErr()
+	if err != nil && !isNotFound(err) {
+		return errors.Wrapf(err, "failed to delete snapshot %s", snapshotID)
+	}
+	return nil
+}
+
+// CreateVolumeFromSnapshot creates a volume from a snapshot and waits for it to become available. The volume has the
+// type and the image metadata of the volume the snapshot was taken of.
+func (osclient *OpenStackClients) CreateVolumeFromSnapshot(name string, snapshot *snapshots.Snapshot) (*volumes.Volume, error) {
+	volume, err := volumes.Create(osclient.BlockStorageClient, volumes.CreateOpts{
+		Name:       name,
+		Size:       snapshot.Size,
+		SnapshotID: snapshot.ID,
+	}).Extract()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create volume from snapshot %s", snapshot.ID)
+	}
+	if err := osclient.WaitForVolume(volume.ID); err != nil {
+		return volume, errors.Wrapf(err, "failed to wait for volume %s", volume.ID)
+	}
+	return osclient.GetVolume(volume.ID)
+}
+
+// CreateIsolatedNetwork creates a network with a subnet of the given CIDR. The subnet has no gateway, so that no
+// router can connect the network, and port security is disabled on its ports.
+func (osclient *OpenStackClients) CreateIsolatedNetwork(name, cidr string) (*networks.Network, error) {
+	portSecurity := false
+	network, err := networks.Create(osclient.NetworkingClient, portsecurity.NetworkCreateOptsExt{
+		CreateOptsBuilder:   networks.CreateOpts{Name: name},
+		PortSecurityEnabled: &portSecurity,
+	}).Extract()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create network %s", name)
+	}
+	noGateway := ""
+	subnet, err := subnets.Create(osclient.NetworkingClient, subnets.CreateOpts{
+		NetworkID: network.ID,
+		Name:      name,
+		CIDR:      cidr,
+		IPVersion: gophercloud.IPv4,
+		GatewayIP: &noGateway,
+	}).Extract()
+	if err != nil {
+		return network, errors.Wrapf(err, "failed to create subnet %s of network %s", cidr, name)
+	}
+	network.Subnets = []string{subnet.ID}
+	return network, nil
+}
+
+// DeleteNetwork deletes a network with its subnets
+func (osclient *OpenStackClients) DeleteNetwork(networkID string) error {
+	err := networks.Delete(osclient.NetworkingClient, networkID).ExtractErr()
+	if err != nil && !isNotFound(err) {
+		return errors.Wrapf(err, "failed to delete network %s", networkID)
+	}
+	return nil
+}
+
+// DeletePort deletes a port
+func (osclient *OpenStackClients) DeletePort(portID string) error {
+	err := ports.Delete(osclient.NetworkingClient, portID).ExtractErr()
+	if err != nil && !isNotFound(err) {
+		return errors.Wrapf(err, "failed to delete port %s", portID)
+	}
+	return nil
+}
+
+// AttachPortToVM attaches a port to the current instance
+func (osclient *OpenStackClients) AttachPortToVM(portID string) error {
+	instanceID, err := GetCurrentInstanceUUID()
+	if err != nil {
+		return fmt.Errorf("failed to get instance ID: %s", err)
+	}
+	_, err = attachinterfaces.Create(osclient.ComputeClient, instanceID, attachinterfaces.CreateOpts{PortID: portID}).Extract()
+	if err != nil {
+		return errors.Wrapf(err, "failed to attach port %s to VM", portID)
+	}
+	return nil
+}
+
+// DetachPortFromVM detaches a port from the current instance
+func (osclient *OpenStackClients) DetachPortFromVM(portID string) error {
+	instanceID, err := GetCurrentInstanceUUID()
+	if err != nil {
+		return fmt.Errorf("failed to get instance ID: %s", err)
+	}
+	err = attachinterfaces.Delete(osclient.ComputeClient, instanceID, portID).ExtractErr()
+	if err != nil && !isNotFound(err) {
+		return errors.Wrapf(err, "failed to detach port %s from VM", portID)
+	}
+	return nil
+}
+
+// DeleteServer deletes a server and waits until it is gone, so that its volumes can be deleted
+func (osclient *OpenStackClients) DeleteServer(serverID string) error {
+	err := servers.Delete(osclient.ComputeClient, serverID).ExtractErr()
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to delete server %s", serverID)
+	}
+	for i := 0; i < constants.MaxIntervalCount; i++ {
+		if _, err := servers.Get(osclient.ComputeClient, serverID).Extract(); isNotFound(err) {
+			return nil
+		}
+		time.Sleep(5 * time.Second)
+	}
+	return errors.Errorf("server %s was not deleted within %d seconds", serverID, constants.MaxIntervalCount*5)
+}
+
+// ExtendVolume extends a volume to size GB and waits until it is extended. Attached volumes are extended online,
+// which needs the Cinder API microversion 3.42.
+func (osclient *OpenStackClients) ExtendVolume(volumeID string, size int) error {
+	blockStorageClient := *osclient.BlockStorageClient
+	blockStorageClient.Microversion = "3.42"
+	err := volumeactions.ExtendSize(&blockStorageClient, volumeID, volumeactions.ExtendSizeOpts{NewSize: size}).ExtractErr()
+	if err != nil {
+		return errors.Wrapf(err, "failed to extend volume %s", volumeID)
+	}
+	for i := 0; i < constants.MaxIntervalCount; i++ {
+		volume, err := volumes.Get(osclient.BlockStorageClient, volumeID).Extract()
+		if err != nil {
+			return errors.Wrapf(err, "failed to get volume %s", volumeID)
+		}
+		if volume.Status == "error_extending" {
+			return errors.Errorf("volume %s could not be extended", volumeID)
+		}
+		if volume.Size >= size && volume.Status != "extending" {
+			return nil
+		}
+		time.Sleep(5 * time.Second)
+	}
+	return errors.Errorf("volume %s was not extended within %d seconds", volumeID, constants.MaxIntervalCount*5)
+}
+
+// isNotFound reports whether an OpenStack API call failed because the resource does not exist
+func isNotFound(err error) bool {
+	var notFound gophercloud.ErrDefault404
+	return errors.As(err, &notFound)
+}
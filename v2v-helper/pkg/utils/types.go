@@ -8,4 +8,6 @@ type VjailbreakSettings struct {
 	VCenterScanConcurrencyLimit         int
 	CleanupVolumesAfterConvertFailure   bool
 	PopulateVMwareMachineFlavors        bool
+	DiskCopyConcurrencyLimit            int
+	NodeBandwidthLimitMbps              int
 }
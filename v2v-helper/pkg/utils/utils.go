@@ -131,6 +131,8 @@ func GetMigrateSettings(ctx context.Context, k8sClient client.Client) (*Vjailbre
 			VCenterScanConcurrencyLimit:         constants.VCenterScanConcurrencyLimit,
 			CleanupVolumesAfterConvertFailure:   constants.CleanupVolumesAfterConvertFailure,
 			PopulateVMwareMachineFlavors:        constants.PopulateVMwareMachineFlavors,
+			DiskCopyConcurrencyLimit:            constants.DiskCopyConcurrencyLimit,
+			NodeBandwidthLimitMbps:              constants.NodeBandwidthLimitMbps,
 		}, nil
 	}
 
@@ -162,6 +164,15 @@ func GetMigrateSettings(ctx context.Context, k8sClient client.Client) (*Vjailbre
 		vjailbreakSettingsCM.Data["POPULATE_VMWARE_MACHINE_FLAVORS"] = strconv.FormatBool(constants.PopulateVMwareMachineFlavors)
 	}
 
+	if vjailbreakSettingsCM.Data["NODE_BANDWIDTH_LIMIT_MBPS"] == "" {
+		vjailbreakSettingsCM.Data["NODE_BANDWIDTH_LIMIT_MBPS"] = strconv.Itoa(constants.NodeBandwidthLimitMbps)
+	}
+
+	diskCopyConcurrencyLimit := atoi(vjailbreakSettingsCM.Data["DISK_COPY_CONCURRENCY_LIMIT"])
+	if diskCopyConcurrencyLimit < 1 {
+		diskCopyConcurrencyLimit = constants.DiskCopyConcurrencyLimit
+	}
+
 	return &VjailbreakSettings{
 		ChangedBlocksCopyIterationThreshold: atoi(vjailbreakSettingsCM.Data["CHANGED_BLOCKS_COPY_ITERATION_THRESHOLD"]),
 		VMActiveWaitIntervalSeconds:         atoi(vjailbreakSettingsCM.Data["VM_ACTIVE_WAIT_INTERVAL_SECONDS"]),
@@ -170,5 +181,25 @@ func GetMigrateSettings(ctx context.Context, k8sClient client.Client) (*Vjailbre
 		VCenterScanConcurrencyLimit:         atoi(vjailbreakSettingsCM.Data["VCENTER_SCAN_CONCURRENCY_LIMIT"]),
 		CleanupVolumesAfterConvertFailure:   vjailbreakSettingsCM.Data["CLEANUP_VOLUMES_AFTER_CONVERT_FAILURE"] == "true",
 		PopulateVMwareMachineFlavors:        vjailbreakSettingsCM.Data["POPULATE_VMWARE_MACHINE_FLAVORS"] == "true",
+		DiskCopyConcurrencyLimit:            diskCopyConcurrencyLimit,
+		NodeBandwidthLimitMbps:              atoi(vjailbreakSettingsCM.Data["NODE_BANDWIDTH_LIMIT_MBPS"]),
 	}, nil
 }
+
+// SaveMigrationTargetResources records the OpenStack resources created for the VM on its Migration object
+func SaveMigrationTargetResources(ctx context.Context, k8sClient client.Client, resources *migratev1alpha1.MigrationTargetResources) error {
+	migrationName, err := GetMigrationObjectName()
+	if err != nil {
+		return err
+	}
+	migration := &migratev1alpha1.Migration{}
+	if err := k8sClient.Get(ctx, k8stypes.NamespacedName{Name: migrationName, Namespace: constants.NamespaceMigrationSystem}, migration); err != nil {
+		return errors.Wrap(err, "failed to get migration")
+	}
+	patch := client.MergeFrom(migration.DeepCopy())
+	migration.Status.TargetResources = resources
+	if err := k8sClient.Status().Patch(ctx, migration, patch); err != nil {
+		return errors.Wrap(err, "failed to patch migration status")
+	}
+	return nil
+}
@@ -37,6 +37,27 @@ type MigrationParams struct {
 	VMwareMachineName       string
 	DisconnectSourceNetwork bool
 	SecurityGroups          string
+	BandwidthLimitMbps      int
+	CopyWindowStart         string
+	CopyWindowEnd           string
+	CopyWindowTimeZone      string
+	CutoverWindowStart      string
+	CutoverWindowEnd        string
+	CutoverWindowTimeZone   string
+	CutoverWindowDays       string
+	CutoverFinalize         string
+	DataVerification        string
+	VTPMPolicy              string
+	NetworkOverrides        string
+	ReconfigureGuestNetwork bool
+	Hooks                   string
+	TestBoot                bool
+	TestBootCIDR            string
+	TestBootTimeout         string
+	SnapshotConsistency     string
+	QuiesceTimeout          string
+	DiskOverride            string
+	SharedDisks             string
 }
 
 // GetMigrationParams is function that returns the migration parameters
@@ -75,5 +96,26 @@ func GetMigrationParams(ctx context.Context, client client.Client) (*MigrationPa
 		VMwareMachineName:       string(configMap.Data["VMWARE_MACHINE_OBJECT_NAME"]),
 		DisconnectSourceNetwork: string(configMap.Data["DISCONNECT_SOURCE_NETWORK"]) == constants.TrueString,
 		SecurityGroups:          string(configMap.Data["SECURITY_GROUPS"]),
+		BandwidthLimitMbps:      atoi(configMap.Data["BANDWIDTH_LIMIT_MBPS"]),
+		CopyWindowStart:         string(configMap.Data["COPY_WINDOW_START"]),
+		CopyWindowEnd:           string(configMap.Data["COPY_WINDOW_END"]),
+		CopyWindowTimeZone:      string(configMap.Data["COPY_WINDOW_TIMEZONE"]),
+		CutoverWindowStart:      string(configMap.Data["CUTOVER_WINDOW_START"]),
+		CutoverWindowEnd:        string(configMap.Data["CUTOVER_WINDOW_END"]),
+		CutoverWindowTimeZone:   string(configMap.Data["CUTOVER_WINDOW_TIMEZONE"]),
+		CutoverWindowDays:       string(configMap.Data["CUTOVER_WINDOW_DAYS"]),
+		CutoverFinalize:         string(configMap.Data["CUTOVER_FINALIZE_DURATION"]),
+		DataVerification:        string(configMap.Data["DATA_VERIFICATION"]),
+		VTPMPolicy:              string(configMap.Data["VTPM_POLICY"]),
+		NetworkOverrides:        string(configMap.Data["NETWORK_OVERRIDES"]),
+		ReconfigureGuestNetwork: string(configMap.Data["RECONFIGURE_GUEST_NETWORK"]) == constants.TrueString,
+		Hooks:                   string(configMap.Data["HOOKS"]),
+		TestBoot:                string(configMap.Data["TEST_BOOT"]) == constants.TrueString,
+		TestBootCIDR:            string(configMap.Data["TEST_BOOT_CIDR"]),
+		TestBootTimeout:         string(configMap.Data["TEST_BOOT_TIMEOUT"]),
+		SnapshotConsistency:     string(configMap.Data["SNAPSHOT_CONSISTENCY"]),
+		QuiesceTimeout:          string(configMap.Data["QUIESCE_TIMEOUT"]),
+		DiskOverride:            string(configMap.Data["DISK_OVERRIDES"]),
+		SharedDisks:             string(configMap.Data["SHARED_DISKS"]),
 	}, nil
 }
@@ -2,6 +2,7 @@ package reporter
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"os"
@@ -23,6 +24,7 @@ type ReporterOps interface {
 	CreateKubernetesEvent(ctx context.Context, eventType, reason, message string) error
 	UpdatePodEvents(ch <-chan string)
 	GetCutoverLabel() (string, error)
+	SetPodAnnotation(key, value string) error
 	WatchPodLabels(ctx context.Context, ch chan<- string) error
 }
 
@@ -219,6 +221,23 @@ func (r *Reporter) UpdatePodEvents(ctx context.Context, ch <-chan string, ackCha
 	}()
 }
 
+// SetPodAnnotation sets an annotation on the pod of the reporter
+func (r *Reporter) SetPodAnnotation(key, value string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{key: value},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build pod patch: %v", err)
+	}
+	_, err = r.Clientset.CoreV1().Pods(r.PodNamespace).Patch(context.TODO(), r.PodName, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to set pod annotation %s: %v", key, err)
+	}
+	return nil
+}
+
 func (r *Reporter) GetCutoverLabel() (string, error) {
 	if err := r.GetPod(); err != nil {
 		return "", fmt.Errorf("failed to get pod: %v", err)
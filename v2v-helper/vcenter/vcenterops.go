@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/url"
+	"path"
 	"strings"
 
 	"github.com/vmware/govmomi/find"
@@ -14,6 +15,7 @@ import (
 	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/session/cache"
 	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
@@ -208,3 +210,81 @@ func (vcclient *VCenterClient) MoveVMFolder(ctx context.Context, vmName, folderN
 
 	return nil
 }
+
+// GetVMFolderPath returns the inventory path of the folder of a VM
+func (vcclient *VCenterClient) GetVMFolderPath(ctx context.Context, vmName string) (string, error) {
+	vm, err := vcclient.GetVMByName(ctx, vmName)
+	if err != nil {
+		return "", fmt.Errorf("failed to find VM '%s': %v", vmName, err)
+	}
+	return path.Dir(vm.InventoryPath), nil
+}
+
+// ConnectNetworkInterfaces connects all network interfaces of a VM and sets them to connect at power on
+func (vcclient *VCenterClient) ConnectNetworkInterfaces(ctx context.Context, vmName string) error {
+	vm, err := vcclient.GetVMByName(ctx, vmName)
+	if err != nil {
+		return fmt.Errorf("failed to find VM '%s': %v", vmName, err)
+	}
+
+	var mvm mo.VirtualMachine
+	if err := vm.Properties(ctx, vm.Reference(), []string{"config.hardware", "runtime.powerState"}, &mvm); err != nil {
+		return fmt.Errorf("failed to get properties of VM '%s': %v", vmName, err)
+	}
+	if mvm.Config == nil {
+		return nil
+	}
+	var deviceChanges []types.BaseVirtualDeviceConfigSpec
+	for _, device := range mvm.Config.Hardware.Device {
+		nic, ok := device.(types.BaseVirtualEthernetCard)
+		if !ok {
+			continue
+		}
+		connectable := nic.GetVirtualEthernetCard().Connectable
+		if connectable == nil {
+			continue
+		}
+		// A powered off VM cannot connect its NICs, they connect when it powers on
+		connectable.Connected = mvm.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOn
+		connectable.StartConnected = true
+		deviceChanges = append(deviceChanges, &types.VirtualDeviceConfigSpec{
+			Operation: types.VirtualDeviceConfigSpecOperationEdit,
+			Device:    device,
+		})
+	}
+	if len(deviceChanges) == 0 {
+		return nil
+	}
+
+	task, err := vm.Reconfigure(ctx, types.VirtualMachineConfigSpec{DeviceChange: deviceChanges})
+	if err != nil {
+		return fmt.Errorf("failed to reconfigure network interfaces of VM '%s': %v", vmName, err)
+	}
+	if err := task.Wait(ctx); err != nil {
+		return fmt.Errorf("failed to connect network interfaces of VM '%s': %v", vmName, err)
+	}
+	return nil
+}
+
+// PowerOnVM powers on a VM if it is not powered on
+func (vcclient *VCenterClient) PowerOnVM(ctx context.Context, vmName string) error {
+	vm, err := vcclient.GetVMByName(ctx, vmName)
+	if err != nil {
+		return fmt.Errorf("failed to find VM '%s': %v", vmName, err)
+	}
+	state, err := vm.PowerState(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get power state of VM '%s': %v", vmName, err)
+	}
+	if state == types.VirtualMachinePowerStatePoweredOn {
+		return nil
+	}
+	task, err := vm.PowerOn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to power on VM '%s': %v", vmName, err)
+	}
+	if err := task.Wait(ctx); err != nil {
+		return fmt.Errorf("failed to power on VM '%s': %v", vmName, err)
+	}
+	return nil
+}
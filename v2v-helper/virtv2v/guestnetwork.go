@@ -0,0 +1,422 @@
+package virtv2v
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vm"
+)
+
+// GuestNICConfig is the network configuration written into the guest for a NIC
+type GuestNICConfig struct {
+	// MAC is the MAC address of the NIC on the target
+	MAC string
+	// SourceMAC is the MAC address of the NIC on the source VM, which the existing configuration of the guest uses
+	SourceMAC string
+	// DHCP configures the NIC with DHCP. The address, gateway and DNS are not used.
+	DHCP         bool
+	Address      string
+	PrefixLength int
+	Gateway      string
+	DNS          []string
+}
+
+const (
+	guestNetworkFilePrefix = "stellaris-migrate"
+	netplanDir             = "/etc/netplan"
+	nmConnectionsDir       = "/etc/NetworkManager/system-connections"
+	ifcfgDir               = "/etc/sysconfig/network-scripts"
+	wickedDir              = "/etc/sysconfig/network"
+	cloudInitConfigDir     = "/etc/cloud/cloud.cfg.d"
+	udevRulesFile          = "/etc/udev/rules.d/70-persistent-net.rules"
+)
+
+// RenderNetplan renders a netplan configuration matching each NIC by its MAC address
+func RenderNetplan(nics []GuestNICConfig) string {
+	var b strings.Builder
+	b.WriteString("# Written by stellaris-migrate\nnetwork:\n  version: 2\n  ethernets:\n")
+	for i, nic := range nics {
+		fmt.Fprintf(&b, "    nic%d:\n", i)
+		fmt.Fprintf(&b, "      match:\n        macaddress: \"%s\"\n", strings.ToLower(nic.MAC))
+		if nic.DHCP {
+			b.WriteString("      dhcp4: true\n")
+			continue
+		}
+		b.WriteString("      dhcp4: false\n")
+		fmt.Fprintf(&b, "      addresses:\n        - %s/%d\n", nic.Address, nic.PrefixLength)
+		if nic.Gateway != "" {
+			// gateway4 is understood by every netplan version, routes with "to: default" only by recent ones
+			fmt.Fprintf(&b, "      gateway4: %s\n", nic.Gateway)
+		}
+		if len(nic.DNS) > 0 {
+			fmt.Fprintf(&b, "      nameservers:\n        addresses: [%s]\n", strings.Join(nic.DNS, ", "))
+		}
+	}
+	return b.String()
+}
+
+// RenderNMKeyfile renders a NetworkManager keyfile connection for a NIC. The connection has a
+// high autoconnect priority so that it wins over the connections copied from the source VM.
+func RenderNMKeyfile(nic GuestNICConfig, id string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[connection]\nid=%s\ntype=ethernet\nautoconnect=true\nautoconnect-priority=100\n\n", id)
+	fmt.Fprintf(&b, "[ethernet]\nmac-address=%s\n\n", strings.ToUpper(nic.MAC))
+	if nic.DHCP {
+		b.WriteString("[ipv4]\nmethod=auto\n\n")
+	} else {
+		b.WriteString("[ipv4]\nmethod=manual\n")
+		if nic.Gateway != "" {
+			fmt.Fprintf(&b, "address1=%s/%d,%s\n", nic.Address, nic.PrefixLength, nic.Gateway)
+		} else {
+			fmt.Fprintf(&b, "address1=%s/%d\n", nic.Address, nic.PrefixLength)
+		}
+		if len(nic.DNS) > 0 {
+			fmt.Fprintf(&b, "dns=%s;\n", strings.Join(nic.DNS, ";"))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("[ipv6]\nmethod=auto\n")
+	return b.String()
+}
+
+// RenderIfcfg renders a RHEL network-scripts ifcfg file for a NIC
+func RenderIfcfg(nic GuestNICConfig, device string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "DEVICE=%s\nHWADDR=%s\nTYPE=Ethernet\nONBOOT=yes\n", device, strings.ToLower(nic.MAC))
+	if nic.DHCP {
+		b.WriteString("BOOTPROTO=dhcp\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "BOOTPROTO=none\nIPADDR=%s\nPREFIX=%d\n", nic.Address, nic.PrefixLength)
+	if nic.Gateway != "" {
+		fmt.Fprintf(&b, "GATEWAY=%s\n", nic.Gateway)
+	}
+	for i, dns := range nic.DNS {
+		fmt.Fprintf(&b, "DNS%d=%s\n", i+1, dns)
+	}
+	return b.String()
+}
+
+// RenderWicked renders the SUSE wicked ifcfg and ifroute files for a NIC. The ifroute file is
+// empty if the NIC has no gateway.
+func RenderWicked(nic GuestNICConfig, device string) (ifcfg, ifroute string) {
+	if nic.DHCP {
+		return "STARTMODE='auto'\nBOOTPROTO='dhcp'\n", ""
+	}
+	ifcfg = fmt.Sprintf("STARTMODE='auto'\nBOOTPROTO='static'\nIPADDR='%s/%d'\n", nic.Address, nic.PrefixLength)
+	if nic.Gateway != "" {
+		ifroute = fmt.Sprintf("default %s - %s\n", nic.Gateway, device)
+	}
+	return ifcfg, ifroute
+}
+
+// RenderWindowsNetworkScript renders a firstboot batch script that configures each adapter,
+// found by its MAC address once the virtio driver has brought it up
+func RenderWindowsNetworkScript(nics []GuestNICConfig) string {
+	var b strings.Builder
+	b.WriteString("@echo off\r\n")
+	for _, nic := range nics {
+		mac := strings.ToUpper(strings.ReplaceAll(nic.MAC, ":", "-"))
+		cmds := []string{
+			fmt.Sprintf("$mac='%s'", mac),
+			"for ($i=0; $i -lt 60; $i++) { $a = Get-NetAdapter | Where-Object { $_.MacAddress -eq $mac }; if ($a) { break }; Start-Sleep -Seconds 5 }",
+			// The command is passed in double quotes, the strings in it are single quoted
+			"if (-not $a) { Write-Output ('adapter ' + $mac + ' not found'); exit 1 }",
+		}
+		if nic.DHCP {
+			cmds = append(cmds,
+				"Set-NetIPInterface -InterfaceIndex $a.ifIndex -Dhcp Enabled",
+				"Set-DnsClientServerAddress -InterfaceIndex $a.ifIndex -ResetServerAddresses")
+		} else {
+			newAddress := fmt.Sprintf("New-NetIPAddress -InterfaceIndex $a.ifIndex -IPAddress '%s' -PrefixLength %d", nic.Address, nic.PrefixLength)
+			if nic.Gateway != "" {
+				newAddress += fmt.Sprintf(" -DefaultGateway '%s'", nic.Gateway)
+			}
+			cmds = append(cmds,
+				"Set-NetIPInterface -InterfaceIndex $a.ifIndex -Dhcp Disabled",
+				"Get-NetIPAddress -InterfaceIndex $a.ifIndex -AddressFamily IPv4 -ErrorAction SilentlyContinue | Remove-NetIPAddress -Confirm:$false",
+				"Get-NetRoute -InterfaceIndex $a.ifIndex -DestinationPrefix '0.0.0.0/0' -ErrorAction SilentlyContinue | Remove-NetRoute -Confirm:$false",
+				newAddress)
+			if len(nic.DNS) > 0 {
+				cmds = append(cmds, fmt.Sprintf("Set-DnsClientServerAddress -InterfaceIndex $a.ifIndex -ServerAddresses '%s'", strings.Join(nic.DNS, "','")))
+			}
+		}
+		fmt.Fprintf(&b, "powershell.exe -NoProfile -ExecutionPolicy Bypass -Command \"%s\" >> C:\\stellaris-migrate-network.log 2>&1\r\n", strings.Join(cmds, "; "))
+	}
+	return b.String()
+}
+
+// ReconfigureGuestNetwork writes the network configuration of each NIC into a Linux guest. The
+// format follows the network stack of the guest: wicked on SUSE, netplan, then network-scripts
+// ifcfg files, then NetworkManager keyfiles. Interfaces configured by name are pinned to their
+// MAC address with udev rules.
+func ReconfigureGuestNetwork(disks []vm.VMDisk, useSingleDisk bool, diskPath, osRelease string, nics []GuestNICConfig) error {
+	os.Setenv("LIBGUESTFS_BACKEND", "direct")
+	return reconfigureGuestNetwork(guestFiles{disks: disks, useSingleDisk: useSingleDisk, diskPath: diskPath}, osRelease, nics)
+}
+
+func reconfigureGuestNetwork(guest guestFS, osRelease string, nics []GuestNICConfig) error {
+	macs := make([]string, len(nics))
+	for i, nic := range nics {
+		macs[i] = strings.ToLower(nic.MAC)
+	}
+
+	if guest.isDir(cloudInitConfigDir) {
+		// Keep cloud-init from replacing the configuration with its own on first boot
+		if err := guest.upload(fmt.Sprintf("%s/99-%s-network.cfg", cloudInitConfigDir, guestNetworkFilePrefix), "network: {config: disabled}\n"); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case strings.Contains(osRelease, "suse"):
+		log.Println("Writing wicked network configuration")
+		devices := interfaceNames(guest, wickedDir, nics)
+		for i, nic := range nics {
+			ifcfg, ifroute := RenderWicked(nic, devices[i])
+			if err := guest.upload(fmt.Sprintf("%s/ifcfg-%s", wickedDir, devices[i]), ifcfg); err != nil {
+				return err
+			}
+			if ifroute != "" {
+				if err := guest.upload(fmt.Sprintf("%s/ifroute-%s", wickedDir, devices[i]), ifroute); err != nil {
+					return err
+				}
+			}
+		}
+		if dns := guestDNS(nics); len(dns) > 0 {
+			config, err := guest.cat(wickedDir + "/config")
+			if err != nil {
+				return err
+			}
+			config = setSysconfigValue(config, "NETCONFIG_DNS_STATIC_SERVERS", strings.Join(dns, " "))
+			if err := guest.upload(wickedDir+"/config", config); err != nil {
+				return err
+			}
+		}
+		return guest.upload(udevRulesFile, RenderUdevRules(devices, macs))
+	case guest.isDir(netplanDir):
+		log.Println("Writing netplan network configuration")
+		// The configuration of the source VM is kept as a backup, netplan only reads .yaml files
+		for _, file := range guest.ls(netplanDir) {
+			if strings.HasSuffix(file, ".yaml") && !strings.Contains(file, guestNetworkFilePrefix) {
+				if _, err := guest.run(true, "mv", path.Join(netplanDir, file), path.Join(netplanDir, file+"."+guestNetworkFilePrefix+".bak")); err != nil {
+					return err
+				}
+			}
+		}
+		netplanFile := fmt.Sprintf("%s/99-%s.yaml", netplanDir, guestNetworkFilePrefix)
+		if err := guest.upload(netplanFile, RenderNetplan(nics)); err != nil {
+			return err
+		}
+		_, err := guest.run(true, "chmod", "0600", netplanFile)
+		return err
+	case len(ifcfgInterfaces(guest)) > 0:
+		log.Println("Writing ifcfg network configuration")
+		devices := interfaceNames(guest, ifcfgDir, nics)
+		for i, nic := range nics {
+			if err := guest.upload(fmt.Sprintf("%s/ifcfg-%s", ifcfgDir, devices[i]), RenderIfcfg(nic, devices[i])); err != nil {
+				return err
+			}
+		}
+		return guest.upload(udevRulesFile, RenderUdevRules(devices, macs))
+	case guest.isDir(nmConnectionsDir):
+		log.Println("Writing NetworkManager keyfile network configuration")
+		for i, nic := range nics {
+			id := fmt.Sprintf("%s-nic%d", guestNetworkFilePrefix, i)
+			keyfile := fmt.Sprintf("%s/%s.nmconnection", nmConnectionsDir, id)
+			if err := guest.upload(keyfile, RenderNMKeyfile(nic, id)); err != nil {
+				return err
+			}
+			// NetworkManager ignores keyfiles readable by other users
+			if _, err := guest.run(true, "chmod", "0600", keyfile); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("no supported network configuration found in the guest")
+}
+
+// RenderUdevRules renders the udev rules naming each interface after the NIC with the MAC address at the same index
+func RenderUdevRules(interfaces, macs []string) string {
+	var b strings.Builder
+	for i, iface := range interfaces {
+		fmt.Fprintf(&b, "SUBSYSTEM==\"net\", ACTION==\"add\", ATTR{address}==\"%s\", NAME=\"%s\"\n", macs[i], iface)
+	}
+	return b.String()
+}
+
+// guestDNS returns the DNS servers of all NICs without duplicates
+func guestDNS(nics []GuestNICConfig) []string {
+	var dns []string
+	for _, nic := range nics {
+		for _, server := range nic.DNS {
+			if !strings.Contains(" "+strings.Join(dns, " ")+" ", " "+server+" ") {
+				dns = append(dns, server)
+			}
+		}
+	}
+	return dns
+}
+
+// setSysconfigValue sets key to value in a sysconfig file, adding the key if it is missing
+func setSysconfigValue(content, key, value string) string {
+	line := fmt.Sprintf("%s=\"%s\"", key, value)
+	re := regexp.MustCompile(fmt.Sprintf(`(?m)^%s=.*$`, regexp.QuoteMeta(key)))
+	if re.MatchString(content) {
+		return re.ReplaceAllLiteralString(content, line)
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + line + "\n"
+}
+
+// guestFS reads and writes the files of a guest
+type guestFS interface {
+	run(write bool, command string, args ...string) (string, error)
+	isDir(dir string) bool
+	ls(dir string) []string
+	cat(file string) (string, error)
+	upload(guestPath, content string) error
+}
+
+// guestFiles reads and writes files in the guest disks with guestfish
+type guestFiles struct {
+	disks         []vm.VMDisk
+	useSingleDisk bool
+	diskPath      string
+}
+
+func (g guestFiles) run(write bool, command string, args ...string) (string, error) {
+	if g.useSingleDisk {
+		return RunCommandInGuest(g.diskPath, strings.Join(append([]string{command}, args...), " "), write)
+	}
+	return RunCommandInGuestAllVolumes(g.disks, command, write, args...)
+}
+
+func (g guestFiles) isDir(dir string) bool {
+	out, err := g.run(false, "is-dir", dir)
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+func (g guestFiles) ls(dir string) []string {
+	out, err := g.run(false, "ls", dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, file := range strings.Split(strings.TrimSpace(out), "\n") {
+		if file = strings.TrimSpace(file); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+func (g guestFiles) cat(file string) (string, error) {
+	// The run helpers lower case their output, which would change the values in the file
+	disks := g.disks
+	if g.useSingleDisk {
+		disks = []vm.VMDisk{{Path: g.diskPath}}
+	}
+	out, err := prepareGuestfishCommand(disks, "cat", false, file).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", file, err)
+	}
+	return string(out), nil
+}
+
+// upload writes content to a file in the guest
+func (g guestFiles) upload(guestPath, content string) error {
+	localPath := path.Join("/home/fedora", path.Base(guestPath))
+	if err := os.WriteFile(localPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to create %s: %s", localPath, err)
+	}
+	log.Printf("Uploading %s to the guest", guestPath)
+	if out, err := g.run(true, "upload", localPath, guestPath); err != nil {
+		return fmt.Errorf("failed to upload %s: %v: %s", guestPath, err, strings.TrimSpace(out))
+	}
+	return nil
+}
+
+// ifcfgInterfaces returns the interfaces with a network-scripts ifcfg file in the guest
+func ifcfgInterfaces(guest guestFS) []string {
+	if !guest.isDir(ifcfgDir) {
+		return nil
+	}
+	var interfaces []string
+	for _, file := range guest.ls(ifcfgDir) {
+		if strings.HasPrefix(file, "ifcfg-") && file != "ifcfg-lo" {
+			interfaces = append(interfaces, strings.TrimPrefix(file, "ifcfg-"))
+		}
+	}
+	return interfaces
+}
+
+// ifcfgMACPattern matches the MAC address set in an ifcfg file, by HWADDR and MACADDR on RHEL and LLADDR on SUSE
+var ifcfgMACPattern = regexp.MustCompile(`(?m)^\s*(?:HWADDR|MACADDR|LLADDR)\s*=\s*["']?([0-9A-Fa-f:]+)["']?\s*$`)
+
+// interfaceNames returns the name of the interface of each NIC, taken from the ifcfg file in dir that has the
+// source or target MAC address of the NIC
+func interfaceNames(guest guestFS, dir string, nics []GuestNICConfig) []string {
+	macs := map[string]string{}
+	for _, file := range guest.ls(dir) {
+		if !strings.HasPrefix(file, "ifcfg-") || file == "ifcfg-lo" || strings.Contains(file, ".") {
+			continue
+		}
+		content, err := guest.cat(path.Join(dir, file))
+		if err != nil {
+			log.Printf("Failed to read %s: %v", file, err)
+			continue
+		}
+		if match := ifcfgMACPattern.FindStringSubmatch(content); match != nil {
+			macs[strings.TrimPrefix(file, "ifcfg-")] = strings.ToLower(match[1])
+		}
+	}
+	return matchInterfaceNames(macs, nics)
+}
+
+// matchInterfaceNames returns the name of the interface of each NIC from the MAC addresses of the configured
+// interfaces. The interfaces are pinned to the NICs by udev rules, so a NIC without a configured interface gets
+// the first kernel name eth0, eth1, ... that is neither used nor configured for another MAC address.
+func matchInterfaceNames(macs map[string]string, nics []GuestNICConfig) []string {
+	interfaces := make([]string, 0, len(macs))
+	for iface := range macs {
+		interfaces = append(interfaces, iface)
+	}
+	slices.Sort(interfaces)
+
+	names := make([]string, len(nics))
+	used := map[string]bool{}
+	for i, nic := range nics {
+		for _, iface := range interfaces {
+			mac := macs[iface]
+			if !used[iface] && (mac == strings.ToLower(nic.SourceMAC) || mac == strings.ToLower(nic.MAC)) {
+				names[i] = iface
+				used[iface] = true
+				break
+			}
+		}
+	}
+	next := 0
+	for i := range names {
+		if names[i] != "" {
+			continue
+		}
+		for names[i] == "" {
+			name := fmt.Sprintf("eth%d", next)
+			next++
+			if !used[name] && macs[name] == "" {
+				names[i] = name
+				used[name] = true
+			}
+		}
+	}
+	return names
+}
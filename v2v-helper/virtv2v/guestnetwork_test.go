@@ -0,0 +1,213 @@
+package virtv2v
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// assertGolden compares got with the golden file testdata/<name>.golden
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	golden := filepath.Join("testdata", name+".golden")
+	if *update {
+		assert.NoError(t, os.WriteFile(golden, []byte(got), 0644))
+	}
+	want, err := os.ReadFile(golden)
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), got)
+}
+
+// fakeGuest is a guest whose files are kept in memory
+type fakeGuest struct {
+	files map[string]string
+	dirs  map[string]bool
+	modes map[string]string
+}
+
+func newFakeGuest(dirs []string, files map[string]string) *fakeGuest {
+	guest := &fakeGuest{files: files, dirs: map[string]bool{}, modes: map[string]string{}}
+	for _, dir := range dirs {
+		guest.dirs[dir] = true
+	}
+	return guest
+}
+
+func (g *fakeGuest) run(write bool, command string, args ...string) (string, error) {
+	switch command {
+	case "mv":
+		g.files[args[1]] = g.files[args[0]]
+		delete(g.files, args[0])
+	case "chmod":
+		g.modes[args[1]] = args[0]
+	default:
+		return "", fmt.Errorf("unexpected command %s", command)
+	}
+	return "", nil
+}
+
+func (g *fakeGuest) isDir(dir string) bool {
+	return g.dirs[dir]
+}
+
+func (g *fakeGuest) ls(dir string) []string {
+	var files []string
+	for file := range g.files {
+		if path.Dir(file) == dir {
+			files = append(files, path.Base(file))
+		}
+	}
+	slices.Sort(files)
+	return files
+}
+
+func (g *fakeGuest) cat(file string) (string, error) {
+	content, ok := g.files[file]
+	if !ok {
+		return "", fmt.Errorf("failed to read %s", file)
+	}
+	return content, nil
+}
+
+func (g *fakeGuest) upload(guestPath, content string) error {
+	g.files[guestPath] = content
+	return nil
+}
+
+// dump returns the files of the guest in order
+func (g *fakeGuest) dump() string {
+	var b strings.Builder
+	files := make([]string, 0, len(g.files))
+	for file := range g.files {
+		files = append(files, file)
+	}
+	slices.Sort(files)
+	for _, file := range files {
+		fmt.Fprintf(&b, "==> %s", file)
+		if mode, ok := g.modes[file]; ok {
+			fmt.Fprintf(&b, " (%s)", mode)
+		}
+		fmt.Fprintf(&b, " <==\n%s\n", g.files[file])
+	}
+	return b.String()
+}
+
+var testNICs = []GuestNICConfig{
+	{MAC: "FA:16:3E:00:00:01", SourceMAC: "00:50:56:00:00:01", Address: "192.168.1.10", PrefixLength: 24, Gateway: "192.168.1.1", DNS: []string{"192.168.1.2", "192.168.1.3"}},
+	{MAC: "00:50:56:00:00:02", SourceMAC: "00:50:56:00:00:02", DHCP: true},
+	{MAC: "00:50:56:00:00:03", SourceMAC: "00:50:56:00:00:03", Address: "10.0.0.10", PrefixLength: 16, DNS: []string{"192.168.1.2"}},
+}
+
+func TestRenderNetplan(t *testing.T) {
+	assertGolden(t, "netplan", RenderNetplan(testNICs))
+}
+
+func TestRenderNMKeyfile(t *testing.T) {
+	var b strings.Builder
+	for i, nic := range testNICs {
+		fmt.Fprintf(&b, "==> nic%d <==\n%s\n", i, RenderNMKeyfile(nic, fmt.Sprintf("stellaris-migrate-nic%d", i)))
+	}
+	assertGolden(t, "nmkeyfile", b.String())
+}
+
+func TestRenderIfcfg(t *testing.T) {
+	var b strings.Builder
+	for i, nic := range testNICs {
+		fmt.Fprintf(&b, "==> eth%d <==\n%s\n", i, RenderIfcfg(nic, fmt.Sprintf("eth%d", i)))
+	}
+	assertGolden(t, "ifcfg", b.String())
+}
+
+func TestRenderWicked(t *testing.T) {
+	var b strings.Builder
+	for i, nic := range testNICs {
+		ifcfg, ifroute := RenderWicked(nic, fmt.Sprintf("eth%d", i))
+		fmt.Fprintf(&b, "==> ifcfg-eth%d <==\n%s\n==> ifroute-eth%d <==\n%s\n", i, ifcfg, i, ifroute)
+	}
+	assertGolden(t, "wicked", b.String())
+}
+
+func TestRenderWindowsNetworkScript(t *testing.T) {
+	script := RenderWindowsNetworkScript(testNICs)
+	// The batch script has CRLF line endings, the golden file is checked in with LF
+	assert.Equal(t, strings.Count(script, "\n"), strings.Count(script, "\r\n"))
+	assertGolden(t, "windows", strings.ReplaceAll(script, "\r\n", "\n"))
+}
+
+func TestReconfigureGuestNetwork(t *testing.T) {
+	tests := []struct {
+		name      string
+		osRelease string
+		dirs      []string
+		files     map[string]string
+	}{
+		{
+			name:      "reconfigure-netplan",
+			osRelease: `id="ubuntu"`,
+			dirs:      []string{cloudInitConfigDir, netplanDir},
+			files: map[string]string{
+				netplanDir + "/50-cloud-init.yaml": "network:\n  version: 2\n",
+			},
+		},
+		{
+			// The interfaces are found by the MAC addresses of their ifcfg files, not by the order of the files
+			name:      "reconfigure-ifcfg",
+			osRelease: `id="rhel"`,
+			dirs:      []string{ifcfgDir, nmConnectionsDir},
+			files: map[string]string{
+				ifcfgDir + "/ifcfg-ens192":    "DEVICE=ens192\nHWADDR=00:50:56:00:00:02\nBOOTPROTO=dhcp\n",
+				ifcfgDir + "/ifcfg-eth10":     "DEVICE=eth10\nHWADDR=\"00:50:56:00:00:01\"\nBOOTPROTO=none\n",
+				ifcfgDir + "/ifcfg-eth2":      "DEVICE=eth2\nBOOTPROTO=dhcp\n",
+				ifcfgDir + "/ifcfg-lo":        "DEVICE=lo\n",
+				ifcfgDir + "/ifcfg-eth10.bak": "DEVICE=eth10\n",
+			},
+		},
+		{
+			name:      "reconfigure-wicked",
+			osRelease: `id="sles"` + "\n" + `id_like="suse"`,
+			dirs:      []string{ifcfgDir, wickedDir},
+			files: map[string]string{
+				wickedDir + "/config":     "NETCONFIG_DNS_POLICY=\"auto\"\nNETCONFIG_DNS_STATIC_SERVERS=\"\"\n",
+				wickedDir + "/ifcfg-eth0": "STARTMODE='auto'\nBOOTPROTO='dhcp'\nLLADDR='00:50:56:00:00:03'\n",
+				wickedDir + "/ifcfg-eth1": "STARTMODE='auto'\nBOOTPROTO='dhcp'\n",
+			},
+		},
+		{
+			name:      "reconfigure-nmkeyfile",
+			osRelease: `id="rhel"`,
+			dirs:      []string{nmConnectionsDir},
+			files:     map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guest := newFakeGuest(tt.dirs, tt.files)
+			assert.NoError(t, reconfigureGuestNetwork(guest, tt.osRelease, testNICs))
+			assertGolden(t, tt.name, guest.dump())
+		})
+	}
+
+	assert.Error(t, reconfigureGuestNetwork(newFakeGuest(nil, map[string]string{}), `id="debian"`, testNICs))
+}
+
+func TestMatchInterfaceNames(t *testing.T) {
+	macs := map[string]string{
+		"eth10":  "00:50:56:00:00:01",
+		"ens192": "00:50:56:00:00:02",
+		"eth0":   "00:50:56:00:00:09",
+	}
+	// Alphabetically eth10 would come before eth2 and ens192 before both
+	assert.Equal(t, []string{"eth10", "ens192", "eth1"}, matchInterfaceNames(macs, testNICs))
+	// The target MAC is matched as well
+	assert.Equal(t, []string{"eth0"}, matchInterfaceNames(map[string]string{"eth0": "fa:16:3e:00:00:01"}, testNICs[:1]))
+	assert.Equal(t, []string{"eth0", "eth1", "eth2"}, matchInterfaceNames(nil, testNICs))
+}
@@ -424,13 +424,10 @@ func AddUdevRules(disks []vm.VMDisk, useSingleDisk bool, diskPath string, interf
 	var ans string
 
 	// Create the udev rules content
-	var udevRules strings.Builder
-	for i, iface := range interfaces {
-		udevRules.WriteString(fmt.Sprintf("SUBSYSTEM==\"net\", ACTION==\"add\", ATTR{address}==\"%s\", NAME=\"%s\"\n", macs[i], iface))
-		log.Printf("Adding udev rule: %s", udevRules.String())
-	}
+	udevRules := RenderUdevRules(interfaces, macs)
+	log.Printf("Adding udev rules: %s", udevRules)
 
-	err := os.WriteFile("/home/fedora/70-persistent-net.rules", []byte(udevRules.String()), 0644)
+	err := os.WriteFile("/home/fedora/70-persistent-net.rules", []byte(udevRules), 0644)
 	if err != nil {
 		return fmt.Errorf("failed to create udev rules file: %s", err)
 	}
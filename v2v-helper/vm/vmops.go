@@ -35,6 +35,7 @@ type VMOperations interface {
 	IsCBTEnabled() (bool, error)
 	EnableCBT() error
 	TakeSnapshot(name string) error
+	TakeQuiescedSnapshot(name string, timeout time.Duration) error
 	DeleteSnapshot(name string) error
 	DeleteSnapshotByRef(snap *types.ManagedObjectReference) error
 	GetSnapshot(name string) (*types.ManagedObjectReference, error)
@@ -63,6 +64,10 @@ type VMInfo struct {
 	GuestNetworks     []migratev1alpha1.GuestNetwork
 	NetworkInterfaces []migratev1alpha1.NIC
 	RDMDisks          []RDMDisk
+	SharedDisks       []SharedDisk
+	VTPM              bool
+	Encrypted         bool
+	BootDisk          string
 }
 
 type NIC struct {
@@ -96,6 +101,7 @@ type VMDisk struct {
 	SnapBackingDisk string
 	ChangeID        string
 	Boot            bool
+	Multiattach     bool
 }
 
 type VMOps struct {
@@ -105,6 +111,17 @@ type VMOps struct {
 	k8sClient k8sclient.Client
 }
 
+// SharedDisk is a disk the VM shares with other VMs in multi-writer mode, copied by the migration of another of
+// them. Its copy is attached to the instance of the VM.
+type SharedDisk struct {
+	// DiskName is the label of the disk on the VM
+	DiskName string
+	// SharedDisk is the name of the SharedDisk of the disk
+	SharedDisk string
+	// VolumeID is the multiattach Cinder volume the disk was copied into
+	VolumeID string
+}
+
 type RDMDisk struct {
 	// DiskName is the name of the disk
 	DiskName string `json:"diskName,omitempty"`
@@ -248,11 +265,130 @@ func (vmops *VMOps) GetVMInfo(ostype string) (VMInfo, error) {
 		UEFI:              uefi,
 		OSType:            ostype,
 		NetworkInterfaces: vmwareMachine.Spec.VMInfo.NetworkInterfaces,
-		GuestNetworks:     vmwareMachine.Spec.VMInfo.GuestNetworks,
+		GuestNetworks:     guestNetworksWithGateways(vmwareMachine.Spec.VMInfo.GuestNetworks, o.Guest),
+		VTPM:              HasVTPM(o.Config),
+		Encrypted:         IsEncrypted(o.Config),
+		BootDisk:          BootDisk(o.Config),
 	}
 	return vminfo, nil
 }
 
+// guestNetworksWithGateways fills the default gateways missing from the guest networks of the VMware machine, which
+// are only recorded by scans of the VM since gateways were added, with the ones VMware Tools currently reports
+func guestNetworksWithGateways(guestNetworks []migratev1alpha1.GuestNetwork, guest *types.GuestInfo) []migratev1alpha1.GuestNetwork {
+	if guest == nil {
+		return guestNetworks
+	}
+	// The gateways are reported by the index of the NIC in guest.net
+	gateways := map[string]string{}
+	for _, ipStack := range guest.IpStack {
+		if ipStack.IpRouteConfig == nil {
+			continue
+		}
+		for _, route := range ipStack.IpRouteConfig.IpRoute {
+			if route.Network != "0.0.0.0" || route.PrefixLength != 0 || route.Gateway.IpAddress == "" {
+				continue
+			}
+			var idx int
+			if _, err := fmt.Sscan(route.Gateway.Device, &idx); err != nil || idx < 0 || idx >= len(guest.Net) {
+				continue
+			}
+			mac := strings.ToLower(guest.Net[idx].MacAddress)
+			if _, found := gateways[mac]; !found {
+				gateways[mac] = route.Gateway.IpAddress
+			}
+		}
+	}
+	result := make([]migratev1alpha1.GuestNetwork, len(guestNetworks))
+	for i, guestNetwork := range guestNetworks {
+		if guestNetwork.Gateway == "" && !strings.Contains(guestNetwork.IP, ":") {
+			guestNetwork.Gateway = gateways[strings.ToLower(guestNetwork.MAC)]
+		}
+		result[i] = guestNetwork
+	}
+	return result
+}
+
+// BootDisk returns the label of the disk the firmware of the VM boots from, the first disk in the boot order or
+// the first disk of the VM if the boot order has none
+func BootDisk(config *types.VirtualMachineConfigInfo) string {
+	if config == nil {
+		return ""
+	}
+	labels := map[int32]string{}
+	var first string
+	for _, device := range config.Hardware.Device {
+		if disk, ok := device.(*types.VirtualDisk); ok {
+			labels[disk.Key] = disk.DeviceInfo.GetDescription().Label
+			if first == "" {
+				first = labels[disk.Key]
+			}
+		}
+	}
+	if config.BootOptions != nil {
+		for _, bootable := range config.BootOptions.BootOrder {
+			if disk, ok := bootable.(*types.VirtualMachineBootOptionsBootableDiskDevice); ok && labels[disk.DeviceKey] != "" {
+				return labels[disk.DeviceKey]
+			}
+		}
+	}
+	return first
+}
+
+// IsIndependentNonPersistent returns true if the changes to the disk are discarded when the VM powers off
+func IsIndependentNonPersistent(disk *types.VirtualDisk) bool {
+	var mode string
+	switch backing := disk.Backing.(type) {
+	case *types.VirtualDiskFlatVer2BackingInfo:
+		mode = backing.DiskMode
+	case *types.VirtualDiskSparseVer2BackingInfo:
+		mode = backing.DiskMode
+	case *types.VirtualDiskSeSparseBackingInfo:
+		mode = backing.DiskMode
+	}
+	return mode == string(types.VirtualDiskModeIndependent_nonpersistent)
+}
+
+// HasVTPM returns true if the VM has a virtual TPM
+func HasVTPM(config *types.VirtualMachineConfigInfo) bool {
+	if config == nil {
+		return false
+	}
+	for _, device := range config.Hardware.Device {
+		if _, ok := device.(*types.VirtualTPM); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEncrypted returns true if the VM or any of its disks is encrypted
+func IsEncrypted(config *types.VirtualMachineConfigInfo) bool {
+	if config == nil {
+		return false
+	}
+	if config.KeyId != nil {
+		return true
+	}
+	for _, device := range config.Hardware.Device {
+		disk, ok := device.(*types.VirtualDisk)
+		if !ok {
+			continue
+		}
+		switch backing := disk.Backing.(type) {
+		case *types.VirtualDiskFlatVer2BackingInfo:
+			if backing.KeyId != nil {
+				return true
+			}
+		case *types.VirtualDiskSeSparseBackingInfo:
+			if backing.KeyId != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func parseChangeID(changeId string) (*ChangeID, error) {
 	changeIdParts := strings.Split(changeId, "/")
 	if len(changeIdParts) != 2 {
@@ -286,11 +422,19 @@ func getChangeID(disk *types.VirtualDisk) (*ChangeID, error) {
 	return parseChangeID(changeId)
 }
 
+// snapshotDisks returns the disks of a snapshot by their device key
+func snapshotDisks(snapshot *mo.VirtualMachineSnapshot) map[int32]*types.VirtualDisk {
+	disks := map[int32]*types.VirtualDisk{}
+	for _, device := range snapshot.Config.Hardware.Device {
+		if disk, ok := device.(*types.VirtualDisk); ok {
+			disks[disk.Key] = disk
+		}
+	}
+	return disks
+}
+
 func (vmops *VMOps) UpdateDisksInfo(vminfo *VMInfo) error {
 	pc := vmops.vcclient.VCPropertyCollector
-	var snapbackingdisk []string
-	var snapname []string
-	var snapid []string
 
 	vm := vmops.VMObj
 
@@ -319,24 +463,20 @@ func (vmops *VMOps) UpdateDisksInfo(vminfo *VMInfo) error {
 			return fmt.Errorf("failed to get snapshot properties: %s", err)
 		}
 
-		for _, device := range s.Config.Hardware.Device {
-			switch disk := device.(type) {
-			case *types.VirtualDisk:
-				backing := disk.Backing.(types.BaseVirtualDeviceFileBackingInfo)
-				info := backing.GetVirtualDeviceFileBackingInfo()
-				snapbackingdisk = append(snapbackingdisk, info.FileName)
-				snapname = append(snapname, o.Snapshot.CurrentSnapshot.Value)
-				changeid, err := getChangeID(disk)
-				if err != nil {
-					return fmt.Errorf("failed to get change ID: %s", err)
-				}
-				snapid = append(snapid, changeid.Value)
-			}
-		}
+		snapdisks := snapshotDisks(&s)
 		for idx := range vminfo.VMDisks {
-			vminfo.VMDisks[idx].SnapBackingDisk = snapbackingdisk[idx]
-			vminfo.VMDisks[idx].Snapname = snapname[idx]
-			vminfo.VMDisks[idx].ChangeID = snapid[idx]
+			disk, ok := snapdisks[vminfo.VMDisks[idx].Disk.Key]
+			if !ok {
+				return fmt.Errorf("disk %s not found in snapshot", vminfo.VMDisks[idx].Name)
+			}
+			changeid, err := getChangeID(disk)
+			if err != nil {
+				return fmt.Errorf("failed to get change ID: %s", err)
+			}
+			info := disk.Backing.(types.BaseVirtualDeviceFileBackingInfo).GetVirtualDeviceFileBackingInfo()
+			vminfo.VMDisks[idx].SnapBackingDisk = info.FileName
+			vminfo.VMDisks[idx].Snapname = o.Snapshot.CurrentSnapshot.Value
+			vminfo.VMDisks[idx].ChangeID = changeid.Value
 		}
 		// Based on VMName and diskname fetch DiskInfo
 		rdmDIskInfo, err := GetVMwareMachine(vmops.ctx, vmops.k8sClient, vminfo.Name)
@@ -351,9 +491,6 @@ func (vmops *VMOps) UpdateDisksInfo(vminfo *VMInfo) error {
 
 func (vmops *VMOps) UpdateDiskInfo(vminfo *VMInfo, disk VMDisk, blockCopySuccess bool) error {
 	pc := vmops.vcclient.VCPropertyCollector
-	var snapbackingdisk []string
-	var snapname []string
-	var snapid []string
 
 	vm := vmops.VMObj
 
@@ -382,33 +519,36 @@ func (vmops *VMOps) UpdateDiskInfo(vminfo *VMInfo, disk VMDisk, blockCopySuccess
 			return fmt.Errorf("failed to get snapshot properties: %s", err)
 		}
 
-		for _, device := range s.Config.Hardware.Device {
-			switch disk := device.(type) {
-			case *types.VirtualDisk:
-				backing := disk.Backing.(types.BaseVirtualDeviceFileBackingInfo)
-				info := backing.GetVirtualDeviceFileBackingInfo()
-				snapbackingdisk = append(snapbackingdisk, info.FileName)
-				snapname = append(snapname, o.Snapshot.CurrentSnapshot.Value)
-				changeid, err := getChangeID(disk)
-				if err != nil {
-					return fmt.Errorf("failed to get change ID: %s", err)
-				}
-				snapid = append(snapid, changeid.Value)
+		snapdisks := snapshotDisks(&s)
+		for idx := range vminfo.VMDisks {
+			if vminfo.VMDisks[idx].Name != disk.Name {
+				continue
 			}
-		}
-		for idx, _ := range vminfo.VMDisks {
-			if vminfo.VMDisks[idx].Name == disk.Name {
-				if blockCopySuccess {
-					vminfo.VMDisks[idx].ChangeID = snapid[idx]
-				}
-				vminfo.VMDisks[idx].SnapBackingDisk = snapbackingdisk[idx]
-				vminfo.VMDisks[idx].Snapname = snapname[idx]
-				log.Printf("Updated disk info for %s", disk.Name)
-				log.Printf("Snapshot backing disk: %s", snapbackingdisk[idx])
-				log.Printf("Snapshot name: %s", snapname[idx])
-				log.Printf("Change ID: %s", snapid[idx])
-				break
+			snapdisk, ok := snapdisks[vminfo.VMDisks[idx].Disk.Key]
+			if !ok {
+				return fmt.Errorf("disk %s not found in snapshot", disk.Name)
+			}
+			changeid, err := getChangeID(snapdisk)
+			if err != nil {
+				return fmt.Errorf("failed to get change ID: %s", err)
+			}
+			info := snapdisk.Backing.(types.BaseVirtualDeviceFileBackingInfo).GetVirtualDeviceFileBackingInfo()
+			if blockCopySuccess {
+				vminfo.VMDisks[idx].ChangeID = changeid.Value
+			}
+			// The disk may have been extended since the last snapshot
+			if capacity := snapdisk.CapacityInBytes; capacity != vminfo.VMDisks[idx].Size {
+				log.Printf("Capacity of disk %s changed from %d to %d bytes", disk.Name, vminfo.VMDisks[idx].Size, capacity)
+				vminfo.VMDisks[idx].Size = capacity
+				vminfo.VMDisks[idx].Disk = snapdisk
 			}
+			vminfo.VMDisks[idx].SnapBackingDisk = info.FileName
+			vminfo.VMDisks[idx].Snapname = o.Snapshot.CurrentSnapshot.Value
+			log.Printf("Updated disk info for %s", disk.Name)
+			log.Printf("Snapshot backing disk: %s", info.FileName)
+			log.Printf("Snapshot name: %s", o.Snapshot.CurrentSnapshot.Value)
+			log.Printf("Change ID: %s", changeid.Value)
+			break
 		}
 	}
 
@@ -488,6 +628,45 @@ func (vmops *VMOps) TakeSnapshot(name string) error {
 	return nil
 }
 
+// TakeQuiescedSnapshot takes a snapshot after VMware Tools quiesced the file systems and applications of the guest.
+// If the snapshot does not complete within timeout its task is cancelled.
+func (vmops *VMOps) TakeQuiescedSnapshot(name string, timeout time.Duration) error {
+	vm := vmops.VMObj
+
+	task, err := vm.CreateSnapshot(vmops.ctx, name, "", false, true)
+	if err != nil {
+		if !strings.Contains(err.Error(), "NotAuthenticated") {
+			return fmt.Errorf("failed to take quiesced snapshot: %s", err)
+		}
+		if err := vmops.RefreshVM(); err != nil {
+			return fmt.Errorf("failed to refresh VM reference: %s", err)
+		}
+		vm = vmops.VMObj
+		task, err = vm.CreateSnapshot(vmops.ctx, name, "", false, true)
+		if err != nil {
+			return fmt.Errorf("failed to take quiesced snapshot: %s", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(vmops.ctx, timeout)
+	defer cancel()
+	err = task.Wait(ctx)
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		return fmt.Errorf("failed while waiting for task: %s", err)
+	}
+	// The task may complete while it is cancelled, the snapshot is only missing if it did not
+	if err := task.Cancel(vmops.ctx); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to cancel quiesced snapshot task: %v", err))
+	}
+	if err := task.Wait(vmops.ctx); err == nil {
+		return nil
+	}
+	return fmt.Errorf("quiesced snapshot timed out after %s", timeout)
+}
+
 func (vmops *VMOps) DeleteSnapshot(name string) error {
 	vm := vmops.VMObj
 
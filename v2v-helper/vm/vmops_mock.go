@@ -6,6 +6,7 @@ package vm
 
 import (
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	object "github.com/vmware/govmomi/object"
@@ -208,6 +209,20 @@ func (mr *MockVMOperationsMockRecorder) ListSnapshots() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSnapshots", reflect.TypeOf((*MockVMOperations)(nil).ListSnapshots))
 }
 
+// TakeQuiescedSnapshot mocks base method.
+func (m *MockVMOperations) TakeQuiescedSnapshot(name string, timeout time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeQuiescedSnapshot", name, timeout)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TakeQuiescedSnapshot indicates an expected call of TakeQuiescedSnapshot.
+func (mr *MockVMOperationsMockRecorder) TakeQuiescedSnapshot(name, timeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeQuiescedSnapshot", reflect.TypeOf((*MockVMOperations)(nil).TakeQuiescedSnapshot), name, timeout)
+}
+
 // TakeSnapshot mocks base method.
 func (m *MockVMOperations) TakeSnapshot(name string) error {
 	m.ctrl.T.Helper()
@@ -7,6 +7,7 @@ import (
 	"os"
 	"testing"
 
+	migratev1alpha1 "github.com/kashyapshashankv/stellaris-migrate/k8s/migration/api/v1alpha1"
 	"github.com/kashyapshashankv/stellaris-migrate/v2v-helper/vcenter"
 	"github.com/stretchr/testify/assert"
 	"github.com/vmware/govmomi"
@@ -132,6 +133,55 @@ func TestGetVMInfo(t *testing.T) {
 	assert.Equal(t, expectedVMInfo, vminfo)
 }
 
+func TestHasVTPMAndIsEncrypted(t *testing.T) {
+	disk := &types.VirtualDisk{VirtualDevice: types.VirtualDevice{Backing: &types.VirtualDiskFlatVer2BackingInfo{}}}
+	config := &types.VirtualMachineConfigInfo{Hardware: types.VirtualHardware{Device: []types.BaseVirtualDevice{disk}}}
+	assert.False(t, HasVTPM(config))
+	assert.False(t, IsEncrypted(config))
+
+	config.Hardware.Device = append(config.Hardware.Device, &types.VirtualTPM{})
+	assert.True(t, HasVTPM(config))
+
+	disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo).KeyId = &types.CryptoKeyId{KeyId: "key"}
+	assert.True(t, IsEncrypted(config))
+
+	assert.False(t, HasVTPM(nil))
+	assert.False(t, IsEncrypted(nil))
+}
+
+func TestGuestNetworksWithGateways(t *testing.T) {
+	guestNetworks := []migratev1alpha1.GuestNetwork{
+		{MAC: "00:50:56:00:00:01", IP: "192.168.1.10", PrefixLength: 24},
+		{MAC: "00:50:56:00:00:01", IP: "fe80::1", PrefixLength: 64},
+		{MAC: "00:50:56:00:00:02", IP: "192.168.2.10", PrefixLength: 24, Gateway: "192.168.2.254"},
+		{MAC: "00:50:56:00:00:03", IP: "192.168.3.10", PrefixLength: 24},
+	}
+	guest := &types.GuestInfo{
+		Net: []types.GuestNicInfo{
+			{MacAddress: "00:50:56:00:00:03"},
+			{MacAddress: "00:50:56:00:00:01"},
+			{MacAddress: "00:50:56:00:00:02"},
+		},
+		IpStack: []types.GuestStackInfo{{IpRouteConfig: &types.NetIpRouteConfigInfo{IpRoute: []types.NetIpRouteConfigInfoIpRoute{
+			{Network: "192.168.1.0", PrefixLength: 24, Gateway: types.NetIpRouteConfigInfoGateway{Device: "1"}},
+			{Network: "0.0.0.0", PrefixLength: 0, Gateway: types.NetIpRouteConfigInfoGateway{IpAddress: "192.168.1.1", Device: "1"}},
+			{Network: "0.0.0.0", PrefixLength: 0, Gateway: types.NetIpRouteConfigInfoGateway{IpAddress: "192.168.2.1", Device: "2"}},
+		}}}},
+	}
+
+	networks := guestNetworksWithGateways(guestNetworks, guest)
+	assert.Equal(t, "192.168.1.1", networks[0].Gateway)
+	// IPv6 addresses do not get the IPv4 gateway
+	assert.Empty(t, networks[1].Gateway)
+	// A gateway recorded by the scan is kept
+	assert.Equal(t, "192.168.2.254", networks[2].Gateway)
+	assert.Empty(t, networks[3].Gateway)
+	// The guest networks of the VMware machine are not changed
+	assert.Empty(t, guestNetworks[0].Gateway)
+
+	assert.Equal(t, guestNetworks, guestNetworksWithGateways(guestNetworks, nil))
+}
+
 func TestEnableCBT(t *testing.T) {
 	simVC, model, server, err := simulateVCenter()
 	defer cleanupSimulator(model, server)